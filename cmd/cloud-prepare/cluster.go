@@ -0,0 +1,170 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// cluster connects to the cluster the first time one of its clients is used, so that the commands and providers which
+// only call the cloud APIs don't need a reachable cluster.
+type cluster struct {
+	connect func() (k8s.Interface, ocp.MachineSetDeployer, error)
+
+	once       sync.Once
+	k8sClient  k8s.Interface
+	msDeployer ocp.MachineSetDeployer
+	err        error
+}
+
+func (c *cluster) clients() (k8s.Interface, ocp.MachineSetDeployer, error) {
+	c.once.Do(func() {
+		c.k8sClient, c.msDeployer, c.err = c.connect()
+	})
+
+	return c.k8sClient, c.msDeployer, c.err
+}
+
+// lazyK8sClient is a k8s.Interface which connects to the cluster on its first call.
+type lazyK8sClient struct {
+	cluster *cluster
+}
+
+func (l lazyK8sClient) client() (k8s.Interface, error) {
+	client, _, err := l.cluster.clients()
+	return client, err
+}
+
+func (l lazyK8sClient) ListNodesWithLabel(ctx context.Context, labelSelector string) (*v1.NodeList, error) {
+	client, err := l.client()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.ListNodesWithLabel(ctx, labelSelector) // nolint:wrapcheck // No need to wrap here
+}
+
+func (l lazyK8sClient) ListGatewayNodes(ctx context.Context) (*v1.NodeList, error) {
+	client, err := l.client()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.ListGatewayNodes(ctx) // nolint:wrapcheck // No need to wrap here
+}
+
+func (l lazyK8sClient) AddGWLabelOnNode(ctx context.Context, nodeName string) error {
+	client, err := l.client()
+	if err != nil {
+		return err
+	}
+
+	return client.AddGWLabelOnNode(ctx, nodeName) // nolint:wrapcheck // No need to wrap here
+}
+
+func (l lazyK8sClient) RemoveGWLabelFromWorkerNodes(ctx context.Context) error {
+	client, err := l.client()
+	if err != nil {
+		return err
+	}
+
+	return client.RemoveGWLabelFromWorkerNodes(ctx) // nolint:wrapcheck // No need to wrap here
+}
+
+func (l lazyK8sClient) RemoveGWLabelFromWorkerNode(ctx context.Context, node *v1.Node) error {
+	client, err := l.client()
+	if err != nil {
+		return err
+	}
+
+	return client.RemoveGWLabelFromWorkerNode(ctx, node) // nolint:wrapcheck // No need to wrap here
+}
+
+func (l lazyK8sClient) GetIPFamilies(ctx context.Context) ([]api.IPFamily, error) {
+	client, err := l.client()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetIPFamilies(ctx) // nolint:wrapcheck // No need to wrap here
+}
+
+func (l lazyK8sClient) GetActiveGatewayNode(ctx context.Context) (string, error) {
+	client, err := l.client()
+	if err != nil {
+		return "", err
+	}
+
+	return client.GetActiveGatewayNode(ctx) // nolint:wrapcheck // No need to wrap here
+}
+
+// lazyMachineSetDeployer is an ocp.MachineSetDeployer which connects to the cluster on its first call.
+type lazyMachineSetDeployer struct {
+	cluster *cluster
+}
+
+func (l lazyMachineSetDeployer) deployer() (ocp.MachineSetDeployer, error) {
+	_, deployer, err := l.cluster.clients()
+	return deployer, err
+}
+
+func (l lazyMachineSetDeployer) Deploy(ctx context.Context, machineSet *unstructured.Unstructured) error {
+	deployer, err := l.deployer()
+	if err != nil {
+		return err
+	}
+
+	return deployer.Deploy(ctx, machineSet) // nolint:wrapcheck // No need to wrap here
+}
+
+func (l lazyMachineSetDeployer) GetWorkerNodeImage(ctx context.Context, workerNodeList []string,
+	machineSet *unstructured.Unstructured, infraID string) (string, error) {
+	deployer, err := l.deployer()
+	if err != nil {
+		return "", err
+	}
+
+	return deployer.GetWorkerNodeImage(ctx, workerNodeList, machineSet, infraID) // nolint:wrapcheck // No need to wrap here
+}
+
+func (l lazyMachineSetDeployer) Delete(ctx context.Context, machineSet *unstructured.Unstructured) error {
+	deployer, err := l.deployer()
+	if err != nil {
+		return err
+	}
+
+	return deployer.Delete(ctx, machineSet) // nolint:wrapcheck // No need to wrap here
+}
+
+func (l lazyMachineSetDeployer) List(ctx context.Context, machineSet *unstructured.Unstructured,
+	namePrefix string) ([]unstructured.Unstructured, error) {
+	deployer, err := l.deployer()
+	if err != nil {
+		return nil, err
+	}
+
+	return deployer.List(ctx, machineSet, namePrefix) // nolint:wrapcheck // No need to wrap here
+}
@@ -0,0 +1,118 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+type command func(ctx context.Context, opts *options, cloud api.Cloud, gwDeployer api.GatewayDeployer) error
+
+var commands = map[string]command{
+	"prepare":          prepare,
+	"cleanup":          cleanup,
+	"deploy-gateways":  deployGateways,
+	"cleanup-gateways": cleanupGateways,
+	"status":           status,
+	"validate":         validate,
+}
+
+func prepare(ctx context.Context, opts *options, cloud api.Cloud, _ api.GatewayDeployer) error {
+	ctx, reporter := opts.reporter(ctx)
+
+	input := opts.prepareInput()
+	input.IPFamilies = opts.prepareIPFamilies(ctx, func(message string, args ...interface{}) {
+		api.Warn(reporter, message, args...)
+	})
+
+	return cloud.PrepareForSubmarinerWithContext(ctx, input, reporter) // nolint:wrapcheck // No need to wrap here
+}
+
+func cleanup(ctx context.Context, opts *options, cloud api.Cloud, _ api.GatewayDeployer) error {
+	ctx, reporter := opts.reporter(ctx)
+
+	return cloud.CleanupAfterSubmarinerWithContext(ctx, reporter) // nolint:wrapcheck // No need to wrap here
+}
+
+func deployGateways(ctx context.Context, opts *options, _ api.Cloud, gwDeployer api.GatewayDeployer) error {
+	ctx, reporter := opts.reporter(ctx)
+
+	return gwDeployer.DeployWithContext(ctx, opts.deployInput(), reporter) // nolint:wrapcheck // No need to wrap here
+}
+
+func cleanupGateways(ctx context.Context, opts *options, _ api.Cloud, gwDeployer api.GatewayDeployer) error {
+	ctx, reporter := opts.reporter(ctx)
+
+	return gwDeployer.CleanupWithContext(ctx, reporter) // nolint:wrapcheck // No need to wrap here
+}
+
+type statusOutput struct {
+	Cloud    *api.CloudStatus   `json:"cloud"`
+	Gateways *api.GatewayStatus `json:"gateways"`
+}
+
+func status(ctx context.Context, _ *options, cloud api.Cloud, gwDeployer api.GatewayDeployer) error {
+	cloudStatus, err := cloud.Status(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error retrieving the cloud status")
+	}
+
+	gwStatus, err := gwDeployer.Status(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error retrieving the gateway status")
+	}
+
+	output, err := json.MarshalIndent(&statusOutput{Cloud: cloudStatus, Gateways: gwStatus}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error encoding the status")
+	}
+
+	fmt.Println(string(output))
+
+	return nil
+}
+
+// validate only reads from the cloud: creating the provider checks the configuration and credentials, and planning
+// checks the cluster's resources can be found.
+func validate(ctx context.Context, opts *options, cloud api.Cloud, gwDeployer api.GatewayDeployer) error {
+	input := opts.prepareInput()
+	input.IPFamilies = opts.prepareIPFamilies(ctx, func(message string, args ...interface{}) {
+		fmt.Fprintf(os.Stderr, "Warning: "+message+"\n", args...)
+	})
+
+	preparePlan, err := cloud.PlanPrepareForSubmariner(ctx, input)
+	if err != nil {
+		return errors.Wrap(err, "error planning the preparation")
+	}
+
+	deployPlan, err := gwDeployer.PlanDeploy(ctx, opts.deployInput())
+	if err != nil {
+		return errors.Wrap(err, "error planning the gateway deployment")
+	}
+
+	fmt.Printf("prepare:\n%s\n\ndeploy-gateways:\n%s\n", preparePlan, deployPlan)
+
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command cloud-prepare prepares clouds for Submariner, and cleans them up afterwards, from the command line.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/provider"
+)
+
+const usage = `Usage: cloud-prepare <command> <provider> [flags]
+
+Commands:
+  prepare            Open the internal ports Submariner needs within the cluster
+  cleanup            Remove the changes made by prepare
+  deploy-gateways    Deploy the gateways and open the public ports
+  cleanup-gateways   Remove the gateways and close the public ports
+  status             Print the current Submariner preparation as JSON
+  validate           Check the configuration and print the changes prepare and deploy-gateways would make
+
+Providers: %s
+
+Run "cloud-prepare <command> <provider> --help" for the flags.
+`
+
+var errInvalidFlags = errors.New("invalid flags")
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Interrupting cancels the operation in progress, which stops at the next cloud API call.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	err := run(ctx, os.Args[1:])
+
+	cancel()
+
+	if err != nil {
+		// The flag package already printed the invalid flags along with the usage.
+		if !errors.Is(err, errInvalidFlags) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	registry := provider.NewDefaultRegistry()
+
+	if len(args) < 2 || strings.HasPrefix(args[0], "-") || strings.HasPrefix(args[1], "-") {
+		fmt.Fprintf(os.Stderr, usage, strings.Join(registry.Providers(), ", "))
+
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help" || args[0] == "help") {
+			return nil
+		}
+
+		return errors.New("a command and a provider are required")
+	}
+
+	cmd, found := commands[args[0]]
+	if !found {
+		return errors.Errorf("unknown command %q", args[0])
+	}
+
+	opts := newOptions(args[0], args[1])
+
+	err := opts.parse(args[2:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	cloud, gwDeployer, err := opts.newProvider(registry)
+	if err != nil {
+		return err
+	}
+
+	if opts.eventsFile != "" {
+		events, err := os.Create(opts.eventsFile)
+		if err != nil {
+			return errors.Wrap(err, "error creating the events file")
+		}
+
+		defer events.Close()
+
+		opts.events = events
+	}
+
+	return cmd(ctx, opts, cloud, gwDeployer)
+}
@@ -0,0 +1,387 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/util"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	"github.com/submariner-io/cloud-prepare/pkg/provider"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	defaultInternalPorts = "4800/udp,8080/tcp"
+	defaultPublicPorts   = "4500/udp,4490/udp"
+)
+
+// configKeys maps the flags which configure the provider to their keys in its configuration. Only the flags which are
+// set are passed on, since the providers reject the keys they don't know.
+var configKeys = map[string]string{
+	"infra-id":          "infraID",
+	"region":            "region",
+	"project-id":        "projectID",
+	"credentials":       "credentialsFile",
+	"profile":           "profile",
+	"cloud-name":        "cloudName",
+	"instance-type":     "instanceType",
+	"image":             "image",
+	"dedicated-gateway": "dedicatedGateway",
+}
+
+type options struct {
+	command  string
+	provider string
+	flags    *flag.FlagSet
+
+	kubeconfig  string
+	kubeContext string
+
+	internalPorts []api.PortSpec
+	publicPorts   []api.PortSpec
+	sourceCIDRs   []string
+	ipFamilies    []api.IPFamily
+	gateways      int
+	rollback      bool
+	eventsFile    string
+
+	// events, if set, receives the progress as JSON lines in addition to the terminal.
+	events io.Writer
+
+	// k8sClient is the client given to the provider, set by newProvider.
+	k8sClient k8s.Interface
+}
+
+func newOptions(command, providerName string) *options {
+	o := &options{command: command, provider: providerName}
+
+	o.flags = flag.NewFlagSet("cloud-prepare "+command+" "+providerName, flag.ContinueOnError)
+	o.flags.StringVar(&o.kubeconfig, "kubeconfig", "",
+		"Path to the kubeconfig file; defaults to $KUBECONFIG, ~/.kube/config or the in-cluster configuration")
+	o.flags.StringVar(&o.kubeContext, "context", "", "The kubeconfig context to use instead of the current one")
+
+	o.flags.String("infra-id", "", "The infrastructure ID of the cluster")
+	o.flags.String("region", "", "The region the cluster is in")
+	o.flags.String("project-id", "", "The project the cluster is in (gcp, rhos)")
+	o.flags.String("credentials", "", "Path to the cloud credentials file; defaults to the provider's standard location (aws, gcp)")
+	o.flags.String("profile", "", "The profile to use from the credentials file (aws)")
+	o.flags.String("cloud-name", "", "The clouds.yaml entry used by the dedicated gateway MachineSets (rhos)")
+	o.flags.String("instance-type", "", "The instance type of the gateways")
+	o.flags.String("image", "", "The image of the dedicated gateway instances (gcp, rhos)")
+	o.flags.Bool("dedicated-gateway", false, "Deploy dedicated gateway instances instead of labelling workers (gcp, rhos)")
+
+	o.flags.Var(portsValue{&o.internalPorts}, "internal-ports",
+		"Comma-separated `ports` to open within the cluster, e.g. 4800/udp (default "+defaultInternalPorts+")")
+	o.flags.Var(portsValue{&o.publicPorts}, "public-ports",
+		"Comma-separated `ports` to open publicly on the gateways, e.g. 4500/udp or esp (default "+defaultPublicPorts+")")
+	o.flags.Var(stringsValue{&o.sourceCIDRs}, "source-cidrs", "Comma-separated `CIDRs` allowed to reach the public ports; any if empty")
+	o.flags.Var(ipFamiliesValue{&o.ipFamilies}, "ip-families", "Comma-separated `families` of the cluster network (default detected from the cluster)")
+	o.flags.IntVar(&o.gateways, "gateways", 0, "The number of gateways to deploy; 0 uses the provider's default")
+	o.flags.BoolVar(&o.rollback, "rollback", false, "Revert the changes already made if the operation fails")
+	o.flags.StringVar(&o.eventsFile, "events-file", "", "Also write the progress to the given `file` as JSON lines, one event per line")
+
+	return o
+}
+
+func (o *options) parse(args []string) error {
+	if err := o.flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err // nolint:wrapcheck // No need to wrap here
+		}
+
+		return errInvalidFlags
+	}
+
+	if o.flags.NArg() > 0 {
+		return errors.Errorf("unexpected arguments %q", o.flags.Args())
+	}
+
+	if o.internalPorts == nil {
+		o.internalPorts, _ = parsePorts(defaultInternalPorts)
+	}
+
+	if o.publicPorts == nil {
+		o.publicPorts, _ = parsePorts(defaultPublicPorts)
+	}
+
+	return nil
+}
+
+// providerConfig returns the provider's configuration, built from the flags which are set.
+func (o *options) providerConfig() map[string]interface{} {
+	config := map[string]interface{}{}
+
+	o.flags.Visit(func(f *flag.Flag) {
+		if key, found := configKeys[f.Name]; found {
+			config[key] = f.Value.(flag.Getter).Get()
+		}
+	})
+
+	return config
+}
+
+func (o *options) newProvider(registry *provider.Registry) (api.Cloud, api.GatewayDeployer, error) {
+	deps := o.dependencies()
+	o.k8sClient = deps.K8sClient
+
+	cloud, gwDeployer, err := registry.New(o.provider, o.providerConfig(), deps)
+
+	return cloud, gwDeployer, errors.Wrapf(err, "error creating the %s provider", o.provider)
+}
+
+// dependencies returns the cluster clients, which only connect to the cluster when they're first used, since the
+// commands which only touch the cloud APIs don't need it.
+func (o *options) dependencies() provider.Dependencies {
+	c := &cluster{connect: o.connect}
+
+	return provider.Dependencies{
+		K8sClient:          lazyK8sClient{cluster: c},
+		MachineSetDeployer: lazyMachineSetDeployer{cluster: c},
+	}
+}
+
+// connect connects to the cluster, using the kubeconfig the same way as kubectl does.
+func (o *options) connect() (k8s.Interface, ocp.MachineSetDeployer, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = o.kubeconfig
+
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules,
+		&clientcmd.ConfigOverrides{CurrentContext: o.kubeContext}).ClientConfig()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error loading the kubeconfig")
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error creating the Kubernetes client")
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error creating the dynamic client")
+	}
+
+	restMapper, err := util.BuildRestMapper(restConfig)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error creating the REST mapper")
+	}
+
+	return k8s.NewInterface(kubeClient), ocp.NewK8sMachinesetDeployer(restMapper, dynamicClient), nil
+}
+
+func (o *options) prepareInput() api.PrepareForSubmarinerInput {
+	return api.PrepareForSubmarinerInput{
+		InternalPorts: o.internalPorts,
+		IPFamilies:    o.ipFamilies,
+		Rollback:      o.rollback,
+	}
+}
+
+func (o *options) deployInput() api.GatewayDeployInput {
+	return api.GatewayDeployInput{
+		PublicPorts:       o.publicPorts,
+		PublicSourceCIDRs: o.sourceCIDRs,
+		IPFamilies:        o.ipFamilies,
+		Gateways:          o.gateways,
+		Rollback:          o.rollback,
+	}
+}
+
+// prepareIPFamilies returns the IP families requested with --ip-families or, if there are none, those of the cluster
+// network. Preparing the cloud doesn't need the cluster otherwise, so if it can't be reached, the preparation falls
+// back to IPv4 only, with a warning.
+func (o *options) prepareIPFamilies(ctx context.Context, warn func(message string, args ...interface{})) []api.IPFamily {
+	families, err := k8s.IPFamiliesOrDetected(ctx, o.k8sClient, o.ipFamilies)
+	if err != nil {
+		warn("Using IPv4 only, set --ip-families to open the ports for IPv6: %v", err)
+		return nil
+	}
+
+	return families
+}
+
+// reporter returns the reporter of an operation's progress, along with the context to run it with, which trace its
+// steps and calls with the global tracer provider, see api.TraceSteps.
+func (o *options) reporter(ctx context.Context) (context.Context, api.Reporter) {
+	events := newTerminalReporter(os.Stderr)
+
+	if o.events != nil {
+		events = api.NewMultiReporter(events, api.NewJSONLinesReporter(o.events))
+	}
+
+	return api.TraceSteps(ctx, api.NewStepReporter(events), nil)
+}
+
+// parsePorts parses a comma-separated list of port specs, each being a port or port range followed by the protocol,
+// e.g. "4500/udp" or "4490-4500/udp", or just a protocol without ports, e.g. "esp".
+func parsePorts(value string) ([]api.PortSpec, error) {
+	ports := []api.PortSpec{}
+
+	for _, spec := range splitList(value) {
+		port, err := parsePort(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		ports = append(ports, port)
+	}
+
+	return ports, nil
+}
+
+func parsePort(spec string) (api.PortSpec, error) {
+	parts := strings.SplitN(spec, "/", 2)
+	if len(parts) == 1 {
+		if _, err := strconv.Atoi(spec); err == nil {
+			return api.PortSpec{}, errors.Errorf("port %q has no protocol", spec)
+		}
+
+		return api.PortSpec{Protocol: strings.ToLower(spec)}, nil
+	}
+
+	port := api.PortSpec{Protocol: strings.ToLower(parts[1])}
+	if port.Protocol == "" {
+		return api.PortSpec{}, errors.Errorf("port %q has no protocol", spec)
+	}
+
+	ports := strings.SplitN(parts[0], "-", 2)
+
+	first, err := strconv.ParseUint(ports[0], 10, 16)
+	if err != nil {
+		return api.PortSpec{}, errors.Errorf("invalid port %q", spec)
+	}
+
+	port.Port = uint16(first)
+
+	if len(ports) == 2 {
+		last, err := strconv.ParseUint(ports[1], 10, 16)
+		if err != nil || last < first {
+			return api.PortSpec{}, errors.Errorf("invalid port range %q", spec)
+		}
+
+		port.EndPort = uint16(last)
+	}
+
+	return port, nil
+}
+
+func splitList(value string) []string {
+	items := []string{}
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+type portsValue struct {
+	ports *[]api.PortSpec
+}
+
+func (v portsValue) String() string {
+	if v.ports == nil {
+		return ""
+	}
+
+	specs := make([]string, len(*v.ports))
+	for i := range *v.ports {
+		specs[i] = (*v.ports)[i].String()
+	}
+
+	return strings.Join(specs, ",")
+}
+
+func (v portsValue) Set(value string) error {
+	ports, err := parsePorts(value)
+	if err != nil {
+		return err
+	}
+
+	*v.ports = ports
+
+	return nil
+}
+
+type stringsValue struct {
+	values *[]string
+}
+
+func (v stringsValue) String() string {
+	if v.values == nil {
+		return ""
+	}
+
+	return strings.Join(*v.values, ",")
+}
+
+func (v stringsValue) Set(value string) error {
+	*v.values = splitList(value)
+
+	return nil
+}
+
+type ipFamiliesValue struct {
+	families *[]api.IPFamily
+}
+
+func (v ipFamiliesValue) String() string {
+	if v.families == nil {
+		return ""
+	}
+
+	families := make([]string, len(*v.families))
+	for i, family := range *v.families {
+		families[i] = string(family)
+	}
+
+	return strings.Join(families, ",")
+}
+
+func (v ipFamiliesValue) Set(value string) error {
+	families := []api.IPFamily{}
+
+	for _, family := range splitList(value) {
+		switch {
+		case strings.EqualFold(family, string(api.IPv4Family)):
+			families = append(families, api.IPv4Family)
+		case strings.EqualFold(family, string(api.IPv6Family)):
+			families = append(families, api.IPv6Family)
+		default:
+			return errors.Errorf("unknown IP family %q, expected %s or %s", family, api.IPv4Family, api.IPv6Family)
+		}
+	}
+
+	*v.families = families
+
+	return nil
+}
@@ -0,0 +1,166 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/provider"
+)
+
+var _ = Describe("Options", func() {
+	Describe("parsing the ports", func() {
+		It("should parse ports, port ranges and protocols", func() {
+			ports, err := parsePorts("4500/udp, 4490-4500/UDP,esp")
+			Expect(err).To(Succeed())
+			Expect(ports).To(Equal([]api.PortSpec{
+				{Port: 4500, Protocol: "udp"},
+				{Port: 4490, EndPort: 4500, Protocol: "udp"},
+				{Protocol: "esp"},
+			}))
+		})
+
+		It("should reject ports without a protocol", func() {
+			_, err := parsePorts("4500")
+			Expect(err).ToNot(Succeed())
+
+			_, err = parsePorts("4500/")
+			Expect(err).ToNot(Succeed())
+		})
+
+		It("should reject invalid ports and ranges", func() {
+			_, err := parsePorts("70000/udp")
+			Expect(err).ToNot(Succeed())
+
+			_, err = parsePorts("4500-4490/udp")
+			Expect(err).ToNot(Succeed())
+		})
+	})
+
+	Describe("parsing the flags", func() {
+		var opts *options
+
+		BeforeEach(func() {
+			opts = newOptions("prepare", "gcp")
+		})
+
+		It("should default the ports", func() {
+			Expect(opts.parse(nil)).To(Succeed())
+			Expect(opts.prepareInput().InternalPorts).To(Equal([]api.PortSpec{
+				{Port: 4800, Protocol: "udp"},
+				{Port: 8080, Protocol: "tcp"},
+			}))
+			Expect(opts.deployInput().PublicPorts).To(Equal([]api.PortSpec{
+				{Port: 4500, Protocol: "udp"},
+				{Port: 4490, Protocol: "udp"},
+			}))
+		})
+
+		It("should only pass the flags which are set to the provider", func() {
+			Expect(opts.parse([]string{"--infra-id", "test-infra", "--project-id", "test-project", "--dedicated-gateway"})).To(Succeed())
+			Expect(opts.providerConfig()).To(Equal(map[string]interface{}{
+				"infraID":          "test-infra",
+				"projectID":        "test-project",
+				"dedicatedGateway": true,
+			}))
+		})
+
+		It("should build the deployment input", func() {
+			Expect(opts.parse([]string{"--gateways", "2", "--source-cidrs", "10.0.0.0/8,fd00::/8", "--ip-families", "ipv4,IPv6",
+				"--rollback"})).To(Succeed())
+
+			input := opts.deployInput()
+			Expect(input.Gateways).To(Equal(2))
+			Expect(input.PublicSourceCIDRs).To(Equal([]string{"10.0.0.0/8", "fd00::/8"}))
+			Expect(input.IPFamilies).To(Equal([]api.IPFamily{api.IPv4Family, api.IPv6Family}))
+			Expect(input.Rollback).To(BeTrue())
+		})
+
+		It("should fail on an unknown IP family", func() {
+			opts.flags.SetOutput(ioutil.Discard)
+			Expect(opts.parse([]string{"--ip-families", "IPv5"})).To(MatchError(errInvalidFlags))
+		})
+
+		It("should return ErrHelp when help is requested", func() {
+			opts.flags.SetOutput(ioutil.Discard)
+			Expect(opts.parse([]string{"--help"})).To(MatchError(flag.ErrHelp))
+		})
+	})
+
+	When("the cluster isn't reachable", func() {
+		var opts *options
+
+		BeforeEach(func() {
+			opts = newOptions("prepare", "generic")
+			Expect(opts.parse([]string{"--kubeconfig", "/nonexistent/kubeconfig"})).To(Succeed())
+		})
+
+		It("should create the provider and run the commands which don't need the cluster", func() {
+			cloud, _, err := opts.newProvider(provider.NewDefaultRegistry())
+			Expect(err).To(Succeed())
+			Expect(cloud.PrepareForSubmarinerWithContext(context.TODO(), opts.prepareInput(), api.NewLoggingReporter())).
+				To(Succeed())
+		})
+
+		It("should prepare for IPv4 only, with a warning, when the IP families aren't set", func() {
+			_, _, err := opts.newProvider(provider.NewDefaultRegistry())
+			Expect(err).To(Succeed())
+
+			warnings := []string{}
+			Expect(opts.prepareIPFamilies(context.TODO(), func(message string, args ...interface{}) {
+				warnings = append(warnings, fmt.Sprintf(message, args...))
+			})).To(BeEmpty())
+			Expect(warnings).To(ConsistOf(ContainSubstring("error loading the kubeconfig")))
+		})
+
+		It("should fail the commands which need the cluster", func() {
+			_, gwDeployer, err := opts.newProvider(provider.NewDefaultRegistry())
+			Expect(err).To(Succeed())
+			Expect(gwDeployer.DeployWithContext(context.TODO(), opts.deployInput(), api.NewLoggingReporter())).
+				To(MatchError(ContainSubstring("error loading the kubeconfig")))
+		})
+	})
+
+	When("an events file is given", func() {
+		It("should also write the progress to it as JSON lines", func() {
+			events := &bytes.Buffer{}
+			opts := newOptions("prepare", "gcp")
+			opts.events = events
+
+			_, reporter := opts.reporter(context.TODO())
+			api.ForResource(reporter, api.FirewallRuleResource, "rule-1").Started("Creating")
+			api.Warn(reporter, "Slow")
+			reporter.Succeeded("Created")
+
+			lines := strings.Split(strings.TrimSpace(events.String()), "\n")
+			Expect(lines).To(HaveLen(3))
+			Expect(lines[0]).To(ContainSubstring(`"type":"started"`))
+			Expect(lines[1]).To(ContainSubstring(`"level":"warning"`))
+			Expect(lines[2]).To(ContainSubstring(`"resourceID":"rule-1"`))
+		})
+	})
+})
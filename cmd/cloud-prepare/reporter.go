@@ -0,0 +1,63 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+type terminalReporter struct {
+	mutex sync.Mutex
+	out   io.Writer
+}
+
+// newTerminalReporter returns an EventReporter which prints the progress for a person to follow, indenting nested
+// steps under their parent.
+func newTerminalReporter(out io.Writer) api.EventReporter {
+	return &terminalReporter{out: out}
+}
+
+func (r *terminalReporter) Report(event *api.Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	indent := strings.Repeat("  ", strings.Count(event.StepID, "."))
+
+	// Reporting is best effort, a failure to write must not fail the operation being reported on.
+	switch event.Type {
+	case api.EventStarted:
+		_, _ = fmt.Fprintf(r.out, "%s• %s...\n", indent, event.Message)
+	case api.EventSucceeded:
+		_, _ = fmt.Fprintf(r.out, "%s✓ %s (%s)\n", indent, event.Message, event.Duration.Round(time.Millisecond))
+	case api.EventFailed:
+		_, _ = fmt.Fprintf(r.out, "%s✗ %s\n", indent, event.Error)
+	case api.EventMessage:
+		if event.Level == api.LevelWarning {
+			_, _ = fmt.Fprintf(r.out, "%s⚠ %s\n", indent, event.Message)
+		} else {
+			_, _ = fmt.Fprintf(r.out, "%s  %s\n", indent, event.Message)
+		}
+	}
+}
@@ -6,16 +6,24 @@ require (
 	github.com/aws/aws-sdk-go-v2 v1.16.1
 	github.com/aws/aws-sdk-go-v2/config v1.15.2
 	github.com/aws/aws-sdk-go-v2/credentials v1.11.1
+	github.com/aws/aws-sdk-go-v2/service/cloudformation v1.20.0
 	github.com/aws/aws-sdk-go-v2/service/ec2 v1.33.0
+	github.com/aws/aws-sdk-go-v2/service/servicequotas v1.13.2
 	github.com/aws/smithy-go v1.11.2
 	github.com/golang/mock v1.6.0
 	github.com/gophercloud/gophercloud v0.24.0
 	github.com/onsi/ginkgo v1.16.5
 	github.com/onsi/gomega v1.19.0
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.11.0
 	github.com/submariner-io/admiral v0.12.0-m3
+	go.opentelemetry.io/otel v1.2.0
+	go.opentelemetry.io/otel/sdk v1.2.0
+	go.opentelemetry.io/otel/trace v1.2.0
+	golang.org/x/time v0.0.0-20210611083556-38a9dc6acbc6
 	google.golang.org/api v0.73.0
 	k8s.io/api v0.19.16
 	k8s.io/apimachinery v0.19.16
 	k8s.io/client-go v0.19.16
+	sigs.k8s.io/yaml v1.2.0
 )
@@ -18,6 +18,8 @@ limitations under the License.
 
 package api
 
+import "context"
+
 // Reporter is responsible for reporting back on the progress of the cloud preparation.
 type Reporter interface {
 	// Started will report that an operation started on the cloud.
@@ -32,13 +34,28 @@ type Reporter interface {
 
 // PortSpec is a specification of port+protocol to open.
 type PortSpec struct {
-	Port     uint16
-	Protocol string
+	// Port is the port to open, or the first port of a range if EndPort is set. It is left at 0 for protocols
+	// without ports, such as ESP, or to open all the ports of a protocol.
+	Port     uint16 `json:"port,omitempty"`
+	Protocol string `json:"protocol"`
+
+	// EndPort, if set, is the last port of the range starting at Port.
+	EndPort uint16 `json:"endPort,omitempty"`
+
+	// SourceCIDRs restricts the sources allowed to reach a public port; all sources are allowed if it is empty.
+	// It is ignored for internal ports, which are only reachable from within the cluster.
+	SourceCIDRs []string `json:"sourceCIDRs,omitempty"`
 }
 
 type PrepareForSubmarinerInput struct {
 	// List of ports to open inside the cluster for proper communication between Submariner services.
 	InternalPorts []PortSpec
+
+	// IP families used by the cluster network; the internal ports are opened for each of them. IPv4 only if empty.
+	IPFamilies []IPFamily
+
+	// Rollback, if true, reverts the changes already made, in reverse order, if the preparation fails.
+	Rollback bool
 }
 
 // Cloud is a potential cloud for installing Submariner on.
@@ -46,27 +63,87 @@ type Cloud interface {
 	// PrepareForSubmariner will prepare the cloud for Submariner to operate on.
 	PrepareForSubmariner(input PrepareForSubmarinerInput, reporter Reporter) error
 
+	// PrepareForSubmarinerWithContext is the same as PrepareForSubmariner but uses the given context for all
+	// cloud API calls, allowing the caller to cancel the operation or set a deadline on it.
+	PrepareForSubmarinerWithContext(ctx context.Context, input PrepareForSubmarinerInput, reporter Reporter) error
+
 	// CleanupAfterSubmariner will clean up the cloud after Submariner is removed.
 	CleanupAfterSubmariner(reporter Reporter) error
+
+	// CleanupAfterSubmarinerWithContext is the same as CleanupAfterSubmariner but uses the given context for all
+	// cloud API calls.
+	CleanupAfterSubmarinerWithContext(ctx context.Context, reporter Reporter) error
+
+	// PlanPrepareForSubmariner returns the changes PrepareForSubmariner would make, without making them.
+	PlanPrepareForSubmariner(ctx context.Context, input PrepareForSubmarinerInput) (*Plan, error)
+
+	// PlanCleanupAfterSubmariner returns the changes CleanupAfterSubmariner would make, without making them.
+	PlanCleanupAfterSubmariner(ctx context.Context) (*Plan, error)
+
+	// Status reports the Submariner preparation which currently exists in the cloud, without changing anything.
+	Status(ctx context.Context) (*CloudStatus, error)
+
+	// ScanOrphans searches the cloud for the resources carrying Submariner's names, tags or descriptions for the cluster,
+	// which are left behind when cleaning up fails or is skipped. They are returned in the order they can be deleted.
+	ScanOrphans(ctx context.Context) ([]InventoryResource, error)
+
+	// DeleteOrphans deletes the given resources, as returned by ScanOrphans.
+	DeleteOrphans(ctx context.Context, orphans []InventoryResource, reporter Reporter) error
 }
 
 type GatewayDeployInput struct {
 	// List of ports to open externally so that Submariner can reach and be reached by other Submariners.
 	PublicPorts []PortSpec
 
+	// PublicSourceCIDRs restricts the sources allowed to reach the public ports which don't specify their own
+	// SourceCIDRs, typically to the public IPs of the peer clusters. All sources are allowed if it is empty.
+	PublicSourceCIDRs []string
+
+	// IP families used by the cluster network; the public ports are opened for each of them, as well as for the
+	// families of any source CIDRs. IPv4 only if empty.
+	IPFamilies []IPFamily
+
 	// Amount of gateways that are being deployed.
 	//
 	// 0 = Deploy gateways per the default deployer policy (Default if not specified)
 	//
-	// 1-* = Deploy the amount of gateways requested (May fail if there aren't enough public subnets)
+	// 1-* = Deploy the amount of gateways requested (May fail if there aren't enough public subnets). If fewer
+	// gateways are requested than are currently deployed, the surplus gateways are removed, but never the active one.
 	Gateways int
+
+	// ActiveGatewayNode finds the node hosting the active gateway, so that it isn't removed when decreasing the number
+	// of gateways. If nil, the deployers with access to the cluster look for the node running the active gateway pod.
+	ActiveGatewayNode ActiveGatewayNodeFunc
+
+	// Rollback, if true, reverts the changes already made, in reverse order, if the deployment fails. Surplus gateways
+	// which were removed aren't restored.
+	Rollback bool
 }
 
+// ActiveGatewayNodeFunc returns the name of the node hosting the active gateway, or an empty string if there is none.
+type ActiveGatewayNodeFunc func(ctx context.Context) (string, error)
+
 // GatewayDeployer will deploy and cleanup dedicated gateways according to the requested policy.
 type GatewayDeployer interface {
 	// Deploy dedicated gateways as requested.
 	Deploy(input GatewayDeployInput, reporter Reporter) error
 
+	// DeployWithContext is the same as Deploy but uses the given context for all cloud and Kubernetes API calls,
+	// allowing the caller to cancel the operation or set a deadline on it.
+	DeployWithContext(ctx context.Context, input GatewayDeployInput, reporter Reporter) error
+
 	// Cleanup any dedicated gateways that were previously deployed.
 	Cleanup(reporter Reporter) error
+
+	// CleanupWithContext is the same as Cleanup but uses the given context for all cloud and Kubernetes API calls.
+	CleanupWithContext(ctx context.Context, reporter Reporter) error
+
+	// PlanDeploy returns the changes Deploy would make, without making them.
+	PlanDeploy(ctx context.Context, input GatewayDeployInput) (*Plan, error)
+
+	// PlanCleanup returns the changes Cleanup would make, without making them.
+	PlanCleanup(ctx context.Context) (*Plan, error)
+
+	// Status reports the gateway deployment which currently exists, without changing anything.
+	Status(ctx context.Context) (*GatewayStatus, error)
 }
@@ -0,0 +1,127 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The categories of the errors returned by the providers. Provider errors wrap the underlying cloud SDK error in one
+// of these, so callers can check the category with errors.Is, e.g. errors.Is(err, api.ErrPermissionDenied), or get
+// the Error with errors.As.
+var (
+	// ErrPermissionDenied means the credentials aren't allowed to perform an operation.
+	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrNotFound means a resource, e.g. the cluster's network, doesn't exist.
+	ErrNotFound = errors.New("not found")
+
+	// ErrQuotaExceeded means an operation would exceed a quota or a limit of the cloud account.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+
+	// ErrConflict means an operation conflicts with the current state of a resource, e.g. it already exists or is
+	// still in use.
+	ErrConflict = errors.New("conflict")
+
+	// ErrTransient means an operation failed because of a temporary condition, such as throttling or an unavailable
+	// service, and can be retried.
+	ErrTransient = errors.New("transient error")
+
+	// ErrUnsupported means an operation or a configuration isn't supported by the cloud or the provider.
+	ErrUnsupported = errors.New("unsupported")
+)
+
+// Error is an error in one of the categories above.
+type Error struct {
+	// Category is the sentinel error of the category, e.g. ErrNotFound.
+	Category error
+
+	// Err is the underlying error.
+	Err error
+}
+
+// NewError returns the given error in the given category, or nil if the error is nil.
+func NewError(category, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{Category: category, Err: err}
+}
+
+// Errorf formats an error in the given category.
+func Errorf(category error, format string, args ...interface{}) error {
+	return &Error{Category: category, Err: fmt.Errorf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is returns true if the target is the category of the error.
+func (e *Error) Is(target error) bool {
+	return target == e.Category
+}
+
+// MissingFieldsError is returned by the validation of a provider's configuration when required fields are missing.
+type MissingFieldsError struct {
+	// Provider is the name of the provider, e.g. "GCP".
+	Provider string
+
+	// Fields are the missing fields.
+	Fields []MissingField
+}
+
+// MissingField is a required field of a provider's configuration.
+type MissingField struct {
+	// Key is the key of the field in the JSON representation of the configuration.
+	Key string
+
+	// Condition explains when the field is required, e.g. "for dedicated gateways", or is empty if it's always required.
+	Condition string
+}
+
+// Add records a missing field, required under the given condition, if any.
+func (e *MissingFieldsError) Add(key, condition string) {
+	e.Fields = append(e.Fields, MissingField{Key: key, Condition: condition})
+}
+
+// OrNil returns the error if any fields are missing, nil otherwise.
+func (e *MissingFieldsError) OrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+func (e *MissingFieldsError) Error() string {
+	keys := make([]string, len(e.Fields))
+	for i := range e.Fields {
+		keys[i] = e.Fields[i].Key
+	}
+
+	return fmt.Sprintf("the %s configuration is missing the required fields %s", e.Provider, strings.Join(keys, ", "))
+}
@@ -0,0 +1,73 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import "time"
+
+// EventType identifies what happened to a step.
+type EventType string
+
+const (
+	EventStarted   EventType = "started"
+	EventSucceeded EventType = "succeeded"
+	EventFailed    EventType = "failed"
+
+	// EventMessage is an informational or warning message reported while a step is in progress.
+	EventMessage EventType = "message"
+)
+
+// EventLevel is the severity of an Event.
+type EventLevel string
+
+const (
+	LevelInfo    EventLevel = "info"
+	LevelWarning EventLevel = "warning"
+	LevelError   EventLevel = "error"
+)
+
+// Event is a structured, machine-readable report on the progress of the cloud preparation.
+type Event struct {
+	// Time is when the event occurred.
+	Time time.Time `json:"time"`
+
+	Type  EventType  `json:"type"`
+	Level EventLevel `json:"level"`
+
+	// StepID uniquely identifies the step within a reporter. Nested steps have IDs prefixed with their parent's ID,
+	// e.g. "2.1" is the first child of step "2".
+	StepID string `json:"stepID"`
+
+	// ParentID is the ID of the enclosing step, if any.
+	ParentID string `json:"parentID,omitempty"`
+
+	// ResourceKind and ResourceID identify the resource the step operates on, if known.
+	ResourceKind ResourceKind `json:"resourceKind,omitempty"`
+	ResourceID   string       `json:"resourceID,omitempty"`
+
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+
+	// Duration is the time elapsed since the step started; it is only set on succeeded and failed events.
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// EventReporter receives structured events.
+type EventReporter interface {
+	Report(event *Event)
+}
@@ -0,0 +1,86 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+type jsonLinesReporter struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONLinesReporter returns an EventReporter which writes each event to the given writer as a single line of JSON.
+func NewJSONLinesReporter(out io.Writer) EventReporter {
+	return &jsonLinesReporter{encoder: json.NewEncoder(out)}
+}
+
+func (r *jsonLinesReporter) Report(event *Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Reporting is best effort, a failure to write an event must not fail the operation being reported on.
+	_ = r.encoder.Encode(event)
+}
+
+type multiReporter []EventReporter
+
+// NewMultiReporter returns an EventReporter which forwards each event to all the given reporters, in order.
+func NewMultiReporter(reporters ...EventReporter) EventReporter {
+	return multiReporter(reporters)
+}
+
+func (m multiReporter) Report(event *Event) {
+	for _, r := range m {
+		r.Report(event)
+	}
+}
+
+type reporterAdapter struct {
+	reporter Reporter
+}
+
+// NewReporterAdapter returns an EventReporter which forwards events to an existing Reporter, so it can be combined
+// with other EventReporters, e.g. using NewMultiReporter. Messages are forwarded as warnings if the Reporter supports
+// them, see Warn, and dropped otherwise, since reporting them as completed operations would close the open step.
+func NewReporterAdapter(reporter Reporter) EventReporter {
+	return &reporterAdapter{reporter: reporter}
+}
+
+func (a *reporterAdapter) Report(event *Event) {
+	switch event.Type {
+	case EventStarted:
+		a.reporter.Started("%s", event.Message)
+	case EventSucceeded:
+		a.reporter.Succeeded("%s", event.Message)
+	case EventFailed:
+		a.reporter.Failed(eventError(event.Error))
+	case EventMessage:
+		Warn(a.reporter, "%s", event.Message)
+	}
+}
+
+type eventError string
+
+func (e eventError) Error() string {
+	return string(e)
+}
@@ -0,0 +1,52 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// GatewaysToRemove returns how many of the current gateways are surplus to the requested number of gateways.
+// Nothing is removed when the deployer's default policy is requested.
+func (i *GatewayDeployInput) GatewaysToRemove(current int) int {
+	if i.Gateways <= 0 || current <= i.Gateways {
+		return 0
+	}
+
+	return current - i.Gateways
+}
+
+// FindActiveGatewayNode returns the name of the node hosting the active gateway, using ActiveGatewayNode if set
+// or defaultFunc otherwise. defaultFunc is nil for deployers without access to the cluster.
+func (i *GatewayDeployInput) FindActiveGatewayNode(ctx context.Context, defaultFunc ActiveGatewayNodeFunc) (string, error) {
+	find := i.ActiveGatewayNode
+	if find == nil {
+		find = defaultFunc
+	}
+
+	if find == nil {
+		return "", errors.New("the active gateway node can't be determined, ActiveGatewayNode must be set to remove gateways")
+	}
+
+	node, err := find(ctx)
+
+	return node, errors.Wrap(err, "error finding the active gateway node")
+}
@@ -0,0 +1,142 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// InventoryScope identifies the operation which created an inventoried resource, and so the cleanup which removes it.
+type InventoryScope string
+
+const (
+	// CloudScope is used for resources created when preparing a cloud for Submariner.
+	CloudScope InventoryScope = "cloud"
+
+	// GatewayScope is used for resources created when deploying gateways.
+	GatewayScope InventoryScope = "gateway"
+)
+
+// InventoryResource is a resource created or adjusted in the cloud or the cluster on behalf of Submariner.
+type InventoryResource struct {
+	// Scope is the operation which created the resource.
+	Scope InventoryScope `json:"scope"`
+
+	// Kind is the kind of resource.
+	Kind ResourceKind `json:"kind"`
+
+	// ID identifies the resource, by cloud ID where there is one and by name otherwise.
+	ID string `json:"id"`
+
+	// Parent identifies the resource containing this one, for example the security group of a rule or the zone of
+	// an instance.
+	Parent string `json:"parent,omitempty"`
+
+	// Description provides additional human-readable details about the resource.
+	Description string `json:"description,omitempty"`
+
+	// PreExisting is true if the resource existed before it was recorded, in which case cleaning up leaves it in place.
+	PreExisting bool `json:"preExisting,omitempty"`
+}
+
+// Key identifies the resource within an inventory.
+func (r *InventoryResource) Key() string {
+	return strings.Join([]string{string(r.Scope), string(r.Kind), r.Parent, r.ID}, "/")
+}
+
+// Inventory records the resources created by clouds and gateway deployers, so that cleaning up removes exactly those.
+type Inventory interface {
+	// Record adds the resource to the inventory. If the resource is already recorded, it keeps the pre-existing state
+	// it was first recorded with.
+	Record(ctx context.Context, resource InventoryResource) error
+
+	// Forget removes the resource from the inventory.
+	Forget(ctx context.Context, resource InventoryResource) error
+
+	// Resources returns the recorded resources, in the order they were first recorded.
+	Resources(ctx context.Context) ([]InventoryResource, error)
+}
+
+// RecordResource records the resource in the inventory, if there is one.
+func RecordResource(ctx context.Context, inventory Inventory, resource InventoryResource) error {
+	if inventory == nil {
+		return nil
+	}
+
+	return errors.Wrapf(inventory.Record(ctx, resource), "error recording %s %q in the inventory", resource.Kind, resource.ID)
+}
+
+// ForgetResource removes the resource from the inventory, if there is one.
+func ForgetResource(ctx context.Context, inventory Inventory, resource InventoryResource) error {
+	if inventory == nil {
+		return nil
+	}
+
+	return errors.Wrapf(inventory.Forget(ctx, resource), "error removing %s %q from the inventory", resource.Kind, resource.ID)
+}
+
+// CleanupInventory removes the resources recorded in the given scope using the remove function, in the reverse order
+// of their recording, and forgets each one once it's removed. Pre-existing resources are forgotten without being
+// removed. The remove function must succeed if the resource no longer exists.
+func CleanupInventory(ctx context.Context, inventory Inventory, scope InventoryScope,
+	remove func(resource *InventoryResource) error) error {
+	resources, err := inventory.Resources(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error retrieving the inventory")
+	}
+
+	for i := len(resources) - 1; i >= 0; i-- {
+		resource := &resources[i]
+		if resource.Scope != scope {
+			continue
+		}
+
+		if !resource.PreExisting {
+			if err := remove(resource); err != nil {
+				return errors.WithMessagef(err, "error removing %s %q", resource.Kind, resource.ID)
+			}
+		}
+
+		if err := ForgetResource(ctx, inventory, *resource); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PlanInventoryCleanup adds the removal of the resources recorded in the given scope to the plan, in the order
+// CleanupInventory would remove them.
+func PlanInventoryCleanup(ctx context.Context, inventory Inventory, scope InventoryScope, plan *Plan) error {
+	resources, err := inventory.Resources(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error retrieving the inventory")
+	}
+
+	for i := len(resources) - 1; i >= 0; i-- {
+		if resources[i].Scope == scope && !resources[i].PreExisting {
+			plan.Add(ChangeDelete, resources[i].Kind, resources[i].ID, "%s", resources[i].Description)
+		}
+	}
+
+	return nil
+}
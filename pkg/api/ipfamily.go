@@ -0,0 +1,98 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import "strings"
+
+// IPFamily is an IP address family used by the cluster network.
+type IPFamily string
+
+const (
+	IPv4Family IPFamily = "IPv4"
+	IPv6Family IPFamily = "IPv6"
+)
+
+// AnySourceCIDRv6 is the IPv6 source CIDR used for public ports which aren't restricted to specific sources.
+const AnySourceCIDRv6 = "::/0"
+
+// CIDRFamily returns the IP family of the given CIDR or address.
+func CIDRFamily(cidr string) IPFamily {
+	if strings.Contains(cidr, ":") {
+		return IPv6Family
+	}
+
+	return IPv4Family
+}
+
+// SourcesFor returns the CIDRs of the given family allowed to reach the port. If the spec isn't restricted to
+// specific sources, all the sources of the family are allowed.
+func (p PortSpec) SourcesFor(family IPFamily) []string {
+	if len(p.SourceCIDRs) == 0 {
+		if family == IPv6Family {
+			return []string{AnySourceCIDRv6}
+		}
+
+		return []string{AnySourceCIDR}
+	}
+
+	sources := []string{}
+
+	for _, cidr := range p.SourceCIDRs {
+		if CIDRFamily(cidr) == family {
+			sources = append(sources, cidr)
+		}
+	}
+
+	return sources
+}
+
+// InternalIPFamilies returns the IP families for which the internal ports must be opened.
+func (i *PrepareForSubmarinerInput) InternalIPFamilies() []IPFamily {
+	return ipFamilies(i.IPFamilies, nil)
+}
+
+// PublicIPFamilies returns the IP families for which the public ports must be opened: those requested in
+// IPFamilies, along with those of any source CIDRs.
+func (i *GatewayDeployInput) PublicIPFamilies() []IPFamily {
+	return ipFamilies(i.IPFamilies, i.PublicPortSpecs())
+}
+
+func ipFamilies(requested []IPFamily, ports []PortSpec) []IPFamily {
+	hasFamily := map[IPFamily]bool{IPv4Family: len(requested) == 0}
+
+	for _, family := range requested {
+		hasFamily[family] = true
+	}
+
+	for _, port := range ports {
+		for _, cidr := range port.SourceCIDRs {
+			hasFamily[CIDRFamily(cidr)] = true
+		}
+	}
+
+	families := []IPFamily{}
+
+	for _, family := range []IPFamily{IPv4Family, IPv6Family} {
+		if hasFamily[family] {
+			families = append(families, family)
+		}
+	}
+
+	return families
+}
@@ -0,0 +1,51 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DeleteOrphans removes the given orphaned resources in order using remove, reporting each one. All the resources are
+// attempted even if some fail; the errors are combined in the returned error.
+func DeleteOrphans(orphans []InventoryResource, reporter Reporter, remove func(resource *InventoryResource) error) error {
+	failures := []string{}
+
+	for i := range orphans {
+		orphan := &orphans[i]
+
+		ForResource(reporter, orphan.Kind, orphan.ID).Started("Deleting orphaned %s %q", orphan.Kind, orphan.ID)
+
+		if err := remove(orphan); err != nil {
+			reporter.Failed(err)
+			failures = append(failures, fmt.Sprintf("%s %q: %v", orphan.Kind, orphan.ID, err))
+
+			continue
+		}
+
+		reporter.Succeeded("Deleted orphaned %s %q", orphan.Kind, orphan.ID)
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("error deleting orphaned resources %s", strings.Join(failures, "; "))
+	}
+
+	return nil
+}
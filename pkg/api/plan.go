@@ -0,0 +1,133 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ChangeAction is the action a planned Change would perform on a resource.
+type ChangeAction string
+
+const (
+	ChangeCreate ChangeAction = "create"
+	ChangeUpdate ChangeAction = "update"
+	ChangeDelete ChangeAction = "delete"
+
+	// ChangeApply is used when the resource will be created or updated, depending on whether it already exists.
+	ChangeApply ChangeAction = "apply"
+)
+
+// ResourceKind is the kind of resource affected by a planned Change.
+type ResourceKind string
+
+const (
+	SecurityGroupResource           ResourceKind = "SecurityGroup"
+	SecurityGroupRuleResource       ResourceKind = "SecurityGroupRule"
+	SecurityGroupAttachmentResource ResourceKind = "SecurityGroupAttachment"
+	FirewallRuleResource            ResourceKind = "FirewallRule"
+	SubnetTagResource               ResourceKind = "SubnetTag"
+	InstanceTagResource             ResourceKind = "InstanceTag"
+	InstanceResource                ResourceKind = "Instance"
+	PublicIPResource                ResourceKind = "PublicIP"
+	MachineSetResource              ResourceKind = "MachineSet"
+	NodeLabelResource               ResourceKind = "NodeLabel"
+)
+
+// Change is a single change that would be made to the cloud or the cluster.
+type Change struct {
+	// Action is what would be done to the resource.
+	Action ChangeAction `json:"action"`
+
+	// Kind is the kind of resource being changed.
+	Kind ResourceKind `json:"kind"`
+
+	// Resource identifies the resource being changed, either by name or by cloud ID.
+	Resource string `json:"resource"`
+
+	// Description provides additional human-readable details about the change.
+	Description string `json:"description,omitempty"`
+}
+
+// Plan is the list of changes an operation would make, in the order it would make them.
+type Plan struct {
+	Changes []Change `json:"changes"`
+}
+
+// Add appends a change to the plan.
+func (p *Plan) Add(action ChangeAction, kind ResourceKind, resource, description string, args ...interface{}) {
+	p.Changes = append(p.Changes, Change{
+		Action:      action,
+		Kind:        kind,
+		Resource:    resource,
+		Description: fmt.Sprintf(description, args...),
+	})
+}
+
+// IsEmpty returns true if the plan contains no changes.
+func (p *Plan) IsEmpty() bool {
+	return len(p.Changes) == 0
+}
+
+// String returns a human-readable representation of the plan, one change per line.
+func (p *Plan) String() string {
+	if p.IsEmpty() {
+		return "No changes"
+	}
+
+	lines := make([]string, 0, len(p.Changes))
+
+	for i := range p.Changes {
+		lines = append(lines, p.Changes[i].String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// JSON returns the JSON representation of the plan.
+func (p *Plan) JSON() ([]byte, error) {
+	changes := p.Changes
+	if changes == nil {
+		changes = []Change{}
+	}
+
+	out, err := json.MarshalIndent(&Plan{Changes: changes}, "", "  ")
+
+	return out, errors.Wrap(err, "error marshalling the plan")
+}
+
+func (c *Change) String() string {
+	symbol := map[ChangeAction]string{
+		ChangeCreate: "+",
+		ChangeUpdate: "~",
+		ChangeDelete: "-",
+		ChangeApply:  "*",
+	}[c.Action]
+
+	str := fmt.Sprintf("%s %s %s %q", symbol, c.Action, c.Kind, c.Resource)
+	if c.Description != "" {
+		str += ": " + c.Description
+	}
+
+	return str
+}
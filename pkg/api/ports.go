@@ -0,0 +1,93 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AnySourceCIDR is the source CIDR used for public ports which aren't restricted to specific sources.
+const AnySourceCIDR = "0.0.0.0/0"
+
+// LastPort returns the last port covered by the spec, which is Port unless a range is specified.
+func (p PortSpec) LastPort() uint16 {
+	if p.EndPort > p.Port {
+		return p.EndPort
+	}
+
+	return p.Port
+}
+
+// IsRange returns true if the spec covers more than one port.
+func (p PortSpec) IsRange() bool {
+	return p.LastPort() != p.Port
+}
+
+// Sources returns the CIDRs allowed to reach the port.
+func (p PortSpec) Sources() []string {
+	if len(p.SourceCIDRs) == 0 {
+		return []string{AnySourceCIDR}
+	}
+
+	return p.SourceCIDRs
+}
+
+// Matches returns true if both specs cover the same ports of the same protocol, regardless of their sources.
+func (p PortSpec) Matches(other PortSpec) bool {
+	return p.Port == other.Port && p.LastPort() == other.LastPort() && strings.EqualFold(p.Protocol, other.Protocol)
+}
+
+// PortString returns the port or port range, e.g. "4500" or "4490-4500", or an empty string if Port is 0.
+func (p PortSpec) PortString() string {
+	switch {
+	case p.Port == 0 && p.EndPort == 0:
+		return ""
+	case p.IsRange():
+		return fmt.Sprintf("%d-%d", p.Port, p.LastPort())
+	default:
+		return fmt.Sprintf("%d", p.Port)
+	}
+}
+
+// String returns the port or port range followed by the protocol, e.g. "4500/udp", or just the protocol if the
+// spec has no port.
+func (p PortSpec) String() string {
+	if ports := p.PortString(); ports != "" {
+		return ports + "/" + p.Protocol
+	}
+
+	return p.Protocol
+}
+
+// PublicPortSpecs returns the public ports to open, with PublicSourceCIDRs applied to those which don't specify
+// their own sources.
+func (i *GatewayDeployInput) PublicPortSpecs() []PortSpec {
+	ports := make([]PortSpec, len(i.PublicPorts))
+
+	for j, port := range i.PublicPorts {
+		if len(port.SourceCIDRs) == 0 {
+			port.SourceCIDRs = i.PublicSourceCIDRs
+		}
+
+		ports[j] = port
+	}
+
+	return ports
+}
@@ -0,0 +1,51 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"k8s.io/apimachinery/pkg/util/errors"
+)
+
+const (
+	MessageValidatePrerequisites  = "Validating pre-requisites"
+	MessageValidatedPrerequisites = "Validated pre-requisites"
+)
+
+// ValidatePrerequisites runs the given checks as the "Validating pre-requisites" step, before any change is made. All
+// the checks are run, so that all the problems are reported together.
+func ValidatePrerequisites(reporter Reporter, checks ...func() error) error {
+	reporter.Started(MessageValidatePrerequisites)
+
+	errs := []error{}
+
+	for _, check := range checks {
+		if err := check(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Flatten(errors.NewAggregate(errs)); err != nil {
+		reporter.Failed(err)
+		return err
+	}
+
+	reporter.Succeeded(MessageValidatedPrerequisites)
+
+	return nil
+}
@@ -0,0 +1,66 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"k8s.io/apimachinery/pkg/util/errors"
+)
+
+// UnlimitedQuota is the Limit of a quota without a limit.
+const UnlimitedQuota = -1
+
+// Quota is the amount of a cloud resource which a change requires, along with the limit and the current usage of the
+// account's quota for that resource.
+type Quota struct {
+	// Name describes the quota, e.g. "vCPUs in region us-east-1".
+	Name string
+
+	Limit    int64
+	Used     int64
+	Required int64
+}
+
+// Available returns the amount of the resource which can still be used.
+func (q *Quota) Available() int64 {
+	if available := q.Limit - q.Used; available > 0 {
+		return available
+	}
+
+	return 0
+}
+
+// IsSufficient returns true if the quota allows for the required amount of the resource.
+func (q *Quota) IsSufficient() bool {
+	return q.Limit == UnlimitedQuota || q.Required <= q.Available()
+}
+
+// ValidateQuotas returns an error for each quota which doesn't allow for the required amount of the resource.
+func ValidateQuotas(quotas ...Quota) error {
+	errs := []error{}
+
+	for i := range quotas {
+		if !quotas[i].IsSufficient() {
+			errs = append(errs, Errorf(ErrQuotaExceeded, "insufficient quota for %s: %d required but only %d of %d available, "+
+				"request a quota increase or free up some resources", quotas[i].Name, quotas[i].Required, quotas[i].Available(),
+				quotas[i].Limit))
+		}
+	}
+
+	return errors.NewAggregate(errs)
+}
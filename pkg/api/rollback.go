@@ -0,0 +1,148 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// rollbackTimeout bounds the time spent reverting the completed steps of a failed operation.
+const rollbackTimeout = 5 * time.Minute
+
+// UndoFunc reverts a completed step. It must succeed if what the step created no longer exists.
+type UndoFunc func(ctx context.Context) error
+
+type undoStep struct {
+	description string
+	undo        UndoFunc
+}
+
+// Transaction collects the undo actions of the completed steps of an operation, so that they can be reverted if a
+// later step fails.
+type Transaction struct {
+	mutex sync.Mutex
+	steps []undoStep
+}
+
+type transactionKey struct{}
+
+// WithTransaction returns a context carrying a new Transaction, in which the steps run with the context register
+// their undo actions using OnRollback.
+func WithTransaction(ctx context.Context) (context.Context, *Transaction) {
+	transaction := &Transaction{}
+
+	return context.WithValue(ctx, transactionKey{}, transaction), transaction
+}
+
+// OnRollback registers the undo action of a completed step in the context's transaction. It does nothing if the
+// context has no transaction, i.e. if rollback wasn't requested.
+func OnRollback(ctx context.Context, description string, undo UndoFunc) {
+	transaction, ok := ctx.Value(transactionKey{}).(*Transaction)
+	if !ok {
+		return
+	}
+
+	transaction.mutex.Lock()
+	defer transaction.mutex.Unlock()
+
+	transaction.steps = append(transaction.steps, undoStep{description: description, undo: undo})
+}
+
+// Rollback runs the registered undo actions in the reverse order of their registration, reporting each one. All the
+// actions are attempted even if some fail; the errors are combined in the returned error.
+func (t *Transaction) Rollback(ctx context.Context, reporter Reporter) error {
+	t.mutex.Lock()
+	steps := t.steps
+	t.steps = nil
+	t.mutex.Unlock()
+
+	failures := []string{}
+
+	for i := len(steps) - 1; i >= 0; i-- {
+		reporter.Started("Rolling back: %s", steps[i].description)
+
+		if err := steps[i].undo(ctx); err != nil {
+			reporter.Failed(err)
+			failures = append(failures, fmt.Sprintf("%s: %v", steps[i].description, err))
+
+			continue
+		}
+
+		reporter.Succeeded("Rolled back: %s", steps[i].description)
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("error rolling back %s", strings.Join(failures, "; "))
+	}
+
+	return nil
+}
+
+// detachedContext carries the values of its parent, but not its deadline or cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (d detachedContext) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
+
+// RunWithRollback runs the operation. If rollback is true, the steps of the operation register their undo actions
+// and, if the operation fails, the completed steps are reverted, even if the operation's context is done. The
+// operation's error is returned in any case, with details of any failure to roll back.
+func RunWithRollback(ctx context.Context, rollback bool, reporter Reporter, operation func(ctx context.Context) error) error {
+	if !rollback {
+		return operation(ctx)
+	}
+
+	ctx, transaction := WithTransaction(ctx)
+
+	err := operation(ctx)
+	if err == nil {
+		return nil
+	}
+
+	// The operation may have failed because its context was cancelled or timed out; the undo actions still need to run.
+	rollbackCtx, cancel := context.WithTimeout(detachedContext{parent: ctx}, rollbackTimeout)
+	defer cancel()
+
+	if rollbackErr := transaction.Rollback(rollbackCtx, reporter); rollbackErr != nil {
+		return errors.WithMessage(err, rollbackErr.Error())
+	}
+
+	return err
+}
@@ -0,0 +1,115 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+var _ = Describe("RunWithRollback", func() {
+	var (
+		reporter *recordingReporter
+		undone   []string
+	)
+
+	BeforeEach(func() {
+		reporter = &recordingReporter{}
+		undone = nil
+	})
+
+	step := func(ctx context.Context, name string) {
+		api.OnRollback(ctx, name, func(ctx context.Context) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			undone = append(undone, name)
+
+			return nil
+		})
+	}
+
+	It("should revert the completed steps in reverse order when the operation fails", func() {
+		err := api.RunWithRollback(context.Background(), true, reporter, func(ctx context.Context) error {
+			step(ctx, "first")
+			step(ctx, "second")
+
+			return errors.New("boom")
+		})
+
+		Expect(err).To(MatchError("boom"))
+		Expect(undone).To(Equal([]string{"second", "first"}))
+		Expect(reporter.reports).To(Equal([]string{
+			"started: Rolling back: second",
+			"succeeded: Rolled back: second",
+			"started: Rolling back: first",
+			"succeeded: Rolled back: first",
+		}))
+	})
+
+	It("should not revert anything when the operation succeeds", func() {
+		Expect(api.RunWithRollback(context.Background(), true, reporter, func(ctx context.Context) error {
+			step(ctx, "first")
+			return nil
+		})).To(Succeed())
+
+		Expect(undone).To(BeEmpty())
+	})
+
+	It("should not register undo actions when rollback isn't requested", func() {
+		Expect(api.RunWithRollback(context.Background(), false, reporter, func(ctx context.Context) error {
+			step(ctx, "first")
+			return errors.New("boom")
+		})).To(MatchError("boom"))
+
+		Expect(undone).To(BeEmpty())
+	})
+
+	It("should revert the completed steps when the operation's context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		err := api.RunWithRollback(ctx, true, reporter, func(ctx context.Context) error {
+			step(ctx, "first")
+			cancel()
+
+			return ctx.Err()
+		})
+
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(undone).To(Equal([]string{"first"}))
+	})
+
+	It("should report the failures to roll back with the operation's error", func() {
+		err := api.RunWithRollback(context.Background(), true, reporter, func(ctx context.Context) error {
+			api.OnRollback(ctx, "first", func(ctx context.Context) error {
+				return errors.New("stuck")
+			})
+
+			return errors.New("boom")
+		})
+
+		Expect(err).To(MatchError("error rolling back first: stuck: boom"))
+		Expect(reporter.reports).To(ContainElement("failed: stuck"))
+	})
+})
@@ -0,0 +1,75 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import "fmt"
+
+// FirewallStatus describes a security group or firewall rule which opens ports for Submariner.
+type FirewallStatus struct {
+	Kind  ResourceKind `json:"kind"`
+	Name  string       `json:"name"`
+	Ports []PortSpec   `json:"ports"`
+}
+
+// CloudStatus describes the Submariner preparation which currently exists in a cloud.
+type CloudStatus struct {
+	// InternalPorts lists the ports which are open for intra-cluster communication.
+	InternalPorts []PortSpec `json:"internalPorts"`
+
+	// Issues lists the partial or inconsistent states which were found.
+	Issues []string `json:"issues,omitempty"`
+}
+
+// GatewayStatus describes the Submariner gateway deployment which currently exists in a cloud.
+type GatewayStatus struct {
+	// Firewall is the security group or firewall rule opening the public ports, nil if it doesn't exist.
+	Firewall *FirewallStatus `json:"firewall,omitempty"`
+
+	// GatewaySubnets lists the subnets tagged for gateways, on clouds which use subnet tags.
+	GatewaySubnets []string `json:"gatewaySubnets,omitempty"`
+
+	// MachineSets lists the dedicated gateway MachineSets.
+	MachineSets []string `json:"machineSets,omitempty"`
+
+	// GatewayNodes lists the nodes labelled as gateways, on deployers which have access to the cluster's nodes.
+	GatewayNodes []string `json:"gatewayNodes,omitempty"`
+
+	// Issues lists the partial or inconsistent states which were found.
+	Issues []string `json:"issues,omitempty"`
+}
+
+// AddIssue records a partial or inconsistent state.
+func (s *CloudStatus) AddIssue(format string, args ...interface{}) {
+	s.Issues = append(s.Issues, fmt.Sprintf(format, args...))
+}
+
+// IsConsistent returns true if no partial or inconsistent state was found.
+func (s *CloudStatus) IsConsistent() bool {
+	return len(s.Issues) == 0
+}
+
+// AddIssue records a partial or inconsistent state.
+func (s *GatewayStatus) AddIssue(format string, args ...interface{}) {
+	s.Issues = append(s.Issues, fmt.Sprintf(format, args...))
+}
+
+// IsConsistent returns true if no partial or inconsistent state was found.
+func (s *GatewayStatus) IsConsistent() bool {
+	return len(s.Issues) == 0
+}
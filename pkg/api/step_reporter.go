@@ -0,0 +1,219 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/errors"
+)
+
+// StepReporter is a Reporter which emits structured events. Each Started call opens a step which is closed by
+// the following Succeeded or Failed call; steps started while another is open are nested within it.
+type StepReporter interface {
+	Reporter
+
+	// Warning will report a non-fatal problem with the current operation.
+	Warning(message string, args ...interface{})
+
+	// ForResource returns a StepReporter sharing this reporter's steps whose events are attributed to the given resource.
+	ForResource(kind ResourceKind, id string) StepReporter
+}
+
+// warningReporter is implemented by the reporters which can report a warning without completing the current step.
+type warningReporter interface {
+	Warning(message string, args ...interface{})
+}
+
+// ForResource returns a Reporter attributing the steps it starts to the given resource, if the given reporter is a
+// StepReporter, otherwise the reporter itself.
+func ForResource(reporter Reporter, kind ResourceKind, id string) Reporter {
+	if steps, ok := reporter.(StepReporter); ok {
+		return steps.ForResource(kind, id)
+	}
+
+	return reporter
+}
+
+// Warn reports a non-fatal problem with the current operation, if the given reporter supports warnings, as a
+// StepReporter does. Other reporters can only report a step's completion, which a warning isn't, so it is dropped.
+func Warn(reporter Reporter, message string, args ...interface{}) {
+	if warnings, ok := reporter.(warningReporter); ok {
+		warnings.Warning(message, args...)
+	}
+}
+
+type step struct {
+	id           string
+	started      time.Time
+	children     int
+	resourceKind ResourceKind
+	resourceID   string
+}
+
+type stepState struct {
+	sync.Mutex
+	eventReporter EventReporter
+	now           func() time.Time
+	open          []*step
+	steps         int
+}
+
+type stepReporter struct {
+	state        *stepState
+	resourceKind ResourceKind
+	resourceID   string
+}
+
+// NewStepReporter returns a StepReporter which sends its events to the given EventReporter. It can be passed
+// anywhere a Reporter is expected.
+func NewStepReporter(eventReporter EventReporter) StepReporter {
+	return &stepReporter{state: &stepState{eventReporter: eventReporter, now: time.Now}}
+}
+
+func (r *stepReporter) Started(message string, args ...interface{}) {
+	r.state.Lock()
+	defer r.state.Unlock()
+
+	event := r.newEvent(EventStarted, LevelInfo, fmt.Sprintf(message, args...))
+
+	s := r.state.newStep(event.Time)
+	s.resourceKind = r.resourceKind
+	s.resourceID = r.resourceID
+	event.StepID = s.id
+	r.state.open = append(r.state.open, s)
+
+	r.state.eventReporter.Report(event)
+}
+
+func (r *stepReporter) Succeeded(message string, args ...interface{}) {
+	r.state.Lock()
+	defer r.state.Unlock()
+
+	r.state.eventReporter.Report(r.closeStep(r.newEvent(EventSucceeded, LevelInfo, fmt.Sprintf(message, args...))))
+}
+
+func (r *stepReporter) Failed(errs ...error) {
+	r.state.Lock()
+	defer r.state.Unlock()
+
+	event := r.newEvent(EventFailed, LevelError, "")
+
+	if err := errors.NewAggregate(errs); err != nil {
+		event.Error = err.Error()
+	}
+
+	r.state.eventReporter.Report(r.closeStep(event))
+}
+
+func (r *stepReporter) Warning(message string, args ...interface{}) {
+	r.state.Lock()
+	defer r.state.Unlock()
+
+	event := r.newEvent(EventMessage, LevelWarning, fmt.Sprintf(message, args...))
+
+	if current := r.state.current(); current != nil {
+		setStep(event, current)
+	} else {
+		event.StepID = r.state.newStep(event.Time).id
+	}
+
+	r.state.eventReporter.Report(event)
+}
+
+func (r *stepReporter) ForResource(kind ResourceKind, id string) StepReporter {
+	return &stepReporter{state: r.state, resourceKind: kind, resourceID: id}
+}
+
+func (r *stepReporter) newEvent(eventType EventType, level EventLevel, message string) *Event {
+	event := &Event{
+		Time:         r.state.now(),
+		Type:         eventType,
+		Level:        level,
+		ResourceKind: r.resourceKind,
+		ResourceID:   r.resourceID,
+		Message:      message,
+	}
+
+	if current := r.state.current(); current != nil {
+		event.ParentID = current.id
+	}
+
+	return event
+}
+
+// closeStep completes the event with the innermost open step and closes it. If no step is open, the event is
+// reported as a step of its own.
+func (r *stepReporter) closeStep(event *Event) *Event {
+	current := r.state.current()
+	if current == nil {
+		event.StepID = r.state.newStep(event.Time).id
+		return event
+	}
+
+	r.state.open = r.state.open[:len(r.state.open)-1]
+
+	setStep(event, current)
+	event.Duration = event.Time.Sub(current.started)
+
+	return event
+}
+
+// setStep attributes the event to the given step, including the step's resource unless the event already has one.
+func setStep(event *Event, s *step) {
+	event.StepID = s.id
+	event.ParentID = parentID(s.id)
+
+	if event.ResourceKind == "" && event.ResourceID == "" {
+		event.ResourceKind = s.resourceKind
+		event.ResourceID = s.resourceID
+	}
+}
+
+func (s *stepState) current() *step {
+	if len(s.open) == 0 {
+		return nil
+	}
+
+	return s.open[len(s.open)-1]
+}
+
+func (s *stepState) newStep(started time.Time) *step {
+	parent := s.current()
+	if parent == nil {
+		s.steps++
+		return &step{id: strconv.Itoa(s.steps), started: started}
+	}
+
+	parent.children++
+
+	return &step{id: parent.id + "." + strconv.Itoa(parent.children), started: started}
+}
+
+func parentID(id string) string {
+	if i := strings.LastIndex(id, "."); i >= 0 {
+		return id[:i]
+	}
+
+	return ""
+}
@@ -0,0 +1,256 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+var _ = Describe("StepReporter", func() {
+	var (
+		events   *recordingEventReporter
+		reporter api.StepReporter
+	)
+
+	BeforeEach(func() {
+		events = &recordingEventReporter{}
+		reporter = api.NewStepReporter(events)
+	})
+
+	It("should number the steps and nest the steps started within another", func() {
+		reporter.Started("Preparing")
+		reporter.Started("Opening port %d", 4500)
+		reporter.Succeeded("Opened port %d", 4500)
+		reporter.Started("Opening port %d", 4490)
+		reporter.Failed(errors.New("boom"))
+		reporter.Succeeded("Prepared")
+		reporter.Started("Deploying")
+		reporter.Succeeded("Deployed")
+
+		Expect(events.summary()).To(Equal([]string{
+			"started 1 Preparing",
+			"started 1.1 (1) Opening port 4500",
+			"succeeded 1.1 (1) Opened port 4500",
+			"started 1.2 (1) Opening port 4490",
+			"failed 1.2 (1) boom",
+			"succeeded 1 Prepared",
+			"started 2 Deploying",
+			"succeeded 2 Deployed",
+		}))
+
+		Expect(events.events[0].Duration).To(BeZero())
+		Expect(events.events[4].Level).To(Equal(api.LevelError))
+	})
+
+	It("should attribute the steps started for a resource to it until they complete", func() {
+		reporter.Started("Deploying")
+		reporter.ForResource(api.SecurityGroupResource, "sg-1").Started("Creating the security group")
+		reporter.Succeeded("Created the security group")
+		reporter.Succeeded("Deployed")
+
+		Expect(events.events[1].ResourceKind).To(Equal(api.SecurityGroupResource))
+		Expect(events.events[1].ResourceID).To(Equal("sg-1"))
+		Expect(events.events[2].ResourceKind).To(Equal(api.SecurityGroupResource))
+		Expect(events.events[2].ResourceID).To(Equal("sg-1"))
+		Expect(events.events[3].ResourceKind).To(BeEmpty())
+	})
+
+	It("should report warnings within the current step without completing it", func() {
+		reporter.Started("Deploying")
+		reporter.Warning("Only %d subnets are available", 1)
+		reporter.Succeeded("Deployed")
+		reporter.Warning("Outside of any step")
+
+		Expect(events.summary()).To(Equal([]string{
+			"started 1 Deploying",
+			"message 1 Only 1 subnets are available",
+			"succeeded 1 Deployed",
+			"message 2 Outside of any step",
+		}))
+		Expect(events.events[1].Level).To(Equal(api.LevelWarning))
+	})
+
+	It("should report completions without a started step as steps of their own", func() {
+		reporter.Succeeded("Done")
+		reporter.Failed(errors.New("boom"))
+
+		Expect(events.summary()).To(Equal([]string{"succeeded 1 Done", "failed 2 boom"}))
+	})
+})
+
+var _ = Describe("ForResource and Warn", func() {
+	When("the reporter is a StepReporter", func() {
+		It("should attribute the step and report the warning", func() {
+			events := &recordingEventReporter{}
+			reporter := api.NewStepReporter(events)
+
+			api.ForResource(reporter, api.SubnetTagResource, "subnet-1").Started("Tagging")
+			api.Warn(reporter, "Careful")
+			reporter.Succeeded("Tagged")
+
+			Expect(events.summary()).To(Equal([]string{"started 1 Tagging", "message 1 Careful", "succeeded 1 Tagged"}))
+			Expect(events.events[1].ResourceID).To(Equal("subnet-1"))
+		})
+	})
+
+	When("the reporter is a plain Reporter", func() {
+		It("should use the reporter as is and drop the warning", func() {
+			reporter := &recordingReporter{}
+
+			api.ForResource(reporter, api.SubnetTagResource, "subnet-1").Started("Tagging")
+			api.Warn(reporter, "Careful")
+			reporter.Succeeded("Tagged")
+
+			Expect(reporter.reports).To(Equal([]string{"started: Tagging", "succeeded: Tagged"}))
+		})
+	})
+})
+
+var _ = Describe("EventReporters", func() {
+	It("should write each event as a line of JSON", func() {
+		out := &bytes.Buffer{}
+		reporter := api.NewStepReporter(api.NewJSONLinesReporter(out))
+
+		reporter.ForResource(api.FirewallRuleResource, "rule-1").Started("Creating")
+		reporter.Succeeded("Created")
+
+		lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+		Expect(lines).To(HaveLen(2))
+
+		event := &api.Event{}
+		Expect(json.Unmarshal(lines[1], event)).To(Succeed())
+		Expect(event.Type).To(Equal(api.EventSucceeded))
+		Expect(event.StepID).To(Equal("1"))
+		Expect(event.ResourceKind).To(Equal(api.FirewallRuleResource))
+		Expect(event.ResourceID).To(Equal("rule-1"))
+		Expect(event.Message).To(Equal("Created"))
+	})
+
+	It("should forward each event to all the reporters in order", func() {
+		first := &recordingEventReporter{}
+		second := &recordingEventReporter{}
+		reporter := api.NewStepReporter(api.NewMultiReporter(first, second))
+
+		reporter.Started("Preparing")
+		reporter.Succeeded("Prepared")
+
+		Expect(first.summary()).To(Equal([]string{"started 1 Preparing", "succeeded 1 Prepared"}))
+		Expect(second.summary()).To(Equal(first.summary()))
+	})
+
+	Describe("adapting a Reporter", func() {
+		It("should forward the steps", func() {
+			adapted := &recordingReporter{}
+			reporter := api.NewStepReporter(api.NewReporterAdapter(adapted))
+
+			reporter.Started("Preparing")
+			reporter.Succeeded("Prepared")
+			reporter.Started("Deploying")
+			reporter.Failed(errors.New("boom"))
+
+			Expect(adapted.reports).To(Equal([]string{
+				"started: Preparing", "succeeded: Prepared", "started: Deploying", "failed: boom",
+			}))
+		})
+
+		It("should forward the warnings to a reporter supporting them without completing the step", func() {
+			adapted := &recordingWarningReporter{}
+			reporter := api.NewStepReporter(api.NewReporterAdapter(adapted))
+
+			reporter.Started("Deploying")
+			reporter.Warning("Careful")
+			reporter.Succeeded("Deployed")
+
+			Expect(adapted.reports).To(Equal([]string{"started: Deploying", "warning: Careful", "succeeded: Deployed"}))
+		})
+
+		It("should drop the warnings for other reporters", func() {
+			adapted := &recordingReporter{}
+			reporter := api.NewStepReporter(api.NewReporterAdapter(adapted))
+
+			reporter.Started("Deploying")
+			reporter.Warning("Careful")
+			reporter.Succeeded("Deployed")
+
+			Expect(adapted.reports).To(Equal([]string{"started: Deploying", "succeeded: Deployed"}))
+		})
+	})
+})
+
+type recordingEventReporter struct {
+	events []*api.Event
+}
+
+func (r *recordingEventReporter) Report(event *api.Event) {
+	r.events = append(r.events, event)
+}
+
+// summary returns the type, step ID, parent ID if any, and message or error of each event.
+func (r *recordingEventReporter) summary() []string {
+	summary := []string{}
+
+	for _, event := range r.events {
+		text := event.Message
+		if event.Type == api.EventFailed {
+			text = event.Error
+		}
+
+		parent := ""
+		if event.ParentID != "" {
+			parent = fmt.Sprintf(" (%s)", event.ParentID)
+		}
+
+		summary = append(summary, fmt.Sprintf("%s %s%s %s", event.Type, event.StepID, parent, text))
+	}
+
+	return summary
+}
+
+type recordingReporter struct {
+	reports []string
+}
+
+func (r *recordingReporter) Started(message string, args ...interface{}) {
+	r.reports = append(r.reports, "started: "+fmt.Sprintf(message, args...))
+}
+
+func (r *recordingReporter) Succeeded(message string, args ...interface{}) {
+	r.reports = append(r.reports, "succeeded: "+fmt.Sprintf(message, args...))
+}
+
+func (r *recordingReporter) Failed(errs ...error) {
+	for _, err := range errs {
+		r.reports = append(r.reports, "failed: "+err.Error())
+	}
+}
+
+type recordingWarningReporter struct {
+	recordingReporter
+}
+
+func (r *recordingWarningReporter) Warning(message string, args ...interface{}) {
+	r.reports = append(r.reports, "warning: "+fmt.Sprintf(message, args...))
+}
@@ -0,0 +1,227 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/util/errors"
+)
+
+// TracerName is the name of the tracer creating the spans of the steps and of the cloud API calls.
+const TracerName = "github.com/submariner-io/cloud-prepare"
+
+// The attributes of the spans of the steps attributed to a resource, see ForResource.
+const (
+	resourceKindKey = attribute.Key("cloud_prepare.resource.kind")
+	resourceIDKey   = attribute.Key("cloud_prepare.resource.id")
+)
+
+// stepTracer is a StepReporter which traces each step in a span before passing it on. Steps started while another is
+// open are nested within it, so their spans are its children.
+type stepTracer struct {
+	spans        *openSpans
+	reporter     Reporter
+	resourceKind ResourceKind
+	resourceID   string
+}
+
+// openSpans are the spans of the open steps, shared by a stepTracer and the tracers attributing steps to resources.
+type openSpans struct {
+	sync.Mutex
+	ctx    context.Context
+	tracer trace.Tracer
+	open   []trace.Span
+}
+
+type stepTracerKey struct{}
+
+type callKey struct{}
+
+// TraceSteps returns a context and a Reporter which trace an operation run with them. Each step reported with the
+// returned Reporter, from Started to Succeeded or Failed, becomes a span, which is the parent of the spans of the steps
+// nested within it and of the cloud API calls made with the returned context while the step runs. The spans are
+// created by the given tracer provider, or by the global one if it's nil. The returned Reporter is a StepReporter,
+// passing the warnings and the resources of the steps on to the given reporter if it supports them.
+func TraceSteps(ctx context.Context, reporter Reporter, provider trace.TracerProvider) (context.Context, Reporter) {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	tracer := &stepTracer{
+		spans: &openSpans{
+			ctx:    ctx,
+			tracer: provider.Tracer(TracerName),
+		},
+		reporter: reporter,
+	}
+
+	return context.WithValue(ctx, stepTracerKey{}, tracer.spans), tracer
+}
+
+func (t *stepTracer) Started(message string, args ...interface{}) {
+	attributes := []attribute.KeyValue{}
+	if t.resourceKind != "" || t.resourceID != "" {
+		attributes = append(attributes, resourceKindKey.String(string(t.resourceKind)), resourceIDKey.String(t.resourceID))
+	}
+
+	t.spans.start(fmt.Sprintf(message, args...), attributes)
+
+	t.reporter.Started(message, args...)
+}
+
+func (t *stepTracer) Succeeded(message string, args ...interface{}) {
+	if step := t.spans.complete(); step != nil {
+		step.AddEvent(fmt.Sprintf(message, args...))
+		step.SetStatus(codes.Ok, "")
+		step.End()
+	}
+
+	t.reporter.Succeeded(message, args...)
+}
+
+func (t *stepTracer) Failed(errs ...error) {
+	if step := t.spans.complete(); step != nil {
+		for _, err := range errs {
+			if err != nil {
+				step.RecordError(err)
+			}
+		}
+
+		if err := errors.NewAggregate(errs); err != nil {
+			step.SetStatus(codes.Error, err.Error())
+		} else {
+			step.SetStatus(codes.Error, "")
+		}
+
+		step.End()
+	}
+
+	t.reporter.Failed(errs...)
+}
+
+// Warning adds the warning as an event of the current step's span, and passes it on if the reporter supports warnings.
+func (t *stepTracer) Warning(message string, args ...interface{}) {
+	if step := t.spans.current(); step != nil {
+		step.AddEvent(fmt.Sprintf(message, args...), trace.WithAttributes(attribute.String("level", string(LevelWarning))))
+	}
+
+	Warn(t.reporter, message, args...)
+}
+
+func (t *stepTracer) ForResource(kind ResourceKind, id string) StepReporter {
+	return &stepTracer{
+		spans:        t.spans,
+		reporter:     ForResource(t.reporter, kind, id),
+		resourceKind: kind,
+		resourceID:   id,
+	}
+}
+
+// start starts the span of a step, as a child of the span of the innermost open step if any.
+func (s *openSpans) start(name string, attributes []attribute.KeyValue) {
+	s.Lock()
+	defer s.Unlock()
+
+	parent := s.ctx
+	if len(s.open) > 0 {
+		parent = trace.ContextWithSpan(parent, s.open[len(s.open)-1])
+	}
+
+	_, span := s.tracer.Start(parent, name, trace.WithAttributes(attributes...))
+	s.open = append(s.open, span)
+}
+
+// complete returns the span of the innermost open step, which is no longer open, or nil if no step is open.
+func (s *openSpans) complete() trace.Span {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(s.open) == 0 {
+		return nil
+	}
+
+	span := s.open[len(s.open)-1]
+	s.open = s.open[:len(s.open)-1]
+
+	return span
+}
+
+// current returns the span of the innermost open step, or nil if no step is open.
+func (s *openSpans) current() trace.Span {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(s.open) == 0 {
+		return nil
+	}
+
+	return s.open[len(s.open)-1]
+}
+
+// StartCall starts the span of a cloud API call with the given name and attributes. Its parent is the span of the
+// call being made with the context, if any, then the innermost open step of the operation traced with the context by
+// TraceSteps, if any, then the context's span. The returned context carries the new span, which must be ended with
+// EndCall.
+func StartCall(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	parent := ctx
+
+	var tracer trace.Tracer
+
+	if steps, ok := ctx.Value(stepTracerKey{}).(*openSpans); ok {
+		tracer = steps.tracer
+
+		if ctx.Value(callKey{}) == nil {
+			parent = steps.withStep(ctx)
+		}
+	} else if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+		tracer = span.TracerProvider().Tracer(TracerName)
+	} else {
+		tracer = otel.Tracer(TracerName)
+	}
+
+	ctx, span := tracer.Start(parent, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+
+	return context.WithValue(ctx, callKey{}, true), span
+}
+
+// withStep returns the given context carrying the span of the innermost open step, if any.
+func (s *openSpans) withStep(ctx context.Context) context.Context {
+	if step := s.current(); step != nil {
+		return trace.ContextWithSpan(ctx, step)
+	}
+
+	return ctx
+}
+
+// EndCall ends the span of a cloud API call started by StartCall, recording the error of the call if any.
+func EndCall(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,153 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var _ = Describe("TraceSteps", func() {
+	var (
+		exporter *tracetest.InMemoryExporter
+		events   *recordingEventReporter
+		ctx      context.Context
+		reporter api.Reporter
+	)
+
+	BeforeEach(func() {
+		exporter = tracetest.NewInMemoryExporter()
+		events = &recordingEventReporter{}
+		ctx, reporter = api.TraceSteps(context.TODO(), api.NewStepReporter(events),
+			sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	})
+
+	findSpan := func(name string) *tracetest.SpanStub {
+		spans := exporter.GetSpans()
+
+		for i := range spans {
+			if spans[i].Name == name {
+				return &spans[i]
+			}
+		}
+
+		Fail("span " + name + " not found")
+
+		return nil
+	}
+
+	It("should trace the steps started within another in child spans", func() {
+		reporter.Started("Preparing")
+		reporter.Started("Opening port %d", 4500)
+		reporter.Succeeded("Opened port %d", 4500)
+		reporter.Started("Opening port %d", 4490)
+		reporter.Failed(errors.New("boom"))
+		reporter.Succeeded("Prepared")
+
+		preparing := findSpan("Preparing")
+		Expect(preparing.Parent.IsValid()).To(BeFalse())
+		Expect(preparing.Status.Code).To(Equal(codes.Ok))
+
+		opened := findSpan("Opening port 4500")
+		Expect(opened.Parent.SpanID()).To(Equal(preparing.SpanContext.SpanID()))
+		Expect(opened.Status.Code).To(Equal(codes.Ok))
+
+		failed := findSpan("Opening port 4490")
+		Expect(failed.Parent.SpanID()).To(Equal(preparing.SpanContext.SpanID()))
+		Expect(failed.Status).To(Equal(sdktrace.Status{Code: codes.Error, Description: "boom"}))
+	})
+
+	It("should make the innermost open step the parent of the calls", func() {
+		reporter.Started("Preparing")
+		reporter.Started("Opening port %d", 4500)
+
+		_, call := api.StartCall(ctx, "CreateRule")
+		api.EndCall(call, nil)
+
+		reporter.Succeeded("Opened port %d", 4500)
+
+		_, call = api.StartCall(ctx, "DescribeRules")
+		api.EndCall(call, nil)
+
+		reporter.Succeeded("Prepared")
+
+		Expect(findSpan("CreateRule").Parent.SpanID()).To(Equal(findSpan("Opening port 4500").SpanContext.SpanID()))
+		Expect(findSpan("DescribeRules").Parent.SpanID()).To(Equal(findSpan("Preparing").SpanContext.SpanID()))
+	})
+
+	It("should pass the steps on to the reporter", func() {
+		reporter.Started("Preparing")
+		reporter.Succeeded("Prepared")
+
+		Expect(events.summary()).To(Equal([]string{"started 1 Preparing", "succeeded 1 Prepared"}))
+	})
+
+	It("should attribute the steps started for a resource to it", func() {
+		reporter.Started("Deploying")
+		api.ForResource(reporter, api.SecurityGroupResource, "sg-1").Started("Creating the security group")
+		reporter.Succeeded("Created the security group")
+		reporter.Succeeded("Deployed")
+
+		creating := findSpan("Creating the security group")
+		Expect(creating.Parent.SpanID()).To(Equal(findSpan("Deploying").SpanContext.SpanID()))
+		Expect(creating.Attributes).To(ContainElements(
+			attribute.String("cloud_prepare.resource.kind", string(api.SecurityGroupResource)),
+			attribute.String("cloud_prepare.resource.id", "sg-1")))
+
+		Expect(events.events[1].ResourceKind).To(Equal(api.SecurityGroupResource))
+		Expect(events.events[1].ResourceID).To(Equal("sg-1"))
+		Expect(events.events[2].ResourceID).To(Equal("sg-1"))
+	})
+
+	It("should add the warnings to the current step and pass them on to the reporter", func() {
+		reporter.Started("Validating")
+		api.Warn(reporter, "No dry run for %s", "OpenStack")
+		reporter.Succeeded("Validated")
+
+		validating := findSpan("Validating")
+		Expect(validating.Events).To(HaveLen(2))
+		Expect(validating.Events[0].Name).To(Equal("No dry run for OpenStack"))
+
+		Expect(events.events[1].Level).To(Equal(api.LevelWarning))
+		Expect(events.events[1].Message).To(Equal("No dry run for OpenStack"))
+	})
+
+	When("the reporter doesn't support warnings", func() {
+		BeforeEach(func() {
+			ctx, reporter = api.TraceSteps(context.TODO(), &recordingReporter{},
+				sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+		})
+
+		It("should still trace them", func() {
+			reporter.Started("Validating")
+			api.Warn(reporter, "No dry run")
+			reporter.Succeeded("Validated")
+
+			Expect(findSpan("Validating").Events[0].Name).To(Equal("No dry run"))
+		})
+	})
+})
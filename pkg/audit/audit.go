@@ -0,0 +1,288 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit records the mutating calls made by the providers, such as the creation of security groups or the
+// labelling of nodes, in a tamper-evident log written to pluggable sinks.
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+// Redacted replaces the values of the secret fields in the request summaries.
+const Redacted = "REDACTED"
+
+// secretNames matches the names of the request fields whose values are redacted.
+const secretNames = `password|secret|token|credential|privatekey|accesskey|userdata`
+
+var (
+	// secretField matches the names of the fields of structured requests whose values are redacted.
+	secretField = regexp.MustCompile(`(?i)` + secretNames)
+
+	// secretAssignment matches the secret fields assigned in the requests summarized as text, e.g. "password=hunter2"
+	// or "apiToken: abc", capturing the name and separator, whose value is redacted.
+	secretAssignment = regexp.MustCompile(`(?i)([\w.-]*(?:` + secretNames + `)[\w.-]*"?\s*[:=]\s*)("[^"]*"|[^\s,;&]+)`)
+)
+
+// Entry is the audit record of a mutating call.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Provider  string    `json:"provider"`
+	Operation string    `json:"operation"`
+	Resource  string    `json:"resource"`
+
+	// Request summarizes the request, as JSON unless it was given as text, with the values of the secret fields
+	// redacted.
+	Request string `json:"request"`
+
+	// Error is the error returned by the call, if it failed.
+	Error string `json:"error,omitempty"`
+
+	// PreviousHash is the Hash of the previous record in the log, empty for the first record.
+	PreviousHash string `json:"previousHash"`
+
+	// Hash is the hex-encoded HMAC-SHA256, keyed with the key of the Log, of the record's other fields, including
+	// PreviousHash, which chains the records so that any change to the log can be detected by Verify. Without the key,
+	// the records can't be forged.
+	Hash string `json:"hash"`
+}
+
+// Sink writes the audit records, e.g. to a file.
+type Sink interface {
+	Write(ctx context.Context, entry *Entry) error
+}
+
+// chainedSink is a Sink which already holds records, which a new Log continues the chain of.
+type chainedSink interface {
+	LastHash() string
+}
+
+// Log chains the records of the mutating calls and writes them to its sinks. A nil Log records nothing.
+type Log struct {
+	// ErrorHandler handles the failures to write a record, which don't fail the call being recorded. It defaults to
+	// the Kubernetes runtime error handler, which logs them.
+	ErrorHandler func(error)
+
+	mutex    sync.Mutex
+	key      []byte
+	sinks    []Sink
+	lastHash string
+}
+
+type logKey struct{}
+
+// NewLog returns a Log writing its records, whose hashes are keyed with the given secret key, to the given sinks. If
+// a sink already holds records, e.g. a FileSink opened on an existing file, the chain continues from its last record.
+func NewLog(key []byte, sinks ...Sink) *Log {
+	log := &Log{key: key, sinks: sinks}
+
+	for _, sink := range sinks {
+		if chained, ok := sink.(chainedSink); ok {
+			log.lastHash = chained.LastHash()
+			break
+		}
+	}
+
+	return log
+}
+
+// NewContext returns a context carrying the given log, which the providers record the mutating calls run with it in.
+// Nothing is recorded for the calls run with a context carrying no log, which is the default.
+func NewContext(ctx context.Context, log *Log) context.Context {
+	if log == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, logKey{}, log)
+}
+
+func fromContext(ctx context.Context) *Log {
+	log, _ := ctx.Value(logKey{}).(*Log)
+	return log
+}
+
+// Record records a mutating call in the log carried by the context, if any, see Log.Record.
+func Record(ctx context.Context, provider, operation, resource string, request interface{}, err error) {
+	fromContext(ctx).Record(ctx, provider, operation, resource, request, err)
+}
+
+// Record records a mutating call of the given provider, e.g. "aws", and operation, e.g. "CreateSecurityGroup", on the
+// given resource, with a summary of the request and the error returned by the call, if any.
+func (l *Log) Record(ctx context.Context, provider, operation, resource string, request interface{}, err error) {
+	if l == nil {
+		return
+	}
+
+	record := &Entry{
+		Time:      time.Now().UTC(),
+		Provider:  provider,
+		Operation: operation,
+		Resource:  resource,
+		Request:   summarize(request),
+	}
+
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	record.PreviousHash = l.lastHash
+	record.Hash = hash(l.key, record)
+	l.lastHash = record.Hash
+
+	for _, sink := range l.sinks {
+		if err := sink.Write(ctx, record); err != nil {
+			l.handleError(errors.Wrapf(err, "error writing the audit record of %s %s", provider, operation))
+		}
+	}
+}
+
+// LastHash returns the hash of the last record in the log, which anchors its chain: keeping it apart from the sinks,
+// e.g. in a Secret, allows Verify to detect the removal of the last records.
+func (l *Log) LastHash() string {
+	if l == nil {
+		return ""
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.lastHash
+}
+
+func (l *Log) handleError(err error) {
+	if l.ErrorHandler != nil {
+		l.ErrorHandler(err)
+		return
+	}
+
+	utilruntime.HandleError(err)
+}
+
+// Verify checks that the given records, in order, form an unbroken chain, from the first record of the log to the one
+// whose hash is lastHash, as returned by Log.LastHash; an empty lastHash expects no records. It also checks that none
+// of the records was changed, using the key of the Log.
+func Verify(key []byte, records []Entry, lastHash string) error {
+	if len(records) > 0 && records[0].PreviousHash != "" {
+		return errors.New("audit record 0 isn't the first record of the log, records were removed from its start")
+	}
+
+	for i := range records {
+		if i > 0 && records[i].PreviousHash != records[i-1].Hash {
+			return fmt.Errorf("audit record %d doesn't follow record %d, records were removed or reordered", i, i-1)
+		}
+
+		if !hmac.Equal([]byte(hash(key, &records[i])), []byte(records[i].Hash)) {
+			return fmt.Errorf("audit record %d was changed", i)
+		}
+	}
+
+	found := ""
+	if len(records) > 0 {
+		found = records[len(records)-1].Hash
+	}
+
+	if found != lastHash {
+		return fmt.Errorf("the last audit record isn't the expected %q, records were removed from the end of the log", lastHash)
+	}
+
+	return nil
+}
+
+func hash(key []byte, record *Entry) string {
+	unhashed := *record
+	unhashed.Hash = ""
+
+	// An Entry can always be marshalled.
+	data, _ := json.Marshal(&unhashed)
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write(data)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// summarize returns the request as JSON, or as is if it's text, with the values of its secret fields redacted.
+func summarize(request interface{}) string {
+	if request == nil {
+		return ""
+	}
+
+	if summary, ok := request.(string); ok {
+		return redactText(summary)
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Sprintf("%T", request)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Sprintf("%T", request)
+	}
+
+	data, _ = json.Marshal(redact(value))
+
+	return string(data)
+}
+
+// redactText redacts the values of the secret fields of a request given as text, which may hold JSON.
+func redactText(summary string) string {
+	var value interface{}
+	if err := json.Unmarshal([]byte(summary), &value); err == nil {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			data, _ := json.Marshal(redact(value))
+			return string(data)
+		}
+	}
+
+	return secretAssignment.ReplaceAllString(summary, "${1}"+Redacted)
+}
+
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if secretField.MatchString(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = redact(field)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,286 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/audit"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeFake "k8s.io/client-go/kubernetes/fake"
+)
+
+var key = []byte("audit key")
+
+type createRequest struct {
+	Name     string
+	Password string
+	Nested   map[string]string
+}
+
+var _ = Describe("Log", func() {
+	var (
+		sink *audit.MemorySink
+		log  *audit.Log
+	)
+
+	BeforeEach(func() {
+		sink = audit.NewMemorySink()
+		log = audit.NewLog(key, sink)
+	})
+
+	It("should record the calls with their request and error", func() {
+		log.Record(context.TODO(), "aws", "CreateSecurityGroup", "sg-1", &createRequest{Name: "group"}, nil)
+		log.Record(context.TODO(), "aws", "CreateTags", "subnet-1", "tag the subnet", errors.New("fake error"))
+
+		entries := sink.Entries()
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Provider).To(Equal("aws"))
+		Expect(entries[0].Operation).To(Equal("CreateSecurityGroup"))
+		Expect(entries[0].Resource).To(Equal("sg-1"))
+		Expect(entries[0].Request).To(ContainSubstring(`"Name":"group"`))
+		Expect(entries[0].Error).To(BeEmpty())
+		Expect(entries[0].Time).ToNot(BeZero())
+		Expect(entries[1].Request).To(Equal("tag the subnet"))
+		Expect(entries[1].Error).To(Equal("fake error"))
+	})
+
+	It("should redact the secrets in the requests", func() {
+		log.Record(context.TODO(), "rhos", "secgroups.Create", "group", &createRequest{
+			Name:     "group",
+			Password: "hunter2",
+			Nested:   map[string]string{"apiToken": "abc", "other": "value"},
+		}, nil)
+
+		request := sink.Entries()[0].Request
+		Expect(request).ToNot(ContainSubstring("hunter2"))
+		Expect(request).ToNot(ContainSubstring("abc"))
+		Expect(request).To(ContainSubstring(`"Password":"` + audit.Redacted + `"`))
+		Expect(request).To(ContainSubstring(`"other":"value"`))
+	})
+
+	It("should redact the secrets in the requests given as text", func() {
+		log.Record(context.TODO(), "aws", "CreateTags", "subnet-1", "name=gateway password=hunter2 apiToken: abc", nil)
+		log.Record(context.TODO(), "aws", "CreateTags", "subnet-1", `{"name":"gateway","secretKey":"hunter2"}`, nil)
+
+		entries := sink.Entries()
+		Expect(entries[0].Request).To(Equal("name=gateway password=" + audit.Redacted + " apiToken: " + audit.Redacted))
+		Expect(entries[1].Request).To(Equal(`{"name":"gateway","secretKey":"` + audit.Redacted + `"}`))
+	})
+
+	It("should chain the entries so that changes can be detected", func() {
+		for _, operation := range []string{"CreateSecurityGroup", "AuthorizeSecurityGroupIngress", "CreateTags"} {
+			log.Record(context.TODO(), "aws", operation, "sg-1", nil, nil)
+		}
+
+		entries := sink.Entries()
+		Expect(entries[0].PreviousHash).To(BeEmpty())
+		Expect(entries[1].PreviousHash).To(Equal(entries[0].Hash))
+		Expect(log.LastHash()).To(Equal(entries[2].Hash))
+		Expect(audit.Verify(key, entries, log.LastHash())).To(Succeed())
+
+		changed := append([]audit.Entry{}, entries...)
+		changed[1].Resource = "sg-2"
+		Expect(audit.Verify(key, changed, log.LastHash())).ToNot(Succeed())
+
+		Expect(audit.Verify(key, []audit.Entry{entries[0], entries[2]}, log.LastHash())).ToNot(Succeed())
+	})
+
+	It("should detect the records forged without the key", func() {
+		log.Record(context.TODO(), "aws", "CreateTags", "subnet-1", nil, nil)
+
+		forged := audit.NewLog([]byte("other key"), audit.NewMemorySink())
+		forged.Record(context.TODO(), "aws", "CreateTags", "subnet-1", nil, nil)
+
+		Expect(audit.Verify(key, sink.Entries(), log.LastHash())).To(Succeed())
+		Expect(audit.Verify([]byte("other key"), sink.Entries(), log.LastHash())).ToNot(Succeed())
+	})
+
+	It("should detect the removal of the first and last records", func() {
+		for _, operation := range []string{"CreateSecurityGroup", "AuthorizeSecurityGroupIngress", "CreateTags"} {
+			log.Record(context.TODO(), "aws", operation, "sg-1", nil, nil)
+		}
+
+		entries := sink.Entries()
+		Expect(audit.Verify(key, entries[1:], log.LastHash())).ToNot(Succeed())
+		Expect(audit.Verify(key, entries[:2], log.LastHash())).ToNot(Succeed())
+		Expect(audit.Verify(key, nil, log.LastHash())).ToNot(Succeed())
+		Expect(audit.Verify(key, nil, "")).To(Succeed())
+	})
+
+	When("a sink fails", func() {
+		It("should pass the error to the error handler and write to the other sinks", func() {
+			var handled []error
+
+			log = audit.NewLog(key, &failingSink{}, sink)
+			log.ErrorHandler = func(err error) {
+				handled = append(handled, err)
+			}
+
+			log.Record(context.TODO(), "gcp", "compute.firewalls.insert", "rule", nil, nil)
+
+			Expect(handled).To(HaveLen(1))
+			Expect(sink.Entries()).To(HaveLen(1))
+		})
+	})
+
+	When("the log is nil", func() {
+		It("should record nothing", func() {
+			var nilLog *audit.Log
+			nilLog.Record(context.TODO(), "aws", "CreateTags", "subnet-1", nil, nil)
+			Expect(nilLog.LastHash()).To(BeEmpty())
+		})
+	})
+
+	When("the log is carried by the context", func() {
+		It("should record the calls recorded by the providers", func() {
+			audit.Record(context.TODO(), "aws", "CreateTags", "subnet-1", nil, nil)
+			Expect(sink.Entries()).To(BeEmpty())
+
+			audit.Record(audit.NewContext(context.TODO(), log), "aws", "CreateTags", "subnet-1", nil, nil)
+			Expect(sink.Entries()).To(HaveLen(1))
+		})
+	})
+})
+
+var _ = Describe("FileSink", func() {
+	var (
+		dir      string
+		path     string
+		lastHash string
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "audit")
+		Expect(err).To(Succeed())
+
+		path = filepath.Join(dir, "audit.log")
+		lastHash = ""
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	record := func(operations ...string) {
+		sink, err := audit.NewFileSink(path, key, lastHash)
+		Expect(err).To(Succeed())
+
+		log := audit.NewLog(key, sink)
+		for _, operation := range operations {
+			log.Record(context.TODO(), "aws", operation, "sg-1", nil, nil)
+		}
+
+		Expect(sink.Close()).To(Succeed())
+
+		lastHash = log.LastHash()
+	}
+
+	It("should append the entries to the file and continue their chain", func() {
+		record("CreateSecurityGroup", "AuthorizeSecurityGroupIngress")
+		record("CreateTags")
+
+		entries, err := audit.ReadFile(path)
+		Expect(err).To(Succeed())
+		Expect(entries).To(HaveLen(3))
+		Expect(entries[2].Operation).To(Equal("CreateTags"))
+		Expect(audit.Verify(key, entries, lastHash)).To(Succeed())
+	})
+
+	When("the file was tampered with", func() {
+		It("should refuse to continue it", func() {
+			record("CreateSecurityGroup", "AuthorizeSecurityGroupIngress")
+
+			entries, err := audit.ReadFile(path)
+			Expect(err).To(Succeed())
+
+			file, err := os.Create(path)
+			Expect(err).To(Succeed())
+			_, err = file.WriteString(`{"operation":"CreateTags","hash":"` + entries[1].Hash + `"}` + "\n")
+			Expect(err).To(Succeed())
+			Expect(file.Close()).To(Succeed())
+
+			_, err = audit.NewFileSink(path, key, lastHash)
+			Expect(err).ToNot(Succeed())
+		})
+	})
+
+	When("the last entries were removed from the file", func() {
+		It("should refuse to continue it", func() {
+			record("CreateSecurityGroup", "AuthorizeSecurityGroupIngress")
+
+			entries, err := audit.ReadFile(path)
+			Expect(err).To(Succeed())
+
+			file, err := os.Create(path)
+			Expect(err).To(Succeed())
+			Expect(json.NewEncoder(file).Encode(&entries[0])).To(Succeed())
+			Expect(file.Close()).To(Succeed())
+
+			_, err = audit.NewFileSink(path, key, lastHash)
+			Expect(err).ToNot(Succeed())
+		})
+	})
+})
+
+var _ = Describe("EventSink", func() {
+	It("should write each entry as an Event about the object", func() {
+		client := kubeFake.NewSimpleClientset()
+		object := &corev1.ObjectReference{Kind: "CloudPreparation", Namespace: "submariner", Name: "cloud"}
+
+		log := audit.NewLog(key, audit.NewEventSink(client, object))
+		log.Record(context.TODO(), "gcp", "compute.instances.setTags", "instance", nil, nil)
+		log.Record(context.TODO(), "gcp", "compute.instances.addAccessConfig", "instance", nil, errors.New("fake error"))
+
+		events, err := client.CoreV1().Events("submariner").List(context.TODO(), metav1.ListOptions{})
+		Expect(err).To(Succeed())
+		Expect(events.Items).To(HaveLen(2))
+
+		reasons := map[string]*corev1.Event{}
+		for i := range events.Items {
+			reasons[events.Items[i].Reason] = &events.Items[i]
+		}
+
+		Expect(reasons).To(HaveKey(audit.EventReason))
+		Expect(reasons[audit.EventReason].InvolvedObject).To(Equal(*object))
+		Expect(reasons[audit.EventReason].Type).To(Equal(corev1.EventTypeNormal))
+		Expect(reasons[audit.EventReason].Message).To(ContainSubstring("compute.instances.setTags"))
+		Expect(reasons[audit.EventReason].Annotations).To(HaveKey(audit.HashAnnotation))
+
+		Expect(reasons).To(HaveKey(audit.EventReasonFailed))
+		Expect(reasons[audit.EventReasonFailed].Type).To(Equal(corev1.EventTypeWarning))
+		Expect(reasons[audit.EventReasonFailed].Message).To(ContainSubstring("fake error"))
+	})
+})
+
+type failingSink struct{}
+
+func (s *failingSink) Write(_ context.Context, _ *audit.Entry) error {
+	return errors.New("fake error")
+}
@@ -0,0 +1,214 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// EventReason is the reason of the Kubernetes Events of the successful calls.
+	EventReason = "CloudOperation"
+
+	// EventReasonFailed is the reason of the Kubernetes Events of the failed calls.
+	EventReasonFailed = "CloudOperationFailed"
+
+	// HashAnnotation holds the hash of the audit entry on its Kubernetes Event.
+	HashAnnotation = "cloud-prepare.submariner.io/audit-hash"
+
+	// PreviousHashAnnotation holds the hash of the previous audit entry on a Kubernetes Event.
+	PreviousHashAnnotation = "cloud-prepare.submariner.io/audit-previous-hash"
+
+	// maxEventMessage is the length Kubernetes Events messages are truncated to.
+	maxEventMessage = 1024
+)
+
+// FileSink appends the entries to a file, one JSON object per line.
+type FileSink struct {
+	mutex    sync.Mutex
+	file     *os.File
+	lastHash string
+}
+
+// NewFileSink returns a FileSink appending to the file at the given path, which is created if needed. If the file
+// already holds entries, they are verified with the given key, and must end with the record whose hash is lastHash,
+// as returned by Log.LastHash for the previous log; a Log using the sink then continues their chain.
+func NewFileSink(path string, key []byte, lastHash string) (*FileSink, error) {
+	entries, err := ReadFile(path)
+	if err != nil && !os.IsNotExist(errors.Cause(err)) {
+		return nil, err
+	}
+
+	if err := Verify(key, entries, lastHash); err != nil {
+		return nil, errors.WithMessagef(err, "the audit log %q can't be continued", path)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening the audit log %q", path)
+	}
+
+	sink := &FileSink{file: file}
+	if len(entries) > 0 {
+		sink.lastHash = entries[len(entries)-1].Hash
+	}
+
+	return sink, nil
+}
+
+// ReadFile reads the entries written to the file at the given path by a FileSink.
+func ReadFile(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening the audit log %q", path)
+	}
+
+	defer file.Close()
+
+	entries := []Entry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+
+	for scanner.Scan() {
+		entry := Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, errors.Wrapf(err, "error parsing entry %d of the audit log %q", len(entries), path)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, errors.Wrapf(scanner.Err(), "error reading the audit log %q", path)
+}
+
+func (s *FileSink) Write(_ context.Context, entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling the audit entry")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return errors.Wrap(err, "error writing the audit entry")
+	}
+
+	return errors.Wrap(s.file.Sync(), "error syncing the audit log")
+}
+
+// LastHash returns the hash of the last entry in the file when the sink was created.
+func (s *FileSink) LastHash() string {
+	return s.lastHash
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	return s.file.Close() // nolint:wrapcheck // No need to wrap here
+}
+
+type eventSink struct {
+	client kubernetes.Interface
+	object *corev1.ObjectReference
+}
+
+// NewEventSink returns a Sink which writes each entry as a Kubernetes Event about the given object, e.g. the
+// CloudPreparation being processed. The hashes of the entries are kept in the annotations of the Events.
+func NewEventSink(client kubernetes.Interface, object *corev1.ObjectReference) Sink {
+	return &eventSink{client: client, object: object}
+}
+
+func (s *eventSink) Write(ctx context.Context, entry *Entry) error {
+	namespace := s.object.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	reason, eventType := EventReason, corev1.EventTypeNormal
+	message := fmt.Sprintf("%s %s on %q: %s", entry.Provider, entry.Operation, entry.Resource, entry.Request)
+
+	if entry.Error != "" {
+		reason, eventType = EventReasonFailed, corev1.EventTypeWarning
+		message = fmt.Sprintf("%s %s on %q failed: %s: %s", entry.Provider, entry.Operation, entry.Resource, entry.Error,
+			entry.Request)
+	}
+
+	if len(message) > maxEventMessage {
+		message = message[:maxEventMessage-3] + "..."
+	}
+
+	_, err := s.client.CoreV1().Events(namespace).Create(ctx, &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.object.Name + "." + entry.Hash[:16],
+			Namespace: namespace,
+			Annotations: map[string]string{
+				HashAnnotation:         entry.Hash,
+				PreviousHashAnnotation: entry.PreviousHash,
+			},
+		},
+		InvolvedObject: *s.object,
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: "cloud-prepare"},
+		FirstTimestamp: metav1.NewTime(entry.Time),
+		LastTimestamp:  metav1.NewTime(entry.Time),
+		Count:          1,
+	}, metav1.CreateOptions{})
+
+	return errors.Wrap(err, "error creating the audit event")
+}
+
+// MemorySink keeps the entries in memory, e.g. to check them in tests.
+type MemorySink struct {
+	mutex   sync.Mutex
+	entries []Entry
+}
+
+// NewMemorySink returns an empty MemorySink.
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+func (s *MemorySink) Write(_ context.Context, entry *Entry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.entries = append(s.entries, *entry)
+
+	return nil
+}
+
+// Entries returns a copy of the entries written so far.
+func (s *MemorySink) Entries() []Entry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]Entry{}, s.entries...)
+}
@@ -24,22 +24,28 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
 	"github.com/pkg/errors"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	awsClient "github.com/submariner-io/cloud-prepare/pkg/aws/client"
+	"github.com/submariner-io/cloud-prepare/pkg/metrics"
+	"github.com/submariner-io/cloud-prepare/pkg/retry"
 )
 
 const (
 	messageRetrieveVPCID          = "Retrieving VPC ID"
 	messageRetrievedVPCID         = "Retrieved VPC ID %s"
-	messageValidatePrerequisites  = "Validating pre-requisites"
-	messageValidatedPrerequisites = "Validated pre-requisites"
+	messageValidatePrerequisites  = api.MessageValidatePrerequisites
+	messageValidatedPrerequisites = api.MessageValidatedPrerequisites
 )
 
 type awsCloud struct {
-	client  awsClient.Interface
-	infraID string
-	region  string
+	client      awsClient.Interface
+	infraID     string
+	region      string
+	inventory   api.Inventory
+	quotas      awsClient.QuotasInterface
+	retryPolicy *retry.Policy
 }
 
 // NewCloud creates a new api.Cloud instance which can prepare AWS for Submariner to be deployed on it.
@@ -54,11 +60,20 @@ func NewCloud(client awsClient.Interface, infraID, region string) api.Cloud {
 // NewCloudFromConfig creates a new api.Cloud instance based on an AWS configuration
 // which can prepare AWS for Submariner to be deployed on it.
 func NewCloudFromConfig(cfg *aws.Config, infraID, region string) api.Cloud {
-	return &awsCloud{
-		client:  ec2.NewFromConfig(*cfg),
-		infraID: infraID,
-		region:  region,
+	ac := &awsCloud{
+		infraID:     infraID,
+		region:      region,
+		retryPolicy: retry.Default(),
 	}
+
+	clientConfig := cfg.Copy()
+	awsClient.Configure(&clientConfig, func() *retry.Policy {
+		return ac.retryPolicy
+	})
+	ac.client = ec2.NewFromConfig(clientConfig)
+	ac.quotas = servicequotas.NewFromConfig(clientConfig)
+
+	return ac
 }
 
 // NewCloudFromSettings creates a new api.Cloud instance using the given credentials file and profile
@@ -77,6 +92,32 @@ func NewCloudFromSettings(credentialsFile, profile, infraID, region string) (api
 	return NewCloudFromConfig(&cfg, infraID, region), nil
 }
 
+// UseInventory records the resources created by the given AWS cloud, and its gateway deployers, in the inventory,
+// which then drives their cleanup. If the supplied cloud is not an awsCloud, an error is returned.
+func UseInventory(cloud api.Cloud, inventory api.Inventory) error {
+	ac, ok := cloud.(*awsCloud)
+	if !ok {
+		return errors.New("the cloud must be AWS")
+	}
+
+	ac.inventory = inventory
+
+	return nil
+}
+
+// UseServiceQuotas checks the account's service quotas, using the given client, before deploying gateways with the
+// given AWS cloud. If the supplied cloud is not an awsCloud, an error is returned.
+func UseServiceQuotas(cloud api.Cloud, client awsClient.QuotasInterface) error {
+	ac, ok := cloud.(*awsCloud)
+	if !ok {
+		return errors.New("the cloud must be AWS")
+	}
+
+	ac.quotas = client
+
+	return nil
+}
+
 // DefaultCredentialsFile returns the default credentials file name.
 func DefaultCredentialsFile() string {
 	return config.DefaultSharedCredentialsFilename()
@@ -88,9 +129,22 @@ func DefaultProfile() string {
 }
 
 func (ac *awsCloud) PrepareForSubmariner(input api.PrepareForSubmarinerInput, reporter api.Reporter) error {
+	return ac.PrepareForSubmarinerWithContext(context.TODO(), input, reporter)
+}
+
+func (ac *awsCloud) PrepareForSubmarinerWithContext(ctx context.Context, input api.PrepareForSubmarinerInput,
+	reporter api.Reporter) error {
+	return metrics.ObserveOperation(ctx, "aws", metrics.PrepareForSubmariner, func() error {
+		return api.RunWithRollback(ctx, input.Rollback, reporter, func(ctx context.Context) error {
+			return ac.prepareForSubmariner(ctx, input, reporter)
+		})
+	})
+}
+
+func (ac *awsCloud) prepareForSubmariner(ctx context.Context, input api.PrepareForSubmarinerInput, reporter api.Reporter) error {
 	reporter.Started(messageRetrieveVPCID)
 
-	vpcID, err := ac.getVpcID()
+	vpcID, err := ac.getVpcID(ctx)
 	if err != nil {
 		reporter.Failed(err)
 		return err
@@ -100,7 +154,7 @@ func (ac *awsCloud) PrepareForSubmariner(input api.PrepareForSubmarinerInput, re
 
 	reporter.Started(messageValidatePrerequisites)
 
-	err = ac.validatePreparePrerequisites(vpcID)
+	err = ac.validatePreparePrerequisites(ctx, vpcID)
 	if err != nil {
 		reporter.Failed(err)
 		return err
@@ -109,28 +163,32 @@ func (ac *awsCloud) PrepareForSubmariner(input api.PrepareForSubmarinerInput, re
 	reporter.Succeeded(messageValidatedPrerequisites)
 
 	for _, port := range input.InternalPorts {
-		reporter.Started("Opening port %v protocol %s for intra-cluster communications", port.Port, port.Protocol)
+		reporter.Started("Opening port %v protocol %s for intra-cluster communications", port.PortString(), port.Protocol)
 
-		err = ac.allowPortInCluster(vpcID, port.Port, port.Protocol)
+		err = ac.allowPortInCluster(ctx, vpcID, port)
 		if err != nil {
 			reporter.Failed(err)
 			return err
 		}
 
-		reporter.Succeeded("Opened port %v protocol %s for intra-cluster communications", port.Port, port.Protocol)
+		reporter.Succeeded("Opened port %v protocol %s for intra-cluster communications", port.PortString(), port.Protocol)
 	}
 
 	return nil
 }
 
-func (ac *awsCloud) validatePreparePrerequisites(vpcID string) error {
-	return ac.validateCreateSecGroupRule(vpcID)
+func (ac *awsCloud) validatePreparePrerequisites(ctx context.Context, vpcID string) error {
+	return ac.validateCreateSecGroupRule(ctx, vpcID)
 }
 
 func (ac *awsCloud) CleanupAfterSubmariner(reporter api.Reporter) error {
+	return ac.CleanupAfterSubmarinerWithContext(context.TODO(), reporter)
+}
+
+func (ac *awsCloud) CleanupAfterSubmarinerWithContext(ctx context.Context, reporter api.Reporter) error {
 	reporter.Started(messageRetrieveVPCID)
 
-	vpcID, err := ac.getVpcID()
+	vpcID, err := ac.getVpcID(ctx)
 	if err != nil {
 		reporter.Failed(err)
 		return err
@@ -140,7 +198,7 @@ func (ac *awsCloud) CleanupAfterSubmariner(reporter api.Reporter) error {
 
 	reporter.Started(messageValidatePrerequisites)
 
-	err = ac.validateCleanupPrerequisites(vpcID)
+	err = ac.validateCleanupPrerequisites(ctx, vpcID)
 	if err != nil {
 		reporter.Failed(err)
 		return err
@@ -150,7 +208,14 @@ func (ac *awsCloud) CleanupAfterSubmariner(reporter api.Reporter) error {
 
 	reporter.Started("Revoking intra-cluster communication permissions")
 
-	err = ac.revokePortsInCluster(vpcID)
+	if ac.inventory != nil {
+		err = api.CleanupInventory(ctx, ac.inventory, api.CloudScope, func(resource *api.InventoryResource) error {
+			return ac.revokeSGRule(ctx, resource)
+		})
+	} else {
+		err = ac.revokePortsInCluster(ctx, vpcID)
+	}
+
 	if err != nil {
 		reporter.Failed(err)
 		return err
@@ -161,6 +226,6 @@ func (ac *awsCloud) CleanupAfterSubmariner(reporter api.Reporter) error {
 	return nil
 }
 
-func (ac *awsCloud) validateCleanupPrerequisites(vpcID string) error {
-	return ac.validateDeleteSecGroupRule(vpcID)
+func (ac *awsCloud) validateCleanupPrerequisites(ctx context.Context, vpcID string) error {
+	return ac.validateDeleteSecGroupRule(ctx, vpcID)
 }
@@ -0,0 +1,332 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/aws/client/fake"
+	ocpFake "github.com/submariner-io/cloud-prepare/pkg/ocp/fake"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	infraID        = "test-infra"
+	region         = "test-region"
+	vpcID          = "vpc-1"
+	workerGroupID  = "sg-worker"
+	masterGroupID  = "sg-master"
+	gatewayGroupID = "sg-gateway"
+	instanceType   = "c5d.large"
+	amiID          = "ami-1"
+	gatewayVCPUs   = 2
+)
+
+func TestAWS(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AWS Suite")
+}
+
+// fakeAWS is a minimal EC2 account holding the cluster's VPC, its security groups, public subnets and nodes. The subnet
+// tags are updated by the calls creating and deleting them, and the machine sets deployed and deleted are recorded.
+type fakeAWS struct {
+	mockCtrl   *gomock.Controller
+	ec2Client  *fake.MockInterface
+	msDeployer *ocpFake.MockMachineSetDeployer
+
+	subnets []types.Subnet
+
+	// nodeSubnets maps the node names, which are the instances' private DNS names, to their subnet IDs.
+	nodeSubnets map[string]string
+
+	// runningInstances are returned when describing the instances by state. Every instance type has gatewayVCPUs vCPUs.
+	runningInstances []types.Instance
+
+	// gatewayInstances are returned when describing the instances by the gateway instance tag.
+	gatewayInstances []types.Instance
+
+	// gatewayPermissions are the inbound permissions of the gateway security group.
+	gatewayPermissions []types.IpPermission
+
+	deployedMachineSets []string
+	deletedMachineSets  []string
+}
+
+func (f *fakeAWS) beforeEach() {
+	f.mockCtrl = gomock.NewController(GinkgoT())
+	f.ec2Client = fake.NewMockInterface(f.mockCtrl)
+	f.msDeployer = ocpFake.NewMockMachineSetDeployer(f.mockCtrl)
+	f.subnets = []types.Subnet{publicSubnet("a", true), publicSubnet("b", true), publicSubnet("c", false)}
+	f.nodeSubnets = map[string]string{}
+	f.runningInstances = nil
+	f.gatewayInstances = nil
+	f.deployedMachineSets = nil
+	f.deletedMachineSets = nil
+
+	f.ec2Client.EXPECT().DescribeVpcs(gomock.Any(), gomock.Any()).Return(&ec2.DescribeVpcsOutput{
+		Vpcs: []types.Vpc{{VpcId: aws.String(vpcID)}},
+	}, nil).AnyTimes()
+
+	f.ec2Client.EXPECT().DescribeSecurityGroups(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input *ec2.DescribeSecurityGroupsInput, _ ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+			groupIDs := map[string]string{
+				infraID + "-worker-sg":        workerGroupID,
+				infraID + "-master-sg":        masterGroupID,
+				infraID + "-submariner-gw-sg": gatewayGroupID,
+			}
+
+			groupID, found := groupIDs[filterValue(input.Filters, "tag:Name")]
+			if !found {
+				return &ec2.DescribeSecurityGroupsOutput{}, nil
+			}
+
+			group := types.SecurityGroup{GroupId: aws.String(groupID)}
+			if groupID == gatewayGroupID {
+				group.IpPermissions = f.gatewayPermissions
+			}
+
+			return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: []types.SecurityGroup{group}}, nil
+		}).AnyTimes()
+
+	f.ec2Client.EXPECT().DescribeSubnets(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input *ec2.DescribeSubnetsInput, _ ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+			subnets := []types.Subnet{}
+
+			for _, subnet := range f.subnets {
+				if hasFilter(input.Filters, "tag:submariner.io/gateway") && !subnetTagged(&subnet) {
+					continue
+				}
+
+				subnets = append(subnets, subnet)
+			}
+
+			return &ec2.DescribeSubnetsOutput{Subnets: subnets}, nil
+		}).AnyTimes()
+
+	f.ec2Client.EXPECT().DescribeInstanceTypeOfferings(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input *ec2.DescribeInstanceTypeOfferingsInput, _ ...func(*ec2.Options)) (
+			*ec2.DescribeInstanceTypeOfferingsOutput, error) {
+			if aws.ToBool(input.DryRun) {
+				return nil, dryRunError()
+			}
+
+			offered := types.InstanceType(filterValue(input.Filters, "instance-type"))
+			if offered == "" {
+				offered = instanceType
+			}
+
+			return &ec2.DescribeInstanceTypeOfferingsOutput{
+				InstanceTypeOfferings: []types.InstanceTypeOffering{{InstanceType: offered}},
+			}, nil
+		}).AnyTimes()
+
+	f.ec2Client.EXPECT().DescribeInstanceTypes(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input *ec2.DescribeInstanceTypesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error) {
+			output := &ec2.DescribeInstanceTypesOutput{}
+
+			for _, requested := range input.InstanceTypes {
+				output.InstanceTypes = append(output.InstanceTypes, types.InstanceTypeInfo{
+					InstanceType: requested,
+					VCpuInfo:     &types.VCpuInfo{DefaultVCpus: aws.Int32(gatewayVCPUs)},
+				})
+			}
+
+			return output, nil
+		}).AnyTimes()
+
+	f.ec2Client.EXPECT().CreateSecurityGroup(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input *ec2.CreateSecurityGroupInput, _ ...func(*ec2.Options)) (*ec2.CreateSecurityGroupOutput, error) {
+			if aws.ToBool(input.DryRun) {
+				return nil, dryRunError()
+			}
+
+			return &ec2.CreateSecurityGroupOutput{GroupId: aws.String(gatewayGroupID)}, nil
+		}).AnyTimes()
+
+	f.ec2Client.EXPECT().AuthorizeSecurityGroupIngress(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input *ec2.AuthorizeSecurityGroupIngressInput, _ ...func(*ec2.Options)) (
+			*ec2.AuthorizeSecurityGroupIngressOutput, error) {
+			if aws.ToBool(input.DryRun) {
+				return nil, dryRunError()
+			}
+
+			return &ec2.AuthorizeSecurityGroupIngressOutput{}, nil
+		}).AnyTimes()
+
+	f.ec2Client.EXPECT().CreateTags(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input *ec2.CreateTagsInput, _ ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error) {
+			if aws.ToBool(input.DryRun) {
+				return nil, dryRunError()
+			}
+
+			f.updateSubnetTags(input.Resources, func(subnet *types.Subnet) {
+				subnet.Tags = append(subnet.Tags, input.Tags...)
+			})
+
+			return &ec2.CreateTagsOutput{}, nil
+		}).AnyTimes()
+
+	f.ec2Client.EXPECT().DeleteTags(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input *ec2.DeleteTagsInput, _ ...func(*ec2.Options)) (*ec2.DeleteTagsOutput, error) {
+			if aws.ToBool(input.DryRun) {
+				return nil, dryRunError()
+			}
+
+			f.updateSubnetTags(input.Resources, func(subnet *types.Subnet) {
+				subnet.Tags = []types.Tag{subnet.Tags[0], subnet.Tags[1]}
+			})
+
+			return &ec2.DeleteTagsOutput{}, nil
+		}).AnyTimes()
+
+	f.ec2Client.EXPECT().DescribeInstances(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input *ec2.DescribeInstancesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+			if hasFilter(input.Filters, "tag:submariner.io") {
+				return &ec2.DescribeInstancesOutput{Reservations: []types.Reservation{{Instances: f.gatewayInstances}}}, nil
+			}
+
+			if hasFilter(input.Filters, "instance-state-name") {
+				return &ec2.DescribeInstancesOutput{Reservations: []types.Reservation{{Instances: f.runningInstances}}}, nil
+			}
+
+			instance := types.Instance{ImageId: aws.String(amiID)}
+
+			if hasFilter(input.Filters, "private-dns-name") {
+				subnetID, found := f.nodeSubnets[filterValue(input.Filters, "private-dns-name")]
+				if !found {
+					return &ec2.DescribeInstancesOutput{}, nil
+				}
+
+				instance.SubnetId = aws.String(subnetID)
+			}
+
+			return &ec2.DescribeInstancesOutput{Reservations: []types.Reservation{{Instances: []types.Instance{instance}}}}, nil
+		}).AnyTimes()
+
+	f.msDeployer.EXPECT().Deploy(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, machineSet *unstructured.Unstructured) error {
+			f.deployedMachineSets = append(f.deployedMachineSets, machineSet.GetName())
+			return nil
+		}).AnyTimes()
+
+	f.msDeployer.EXPECT().Delete(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, machineSet *unstructured.Unstructured) error {
+			f.deletedMachineSets = append(f.deletedMachineSets, machineSet.GetName())
+			return nil
+		}).AnyTimes()
+}
+
+func (f *fakeAWS) afterEach() {
+	f.mockCtrl.Finish()
+}
+
+func (f *fakeAWS) updateSubnetTags(subnetIDs []string, update func(subnet *types.Subnet)) {
+	for i := range f.subnets {
+		for _, id := range subnetIDs {
+			if aws.ToString(f.subnets[i].SubnetId) == id {
+				update(&f.subnets[i])
+			}
+		}
+	}
+}
+
+// taggedSubnets returns the IDs of the subnets tagged for Submariner gateways.
+func (f *fakeAWS) taggedSubnets() []string {
+	ids := []string{}
+
+	for i := range f.subnets {
+		if subnetTagged(&f.subnets[i]) {
+			ids = append(ids, aws.ToString(f.subnets[i].SubnetId))
+		}
+	}
+
+	return ids
+}
+
+// publicSubnet returns the public subnet in the given zone of the region, which is only tagged for Submariner gateways
+// if tagged is true. Its name and cluster tags always come first.
+func publicSubnet(zone string, tagged bool) types.Subnet {
+	subnet := types.Subnet{
+		SubnetId:         aws.String("subnet-" + zone),
+		AvailabilityZone: aws.String(region + zone),
+		Tags: []types.Tag{
+			{Key: aws.String("Name"), Value: aws.String(infraID + "-public-" + region + zone)},
+			{Key: aws.String("kubernetes.io/cluster/" + infraID), Value: aws.String("owned")},
+		},
+	}
+
+	if tagged {
+		subnet.Tags = append(subnet.Tags,
+			types.Tag{Key: aws.String("kubernetes.io/role/internal-elb"), Value: aws.String("")},
+			types.Tag{Key: aws.String("submariner.io/gateway"), Value: aws.String("")})
+	}
+
+	return subnet
+}
+
+// runningInstance returns a running instance of the given type with the given number of vCPUs.
+func runningInstance(instanceType string, vCPUs int32) types.Instance {
+	return types.Instance{
+		InstanceType: types.InstanceType(instanceType),
+		CpuOptions:   &types.CpuOptions{CoreCount: aws.Int32(vCPUs / 2), ThreadsPerCore: aws.Int32(2)},
+	}
+}
+
+func subnetTagged(subnet *types.Subnet) bool {
+	for _, tag := range subnet.Tags {
+		if aws.ToString(tag.Key) == "submariner.io/gateway" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasFilter(filters []types.Filter, name string) bool {
+	for _, filter := range filters {
+		if aws.ToString(filter.Name) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func filterValue(filters []types.Filter, name string) string {
+	for _, filter := range filters {
+		if aws.ToString(filter.Name) == name {
+			return strings.Join(filter.Values, ",")
+		}
+	}
+
+	return ""
+}
+
+func dryRunError() error {
+	return &smithy.GenericAPIError{Code: "DryRunOperation", Message: "Request would have succeeded"}
+}
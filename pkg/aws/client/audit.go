@@ -0,0 +1,118 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aws/smithy-go/middleware"
+	"github.com/submariner-io/cloud-prepare/pkg/audit"
+)
+
+// auditResourceFields are the fields of the requests which identify the resource they change, by order of precedence.
+var auditResourceFields = []string{"GroupId", "GroupName", "Resources", "InstanceId", "InstanceIds", "SubnetId", "VpcId"}
+
+// readOnlyOperations are the operations which don't change any resource, besides those describing, getting or listing
+// resources.
+var readOnlyOperations = map[string]bool{"ValidateTemplate": true, "EstimateTemplateCost": true}
+
+// AddAudit adds a middleware to the stack of an AWS SDK client, which records the calls to the mutating operations,
+// i.e. those which don't describe, get, list or validate resources, in the audit log. The dry runs, which only check
+// the permissions to make a call, aren't recorded. It's meant to be added to the APIOptions of the client configuration.
+func AddAudit(stack *middleware.Stack) error {
+	operation := stack.ID()
+	if !isMutating(operation) {
+		return nil
+	}
+
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("SubmarinerAudit", // nolint:wrapcheck // No need to wrap
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (
+			middleware.InitializeOutput, middleware.Metadata, error) {
+			out, metadata, err := next.HandleInitialize(ctx, in)
+			if isDryRun(in.Parameters) {
+				return out, metadata, err // nolint:wrapcheck // No need to wrap here
+			}
+
+			audit.Record(ctx, "aws", operation, auditResource(in.Parameters), in.Parameters, err)
+
+			return out, metadata, err // nolint:wrapcheck // No need to wrap here
+		}), middleware.Before)
+}
+
+func isMutating(operation string) bool {
+	if readOnlyOperations[operation] {
+		return false
+	}
+
+	for _, prefix := range []string{"Describe", "Get", "List"} {
+		if strings.HasPrefix(operation, prefix) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isDryRun returns true if the given request only checks the permissions to make the call, i.e. its DryRun field is
+// true.
+func isDryRun(params interface{}) bool {
+	request := reflect.Indirect(reflect.ValueOf(params))
+	if request.Kind() != reflect.Struct {
+		return false
+	}
+
+	dryRun := reflect.Indirect(request.FieldByName("DryRun"))
+
+	return dryRun.Kind() == reflect.Bool && dryRun.Bool()
+}
+
+// auditResource returns the IDs of the resources changed by the given request.
+func auditResource(params interface{}) string {
+	request := reflect.Indirect(reflect.ValueOf(params))
+	if request.Kind() != reflect.Struct {
+		return ""
+	}
+
+	for _, name := range auditResourceFields {
+		field := reflect.Indirect(request.FieldByName(name))
+
+		switch field.Kind() { // nolint:exhaustive // Only strings and slices of strings identify resources
+		case reflect.String:
+			if id := field.String(); id != "" {
+				return id
+			}
+		case reflect.Slice:
+			if field.Len() == 0 {
+				continue
+			}
+
+			ids := make([]string, field.Len())
+			for i := range ids {
+				ids[i] = fmt.Sprint(field.Index(i).Interface())
+			}
+
+			return strings.Join(ids, ",")
+		}
+	}
+
+	return ""
+}
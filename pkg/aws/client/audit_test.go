@@ -0,0 +1,78 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/smithy-go/middleware"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/audit"
+	"github.com/submariner-io/cloud-prepare/pkg/aws/client"
+)
+
+var _ = Describe("AddAudit", func() {
+	var (
+		sink *audit.MemorySink
+		ctx  context.Context
+	)
+
+	BeforeEach(func() {
+		sink = audit.NewMemorySink()
+		ctx = audit.NewContext(context.TODO(), audit.NewLog([]byte("key"), sink))
+	})
+
+	// handle sends the request of the given operation through a stack with the audit.
+	handle := func(operation string, request interface{}) {
+		stack := middleware.NewStack(operation, func() interface{} { return nil })
+		Expect(client.AddAudit(stack)).To(Succeed())
+
+		_, _, err := middleware.DecorateHandler(middleware.HandlerFunc(
+			func(_ context.Context, _ interface{}) (interface{}, middleware.Metadata, error) {
+				return nil, middleware.Metadata{}, nil
+			}), stack).Handle(ctx, request)
+		Expect(err).To(Succeed())
+	}
+
+	It("should record the mutating calls", func() {
+		handle("CreateTags", &ec2.CreateTagsInput{Resources: []string{"subnet-1"}})
+
+		entries := sink.Entries()
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Operation).To(Equal("CreateTags"))
+		Expect(entries[0].Resource).To(Equal("subnet-1"))
+	})
+
+	It("should not record the dry runs", func() {
+		handle("RunInstances", &ec2.RunInstancesInput{DryRun: aws.Bool(true)})
+		Expect(sink.Entries()).To(BeEmpty())
+
+		handle("RunInstances", &ec2.RunInstancesInput{DryRun: aws.Bool(false)})
+		Expect(sink.Entries()).To(HaveLen(1))
+	})
+
+	It("should not record the read-only calls", func() {
+		handle("DescribeVpcs", &ec2.DescribeVpcsInput{})
+		handle("ValidateTemplate", struct{}{})
+		Expect(sink.Entries()).To(BeEmpty())
+	})
+})
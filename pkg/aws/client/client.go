@@ -26,7 +26,10 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	"github.com/submariner-io/cloud-prepare/pkg/retry"
 )
 
 //go:generate mockgen -source=./client.go -destination=./fake/client.go -package=fake
@@ -45,15 +48,39 @@ type Interface interface {
 		optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error)
 	DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput,
 		optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+	DescribeSecurityGroupRules(ctx context.Context, params *ec2.DescribeSecurityGroupRulesInput,
+		optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupRulesOutput, error)
 	DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput,
 		optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error)
 	DescribeInstanceTypeOfferings(ctx context.Context, params *ec2.DescribeInstanceTypeOfferingsInput,
 		optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypeOfferingsOutput, error)
+	DescribeInstanceTypes(ctx context.Context, params *ec2.DescribeInstanceTypesInput,
+		optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error)
 	DeleteSecurityGroup(ctx context.Context, params *ec2.DeleteSecurityGroupInput,
 		optFns ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error)
 	DeleteTags(ctx context.Context, params *ec2.DeleteTagsInput, optFns ...func(*ec2.Options)) (*ec2.DeleteTagsOutput, error)
 	RevokeSecurityGroupIngress(ctx context.Context, params *ec2.RevokeSecurityGroupIngressInput,
 		optFns ...func(*ec2.Options)) (*ec2.RevokeSecurityGroupIngressOutput, error)
+	TerminateInstances(ctx context.Context, params *ec2.TerminateInstancesInput,
+		optFns ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error)
+}
+
+// QuotasInterface wraps an actual AWS SDK service quotas client to allow for easier testing.
+type QuotasInterface interface {
+	GetServiceQuota(ctx context.Context, params *servicequotas.GetServiceQuotaInput,
+		optFns ...func(*servicequotas.Options)) (*servicequotas.GetServiceQuotaOutput, error)
+}
+
+// CloudFormationInterface wraps an actual AWS SDK CloudFormation client to allow for easier testing.
+type CloudFormationInterface interface {
+	CreateStack(ctx context.Context, params *cloudformation.CreateStackInput,
+		optFns ...func(*cloudformation.Options)) (*cloudformation.CreateStackOutput, error)
+	UpdateStack(ctx context.Context, params *cloudformation.UpdateStackInput,
+		optFns ...func(*cloudformation.Options)) (*cloudformation.UpdateStackOutput, error)
+	DescribeStacks(ctx context.Context, params *cloudformation.DescribeStacksInput,
+		optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error)
+	DeleteStack(ctx context.Context, params *cloudformation.DeleteStackInput,
+		optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteStackOutput, error)
 }
 
 type awsClient struct {
@@ -90,6 +117,11 @@ func (ac *awsClient) DescribeSecurityGroups(ctx context.Context, input *ec2.Desc
 	return ac.ec2Client.DescribeSecurityGroups(ctx, input, optFns...)
 }
 
+func (ac *awsClient) DescribeSecurityGroupRules(ctx context.Context, input *ec2.DescribeSecurityGroupRulesInput,
+	optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupRulesOutput, error) {
+	return ac.ec2Client.DescribeSecurityGroupRules(ctx, input, optFns...)
+}
+
 func (ac *awsClient) DescribeSubnets(ctx context.Context, input *ec2.DescribeSubnetsInput,
 	optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
 	return ac.ec2Client.DescribeSubnets(ctx, input, optFns...)
@@ -110,11 +142,21 @@ func (ac *awsClient) RevokeSecurityGroupIngress(ctx context.Context, input *ec2.
 	return ac.ec2Client.RevokeSecurityGroupIngress(ctx, input, optFns...)
 }
 
+func (ac *awsClient) TerminateInstances(ctx context.Context, input *ec2.TerminateInstancesInput,
+	optFns ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error) {
+	return ac.ec2Client.TerminateInstances(ctx, input, optFns...)
+}
+
 func (ac *awsClient) DescribeInstanceTypeOfferings(ctx context.Context, input *ec2.DescribeInstanceTypeOfferingsInput,
 	optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypeOfferingsOutput, error) {
 	return ac.ec2Client.DescribeInstanceTypeOfferings(ctx, input, optFns...)
 }
 
+func (ac *awsClient) DescribeInstanceTypes(ctx context.Context, input *ec2.DescribeInstanceTypesInput,
+	optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error) {
+	return ac.ec2Client.DescribeInstanceTypes(ctx, input, optFns...)
+}
+
 func New(accessKeyID, secretAccessKey, region string) (Interface, error) {
 	cfg, err := config.LoadDefaultConfig(context.TODO(),
 		config.WithRegion(region),
@@ -134,6 +176,11 @@ func New(accessKeyID, secretAccessKey, region string) (Interface, error) {
 		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
 	}
 
+	policy := retry.Default()
+	Configure(&cfg, func() *retry.Policy {
+		return policy
+	})
+
 	return &awsClient{
 		ec2Client: *ec2.NewFromConfig(cfg),
 	}, nil
@@ -0,0 +1,88 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+var errorCategories = map[string]error{
+	"AccessDenied":                  api.ErrPermissionDenied,
+	"AccessDeniedException":         api.ErrPermissionDenied,
+	"AuthFailure":                   api.ErrPermissionDenied,
+	"UnauthorizedOperation":         api.ErrPermissionDenied,
+	"NoSuchResourceException":       api.ErrNotFound,
+	"ServiceQuotaExceededException": api.ErrQuotaExceeded,
+	"DependencyViolation":           api.ErrConflict,
+	"IncorrectState":                api.ErrConflict,
+	"RequestLimitExceeded":          api.ErrTransient,
+	"Throttling":                    api.ErrTransient,
+	"ThrottlingException":           api.ErrTransient,
+	"TooManyRequestsException":      api.ErrTransient,
+	"InternalError":                 api.ErrTransient,
+	"InternalFailure":               api.ErrTransient,
+	"ServiceUnavailable":            api.ErrTransient,
+	"Unavailable":                   api.ErrTransient,
+	"InsufficientInstanceCapacity":  api.ErrTransient,
+	"UnsupportedOperation":          api.ErrUnsupported,
+}
+
+// AddErrorCategories adds a middleware to the stack of an AWS SDK client, which puts the errors returned by the
+// API in the api error categories. It's meant to be added to the APIOptions of the client configuration.
+func AddErrorCategories(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("SubmarinerErrorCategories", // nolint:wrapcheck // No need to wrap
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (
+			middleware.InitializeOutput, middleware.Metadata, error) {
+			out, metadata, err := next.HandleInitialize(ctx, in)
+
+			return out, metadata, categorizeError(err)
+		}), middleware.Before)
+}
+
+func categorizeError(err error) error {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	code := apiErr.ErrorCode()
+
+	if category, ok := errorCategories[code]; ok {
+		return api.NewError(category, err)
+	}
+
+	switch {
+	case strings.HasSuffix(code, "NotFound"):
+		return api.NewError(api.ErrNotFound, err)
+	case strings.HasSuffix(code, "LimitExceeded"):
+		return api.NewError(api.ErrQuotaExceeded, err)
+	case strings.HasSuffix(code, ".Duplicate") || strings.HasSuffix(code, ".InUse"):
+		return api.NewError(api.ErrConflict, err)
+	case strings.HasPrefix(code, "Unsupported"):
+		return api.NewError(api.ErrUnsupported, err)
+	}
+
+	return err
+}
@@ -0,0 +1,93 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/aws/client"
+)
+
+var _ = Describe("AddErrorCategories", func() {
+	// handle sends a request through a stack with the error categories, whose handler returns the given error.
+	handle := func(err error) error {
+		stack := middleware.NewStack("test", func() interface{} { return nil })
+		Expect(client.AddErrorCategories(stack)).To(Succeed())
+
+		_, _, err = middleware.DecorateHandler(middleware.HandlerFunc(
+			func(_ context.Context, _ interface{}) (interface{}, middleware.Metadata, error) {
+				return nil, middleware.Metadata{}, err
+			}), stack).Handle(context.TODO(), struct{}{})
+
+		return err
+	}
+
+	table.DescribeTable("should put the AWS API errors in their category",
+		func(code string, category error) {
+			apiErr := &smithy.GenericAPIError{Code: code, Message: "fake error"}
+			err := handle(apiErr)
+
+			Expect(errors.Is(err, category)).To(BeTrue())
+			Expect(errors.As(err, new(smithy.APIError))).To(BeTrue())
+		},
+		table.Entry("access denied", "AccessDenied", api.ErrPermissionDenied),
+		table.Entry("access denied exception", "AccessDeniedException", api.ErrPermissionDenied),
+		table.Entry("authentication failure", "AuthFailure", api.ErrPermissionDenied),
+		table.Entry("unauthorized operation", "UnauthorizedOperation", api.ErrPermissionDenied),
+		table.Entry("missing service quota", "NoSuchResourceException", api.ErrNotFound),
+		table.Entry("missing resource", "InvalidVpcID.NotFound", api.ErrNotFound),
+		table.Entry("service quota exceeded", "ServiceQuotaExceededException", api.ErrQuotaExceeded),
+		table.Entry("limit exceeded", "RulesPerSecurityGroupLimitExceeded", api.ErrQuotaExceeded),
+		table.Entry("dependency violation", "DependencyViolation", api.ErrConflict),
+		table.Entry("incorrect state", "IncorrectState", api.ErrConflict),
+		table.Entry("duplicate", "InvalidPermission.Duplicate", api.ErrConflict),
+		table.Entry("in use", "InvalidGroup.InUse", api.ErrConflict),
+		table.Entry("request limit exceeded", "RequestLimitExceeded", api.ErrTransient),
+		table.Entry("throttling", "Throttling", api.ErrTransient),
+		table.Entry("throttling exception", "ThrottlingException", api.ErrTransient),
+		table.Entry("too many requests", "TooManyRequestsException", api.ErrTransient),
+		table.Entry("internal error", "InternalError", api.ErrTransient),
+		table.Entry("internal failure", "InternalFailure", api.ErrTransient),
+		table.Entry("service unavailable", "ServiceUnavailable", api.ErrTransient),
+		table.Entry("unavailable", "Unavailable", api.ErrTransient),
+		table.Entry("insufficient capacity", "InsufficientInstanceCapacity", api.ErrTransient),
+		table.Entry("unsupported operation", "UnsupportedOperation", api.ErrUnsupported),
+		table.Entry("unsupported", "Unsupported", api.ErrUnsupported),
+	)
+
+	table.DescribeTable("should return the other errors as is",
+		func(err error) {
+			Expect(handle(err)).To(Equal(err))
+		},
+		table.Entry("invalid parameter", &smithy.GenericAPIError{Code: "InvalidParameterValue", Message: "fake error"}),
+		table.Entry("dry run", &smithy.GenericAPIError{Code: "DryRunOperation", Message: "fake error"}),
+		table.Entry("not an API error", errors.New("fake error")),
+	)
+
+	It("should return nil if there is no error", func() {
+		Expect(handle(nil)).To(Succeed())
+	})
+})
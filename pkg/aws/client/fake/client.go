@@ -25,7 +25,9 @@ import (
 	context "context"
 	reflect "reflect"
 
+	cloudformation "github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	ec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	servicequotas "github.com/aws/aws-sdk-go-v2/service/servicequotas"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -172,6 +174,26 @@ func (mr *MockInterfaceMockRecorder) DescribeInstanceTypeOfferings(ctx, params i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeInstanceTypeOfferings", reflect.TypeOf((*MockInterface)(nil).DescribeInstanceTypeOfferings), varargs...)
 }
 
+// DescribeInstanceTypes mocks base method.
+func (m *MockInterface) DescribeInstanceTypes(ctx context.Context, params *ec2.DescribeInstanceTypesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeInstanceTypes", varargs...)
+	ret0, _ := ret[0].(*ec2.DescribeInstanceTypesOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeInstanceTypes indicates an expected call of DescribeInstanceTypes.
+func (mr *MockInterfaceMockRecorder) DescribeInstanceTypes(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeInstanceTypes", reflect.TypeOf((*MockInterface)(nil).DescribeInstanceTypes), varargs...)
+}
+
 // DescribeInstances mocks base method.
 func (m *MockInterface) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
 	m.ctrl.T.Helper()
@@ -192,6 +214,26 @@ func (mr *MockInterfaceMockRecorder) DescribeInstances(ctx, params interface{},
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeInstances", reflect.TypeOf((*MockInterface)(nil).DescribeInstances), varargs...)
 }
 
+// DescribeSecurityGroupRules mocks base method.
+func (m *MockInterface) DescribeSecurityGroupRules(ctx context.Context, params *ec2.DescribeSecurityGroupRulesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupRulesOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeSecurityGroupRules", varargs...)
+	ret0, _ := ret[0].(*ec2.DescribeSecurityGroupRulesOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeSecurityGroupRules indicates an expected call of DescribeSecurityGroupRules.
+func (mr *MockInterfaceMockRecorder) DescribeSecurityGroupRules(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeSecurityGroupRules", reflect.TypeOf((*MockInterface)(nil).DescribeSecurityGroupRules), varargs...)
+}
+
 // DescribeSecurityGroups mocks base method.
 func (m *MockInterface) DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
 	m.ctrl.T.Helper()
@@ -271,3 +313,169 @@ func (mr *MockInterfaceMockRecorder) RevokeSecurityGroupIngress(ctx, params inte
 	varargs := append([]interface{}{ctx, params}, optFns...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSecurityGroupIngress", reflect.TypeOf((*MockInterface)(nil).RevokeSecurityGroupIngress), varargs...)
 }
+
+// TerminateInstances mocks base method.
+func (m *MockInterface) TerminateInstances(ctx context.Context, params *ec2.TerminateInstancesInput, optFns ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "TerminateInstances", varargs...)
+	ret0, _ := ret[0].(*ec2.TerminateInstancesOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TerminateInstances indicates an expected call of TerminateInstances.
+func (mr *MockInterfaceMockRecorder) TerminateInstances(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TerminateInstances", reflect.TypeOf((*MockInterface)(nil).TerminateInstances), varargs...)
+}
+
+// MockQuotasInterface is a mock of QuotasInterface interface.
+type MockQuotasInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockQuotasInterfaceMockRecorder
+}
+
+// MockQuotasInterfaceMockRecorder is the mock recorder for MockQuotasInterface.
+type MockQuotasInterfaceMockRecorder struct {
+	mock *MockQuotasInterface
+}
+
+// NewMockQuotasInterface creates a new mock instance.
+func NewMockQuotasInterface(ctrl *gomock.Controller) *MockQuotasInterface {
+	mock := &MockQuotasInterface{ctrl: ctrl}
+	mock.recorder = &MockQuotasInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockQuotasInterface) EXPECT() *MockQuotasInterfaceMockRecorder {
+	return m.recorder
+}
+
+// GetServiceQuota mocks base method.
+func (m *MockQuotasInterface) GetServiceQuota(ctx context.Context, params *servicequotas.GetServiceQuotaInput, optFns ...func(*servicequotas.Options)) (*servicequotas.GetServiceQuotaOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetServiceQuota", varargs...)
+	ret0, _ := ret[0].(*servicequotas.GetServiceQuotaOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServiceQuota indicates an expected call of GetServiceQuota.
+func (mr *MockQuotasInterfaceMockRecorder) GetServiceQuota(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServiceQuota", reflect.TypeOf((*MockQuotasInterface)(nil).GetServiceQuota), varargs...)
+}
+
+// MockCloudFormationInterface is a mock of CloudFormationInterface interface.
+type MockCloudFormationInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockCloudFormationInterfaceMockRecorder
+}
+
+// MockCloudFormationInterfaceMockRecorder is the mock recorder for MockCloudFormationInterface.
+type MockCloudFormationInterfaceMockRecorder struct {
+	mock *MockCloudFormationInterface
+}
+
+// NewMockCloudFormationInterface creates a new mock instance.
+func NewMockCloudFormationInterface(ctrl *gomock.Controller) *MockCloudFormationInterface {
+	mock := &MockCloudFormationInterface{ctrl: ctrl}
+	mock.recorder = &MockCloudFormationInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCloudFormationInterface) EXPECT() *MockCloudFormationInterfaceMockRecorder {
+	return m.recorder
+}
+
+// CreateStack mocks base method.
+func (m *MockCloudFormationInterface) CreateStack(ctx context.Context, params *cloudformation.CreateStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateStackOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateStack", varargs...)
+	ret0, _ := ret[0].(*cloudformation.CreateStackOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateStack indicates an expected call of CreateStack.
+func (mr *MockCloudFormationInterfaceMockRecorder) CreateStack(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStack", reflect.TypeOf((*MockCloudFormationInterface)(nil).CreateStack), varargs...)
+}
+
+// DeleteStack mocks base method.
+func (m *MockCloudFormationInterface) DeleteStack(ctx context.Context, params *cloudformation.DeleteStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteStackOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteStack", varargs...)
+	ret0, _ := ret[0].(*cloudformation.DeleteStackOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteStack indicates an expected call of DeleteStack.
+func (mr *MockCloudFormationInterfaceMockRecorder) DeleteStack(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteStack", reflect.TypeOf((*MockCloudFormationInterface)(nil).DeleteStack), varargs...)
+}
+
+// DescribeStacks mocks base method.
+func (m *MockCloudFormationInterface) DescribeStacks(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeStacks", varargs...)
+	ret0, _ := ret[0].(*cloudformation.DescribeStacksOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeStacks indicates an expected call of DescribeStacks.
+func (mr *MockCloudFormationInterfaceMockRecorder) DescribeStacks(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeStacks", reflect.TypeOf((*MockCloudFormationInterface)(nil).DescribeStacks), varargs...)
+}
+
+// UpdateStack mocks base method.
+func (m *MockCloudFormationInterface) UpdateStack(ctx context.Context, params *cloudformation.UpdateStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.UpdateStackOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdateStack", varargs...)
+	ret0, _ := ret[0].(*cloudformation.UpdateStackOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateStack indicates an expected call of UpdateStack.
+func (mr *MockCloudFormationInterfaceMockRecorder) UpdateStack(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStack", reflect.TypeOf((*MockCloudFormationInterface)(nil).UpdateStack), varargs...)
+}
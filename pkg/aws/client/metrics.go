@@ -0,0 +1,46 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/submariner-io/cloud-prepare/pkg/metrics"
+)
+
+// AddMetrics adds a middleware to the stack of an AWS SDK client, which records each call to the API in the metrics,
+// with the operation named after the service and the operation, e.g. "EC2.DescribeVpcs". It's meant to be added to
+// the APIOptions of the client configuration.
+func AddMetrics(stack *middleware.Stack) error {
+	return stack.Initialize.Insert(middleware.InitializeMiddlewareFunc("SubmarinerMetrics", // nolint:wrapcheck // No need to wrap
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (
+			middleware.InitializeOutput, middleware.Metadata, error) {
+			start := time.Now()
+
+			out, metadata, err := next.HandleInitialize(ctx, in)
+
+			metrics.ObserveCall(ctx, "aws", awsmiddleware.GetServiceID(ctx)+"."+awsmiddleware.GetOperationName(ctx), start,
+				categorizeError(err))
+
+			return out, metadata, err // nolint:wrapcheck // No need to wrap here
+		}), "RegisterServiceMetadata", middleware.After)
+}
@@ -0,0 +1,70 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/retry"
+)
+
+// APIOptions returns the middlewares added to the stacks of the AWS SDK clients, meant to be appended to the APIOptions
+// of the client configuration: they categorize the errors, apply the retry policy returned by the given function, and
+// trace, measure and audit the calls.
+func APIOptions(policy func() *retry.Policy) []func(*middleware.Stack) error {
+	return []func(*middleware.Stack) error{AddErrorCategories, AddRetryPolicy(policy), AddTracing, AddMetrics, AddAudit}
+}
+
+// Configure adds the middlewares returned by APIOptions to the given AWS SDK client configuration, and disables the
+// retries of the SDK itself, which the retry policy replaces: a call is only retried as many times as the policy
+// allows, and not at all with a nil policy.
+func Configure(cfg *aws.Config, policy func() *retry.Policy) {
+	cfg.APIOptions = append(cfg.APIOptions, APIOptions(policy)...)
+	cfg.Retryer = func() aws.Retryer {
+		return aws.NopRetryer{}
+	}
+}
+
+// AddRetryPolicy returns a function adding a middleware to the stack of an AWS SDK client, which applies the retry
+// policy returned by the given function to every call; the clients using it should disable the retries of the SDK,
+// see Configure. The policy is looked up on each call so that it can be replaced once the client is created; a nil
+// policy disables it.
+func AddRetryPolicy(policy func() *retry.Policy) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("SubmarinerRetryPolicy", // nolint:wrapcheck // No need to wrap
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (
+				out middleware.InitializeOutput, metadata middleware.Metadata, err error) {
+				err = policy().Do(ctx, isRetriable, func() error {
+					out, metadata, err = next.HandleInitialize(ctx, in)
+					return err // nolint:wrapcheck // No need to wrap here
+				})
+
+				return out, metadata, err // nolint:wrapcheck // No need to wrap here
+			}), middleware.Before)
+	}
+}
+
+// isRetriable returns true for throttling errors, such as RequestLimitExceeded, and the other transient errors.
+func isRetriable(err error) bool {
+	return errors.Is(err, api.ErrTransient)
+}
@@ -0,0 +1,115 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/aws/client"
+	"github.com/submariner-io/cloud-prepare/pkg/retry"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var _ = Describe("Configure", func() {
+	var (
+		server   *httptest.Server
+		requests int32
+		policy   *retry.Policy
+		ec2Error string
+	)
+
+	BeforeEach(func() {
+		atomic.StoreInt32(&requests, 0)
+		ec2Error = "RequestLimitExceeded"
+		policy = &retry.Policy{Backoff: wait.Backoff{Steps: 2, Duration: time.Millisecond, Factor: 1}}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`<Response><Errors><Error><Code>` + ec2Error + `</Code><Message>fake error</Message></Error>` +
+				`</Errors><RequestID>1</RequestID></Response>`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	describeVpcs := func() error {
+		cfg := aws.Config{
+			Region:      "us-east-1",
+			Credentials: credentials.NewStaticCredentialsProvider("id", "secret", ""),
+			EndpointResolverWithOptions: aws.EndpointResolverWithOptionsFunc(
+				func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+					return aws.Endpoint{URL: server.URL}, nil
+				}),
+		}
+
+		client.Configure(&cfg, func() *retry.Policy {
+			return policy
+		})
+
+		_, err := ec2.NewFromConfig(cfg).DescribeVpcs(context.TODO(), &ec2.DescribeVpcsInput{})
+
+		return err
+	}
+
+	When("a call is throttled", func() {
+		It("should only be retried as many times as the policy allows", func() {
+			err := describeVpcs()
+			Expect(errors.Is(err, api.ErrTransient)).To(BeTrue())
+			Expect(atomic.LoadInt32(&requests)).To(Equal(int32(3)))
+		})
+	})
+
+	When("the policy is nil", func() {
+		BeforeEach(func() {
+			policy = nil
+		})
+
+		It("should not retry the call", func() {
+			Expect(describeVpcs()).ToNot(Succeed())
+			Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+		})
+	})
+
+	When("a call fails with an error which isn't transient", func() {
+		BeforeEach(func() {
+			ec2Error = "UnauthorizedOperation"
+		})
+
+		It("should not retry it", func() {
+			err := describeVpcs()
+			Expect(errors.Is(err, api.ErrPermissionDenied)).To(BeTrue())
+			Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+		})
+	})
+})
@@ -0,0 +1,53 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+// AddTracing adds a middleware to the stack of an AWS SDK client, which traces each call to the API in a span named
+// after the service and the operation, e.g. "EC2.DescribeVpcs", see api.StartCall. It's meant to be added to the
+// APIOptions of the client configuration.
+func AddTracing(stack *middleware.Stack) error {
+	return stack.Initialize.Insert(middleware.InitializeMiddlewareFunc("SubmarinerTracing", // nolint:wrapcheck // No need to wrap
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (
+			middleware.InitializeOutput, middleware.Metadata, error) {
+			service := awsmiddleware.GetServiceID(ctx)
+			operation := awsmiddleware.GetOperationName(ctx)
+
+			ctx, span := api.StartCall(ctx, service+"."+operation,
+				semconv.CloudProviderAWS,
+				semconv.CloudRegionKey.String(awsmiddleware.GetRegion(ctx)),
+				semconv.RPCSystemKey.String("aws-api"),
+				semconv.RPCServiceKey.String(service),
+				semconv.RPCMethodKey.String(operation))
+
+			out, metadata, err := next.HandleInitialize(ctx, in)
+
+			api.EndCall(span, err)
+
+			return out, metadata, err // nolint:wrapcheck // No need to wrap here
+		}), "RegisterServiceMetadata", middleware.After)
+}
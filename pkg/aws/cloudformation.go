@@ -0,0 +1,521 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	cftypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	awsClient "github.com/submariner-io/cloud-prepare/pkg/aws/client"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// The parameters of the CloudFormation templates, which identify the cluster's existing resources.
+const (
+	VpcIDParameter                 = "VpcId"
+	WorkerSecurityGroupIDParameter = "WorkerSecurityGroupId"
+	MasterSecurityGroupIDParameter = "MasterSecurityGroupId"
+	PublicSubnetIDsParameter       = "PublicSubnetIds"
+)
+
+const (
+	gatewaySecurityGroupResource = "GatewaySecurityGroup"
+	subnetTaggerRoleResource     = "SubnetTaggerRole"
+	subnetTaggerResource         = "SubnetTagger"
+
+	// subnetTaggerRuntime is the Lambda runtime of the function tagging the subnets.
+	subnetTaggerRuntime = "python3.12"
+)
+
+var stackPollInterval = 10 * time.Second
+
+// subnetTaggerCode is the code of the function backing the custom resource which tags the public subnets. CloudFormation
+// can't tag existing resources, but this keeps the tags in the stack: they're removed from the subnets which are no
+// longer listed, and from all the subnets when the stack is deleted. The physical ID doesn't change on updates, so that
+// CloudFormation doesn't delete the previous resource, and its tags, after an update.
+const subnetTaggerCode = `import boto3
+import cfnresponse
+
+
+def handler(event, context):
+    physical_id = event.get("PhysicalResourceId", event["LogicalResourceId"])
+    try:
+        ec2 = boto3.client("ec2")
+        properties = event["ResourceProperties"]
+        tags = [{"Key": key, "Value": value} for key, value in sorted(properties["Tags"].items())]
+        subnets = set(properties["SubnetIds"])
+        if event["RequestType"] == "Delete":
+            removed, subnets = subnets, set()
+        else:
+            removed = set(event.get("OldResourceProperties", {}).get("SubnetIds", [])) - subnets
+        if removed:
+            ec2.delete_tags(Resources=sorted(removed), Tags=tags)
+        if subnets:
+            ec2.create_tags(Resources=sorted(subnets), Tags=tags)
+        cfnresponse.send(event, context, cfnresponse.SUCCESS, {}, physical_id)
+    except Exception as e:
+        cfnresponse.send(event, context, cfnresponse.FAILED, {"Error": str(e)}, physical_id)
+`
+
+// RenderCloudFormation returns a CloudFormation template which makes the same changes as PrepareForSubmariner, if
+// prepare is set, and the security group and subnet tags of the gateway deployment, if deploy is set, so that they can
+// be deleted as a unit by deleting the stack. The cluster's VPC, worker and master security groups, and the public
+// subnets to tag for the gateways are parameters of the template. Tagging the subnets requires CAPABILITY_IAM.
+func RenderCloudFormation(infraID string, prepare *api.PrepareForSubmarinerInput, deploy *api.GatewayDeployInput) ([]byte, error) {
+	parameters := map[string]interface{}{
+		VpcIDParameter: map[string]interface{}{
+			"Type":        "AWS::EC2::VPC::Id",
+			"Description": "The cluster's VPC",
+		},
+		WorkerSecurityGroupIDParameter: map[string]interface{}{
+			"Type":        "AWS::EC2::SecurityGroup::Id",
+			"Description": fmt.Sprintf("The %s-worker-sg security group", infraID),
+		},
+		MasterSecurityGroupIDParameter: map[string]interface{}{
+			"Type":        "AWS::EC2::SecurityGroup::Id",
+			"Description": fmt.Sprintf("The %s-master-sg security group", infraID),
+		},
+	}
+
+	resources := map[string]interface{}{}
+
+	if prepare != nil {
+		for _, port := range prepare.InternalPorts {
+			addClusterSGRules(resources, port)
+		}
+	}
+
+	if deploy != nil {
+		parameters[PublicSubnetIDsParameter] = map[string]interface{}{
+			"Type":        "List<AWS::EC2::Subnet::Id>",
+			"Description": "The public subnets to tag for the Submariner gateways",
+		}
+
+		addGatewaySG(resources, infraID, deploy)
+		addSubnetTagger(resources)
+	}
+
+	if len(resources) == 0 {
+		return nil, errors.New("there is nothing to prepare or deploy")
+	}
+
+	template, err := json.MarshalIndent(map[string]interface{}{
+		"AWSTemplateFormatVersion": "2010-09-09",
+		"Description":              fmt.Sprintf("Submariner's footprint in the %s cluster", infraID),
+		"Parameters":               parameters,
+		"Resources":                resources,
+	}, "", "  ")
+
+	return template, errors.Wrap(err, "error marshalling the CloudFormation template")
+}
+
+// addClusterSGRules adds the rules which allowPortInCluster authorizes.
+func addClusterSGRules(resources map[string]interface{}, port api.PortSpec) {
+	rules := []struct {
+		source, destination, description string
+	}{
+		{"Worker", "Worker", "between the workers"},
+		{"Worker", "Master", "from worker to master nodes"},
+		{"Master", "Worker", "from master to worker nodes"},
+	}
+
+	for _, rule := range rules {
+		properties := ingressProperties(port, fmt.Sprintf("%s %s", internalTraffic, rule.description))
+		properties["GroupId"] = ref(rule.destination + "SecurityGroupId")
+		properties["SourceSecurityGroupId"] = ref(rule.source + "SecurityGroupId")
+
+		resources[logicalID("Internal", port, rule.source, "To", rule.destination)] = map[string]interface{}{
+			"Type":       "AWS::EC2::SecurityGroupIngress",
+			"Properties": properties,
+		}
+	}
+}
+
+// addGatewaySG adds the security group, and its rules, which createGatewaySG creates.
+func addGatewaySG(resources map[string]interface{}, infraID string, input *api.GatewayDeployInput) {
+	groupName := infraID + "-submariner-gw-sg"
+
+	resources[gatewaySecurityGroupResource] = map[string]interface{}{
+		"Type": "AWS::EC2::SecurityGroup",
+		"Properties": map[string]interface{}{
+			"GroupName":        groupName,
+			"GroupDescription": "Submariner Gateway",
+			"VpcId":            ref(VpcIDParameter),
+			"Tags": []map[string]string{
+				{"Key": "Name", "Value": groupName},
+				{"Key": "kubernetes.io/cluster/" + infraID, "Value": "owned"},
+			},
+		},
+	}
+
+	families := input.PublicIPFamilies()
+
+	for _, port := range input.PublicPortSpecs() {
+		for _, family := range families {
+			for i, cidr := range port.SourcesFor(family) {
+				properties := ingressProperties(port, "Public Submariner traffic")
+				properties["GroupId"] = map[string]interface{}{"Fn::GetAtt": []string{gatewaySecurityGroupResource, "GroupId"}}
+
+				if family == api.IPv6Family {
+					properties["CidrIpv6"] = cidr
+				} else {
+					properties["CidrIp"] = cidr
+				}
+
+				resources[logicalID("Public", port, string(family), fmt.Sprint(i))] = map[string]interface{}{
+					"Type":       "AWS::EC2::SecurityGroupIngress",
+					"Properties": properties,
+				}
+			}
+		}
+	}
+}
+
+// addSubnetTagger adds the custom resource which tags the public subnets as tagPublicSubnet does, along with the function
+// backing it and its role.
+func addSubnetTagger(resources map[string]interface{}) {
+	resources[subnetTaggerRoleResource] = map[string]interface{}{
+		"Type": "AWS::IAM::Role",
+		"Properties": map[string]interface{}{
+			"AssumeRolePolicyDocument": policyDocument(map[string]interface{}{
+				"Effect":    "Allow",
+				"Principal": map[string]interface{}{"Service": []string{"lambda.amazonaws.com"}},
+				"Action":    []string{"sts:AssumeRole"},
+			}),
+			"ManagedPolicyArns": []interface{}{
+				map[string]interface{}{"Fn::Sub": "arn:${AWS::Partition}:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"},
+			},
+			"Policies": []interface{}{
+				map[string]interface{}{
+					"PolicyName": "tag-subnets",
+					"PolicyDocument": policyDocument(map[string]interface{}{
+						"Effect":   "Allow",
+						"Action":   []string{"ec2:CreateTags", "ec2:DeleteTags"},
+						"Resource": "*",
+					}),
+				},
+			},
+		},
+	}
+
+	resources[subnetTaggerResource] = map[string]interface{}{
+		"Type": "AWS::Lambda::Function",
+		"Properties": map[string]interface{}{
+			"Handler": "index.handler",
+			"Runtime": subnetTaggerRuntime,
+			"Timeout": 60,
+			"Role":    map[string]interface{}{"Fn::GetAtt": []string{subnetTaggerRoleResource, "Arn"}},
+			"Code":    map[string]interface{}{"ZipFile": subnetTaggerCode},
+		},
+	}
+
+	resources["PublicSubnetTags"] = map[string]interface{}{
+		"Type": "Custom::SubnetTags",
+		"Properties": map[string]interface{}{
+			"ServiceToken": map[string]interface{}{"Fn::GetAtt": []string{subnetTaggerResource, "Arn"}},
+			"SubnetIds":    ref(PublicSubnetIDsParameter),
+			"Tags": map[string]string{
+				*tagInternalELB.Key:       aws.ToString(tagInternalELB.Value),
+				*tagSubmarinerGateway.Key: aws.ToString(tagSubmarinerGateway.Value),
+			},
+		},
+	}
+}
+
+// ingressProperties returns the properties of an ingress rule allowing the given port, like newIPPermission.
+func ingressProperties(port api.PortSpec, description string) map[string]interface{} {
+	permission := newIPPermission(port)
+
+	properties := map[string]interface{}{
+		"IpProtocol":  aws.ToString(permission.IpProtocol),
+		"Description": description,
+	}
+
+	if permission.FromPort != nil {
+		properties["FromPort"] = *permission.FromPort
+		properties["ToPort"] = *permission.ToPort
+	}
+
+	return properties
+}
+
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"Ref": name}
+}
+
+func policyDocument(statement map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"Version":   "2012-10-17",
+		"Statement": []interface{}{statement},
+	}
+}
+
+// logicalID returns the alphanumeric logical ID of a rule, e.g. "InternalUdp4800WorkerToMaster".
+func logicalID(prefix string, port api.PortSpec, suffixes ...string) string {
+	parts := append([]string{prefix, port.Protocol}, strings.FieldsFunc(port.PortString(), func(r rune) bool {
+		return r == '-'
+	})...)
+
+	id := ""
+
+	for _, part := range append(parts, suffixes...) {
+		part = strings.Map(func(r rune) rune {
+			if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return r
+			}
+
+			return -1
+		}, part)
+
+		if part != "" {
+			id += strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		}
+	}
+
+	return id
+}
+
+// DeployCloudFormation creates the stack with the given name from the template rendered by RenderCloudFormation, or
+// updates it if it exists, and waits for the stack to be complete. The template's parameters are discovered with the
+// given AWS cloud: the public subnets are selected as the gateway deployer selects them.
+func DeployCloudFormation(ctx context.Context, cloud api.Cloud, client awsClient.CloudFormationInterface, stackName string,
+	prepare *api.PrepareForSubmarinerInput, deploy *api.GatewayDeployInput, reporter api.Reporter) error {
+	ac, ok := cloud.(*awsCloud)
+	if !ok {
+		return errors.New("the cloud must be AWS")
+	}
+
+	reporter.Started("Rendering the CloudFormation template of stack %q", stackName)
+
+	template, err := RenderCloudFormation(ac.infraID, prepare, deploy)
+	if err != nil {
+		reporter.Failed(err)
+		return err
+	}
+
+	parameters, err := ac.cloudFormationParameters(ctx, deploy)
+	if err != nil {
+		reporter.Failed(err)
+		return err
+	}
+
+	reporter.Succeeded("Rendered the CloudFormation template of stack %q", stackName)
+
+	reporter.Started("Deploying CloudFormation stack %q", stackName)
+
+	if err := applyStack(ctx, client, stackName, string(template), parameters, ac.withAWSInfo("kubernetes.io/cluster/{infraID}")); err != nil {
+		reporter.Failed(err)
+		return err
+	}
+
+	reporter.Succeeded("Deployed CloudFormation stack %q", stackName)
+
+	return nil
+}
+
+// cloudFormationParameters discovers the values of the template's parameters.
+func (ac *awsCloud) cloudFormationParameters(ctx context.Context, deploy *api.GatewayDeployInput) ([]cftypes.Parameter, error) {
+	vpcID, err := ac.getVpcID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	workerGroupID, err := ac.getSecurityGroupID(ctx, vpcID, "{infraID}-worker-sg")
+	if err != nil {
+		return nil, err
+	}
+
+	masterGroupID, err := ac.getSecurityGroupID(ctx, vpcID, "{infraID}-master-sg")
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := []cftypes.Parameter{
+		stackParameter(VpcIDParameter, vpcID),
+		stackParameter(WorkerSecurityGroupIDParameter, aws.ToString(workerGroupID)),
+		stackParameter(MasterSecurityGroupIDParameter, aws.ToString(masterGroupID)),
+	}
+
+	if deploy == nil {
+		return parameters, nil
+	}
+
+	publicSubnets, err := ac.findPublicSubnets(ctx, vpcID, ac.filterByName("{infraID}-public-{region}*"))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := utilerrors.NewAggregate(validateGatewaySubnets(*deploy, publicSubnets)); err != nil {
+		return nil, err
+	}
+
+	taggedSubnets, subnetsToTag := selectGatewaySubnets(publicSubnets, deploy.Gateways)
+
+	subnetIDs := []string{}
+	for _, subnet := range append(taggedSubnets, subnetsToTag...) {
+		subnetIDs = append(subnetIDs, aws.ToString(subnet.SubnetId))
+	}
+
+	return append(parameters, stackParameter(PublicSubnetIDsParameter, strings.Join(subnetIDs, ","))), nil
+}
+
+func stackParameter(key, value string) cftypes.Parameter {
+	return cftypes.Parameter{ParameterKey: aws.String(key), ParameterValue: aws.String(value)}
+}
+
+// applyStack creates or updates the stack, and waits for the operation to complete. A stack whose creation was rolled
+// back can't be updated, so it's deleted and created again.
+func applyStack(ctx context.Context, client awsClient.CloudFormationInterface, stackName, template string,
+	parameters []cftypes.Parameter, clusterTag string) error {
+	capabilities := []cftypes.Capability{cftypes.CapabilityCapabilityIam}
+
+	stack, err := describeStack(ctx, client, stackName)
+	if err == nil && stack.StackStatus == cftypes.StackStatusRollbackComplete {
+		err = deleteStack(ctx, client, stackName)
+		if err == nil {
+			err = newNotFoundError("CloudFormation stack %s", stackName)
+		}
+	}
+
+	if isNotFoundError(err) {
+		_, err = client.CreateStack(ctx, &cloudformation.CreateStackInput{
+			StackName:    aws.String(stackName),
+			TemplateBody: aws.String(template),
+			Parameters:   parameters,
+			Capabilities: capabilities,
+			Tags:         []cftypes.Tag{{Key: aws.String(clusterTag), Value: aws.String("owned")}},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "error creating CloudFormation stack %q", stackName)
+		}
+
+		return waitForStack(ctx, client, stackName)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = client.UpdateStack(ctx, &cloudformation.UpdateStackInput{
+		StackName:    aws.String(stackName),
+		TemplateBody: aws.String(template),
+		Parameters:   parameters,
+		Capabilities: capabilities,
+	})
+	if isAWSError(err, "ValidationError") && strings.Contains(err.Error(), "No updates are to be performed") {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "error updating CloudFormation stack %q", stackName)
+	}
+
+	return waitForStack(ctx, client, stackName)
+}
+
+func describeStack(ctx context.Context, client awsClient.CloudFormationInterface, stackName string) (*cftypes.Stack, error) {
+	output, err := client.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{StackName: aws.String(stackName)})
+	if isAWSError(err, "ValidationError") && strings.Contains(err.Error(), "does not exist") {
+		return nil, newNotFoundError("CloudFormation stack %s", stackName)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "error describing CloudFormation stack %q", stackName)
+	}
+
+	if len(output.Stacks) == 0 {
+		return nil, newNotFoundError("CloudFormation stack %s", stackName)
+	}
+
+	return &output.Stacks[0], nil
+}
+
+// deleteStack deletes the stack, and waits for its deletion to complete.
+func deleteStack(ctx context.Context, client awsClient.CloudFormationInterface, stackName string) error {
+	_, err := client.DeleteStack(ctx, &cloudformation.DeleteStackInput{StackName: aws.String(stackName)})
+	if err != nil {
+		return errors.Wrapf(err, "error deleting CloudFormation stack %q", stackName)
+	}
+
+	var stack *cftypes.Stack
+
+	err = wait.PollImmediateUntil(stackPollInterval, func() (bool, error) {
+		var err error
+
+		stack, err = describeStack(ctx, client, stackName)
+		if isNotFoundError(err) {
+			stack = nil
+			return true, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		return stack.StackStatus != cftypes.StackStatusDeleteInProgress, nil
+	}, ctx.Done())
+	if err != nil {
+		return errors.Wrapf(err, "error waiting for the deletion of CloudFormation stack %q", stackName)
+	}
+
+	if stack != nil && stack.StackStatus != cftypes.StackStatusDeleteComplete {
+		return fmt.Errorf("CloudFormation stack %q is %s: %s", stackName, stack.StackStatus, aws.ToString(stack.StackStatusReason))
+	}
+
+	return nil
+}
+
+// waitForStack waits until the stack's creation or update is complete, and fails if it failed or was rolled back. Once
+// an update is complete, the stack's status is UPDATE_COMPLETE_CLEANUP_IN_PROGRESS while the replaced resources are
+// deleted, which doesn't affect the outcome of the update.
+func waitForStack(ctx context.Context, client awsClient.CloudFormationInterface, stackName string) error {
+	var stack *cftypes.Stack
+
+	err := wait.PollImmediateUntil(stackPollInterval, func() (bool, error) {
+		var err error
+
+		stack, err = describeStack(ctx, client, stackName)
+		if err != nil {
+			return false, err
+		}
+
+		return stack.StackStatus == cftypes.StackStatusUpdateCompleteCleanupInProgress ||
+			!strings.HasSuffix(string(stack.StackStatus), "_IN_PROGRESS"), nil
+	}, ctx.Done())
+	if err != nil {
+		return errors.Wrapf(err, "error waiting for CloudFormation stack %q", stackName)
+	}
+
+	switch stack.StackStatus { // nolint:exhaustive // The other statuses are failures
+	case cftypes.StackStatusCreateComplete, cftypes.StackStatusUpdateComplete, cftypes.StackStatusUpdateCompleteCleanupInProgress:
+		return nil
+	default:
+		return fmt.Errorf("CloudFormation stack %q is %s: %s", stackName, stack.StackStatus, aws.ToString(stack.StackStatusReason))
+	}
+}
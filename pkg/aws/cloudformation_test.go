@@ -0,0 +1,250 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	cftypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/aws"
+	"github.com/submariner-io/cloud-prepare/pkg/aws/client/fake"
+)
+
+const stackName = "submariner"
+
+var _ = Describe("CloudFormation", func() {
+	var (
+		prepare *api.PrepareForSubmarinerInput
+		deploy  *api.GatewayDeployInput
+	)
+
+	BeforeEach(func() {
+		prepare = &api.PrepareForSubmarinerInput{
+			InternalPorts: []api.PortSpec{{Port: 4800, Protocol: "udp"}, {Protocol: "50"}},
+		}
+
+		deploy = &api.GatewayDeployInput{
+			PublicPorts:       []api.PortSpec{{Port: 4500, Protocol: "udp"}, {Port: 4490, EndPort: 4495, Protocol: "udp"}},
+			PublicSourceCIDRs: []string{"198.51.100.0/24", "2001:db8::/32"},
+			Gateways:          2,
+		}
+	})
+
+	Describe("RenderCloudFormation", func() {
+		It("should render the preparation and the gateways", func() {
+			template, err := aws.RenderCloudFormation(infraID, prepare, deploy)
+			Expect(err).To(Succeed())
+			Expect(string(template)).To(Equal(golden("cloudformation.json", template)))
+		})
+
+		When("there is nothing to prepare or deploy", func() {
+			It("should return an error", func() {
+				_, err := aws.RenderCloudFormation(infraID, &api.PrepareForSubmarinerInput{}, nil)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("DeployCloudFormation", testDeployCloudFormation)
+})
+
+// fakeStack is a CloudFormation stack, missing while its status is empty, whose status changes as it's created, updated
+// or deleted.
+type fakeStack struct {
+	status       cftypes.StackStatus
+	createStatus cftypes.StackStatus
+	updateStatus cftypes.StackStatus
+	updateErr    error
+	operations   []string
+}
+
+func testDeployCloudFormation() {
+	var (
+		t        *fakeAWS
+		stack    *fakeStack
+		cfClient *fake.MockCloudFormationInterface
+		input    *api.GatewayDeployInput
+	)
+
+	BeforeEach(func() {
+		t = &fakeAWS{}
+		t.beforeEach()
+
+		stack = &fakeStack{createStatus: cftypes.StackStatusCreateComplete, updateStatus: cftypes.StackStatusUpdateComplete}
+		input = &api.GatewayDeployInput{PublicPorts: []api.PortSpec{{Port: 4500, Protocol: "udp"}}, Gateways: 2}
+
+		cfClient = fake.NewMockCloudFormationInterface(t.mockCtrl)
+
+		cfClient.EXPECT().DescribeStacks(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ *cloudformation.DescribeStacksInput, _ ...func(*cloudformation.Options)) (
+				*cloudformation.DescribeStacksOutput, error) {
+				if stack.status == "" {
+					return nil, &smithy.GenericAPIError{Code: "ValidationError", Message: "Stack with id submariner does not exist"}
+				}
+
+				return &cloudformation.DescribeStacksOutput{Stacks: []cftypes.Stack{{
+					StackName:         awssdk.String(stackName),
+					StackStatus:       stack.status,
+					StackStatusReason: awssdk.String("fake reason"),
+				}}}, nil
+			}).AnyTimes()
+
+		cfClient.EXPECT().CreateStack(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, input *cloudformation.CreateStackInput, _ ...func(*cloudformation.Options)) (
+				*cloudformation.CreateStackOutput, error) {
+				Expect(input.Capabilities).To(ConsistOf(cftypes.CapabilityCapabilityIam))
+				Expect(input.Parameters).To(ContainElement(cftypes.Parameter{
+					ParameterKey: awssdk.String(aws.PublicSubnetIDsParameter), ParameterValue: awssdk.String("subnet-a,subnet-b"),
+				}))
+
+				stack.operations = append(stack.operations, "create")
+				stack.status = stack.createStatus
+
+				return &cloudformation.CreateStackOutput{}, nil
+			}).AnyTimes()
+
+		cfClient.EXPECT().UpdateStack(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ *cloudformation.UpdateStackInput, _ ...func(*cloudformation.Options)) (
+				*cloudformation.UpdateStackOutput, error) {
+				stack.operations = append(stack.operations, "update")
+				if stack.updateErr != nil {
+					return nil, stack.updateErr
+				}
+
+				stack.status = stack.updateStatus
+
+				return &cloudformation.UpdateStackOutput{}, nil
+			}).AnyTimes()
+
+		cfClient.EXPECT().DeleteStack(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ *cloudformation.DeleteStackInput, _ ...func(*cloudformation.Options)) (
+				*cloudformation.DeleteStackOutput, error) {
+				stack.operations = append(stack.operations, "delete")
+				stack.status = ""
+
+				return &cloudformation.DeleteStackOutput{}, nil
+			}).AnyTimes()
+	})
+
+	AfterEach(func() {
+		t.afterEach()
+	})
+
+	// deployStack deploys the stack, failing if it's still waiting for the stack after a second.
+	deployStack := func() error {
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+		defer cancel()
+
+		return aws.DeployCloudFormation(ctx, aws.NewCloud(t.ec2Client, infraID, region), cfClient, stackName,
+			&api.PrepareForSubmarinerInput{InternalPorts: []api.PortSpec{{Port: 4800, Protocol: "udp"}}}, input,
+			api.NewLoggingReporter())
+	}
+
+	When("the stack doesn't exist", func() {
+		It("should create it", func() {
+			Expect(deployStack()).To(Succeed())
+			Expect(stack.operations).To(Equal([]string{"create"}))
+		})
+
+		When("its creation fails", func() {
+			It("should return an error", func() {
+				stack.createStatus = cftypes.StackStatusRollbackComplete
+
+				err := deployStack()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("ROLLBACK_COMPLETE: fake reason"))
+			})
+		})
+	})
+
+	When("the stack exists", func() {
+		BeforeEach(func() {
+			stack.status = cftypes.StackStatusCreateComplete
+		})
+
+		It("should update it", func() {
+			Expect(deployStack()).To(Succeed())
+			Expect(stack.operations).To(Equal([]string{"update"}))
+		})
+
+		When("the replaced resources are still being cleaned up", func() {
+			It("should succeed", func() {
+				stack.updateStatus = cftypes.StackStatusUpdateCompleteCleanupInProgress
+				Expect(deployStack()).To(Succeed())
+			})
+		})
+
+		When("there are no updates to perform", func() {
+			It("should succeed", func() {
+				stack.updateErr = &smithy.GenericAPIError{Code: "ValidationError", Message: "No updates are to be performed."}
+				Expect(deployStack()).To(Succeed())
+			})
+		})
+
+		When("the update fails", func() {
+			It("should return an error", func() {
+				stack.updateStatus = cftypes.StackStatusUpdateRollbackComplete
+				Expect(deployStack()).ToNot(Succeed())
+			})
+		})
+
+		When("the update can't be started", func() {
+			It("should return an error", func() {
+				stack.updateErr = errors.New("fake error")
+				Expect(deployStack()).ToNot(Succeed())
+			})
+		})
+	})
+
+	When("the stack's creation was rolled back", func() {
+		It("should delete it and create it again", func() {
+			stack.status = cftypes.StackStatusRollbackComplete
+
+			Expect(deployStack()).To(Succeed())
+			Expect(stack.operations).To(Equal([]string{"delete", "create"}))
+		})
+	})
+}
+
+// golden returns the content of the golden file in testdata, after rewriting it with the actual output if UPDATE_GOLDEN
+// is set.
+func golden(name string, actual []byte) string {
+	path := filepath.Join("testdata", name)
+
+	if os.Getenv("UPDATE_GOLDEN") != "" {
+		Expect(ioutil.WriteFile(path, actual, 0o600)).To(Succeed())
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	Expect(err).To(Succeed())
+
+	return string(expected)
+}
@@ -0,0 +1,104 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+)
+
+// Config is the configuration used to create the AWS Cloud and GatewayDeployer with NewProvider.
+type Config struct {
+	InfraID string `json:"infraID"`
+	Region  string `json:"region"`
+
+	// CredentialsFile and Profile select the AWS credentials; DefaultCredentialsFile and DefaultProfile are used if empty.
+	CredentialsFile string `json:"credentialsFile,omitempty"`
+	Profile         string `json:"profile,omitempty"`
+
+	// InstanceType is the instance type of the dedicated gateway nodes; if empty, the first of the preferred instance
+	// types available in the region is used.
+	InstanceType string `json:"instanceType,omitempty"`
+
+	// Inventory, if set, records the resources which are created, and drives their cleanup.
+	Inventory api.Inventory `json:"-"`
+}
+
+// Validate returns an api.MissingFieldsError if any required field is missing.
+func (c *Config) Validate() error {
+	missing := &api.MissingFieldsError{Provider: "AWS"}
+
+	if c.InfraID == "" {
+		missing.Add("infraID", "")
+	}
+
+	if c.Region == "" {
+		missing.Add("region", "")
+	}
+
+	return missing.OrNil()
+}
+
+// NewProvider creates the AWS Cloud and GatewayDeployer for the given configuration. The Kubernetes client is optional;
+// it finds the node hosting the active gateway when removing gateways, see UseK8sClient.
+func NewProvider(config *Config, msDeployer ocp.MachineSetDeployer, k8sClient k8s.Interface) (api.Cloud, api.GatewayDeployer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if msDeployer == nil {
+		return nil, nil, errors.New("the AWS provider requires a MachineSetDeployer")
+	}
+
+	credentialsFile := config.CredentialsFile
+	if credentialsFile == "" {
+		credentialsFile = DefaultCredentialsFile()
+	}
+
+	profile := config.Profile
+	if profile == "" {
+		profile = DefaultProfile()
+	}
+
+	cloud, err := NewCloudFromSettings(credentialsFile, profile, config.InfraID, config.Region)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if config.Inventory != nil {
+		if err := UseInventory(cloud, config.Inventory); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	gwDeployer, err := NewOcpGatewayDeployer(cloud, msDeployer, config.InstanceType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if k8sClient != nil {
+		if err := UseK8sClient(gwDeployer, k8sClient); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return cloud, gwDeployer, nil
+}
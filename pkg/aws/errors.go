@@ -23,23 +23,15 @@ import (
 	"fmt"
 
 	"github.com/aws/smithy-go"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
 )
 
-type notFoundError struct {
-	s string
-}
-
-func (e notFoundError) Error() string {
-	return fmt.Sprintf("%s not found", e.s)
-}
-
 func newNotFoundError(msg string, args ...interface{}) error {
-	return notFoundError{fmt.Sprintf(msg, args...)}
+	return api.Errorf(api.ErrNotFound, "%s not found", fmt.Sprintf(msg, args...))
 }
 
 func isNotFoundError(err error) bool {
-	var e notFoundError
-	return errors.As(err, &e)
+	return errors.Is(err, api.ErrNotFound)
 }
 
 func appendIfError(errs []error, err error) []error {
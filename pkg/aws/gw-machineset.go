@@ -18,6 +18,9 @@ limitations under the License.
 
 package aws
 
+// tagGatewayInstance is set on the gateway instances by the machine set template.
+var tagGatewayInstance = ec2Tag("submariner.io", "gateway")
+
 var machineSetYAML = `apiVersion: machine.openshift.io/v1beta1
 kind: MachineSet
 metadata:
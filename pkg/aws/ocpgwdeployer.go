@@ -24,10 +24,13 @@ import (
 	"fmt"
 	"text/template"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/pkg/errors"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/metrics"
 	"github.com/submariner-io/cloud-prepare/pkg/ocp"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
@@ -38,6 +41,7 @@ type ocpGatewayDeployer struct {
 	aws          *awsCloud
 	msDeployer   ocp.MachineSetDeployer
 	instanceType string
+	k8sClient    k8s.Interface
 }
 
 var preferredInstances = []string{"c5d.large", "m5n.large"}
@@ -57,10 +61,43 @@ func NewOcpGatewayDeployer(cloud api.Cloud, msDeployer ocp.MachineSetDeployer, i
 	}, nil
 }
 
+// UseK8sClient makes the given AWS GatewayDeployer find the node hosting the active gateway with the given client, when
+// removing gateways without GatewayDeployInput.ActiveGatewayNode. If the supplied deployer is not an AWS one, an error
+// is returned.
+func UseK8sClient(gwDeployer api.GatewayDeployer, k8sClient k8s.Interface) error {
+	d, ok := gwDeployer.(*ocpGatewayDeployer)
+	if !ok {
+		return errors.New("the gateway deployer must be AWS")
+	}
+
+	d.k8sClient = k8sClient
+
+	return nil
+}
+
 func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, reporter api.Reporter) error {
+	return d.DeployWithContext(context.TODO(), input, reporter)
+}
+
+func (d *ocpGatewayDeployer) DeployWithContext(ctx context.Context, input api.GatewayDeployInput, reporter api.Reporter) error {
+	return metrics.ObserveOperation(ctx, "aws", metrics.Deploy, func() error {
+		return api.RunWithRollback(ctx, input.Rollback, reporter, func(ctx context.Context) error {
+			return d.deploy(ctx, input, reporter)
+		})
+	})
+}
+
+func (d *ocpGatewayDeployer) deploy(ctx context.Context, input api.GatewayDeployInput, reporter api.Reporter) error {
+	families, err := k8s.IPFamiliesOrDetected(ctx, d.k8sClient, input.IPFamilies)
+	if err != nil {
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	input.IPFamilies = families
+
 	reporter.Started(messageRetrieveVPCID)
 
-	vpcID, err := d.aws.getVpcID()
+	vpcID, err := d.aws.getVpcID(ctx)
 	if err != nil {
 		reporter.Failed(err)
 		return err
@@ -70,13 +107,13 @@ func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, reporter api.R
 
 	reporter.Started(messageValidatePrerequisites)
 
-	publicSubnets, err := d.aws.findPublicSubnets(vpcID, d.aws.filterByName("{infraID}-public-{region}*"))
+	publicSubnets, err := d.aws.findPublicSubnets(ctx, vpcID, d.aws.filterByName("{infraID}-public-{region}*"))
 	if err != nil {
 		reporter.Failed(err)
 		return err
 	}
 
-	err = d.validateDeployPrerequisites(vpcID, input, publicSubnets)
+	err = d.validateDeployPrerequisites(ctx, vpcID, input, publicSubnets, reporter)
 	if err != nil {
 		reporter.Failed(err)
 		return err
@@ -84,9 +121,10 @@ func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, reporter api.R
 
 	reporter.Succeeded(messageValidatedPrerequisites)
 
-	reporter.Started("Creating Submariner gateway security group")
+	api.ForResource(reporter, api.SecurityGroupResource, d.aws.withAWSInfo("{infraID}-submariner-gw-sg")).
+		Started("Creating Submariner gateway security group")
 
-	gatewaySG, err := d.aws.createGatewaySG(vpcID, input.PublicPorts)
+	gatewaySG, err := d.aws.createGatewaySG(ctx, vpcID, input.PublicPortSpecs(), input.PublicIPFamilies())
 	if err != nil {
 		reporter.Failed(err)
 		return err
@@ -94,30 +132,36 @@ func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, reporter api.R
 
 	reporter.Succeeded("Created Submariner gateway security group %s", gatewaySG)
 
-	subnets, err := d.aws.getSubnetsSupportingInstanceType(publicSubnets, d.instanceType)
+	subnets, err := d.aws.getSubnetsSupportingInstanceType(ctx, publicSubnets, d.instanceType)
 	if err != nil {
 		return err
 	}
 
-	taggedSubnets, _ := filterSubnets(subnets, func(subnet *types.Subnet) (bool, error) {
-		return subnetTagged(subnet), nil
-	})
-	untaggedSubnets, _ := filterSubnets(subnets, func(subnet *types.Subnet) (bool, error) {
-		return !subnetTagged(subnet), nil
-	})
+	taggedSubnets, subnetsToTag := selectGatewaySubnets(subnets, input.Gateways)
 
-	for i := range untaggedSubnets {
-		subnet := &untaggedSubnets[i]
+	err = d.aws.recordTaggedSubnets(ctx, taggedSubnets)
+	if err != nil {
+		return err
+	}
 
-		if input.Gateways > 0 && len(taggedSubnets) == input.Gateways {
-			break
+	if gatewaysToRemove := input.GatewaysToRemove(len(taggedSubnets)); gatewaysToRemove > 0 {
+		taggedSubnets, err = d.removeGateways(ctx, &input, vpcID, taggedSubnets, gatewaysToRemove, reporter)
+		if err != nil {
+			return err
 		}
+	}
+
+	// The gateways in the subnets which were already tagged are re-applied, only those in the newly tagged ones are new.
+	deployedGateways := len(taggedSubnets)
 
+	for i := range subnetsToTag {
+		subnet := &subnetsToTag[i]
 		subnetName := extractName(subnet.Tags)
 
-		reporter.Started("Adjusting public subnet %s to support Submariner", subnetName)
+		api.ForResource(reporter, api.SubnetTagResource, aws.ToString(subnet.SubnetId)).
+			Started("Adjusting public subnet %s to support Submariner", subnetName)
 
-		err = d.aws.tagPublicSubnet(subnet.SubnetId)
+		err = d.aws.tagPublicSubnet(ctx, subnet)
 		if err != nil {
 			reporter.Failed(err)
 			return err
@@ -132,56 +176,164 @@ func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, reporter api.R
 		subnet := &taggedSubnets[i]
 		subnetName := extractName(subnet.Tags)
 
-		reporter.Started("Deploying gateway node for public subnet %s", subnetName)
+		api.ForResource(reporter, api.MachineSetResource, d.machineSetName(subnet)).
+			Started("Deploying gateway node for public subnet %s", subnetName)
 
-		err = d.deployGateway(vpcID, gatewaySG, subnet)
+		err = d.deployGateway(ctx, vpcID, gatewaySG, subnet)
 		if err != nil {
 			reporter.Failed(err)
 			return err
 		}
 
+		if i >= deployedGateways {
+			metrics.GatewaysDeployed(ctx, "aws", 1)
+		}
+
 		reporter.Succeeded("Deployed gateway node for public subnet %s", subnetName)
 	}
 
 	return nil
 }
 
-func (d *ocpGatewayDeployer) validateDeployPrerequisites(vpcID string, input api.GatewayDeployInput,
-	publicSubnets []types.Subnet) error {
+// removeGateways removes the gateways from the given number of tagged subnets, and returns the remaining ones.
+func (d *ocpGatewayDeployer) removeGateways(ctx context.Context, input *api.GatewayDeployInput, vpcID string,
+	taggedSubnets []types.Subnet, count int, reporter api.Reporter) ([]types.Subnet, error) {
+	reporter.Started("Determining the %d surplus gateways to remove", count)
+
+	remaining, surplus, err := d.splitSurplusSubnets(ctx, input, vpcID, taggedSubnets, count)
+	if err != nil {
+		reporter.Failed(err)
+		return nil, err
+	}
+
+	reporter.Succeeded("Determined the surplus gateways to remove")
+
+	for i := range surplus {
+		subnet := &surplus[i]
+		subnetName := extractName(subnet.Tags)
+
+		api.ForResource(reporter, api.MachineSetResource, d.machineSetName(subnet)).
+			Started("Removing surplus gateway node for public subnet %s", subnetName)
+
+		err = d.deleteGateway(ctx, subnet)
+		if err == nil {
+			err = d.aws.untagPublicSubnet(ctx, subnet)
+		}
+
+		if err != nil {
+			reporter.Failed(err)
+			return nil, err
+		}
+
+		reporter.Succeeded("Removed surplus gateway node for public subnet %s", subnetName)
+	}
+
+	return remaining, nil
+}
+
+// splitSurplusSubnets splits the tagged subnets into those to keep and the given number of those whose gateways
+// should be removed. The subnet hosting the active gateway is always kept.
+func (d *ocpGatewayDeployer) splitSurplusSubnets(ctx context.Context, input *api.GatewayDeployInput, vpcID string,
+	taggedSubnets []types.Subnet, count int) ([]types.Subnet, []types.Subnet, error) {
+	// Without access to the cluster, the active gateway node must be supplied by the caller.
+	var activeGatewayNode api.ActiveGatewayNodeFunc
+	if d.k8sClient != nil {
+		activeGatewayNode = d.k8sClient.GetActiveGatewayNode
+	}
+
+	activeNode, err := input.FindActiveGatewayNode(ctx, activeGatewayNode)
+	if err != nil {
+		return nil, nil, err // nolint:wrapcheck // No need to wrap here
+	}
+
+	activeSubnetID, err := d.aws.findNodeSubnetID(ctx, vpcID, activeNode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	remaining := []types.Subnet{}
+	surplus := []types.Subnet{}
+
+	for i := range taggedSubnets {
+		if len(surplus) < count && (taggedSubnets[i].SubnetId == nil || *taggedSubnets[i].SubnetId != activeSubnetID) {
+			surplus = append(surplus, taggedSubnets[i])
+		} else {
+			remaining = append(remaining, taggedSubnets[i])
+		}
+	}
+
+	return remaining, surplus, nil
+}
+
+func (d *ocpGatewayDeployer) validateDeployPrerequisites(ctx context.Context, vpcID string, input api.GatewayDeployInput,
+	publicSubnets []types.Subnet, reporter api.Reporter) error {
 	var errs []error
-	var subnets []types.Subnet
 
-	errs = appendIfError(errs, d.aws.validateCreateSecGroup(vpcID))
-	errs = appendIfError(errs, d.aws.validateCreateSecGroupRule(vpcID))
-	err := d.aws.validateDescribeInstanceTypeOfferings()
+	errs = appendIfError(errs, d.aws.validateCreateSecGroup(ctx, vpcID))
+	errs = appendIfError(errs, d.aws.validateCreateSecGroupRule(ctx, vpcID))
+	err := d.aws.validateDescribeInstanceTypeOfferings(ctx)
 	errs = appendIfError(errs, err)
 
 	if err != nil {
 		return utilerrors.NewAggregate(errs)
 	}
 
-	// If instanceType is not specified, auto-select the most suitable one.
-	if d.instanceType == "" {
-		for _, instanceType := range preferredInstances {
-			subnets, err = d.aws.getSubnetsSupportingInstanceType(publicSubnets, instanceType)
-			if err != nil {
-				return err
-			}
+	autoSelected := d.instanceType == ""
 
-			if len(subnets) != 0 {
-				d.instanceType = instanceType
-				break
-			}
-		}
-	} else {
-		subnets, err = d.aws.getSubnetsSupportingInstanceType(publicSubnets, d.instanceType)
+	instanceType, subnets, err := d.findSubnetsSupportingInstanceType(ctx, publicSubnets)
+	if err != nil {
+		return err
+	}
+
+	if autoSelected && instanceType != "" && instanceType != preferredInstances[0] {
+		api.Warn(reporter, "The preferred instance type %s isn't offered in the public subnets, using %s instead",
+			preferredInstances[0], instanceType)
+	}
+
+	d.instanceType = instanceType
+
+	errs = append(errs, validateGatewaySubnets(input, subnets)...)
+
+	if len(subnets) > 0 {
+		errs = appendIfError(errs, d.aws.validateCreateTag(ctx, *subnets[0].SubnetId))
+	}
+
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+
+	_, subnetsToTag := selectGatewaySubnets(subnets, input.Gateways)
+
+	return d.validateQuotas(ctx, vpcID, &input, len(subnetsToTag))
+}
+
+// findSubnetsSupportingInstanceType returns the instance type to use for gateways, along with the public subnets
+// supporting it. If no instance type was specified, the most suitable one is auto-selected.
+func (d *ocpGatewayDeployer) findSubnetsSupportingInstanceType(ctx context.Context, publicSubnets []types.Subnet) (string,
+	[]types.Subnet, error) {
+	if d.instanceType != "" {
+		subnets, err := d.aws.getSubnetsSupportingInstanceType(ctx, publicSubnets, d.instanceType)
+		return d.instanceType, subnets, err
+	}
+
+	for _, instanceType := range preferredInstances {
+		subnets, err := d.aws.getSubnetsSupportingInstanceType(ctx, publicSubnets, instanceType)
 		if err != nil {
-			return err
+			return "", nil, err
+		}
+
+		if len(subnets) != 0 {
+			return instanceType, subnets, nil
 		}
 	}
 
-	subnetsCount := len(subnets)
-	if subnetsCount == 0 {
+	return "", nil, nil
+}
+
+func validateGatewaySubnets(input api.GatewayDeployInput, subnets []types.Subnet) []error {
+	var errs []error
+
+	if len(subnets) == 0 {
 		errs = append(errs, errors.New("found no public subnets to deploy Submariner gateway(s)"))
 	}
 
@@ -189,11 +341,7 @@ func (d *ocpGatewayDeployer) validateDeployPrerequisites(vpcID string, input api
 		errs = append(errs, fmt.Errorf("not enough public subnets to deploy %v Submariner gateway(s)", input.Gateways))
 	}
 
-	if len(subnets) > 0 {
-		errs = appendIfError(errs, d.aws.validateCreateTag(*subnets[0].SubnetId))
-	}
-
-	return utilerrors.NewAggregate(errs)
+	return errs
 }
 
 type machineSetConfig struct {
@@ -206,8 +354,8 @@ type machineSetConfig struct {
 	PublicSubnet  string
 }
 
-func (d *ocpGatewayDeployer) findAMIID(vpcID string) (string, error) {
-	result, err := d.aws.client.DescribeInstances(context.TODO(), &ec2.DescribeInstancesInput{
+func (d *ocpGatewayDeployer) findAMIID(ctx context.Context, vpcID string) (string, error) {
+	result, err := d.aws.client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
 		Filters: []types.Filter{
 			ec2Filter("vpc-id", vpcID),
 			d.aws.filterByName("{infraID}-worker*"),
@@ -279,8 +427,8 @@ func (d *ocpGatewayDeployer) initMachineSet(gwSecurityGroup, amiID string, publi
 	return machineSet, nil
 }
 
-func (d *ocpGatewayDeployer) deployGateway(vpcID, gatewaySecurityGroup string, publicSubnet *types.Subnet) error {
-	amiID, err := d.findAMIID(vpcID)
+func (d *ocpGatewayDeployer) deployGateway(ctx context.Context, vpcID, gatewaySecurityGroup string, publicSubnet *types.Subnet) error {
+	amiID, err := d.findAMIID(ctx, vpcID)
 	if err != nil {
 		return err
 	}
@@ -290,13 +438,42 @@ func (d *ocpGatewayDeployer) deployGateway(vpcID, gatewaySecurityGroup string, p
 		return err
 	}
 
-	return errors.Wrapf(d.msDeployer.Deploy(machineSet), "error deploying machine set %q", machineSet.GetName())
+	if err := d.msDeployer.Deploy(ctx, machineSet); err != nil {
+		return errors.Wrapf(err, "error deploying machine set %q", machineSet.GetName())
+	}
+
+	api.OnRollback(ctx, "delete machine set "+machineSet.GetName(), func(ctx context.Context) error {
+		return d.deleteGateway(ctx, publicSubnet)
+	})
+
+	return api.RecordResource(ctx, d.aws.inventory, machineSetResource(machineSet.GetName(), publicSubnet))
+}
+
+// machineSetName returns the name of the gateway machine set deployed in the given subnet.
+func (d *ocpGatewayDeployer) machineSetName(publicSubnet *types.Subnet) string {
+	return d.aws.withAWSInfo("{infraID}-submariner-gw-") + aws.ToString(publicSubnet.AvailabilityZone)
+}
+
+// machineSetResource returns the inventory resource for the named gateway machine set, deployed in the given subnet.
+// The subnet's availability zone is all that's needed to identify the machine set when it's deleted.
+func machineSetResource(name string, publicSubnet *types.Subnet) api.InventoryResource {
+	return api.InventoryResource{
+		Scope:       api.GatewayScope,
+		Kind:        api.MachineSetResource,
+		ID:          name,
+		Parent:      aws.ToString(publicSubnet.AvailabilityZone),
+		Description: "gateway node for public subnet " + extractName(publicSubnet.Tags),
+	}
 }
 
 func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
+	return d.CleanupWithContext(context.TODO(), reporter)
+}
+
+func (d *ocpGatewayDeployer) CleanupWithContext(ctx context.Context, reporter api.Reporter) error {
 	reporter.Started(messageRetrieveVPCID)
 
-	vpcID, err := d.aws.getVpcID()
+	vpcID, err := d.aws.getVpcID(ctx)
 	if err != nil {
 		reporter.Failed(err)
 		return err
@@ -306,7 +483,7 @@ func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
 
 	reporter.Started(messageValidatePrerequisites)
 
-	err = d.validateCleanupPrerequisites(vpcID)
+	err = d.validateCleanupPrerequisites(ctx, vpcID)
 	if err != nil {
 		reporter.Failed(err)
 		return err
@@ -314,7 +491,11 @@ func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
 
 	reporter.Succeeded(messageValidatedPrerequisites)
 
-	subnets, err := d.aws.getTaggedPublicSubnets(vpcID)
+	if d.aws.inventory != nil {
+		return d.cleanupInventory(ctx, reporter)
+	}
+
+	subnets, err := d.aws.getTaggedPublicSubnets(ctx, vpcID)
 	if err != nil {
 		return err
 	}
@@ -323,9 +504,10 @@ func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
 		subnet := &subnets[i]
 		subnetName := extractName(subnet.Tags)
 
-		reporter.Started("Removing gateway node for public subnet %s", subnetName)
+		api.ForResource(reporter, api.MachineSetResource, d.machineSetName(subnet)).
+			Started("Removing gateway node for public subnet %s", subnetName)
 
-		err = d.deleteGateway(subnet)
+		err = d.deleteGateway(ctx, subnet)
 		if err != nil {
 			reporter.Failed(err)
 			return err
@@ -333,9 +515,10 @@ func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
 
 		reporter.Succeeded("Removed gateway node for public subnet %s", subnetName)
 
-		reporter.Started("Untagging public subnet %s from supporting Submariner", subnetName)
+		api.ForResource(reporter, api.SubnetTagResource, aws.ToString(subnet.SubnetId)).
+			Started("Untagging public subnet %s from supporting Submariner", subnetName)
 
-		err = d.aws.untagPublicSubnet(subnet.SubnetId)
+		err = d.aws.untagPublicSubnet(ctx, subnet)
 		if err != nil {
 			reporter.Failed(err)
 			return err
@@ -344,9 +527,10 @@ func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
 		reporter.Succeeded("Untagged public subnet %s from supporting Submariner", subnetName)
 	}
 
-	reporter.Started("Deleting Submariner gateway security group")
+	api.ForResource(reporter, api.SecurityGroupResource, d.aws.withAWSInfo("{infraID}-submariner-gw-sg")).
+		Started("Deleting Submariner gateway security group")
 
-	err = d.aws.deleteGatewaySG(vpcID)
+	err = d.aws.deleteGatewaySG(ctx, vpcID)
 	if err != nil {
 		reporter.Failed(err)
 		return err
@@ -357,28 +541,60 @@ func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
 	return nil
 }
 
-func (d *ocpGatewayDeployer) validateCleanupPrerequisites(vpcID string) error {
+// cleanupInventory removes the gateway resources recorded in the inventory, most recently created first.
+func (d *ocpGatewayDeployer) cleanupInventory(ctx context.Context, reporter api.Reporter) error {
+	reporter.Started("Removing the Submariner gateway resources")
+
+	err := api.CleanupInventory(ctx, d.aws.inventory, api.GatewayScope, func(resource *api.InventoryResource) error {
+		switch resource.Kind { // nolint:exhaustive // Other kinds aren't created by the AWS gateway deployer
+		case api.MachineSetResource:
+			return d.deleteGateway(ctx, &types.Subnet{AvailabilityZone: aws.String(resource.Parent)})
+		case api.SubnetTagResource:
+			return d.aws.untagPublicSubnet(ctx, &types.Subnet{SubnetId: aws.String(resource.ID)})
+		case api.SecurityGroupRuleResource:
+			return d.aws.revokeSGRule(ctx, resource)
+		case api.SecurityGroupResource:
+			return d.aws.deleteSecurityGroup(ctx, aws.String(resource.ID))
+		default:
+			return fmt.Errorf("unexpected %s resource in the AWS inventory", resource.Kind)
+		}
+	})
+	if err != nil {
+		reporter.Failed(err)
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	reporter.Succeeded("Removed the Submariner gateway resources")
+
+	return nil
+}
+
+func (d *ocpGatewayDeployer) validateCleanupPrerequisites(ctx context.Context, vpcID string) error {
 	var errs []error
 
-	errs = appendIfError(errs, d.aws.validateDeleteSecGroup(vpcID))
+	errs = appendIfError(errs, d.aws.validateDeleteSecGroup(ctx, vpcID))
 
-	subnets, err := d.aws.getTaggedPublicSubnets(vpcID)
+	subnets, err := d.aws.getTaggedPublicSubnets(ctx, vpcID)
 	if err != nil {
 		return err
 	}
 
 	if len(subnets) > 0 {
-		errs = appendIfError(errs, d.aws.validateRemoveTag(subnets[0].SubnetId))
+		errs = appendIfError(errs, d.aws.validateRemoveTag(ctx, subnets[0].SubnetId))
 	}
 
 	return utilerrors.NewAggregate(errs)
 }
 
-func (d *ocpGatewayDeployer) deleteGateway(publicSubnet *types.Subnet) error {
+func (d *ocpGatewayDeployer) deleteGateway(ctx context.Context, publicSubnet *types.Subnet) error {
 	machineSet, err := d.initMachineSet("", "", publicSubnet)
 	if err != nil {
 		return err
 	}
 
-	return errors.Wrapf(d.msDeployer.Delete(machineSet), "error deleting machine set %q", machineSet.GetName())
+	if err := d.msDeployer.Delete(ctx, machineSet); err != nil {
+		return errors.Wrapf(err, "error deleting machine set %q", machineSet.GetName())
+	}
+
+	return api.ForgetResource(ctx, d.aws.inventory, machineSetResource(machineSet.GetName(), publicSubnet))
 }
@@ -0,0 +1,226 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws_test
+
+import (
+	"context"
+	"strings"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/aws"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kubeFake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("OCP GatewayDeployer", func() {
+	Describe("Deploy", testDeploy)
+	Describe("Status", testGatewayStatus)
+})
+
+func testDeploy() {
+	const activeNode = "ip-10-0-1-1.ec2.internal"
+
+	var (
+		t          *fakeAWS
+		gwDeployer api.GatewayDeployer
+		input      api.GatewayDeployInput
+	)
+
+	BeforeEach(func() {
+		t = &fakeAWS{}
+		t.beforeEach()
+
+		// The active gateway runs in the first tagged subnet, which is the first one a naive removal would pick.
+		t.nodeSubnets[activeNode] = "subnet-a"
+
+		var err error
+
+		gwDeployer, err = aws.NewOcpGatewayDeployer(aws.NewCloud(t.ec2Client, infraID, region), t.msDeployer, instanceType)
+		Expect(err).To(Succeed())
+
+		input = api.GatewayDeployInput{
+			PublicPorts: []api.PortSpec{{Port: 4500, Protocol: "udp"}},
+		}
+	})
+
+	AfterEach(func() {
+		t.afterEach()
+	})
+
+	When("more gateways are requested than are deployed", func() {
+		It("should tag another subnet and deploy a gateway in each tagged subnet", func() {
+			input.Gateways = 3
+			Expect(gwDeployer.Deploy(input, api.NewLoggingReporter())).To(Succeed())
+
+			Expect(t.taggedSubnets()).To(Equal([]string{"subnet-a", "subnet-b", "subnet-c"}))
+			Expect(t.deployedMachineSets).To(ConsistOf(machineSetName("a"), machineSetName("b"), machineSetName("c")))
+			Expect(t.deletedMachineSets).To(BeEmpty())
+		})
+
+		It("should record only the newly deployed gateways in the metrics", func() {
+			registry := prometheus.NewRegistry()
+			m, err := metrics.New(registry)
+			Expect(err).To(Succeed())
+
+			ctx := metrics.NewContext(context.TODO(), m)
+
+			input.Gateways = 3
+			Expect(gwDeployer.DeployWithContext(ctx, input, api.NewLoggingReporter())).To(Succeed())
+			Expect(gwDeployer.DeployWithContext(ctx, input, api.NewLoggingReporter())).To(Succeed())
+
+			Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP cloud_prepare_gateways_deployed_total Number of gateways deployed, by provider.
+# TYPE cloud_prepare_gateways_deployed_total counter
+cloud_prepare_gateways_deployed_total{provider="aws"} 1
+`), "cloud_prepare_gateways_deployed_total")).To(Succeed())
+		})
+	})
+
+	When("fewer gateways are requested than are deployed", func() {
+		Context("and the deployer has a Kubernetes client", func() {
+			BeforeEach(func() {
+				Expect(aws.UseK8sClient(gwDeployer, k8s.NewInterface(kubeFake.NewSimpleClientset(activeGatewayPod(activeNode))))).
+					To(Succeed())
+			})
+
+			It("should remove a gateway other than the active one", func() {
+				input.Gateways = 1
+				Expect(gwDeployer.Deploy(input, api.NewLoggingReporter())).To(Succeed())
+
+				Expect(t.taggedSubnets()).To(Equal([]string{"subnet-a"}))
+				Expect(t.deletedMachineSets).To(Equal([]string{machineSetName("b")}))
+				Expect(t.deployedMachineSets).To(Equal([]string{machineSetName("a")}))
+			})
+		})
+
+		Context("and the active gateway node is supplied", func() {
+			It("should remove a gateway other than the active one", func() {
+				input.Gateways = 1
+				input.ActiveGatewayNode = func(ctx context.Context) (string, error) {
+					return activeNode, nil
+				}
+
+				Expect(gwDeployer.Deploy(input, api.NewLoggingReporter())).To(Succeed())
+
+				Expect(t.taggedSubnets()).To(Equal([]string{"subnet-a"}))
+				Expect(t.deletedMachineSets).To(Equal([]string{machineSetName("b")}))
+			})
+		})
+
+		Context("and the active gateway node can't be determined", func() {
+			It("should fail without removing any gateway", func() {
+				input.Gateways = 1
+				Expect(gwDeployer.Deploy(input, api.NewLoggingReporter())).To(MatchError(ContainSubstring("ActiveGatewayNode must be set")))
+
+				Expect(t.taggedSubnets()).To(Equal([]string{"subnet-a", "subnet-b"}))
+				Expect(t.deletedMachineSets).To(BeEmpty())
+			})
+		})
+
+		Context("and there is no active gateway", func() {
+			BeforeEach(func() {
+				Expect(aws.UseK8sClient(gwDeployer, k8s.NewInterface(kubeFake.NewSimpleClientset()))).To(Succeed())
+			})
+
+			It("should remove the surplus gateways in order", func() {
+				input.Gateways = 1
+				Expect(gwDeployer.Deploy(input, api.NewLoggingReporter())).To(Succeed())
+
+				Expect(t.taggedSubnets()).To(Equal([]string{"subnet-b"}))
+				Expect(t.deletedMachineSets).To(Equal([]string{machineSetName("a")}))
+			})
+		})
+	})
+}
+
+func testGatewayStatus() {
+	var (
+		t          *fakeAWS
+		gwDeployer api.GatewayDeployer
+	)
+
+	BeforeEach(func() {
+		t = &fakeAWS{}
+		t.beforeEach()
+
+		t.msDeployer.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return([]unstructured.Unstructured{}, nil).AnyTimes()
+
+		var err error
+
+		gwDeployer, err = aws.NewOcpGatewayDeployer(aws.NewCloud(t.ec2Client, infraID, region), t.msDeployer, instanceType)
+		Expect(err).To(Succeed())
+	})
+
+	AfterEach(func() {
+		t.afterEach()
+	})
+
+	When("the deployer has a Kubernetes client", func() {
+		It("should report the labelled gateway nodes", func() {
+			Expect(aws.UseK8sClient(gwDeployer, k8s.NewInterface(kubeFake.NewSimpleClientset(
+				gatewayNode("node-1"), gatewayNode("node-2"), &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-3"}})))).
+				To(Succeed())
+
+			status, err := gwDeployer.Status(context.TODO())
+			Expect(err).To(Succeed())
+			Expect(status.GatewayNodes).To(ConsistOf("node-1", "node-2"))
+		})
+	})
+
+	When("the deployer has no Kubernetes client", func() {
+		It("should not report any gateway nodes", func() {
+			status, err := gwDeployer.Status(context.TODO())
+			Expect(err).To(Succeed())
+			Expect(status.GatewayNodes).To(BeEmpty())
+		})
+	})
+}
+
+func machineSetName(zone string) string {
+	return infraID + "-submariner-gw-" + region + zone
+}
+
+func gatewayNode(name string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{k8s.SubmarinerGatewayLabel: "true"},
+		},
+	}
+}
+
+func activeGatewayPod(nodeName string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "submariner-gateway",
+			Namespace: "submariner-operator",
+			Labels:    map[string]string{k8s.GatewayStatusLabel: "active"},
+		},
+		Spec: corev1.PodSpec{NodeName: nodeName},
+	}
+}
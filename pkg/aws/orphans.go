@@ -0,0 +1,190 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+// ScanOrphans searches the cluster's VPC for gateway instances, public subnets carrying the Submariner tags, Submariner
+// security groups, and internal Submariner rules in the worker and master security groups. The gateway instances come
+// first since the gateway security group can only be deleted once they're terminated.
+func (ac *awsCloud) ScanOrphans(ctx context.Context) ([]api.InventoryResource, error) {
+	vpcID, err := ac.getVpcID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orphans, err := ac.scanGatewayInstances(ctx, vpcID)
+	if err != nil {
+		return nil, err
+	}
+
+	subnets, err := ac.scanTaggedSubnets(ctx, vpcID)
+	if err != nil {
+		return nil, err
+	}
+
+	orphans = append(orphans, subnets...)
+
+	groups, err := ac.client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: []types.Filter{
+			ec2Filter("vpc-id", vpcID),
+			ac.filterByName("{infraID}-submariner-*"),
+			ac.filterByCurrentCluster(),
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error describing AWS security groups")
+	}
+
+	for i := range groups.SecurityGroups {
+		orphans = append(orphans, api.InventoryResource{
+			Scope:       api.GatewayScope,
+			Kind:        api.SecurityGroupResource,
+			ID:          aws.ToString(groups.SecurityGroups[i].GroupId),
+			Description: aws.ToString(groups.SecurityGroups[i].GroupName),
+		})
+	}
+
+	rules, err := ac.scanInternalRules(ctx, vpcID)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(orphans, rules...), nil
+}
+
+// scanGatewayInstances returns the cluster's instances which haven't been terminated and carry the tag set by the
+// gateway MachineSets.
+func (ac *awsCloud) scanGatewayInstances(ctx context.Context, vpcID string) ([]api.InventoryResource, error) {
+	orphans := []api.InventoryResource{}
+
+	input := &ec2.DescribeInstancesInput{
+		Filters: []types.Filter{
+			ec2Filter("vpc-id", vpcID),
+			ec2FilterByTag(tagGatewayInstance),
+			ac.filterByCurrentCluster(),
+			{Name: aws.String("instance-state-name"), Values: []string{"pending", "running", "stopping", "stopped"}},
+		},
+	}
+
+	for {
+		result, err := ac.client.DescribeInstances(ctx, input)
+		if err != nil {
+			return nil, errors.Wrap(err, "error describing AWS instances")
+		}
+
+		for i := range result.Reservations {
+			for j := range result.Reservations[i].Instances {
+				instance := &result.Reservations[i].Instances[j]
+
+				orphans = append(orphans, api.InventoryResource{
+					Scope:       api.GatewayScope,
+					Kind:        api.InstanceResource,
+					ID:          aws.ToString(instance.InstanceId),
+					Description: extractName(instance.Tags),
+				})
+			}
+		}
+
+		if result.NextToken == nil {
+			return orphans, nil
+		}
+
+		input.NextToken = result.NextToken
+	}
+}
+
+func (ac *awsCloud) scanTaggedSubnets(ctx context.Context, vpcID string) ([]api.InventoryResource, error) {
+	publicSubnets, err := ac.findPublicSubnets(ctx, vpcID, ac.filterByName("{infraID}-public-{region}*"))
+	if err != nil {
+		return nil, err
+	}
+
+	orphans := []api.InventoryResource{}
+
+	// Only Submariner tags public subnets for internal load balancers, so either tag reveals a leftover.
+	for i := range publicSubnets {
+		if hasTag(publicSubnets[i].Tags, tagSubmarinerGateway) || hasTag(publicSubnets[i].Tags, tagInternalELB) {
+			orphans = append(orphans, subnetTagResource(&publicSubnets[i], false))
+		}
+	}
+
+	return orphans, nil
+}
+
+func (ac *awsCloud) scanInternalRules(ctx context.Context, vpcID string) ([]api.InventoryResource, error) {
+	orphans := []api.InventoryResource{}
+
+	for _, name := range []string{"{infraID}-worker-sg", "{infraID}-master-sg"} {
+		groupID, err := ac.getSecurityGroupID(ctx, vpcID, name)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := ac.client.DescribeSecurityGroupRules(ctx, &ec2.DescribeSecurityGroupRulesInput{
+			Filters: []types.Filter{ec2Filter("group-id", aws.ToString(groupID))},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "error describing AWS security group rules")
+		}
+
+		for i := range result.SecurityGroupRules {
+			rule := &result.SecurityGroupRules[i]
+
+			if aws.ToBool(rule.IsEgress) || !strings.Contains(aws.ToString(rule.Description), internalTraffic) {
+				continue
+			}
+
+			port := permissionPortSpec(&types.IpPermission{IpProtocol: rule.IpProtocol, FromPort: rule.FromPort, ToPort: rule.ToPort})
+
+			orphans = append(orphans, sgRuleResource(api.CloudScope, groupID, aws.ToString(rule.SecurityGroupRuleId),
+				fmt.Sprintf("%s: %s", aws.ToString(rule.Description), port), false))
+		}
+	}
+
+	return orphans, nil
+}
+
+func (ac *awsCloud) DeleteOrphans(ctx context.Context, orphans []api.InventoryResource, reporter api.Reporter) error {
+	return api.DeleteOrphans(orphans, reporter, func(resource *api.InventoryResource) error {
+		switch resource.Kind { // nolint:exhaustive // Other kinds aren't found by ScanOrphans
+		case api.InstanceResource:
+			_, err := ac.client.TerminateInstances(ctx, &ec2.TerminateInstancesInput{InstanceIds: []string{resource.ID}})
+			return errors.Wrapf(err, "error terminating instance %q", resource.ID)
+		case api.SubnetTagResource:
+			return ac.untagPublicSubnet(ctx, &types.Subnet{SubnetId: aws.String(resource.ID)})
+		case api.SecurityGroupResource:
+			return ac.deleteSecurityGroup(ctx, aws.String(resource.ID))
+		case api.SecurityGroupRuleResource:
+			return ac.revokeSGRule(ctx, resource)
+		default:
+			return fmt.Errorf("unexpected orphaned %s resource on AWS", resource.Kind)
+		}
+	})
+}
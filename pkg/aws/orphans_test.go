@@ -0,0 +1,81 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws_test
+
+import (
+	"context"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/aws"
+)
+
+var _ = Describe("Orphans", func() {
+	var (
+		t     *fakeAWS
+		cloud api.Cloud
+	)
+
+	BeforeEach(func() {
+		t = &fakeAWS{}
+		t.beforeEach()
+
+		t.subnets = []types.Subnet{publicSubnet("a", false)}
+		t.gatewayInstances = []types.Instance{{
+			InstanceId: awssdk.String("i-gateway"),
+			Tags:       []types.Tag{{Key: awssdk.String("Name"), Value: awssdk.String(infraID + "-submariner-gw-a")}},
+		}}
+
+		t.ec2Client.EXPECT().DescribeSecurityGroupRules(gomock.Any(), gomock.Any()).Return(
+			&ec2.DescribeSecurityGroupRulesOutput{}, nil).AnyTimes()
+
+		cloud = aws.NewCloud(t.ec2Client, infraID, region)
+	})
+
+	AfterEach(func() {
+		t.afterEach()
+	})
+
+	It("should report the gateway instances", func() {
+		orphans, err := cloud.ScanOrphans(context.TODO())
+		Expect(err).To(Succeed())
+		Expect(orphans).To(Equal([]api.InventoryResource{{
+			Scope:       api.GatewayScope,
+			Kind:        api.InstanceResource,
+			ID:          "i-gateway",
+			Description: infraID + "-submariner-gw-a",
+		}}))
+	})
+
+	It("should terminate the orphaned gateway instances", func() {
+		t.ec2Client.EXPECT().TerminateInstances(gomock.Any(), &ec2.TerminateInstancesInput{InstanceIds: []string{"i-gateway"}}).
+			Return(&ec2.TerminateInstancesOutput{}, nil)
+
+		Expect(cloud.DeleteOrphans(context.TODO(), []api.InventoryResource{{
+			Scope: api.GatewayScope,
+			Kind:  api.InstanceResource,
+			ID:    "i-gateway",
+		}}, api.NewLoggingReporter())).To(Succeed())
+	})
+})
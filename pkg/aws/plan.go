@@ -0,0 +1,285 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/submariner-io/admiral/pkg/stringset"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+func (ac *awsCloud) PlanPrepareForSubmariner(ctx context.Context, input api.PrepareForSubmarinerInput) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	vpcID, err := ac.getVpcID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	workerGroup, err := ac.getSecurityGroup(ctx, vpcID, "{infraID}-worker-sg")
+	if err != nil {
+		return nil, err
+	}
+
+	masterGroup, err := ac.getSecurityGroup(ctx, vpcID, "{infraID}-master-sg")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, port := range input.InternalPorts {
+		planClusterSGRule(plan, &workerGroup, &workerGroup, port)
+		planClusterSGRule(plan, &workerGroup, &masterGroup, port)
+		planClusterSGRule(plan, &masterGroup, &workerGroup, port)
+	}
+
+	return plan, nil
+}
+
+func (ac *awsCloud) PlanCleanupAfterSubmariner(ctx context.Context) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	if ac.inventory != nil {
+		if err := api.PlanInventoryCleanup(ctx, ac.inventory, api.CloudScope, plan); err != nil {
+			return nil, err // nolint:wrapcheck // No need to wrap here
+		}
+
+		return plan, nil
+	}
+
+	vpcID, err := ac.getVpcID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range []string{"{infraID}-worker-sg", "{infraID}-master-sg"} {
+		group, err := ac.getSecurityGroup(ctx, vpcID, name)
+		if err != nil {
+			return nil, err
+		}
+
+		permissions := internalPermissions(&group)
+		for i := range permissions {
+			plan.Add(api.ChangeDelete, api.SecurityGroupRuleResource, *group.GroupId, "revoke %s", describePermission(&permissions[i]))
+		}
+	}
+
+	return plan, nil
+}
+
+func (d *ocpGatewayDeployer) PlanDeploy(ctx context.Context, input api.GatewayDeployInput) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	families, err := k8s.IPFamiliesOrDetected(ctx, d.k8sClient, input.IPFamilies)
+	if err != nil {
+		return nil, err // nolint:wrapcheck // No need to wrap here
+	}
+
+	input.IPFamilies = families
+
+	vpcID, err := d.aws.getVpcID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	publicSubnets, err := d.aws.findPublicSubnets(ctx, vpcID, d.aws.filterByName("{infraID}-public-{region}*"))
+	if err != nil {
+		return nil, err
+	}
+
+	instanceType, subnets, err := d.findSubnetsSupportingInstanceType(ctx, publicSubnets)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := utilerrors.NewAggregate(validateGatewaySubnets(input, subnets)); err != nil {
+		return nil, err
+	}
+
+	gatewaySG, err := d.aws.planGatewaySG(ctx, plan, vpcID, input.PublicPortSpecs(), input.PublicIPFamilies())
+	if err != nil {
+		return nil, err
+	}
+
+	taggedSubnets, subnetsToTag := selectGatewaySubnets(subnets, input.Gateways)
+
+	if gatewaysToRemove := input.GatewaysToRemove(len(taggedSubnets)); gatewaysToRemove > 0 {
+		var surplus []types.Subnet
+
+		taggedSubnets, surplus, err = d.splitSurplusSubnets(ctx, &input, vpcID, taggedSubnets, gatewaysToRemove)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range surplus {
+			if err := d.planDeleteGateway(plan, &surplus[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for i := range subnetsToTag {
+		plan.Add(api.ChangeCreate, api.SubnetTagResource, *subnetsToTag[i].SubnetId, "tag public subnet %s with %q and %q",
+			extractName(subnetsToTag[i].Tags), *tagInternalELB.Key, *tagSubmarinerGateway.Key)
+	}
+
+	taggedSubnets = append(taggedSubnets, subnetsToTag...)
+
+	for i := range taggedSubnets {
+		machineSet, err := d.initMachineSet(gatewaySG, "", &taggedSubnets[i])
+		if err != nil {
+			return nil, err
+		}
+
+		plan.Add(api.ChangeApply, api.MachineSetResource, machineSet.GetName(), "gateway node of type %s for public subnet %s",
+			instanceType, extractName(taggedSubnets[i].Tags))
+	}
+
+	return plan, nil
+}
+
+func (d *ocpGatewayDeployer) PlanCleanup(ctx context.Context) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	if d.aws.inventory != nil {
+		if err := api.PlanInventoryCleanup(ctx, d.aws.inventory, api.GatewayScope, plan); err != nil {
+			return nil, err // nolint:wrapcheck // No need to wrap here
+		}
+
+		return plan, nil
+	}
+
+	vpcID, err := d.aws.getVpcID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subnets, err := d.aws.getTaggedPublicSubnets(ctx, vpcID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range subnets {
+		if err := d.planDeleteGateway(plan, &subnets[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	groupName := d.aws.withAWSInfo("{infraID}-submariner-gw-sg")
+
+	_, err = d.aws.getSecurityGroupID(ctx, vpcID, groupName)
+	if err == nil {
+		plan.Add(api.ChangeDelete, api.SecurityGroupResource, groupName, "")
+	} else if !isNotFoundError(err) {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func (d *ocpGatewayDeployer) planDeleteGateway(plan *api.Plan, subnet *types.Subnet) error {
+	machineSet, err := d.initMachineSet("", "", subnet)
+	if err != nil {
+		return err
+	}
+
+	plan.Add(api.ChangeDelete, api.MachineSetResource, machineSet.GetName(), "gateway node for public subnet %s",
+		extractName(subnet.Tags))
+	plan.Add(api.ChangeDelete, api.SubnetTagResource, *subnet.SubnetId, "untag public subnet %s",
+		extractName(subnet.Tags))
+
+	return nil
+}
+
+func (ac *awsCloud) planGatewaySG(ctx context.Context, plan *api.Plan, vpcID string, ports []api.PortSpec,
+	families []api.IPFamily) (string, error) {
+	groupName := ac.withAWSInfo("{infraID}-submariner-gw-sg")
+
+	group, err := ac.getSecurityGroup(ctx, vpcID, groupName)
+	if err != nil {
+		if !isNotFoundError(err) {
+			return "", err
+		}
+
+		plan.Add(api.ChangeCreate, api.SecurityGroupResource, groupName, "in VPC %s", vpcID)
+	}
+
+	for _, port := range ports {
+		for _, family := range families {
+			for _, cidr := range port.SourcesFor(family) {
+				if hasPermission(group.IpPermissions, port, func(permission *types.IpPermission) bool {
+					return stringset.New(permissionCIDRs(permission)...).Contains(cidr)
+				}) {
+					continue
+				}
+
+				plan.Add(api.ChangeCreate, api.SecurityGroupRuleResource, groupName, "allow %s from %s", port, cidr)
+			}
+		}
+	}
+
+	return groupName, nil
+}
+
+func planClusterSGRule(plan *api.Plan, srcGroup, destGroup *types.SecurityGroup, port api.PortSpec) {
+	if hasPermission(destGroup.IpPermissions, port, func(permission *types.IpPermission) bool {
+		for _, groupPair := range permission.UserIdGroupPairs {
+			if groupPair.GroupId != nil && *groupPair.GroupId == *srcGroup.GroupId {
+				return true
+			}
+		}
+
+		return false
+	}) {
+		return
+	}
+
+	plan.Add(api.ChangeCreate, api.SecurityGroupRuleResource, *destGroup.GroupId, "allow %s from %s", port, *srcGroup.GroupId)
+}
+
+func hasPermission(permissions []types.IpPermission, port api.PortSpec, sourceMatches func(*types.IpPermission) bool) bool {
+	expected := newIPPermission(port)
+
+	for i := range permissions {
+		if samePorts(&permissions[i], &expected) && sourceMatches(&permissions[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func describePermission(permission *types.IpPermission) string {
+	sources := []string{}
+
+	for _, groupPair := range permission.UserIdGroupPairs {
+		if groupPair.GroupId != nil {
+			sources = append(sources, *groupPair.GroupId)
+		}
+	}
+
+	sources = append(sources, permissionCIDRs(permission)...)
+
+	return fmt.Sprintf("%s from %s", permissionPortSpec(permission), strings.Join(sources, ", "))
+}
@@ -0,0 +1,246 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+const (
+	// Running On-Demand Standard (A, C, D, H, I, M, R, T, Z) instances, in vCPUs.
+	standardInstancesQuotaCode = "L-1216C47A"
+	// Inbound or outbound rules per security group.
+	securityGroupRulesQuotaCode = "L-0EA8095F"
+)
+
+// standardInstanceSeries are the series of the instance families counted by the Standard instances quota, e.g. "c" for
+// "c5d.large" or "im" for "im4gn.large". Other series sharing their first letter have quotas of their own, e.g. "inf"
+// (Inferentia), "mac", "dl" (Deep Learning), "trn" (Trainium) and "hpc".
+var standardInstanceSeries = map[string]bool{
+	"a": true, "c": true, "d": true, "h": true, "i": true, "im": true, "is": true, "m": true, "r": true, "t": true, "z": true,
+}
+
+// validateQuotas checks that the account's service quotas allow for the given number of new gateways, along with
+// the rules missing from the gateway security group. Quotas which can't be retrieved are skipped.
+func (d *ocpGatewayDeployer) validateQuotas(ctx context.Context, vpcID string, input *api.GatewayDeployInput,
+	newGateways int) error {
+	if d.aws.quotas == nil {
+		return nil
+	}
+
+	quotas := []api.Quota{}
+
+	if newGateways > 0 && isStandardInstanceType(d.instanceType) {
+		quota, err := d.vCPUsQuota(ctx, newGateways)
+		if err != nil {
+			return err
+		}
+
+		if quota != nil {
+			quotas = append(quotas, *quota)
+		}
+	}
+
+	limit, err := d.aws.getServiceQuota(ctx, "vpc", securityGroupRulesQuotaCode)
+	if err != nil {
+		return err
+	}
+
+	if limit != nil {
+		group, err := d.aws.getSecurityGroup(ctx, vpcID, "{infraID}-submariner-gw-sg")
+		if err != nil && !isNotFoundError(err) {
+			return err
+		}
+
+		// The quota applies separately to the IPv4 and the IPv6 rules.
+		for _, family := range input.PublicIPFamilies() {
+			quotas = append(quotas, api.Quota{
+				Name:     fmt.Sprintf("%s inbound rules per security group", family),
+				Limit:    *limit,
+				Used:     int64(inboundRules(&group, family)),
+				Required: int64(missingRules(input, &group, family)),
+			})
+		}
+	}
+
+	return api.ValidateQuotas(quotas...)
+}
+
+func (d *ocpGatewayDeployer) vCPUsQuota(ctx context.Context, newGateways int) (*api.Quota, error) {
+	limit, err := d.aws.getServiceQuota(ctx, "ec2", standardInstancesQuotaCode)
+	if err != nil || limit == nil {
+		return nil, err
+	}
+
+	result, err := d.aws.client.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []types.InstanceType{types.InstanceType(d.instanceType)},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error describing instance type %q", d.instanceType)
+	}
+
+	if len(result.InstanceTypes) == 0 || result.InstanceTypes[0].VCpuInfo == nil {
+		return nil, nil
+	}
+
+	used, err := d.aws.standardVCPUsInUse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.Quota{
+		Name:     fmt.Sprintf("running On-Demand Standard instance vCPUs in region %s", d.aws.region),
+		Limit:    *limit,
+		Used:     used,
+		Required: int64(aws.ToInt32(result.InstanceTypes[0].VCpuInfo.DefaultVCpus)) * int64(newGateways),
+	}, nil
+}
+
+// getServiceQuota returns the value of the given quota, or nil if it can't be retrieved.
+func (ac *awsCloud) getServiceQuota(ctx context.Context, serviceCode, quotaCode string) (*int64, error) {
+	result, err := ac.quotas.GetServiceQuota(ctx, &servicequotas.GetServiceQuotaInput{
+		ServiceCode: aws.String(serviceCode),
+		QuotaCode:   aws.String(quotaCode),
+	})
+
+	if isAWSError(err, "AccessDeniedException") || isAWSError(err, "NoSuchResourceException") {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "error retrieving the %s service quota %s", serviceCode, quotaCode)
+	}
+
+	if result.Quota == nil || result.Quota.Value == nil {
+		return nil, nil
+	}
+
+	limit := int64(*result.Quota.Value)
+
+	return &limit, nil
+}
+
+func (ac *awsCloud) standardVCPUsInUse(ctx context.Context) (int64, error) {
+	var used int64
+
+	input := &ec2.DescribeInstancesInput{
+		Filters: []types.Filter{{Name: aws.String("instance-state-name"), Values: []string{"pending", "running"}}},
+	}
+
+	for {
+		result, err := ac.client.DescribeInstances(ctx, input)
+		if err != nil {
+			return 0, errors.Wrap(err, "error describing AWS instances")
+		}
+
+		for i := range result.Reservations {
+			for j := range result.Reservations[i].Instances {
+				instance := &result.Reservations[i].Instances[j]
+				if instance.CpuOptions == nil || !isOnDemand(instance) || !isStandardInstanceType(string(instance.InstanceType)) {
+					continue
+				}
+
+				used += int64(aws.ToInt32(instance.CpuOptions.CoreCount) * aws.ToInt32(instance.CpuOptions.ThreadsPerCore))
+			}
+		}
+
+		if result.NextToken == nil {
+			return used, nil
+		}
+
+		input.NextToken = result.NextToken
+	}
+}
+
+// isOnDemand returns true if the instance is counted by the On-Demand quotas, i.e. isn't a Spot or a Scheduled instance.
+func isOnDemand(instance *types.Instance) bool {
+	return instance.InstanceLifecycle != types.InstanceLifecycleTypeSpot &&
+		instance.InstanceLifecycle != types.InstanceLifecycleTypeScheduled
+}
+
+// inboundRules returns the number of inbound rules of the given IP family in the group. Rules referencing other
+// groups or prefix lists count towards both families.
+func inboundRules(group *types.SecurityGroup, family api.IPFamily) int {
+	rules := 0
+
+	for i := range group.IpPermissions {
+		permission := &group.IpPermissions[i]
+		rules += len(permission.UserIdGroupPairs) + len(permission.PrefixListIds)
+
+		for _, cidr := range permissionCIDRs(permission) {
+			if api.CIDRFamily(cidr) == family {
+				rules++
+			}
+		}
+	}
+
+	return rules
+}
+
+// missingRules returns the number of rules of the given IP family which need to be added to the gateway security group.
+func missingRules(input *api.GatewayDeployInput, group *types.SecurityGroup, family api.IPFamily) int {
+	existingPorts := publicPermissionPorts(group)
+	missing := 0
+
+	for _, port := range input.PublicPortSpecs() {
+		for _, cidr := range port.SourcesFor(family) {
+			if !containsSourcePort(existingPorts, port, cidr) {
+				missing++
+			}
+		}
+	}
+
+	return missing
+}
+
+// containsSourcePort returns true if one of the ports matches the given port and allows the given source CIDR.
+func containsSourcePort(ports []api.PortSpec, port api.PortSpec, cidr string) bool {
+	for i := range ports {
+		if ports[i].Matches(port) {
+			for _, source := range ports[i].SourceCIDRs {
+				if source == cidr {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// isStandardInstanceType returns true if the instance type is counted by the Standard instances quota. Its series is
+// the lowercase prefix of its family, before the generation number.
+func isStandardInstanceType(instanceType string) bool {
+	series := strings.SplitN(instanceType, ".", 2)[0]
+
+	if end := strings.IndexFunc(series, func(r rune) bool { return r < 'a' || r > 'z' }); end >= 0 {
+		series = series[:end]
+	}
+
+	return standardInstanceSeries[series]
+}
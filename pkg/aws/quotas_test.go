@@ -0,0 +1,263 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws_test
+
+import (
+	"context"
+	"errors"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	quotaTypes "github.com/aws/aws-sdk-go-v2/service/servicequotas/types"
+	"github.com/aws/smithy-go"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/aws"
+	"github.com/submariner-io/cloud-prepare/pkg/aws/client/fake"
+)
+
+const (
+	standardInstancesQuotaCode  = "L-1216C47A"
+	securityGroupRulesQuotaCode = "L-0EA8095F"
+)
+
+var _ = Describe("Quotas", func() {
+	var (
+		t             *fakeAWS
+		quotas        map[string]float64
+		quotaErr      error
+		cloud         api.Cloud
+		input         api.GatewayDeployInput
+		quotasChecked bool
+	)
+
+	deploy := func(gatewayType string) error {
+		gwDeployer, err := aws.NewOcpGatewayDeployer(cloud, t.msDeployer, gatewayType)
+		Expect(err).To(Succeed())
+
+		return gwDeployer.Deploy(input, api.NewLoggingReporter())
+	}
+
+	BeforeEach(func() {
+		t = &fakeAWS{}
+		t.beforeEach()
+
+		quotas = map[string]float64{standardInstancesQuotaCode: 10, securityGroupRulesQuotaCode: 60}
+		quotaErr = nil
+		quotasChecked = false
+
+		cloud = aws.NewCloud(t.ec2Client, infraID, region)
+
+		quotasClient := fake.NewMockQuotasInterface(t.mockCtrl)
+		quotasClient.EXPECT().GetServiceQuota(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, input *servicequotas.GetServiceQuotaInput, _ ...func(*servicequotas.Options)) (
+				*servicequotas.GetServiceQuotaOutput, error) {
+				quotasChecked = true
+
+				if quotaErr != nil {
+					return nil, quotaErr
+				}
+
+				value, found := quotas[awssdk.ToString(input.QuotaCode)]
+				if !found {
+					return &servicequotas.GetServiceQuotaOutput{}, nil
+				}
+
+				return &servicequotas.GetServiceQuotaOutput{Quota: &quotaTypes.ServiceQuota{Value: awssdk.Float64(value)}}, nil
+			}).AnyTimes()
+
+		Expect(aws.UseServiceQuotas(cloud, quotasClient)).To(Succeed())
+
+		// Two gateways are already deployed, so a third one needs one new gateway instance.
+		input = api.GatewayDeployInput{
+			PublicPorts: []api.PortSpec{{Port: 4500, Protocol: "udp"}},
+			Gateways:    3,
+		}
+	})
+
+	AfterEach(func() {
+		t.afterEach()
+	})
+
+	When("the Standard instances quota allows for the new gateways", func() {
+		BeforeEach(func() {
+			quotas[standardInstancesQuotaCode] = 6
+			t.runningInstances = []types.Instance{runningInstance("m5.xlarge", 4)}
+		})
+
+		It("should deploy the gateways", func() {
+			Expect(deploy(instanceType)).To(Succeed())
+			Expect(quotasChecked).To(BeTrue())
+			Expect(t.deployedMachineSets).To(ConsistOf(machineSetName("a"), machineSetName("b"), machineSetName("c")))
+		})
+	})
+
+	When("non-Standard instances are running", func() {
+		BeforeEach(func() {
+			quotas[standardInstancesQuotaCode] = 6
+			t.runningInstances = []types.Instance{
+				runningInstance("m5.xlarge", 4), runningInstance("inf1.xlarge", 4), runningInstance("mac1.metal", 12),
+				runningInstance("g4dn.xlarge", 4),
+			}
+		})
+
+		It("should not count them against the Standard instances quota", func() {
+			Expect(deploy(instanceType)).To(Succeed())
+		})
+	})
+
+	When("Spot instances are running", func() {
+		BeforeEach(func() {
+			quotas[standardInstancesQuotaCode] = 6
+			spotInstance := runningInstance("m5.xlarge", 4)
+			spotInstance.InstanceLifecycle = types.InstanceLifecycleTypeSpot
+			t.runningInstances = []types.Instance{runningInstance("m5.xlarge", 4), spotInstance}
+		})
+
+		It("should not count them against the On-Demand Standard instances quota", func() {
+			Expect(deploy(instanceType)).To(Succeed())
+		})
+	})
+
+	When("the Standard instances quota doesn't allow for the new gateways", func() {
+		BeforeEach(func() {
+			quotas[standardInstancesQuotaCode] = 5
+			t.runningInstances = []types.Instance{runningInstance("m5.xlarge", 4)}
+		})
+
+		It("should fail without deploying any gateway", func() {
+			err := deploy(instanceType)
+			Expect(errors.Is(err, api.ErrQuotaExceeded)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("Standard instance vCPUs")))
+			Expect(t.deployedMachineSets).To(BeEmpty())
+			Expect(t.taggedSubnets()).To(Equal([]string{"subnet-a", "subnet-b"}))
+		})
+	})
+
+	When("no new gateways are needed", func() {
+		BeforeEach(func() {
+			quotas[standardInstancesQuotaCode] = 0
+			input.Gateways = 2
+		})
+
+		It("should not check the Standard instances quota", func() {
+			Expect(deploy(instanceType)).To(Succeed())
+		})
+	})
+
+	When("the security group rules quota is too low", func() {
+		BeforeEach(func() {
+			quotas[securityGroupRulesQuotaCode] = 0
+		})
+
+		It("should fail without deploying any gateway", func() {
+			err := deploy(instanceType)
+			Expect(errors.Is(err, api.ErrQuotaExceeded)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("inbound rules per security group")))
+			Expect(t.deployedMachineSets).To(BeEmpty())
+		})
+	})
+
+	When("the gateway security group already allows the public ports", func() {
+		BeforeEach(func() {
+			quotas[securityGroupRulesQuotaCode] = 2
+			t.gatewayPermissions = []types.IpPermission{
+				publicPermission(4500, "udp", api.AnySourceCIDR), publicPermission(22, "tcp", "10.0.0.0/8"),
+			}
+		})
+
+		It("should only count its existing rules", func() {
+			Expect(deploy(instanceType)).To(Succeed())
+		})
+	})
+
+	When("the gateway security group's rules leave no room for the public ports", func() {
+		BeforeEach(func() {
+			quotas[securityGroupRulesQuotaCode] = 2
+			t.gatewayPermissions = []types.IpPermission{
+				publicPermission(22, "tcp", "10.0.0.0/8"), publicPermission(80, "tcp", "10.0.0.0/8"),
+			}
+		})
+
+		It("should fail without deploying any gateway", func() {
+			err := deploy(instanceType)
+			Expect(errors.Is(err, api.ErrQuotaExceeded)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("inbound rules per security group")))
+			Expect(t.deployedMachineSets).To(BeEmpty())
+		})
+	})
+
+	When("the quotas can't be retrieved", func() {
+		BeforeEach(func() {
+			quotas[standardInstancesQuotaCode] = 0
+			quotaErr = &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "fake error"}
+		})
+
+		It("should skip the quota checks", func() {
+			Expect(deploy(instanceType)).To(Succeed())
+			Expect(quotasChecked).To(BeTrue())
+		})
+	})
+
+	When("retrieving the quotas fails", func() {
+		BeforeEach(func() {
+			quotaErr = errors.New("fake error")
+		})
+
+		It("should return the error", func() {
+			Expect(deploy(instanceType)).To(MatchError(ContainSubstring("fake error")))
+			Expect(t.deployedMachineSets).To(BeEmpty())
+		})
+	})
+
+	Context("with an exhausted Standard instances quota", func() {
+		BeforeEach(func() {
+			quotas[standardInstancesQuotaCode] = 0
+		})
+
+		table.DescribeTable("the gateway instance type should be classified by its series",
+			func(instanceType string, standard bool) {
+				Expect(errors.Is(deploy(instanceType), api.ErrQuotaExceeded)).To(Equal(standard))
+			},
+			table.Entry("Standard general purpose", "m5.xlarge", true),
+			table.Entry("Standard burstable", "t3.micro", true),
+			table.Entry("Standard storage optimized", "im4gn.large", true),
+			table.Entry("Standard storage optimized with a multi-letter series", "is4gen.large", true),
+			table.Entry("Inferentia", "inf1.xlarge", false),
+			table.Entry("Mac", "mac1.metal", false),
+			table.Entry("Deep Learning", "dl1.24xlarge", false),
+			table.Entry("Trainium", "trn1.2xlarge", false),
+			table.Entry("HPC", "hpc6a.48xlarge", false),
+			table.Entry("GPU", "g4dn.xlarge", false),
+		)
+	})
+})
+
+func publicPermission(port int32, protocol, cidr string) types.IpPermission {
+	return types.IpPermission{
+		IpProtocol: awssdk.String(protocol),
+		FromPort:   awssdk.Int32(port),
+		ToPort:     awssdk.Int32(port),
+		IpRanges:   []types.IpRange{{CidrIp: awssdk.String(cidr)}},
+	}
+}
@@ -0,0 +1,40 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"errors"
+
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/retry"
+)
+
+// UseRetryPolicy replaces the policy used to retry and rate limit the calls made by the given AWS cloud, and its gateway
+// deployers; a nil policy disables both. The policy only applies to the clients created by NewCloudFromConfig or
+// NewCloudFromSettings. If the supplied cloud is not an awsCloud, an error is returned.
+func UseRetryPolicy(cloud api.Cloud, policy *retry.Policy) error {
+	ac, ok := cloud.(*awsCloud)
+	if !ok {
+		return errors.New("the cloud must be AWS")
+	}
+
+	ac.retryPolicy = policy
+
+	return nil
+}
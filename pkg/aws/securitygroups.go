@@ -30,13 +30,12 @@ import (
 	"github.com/pkg/errors"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/util/retry"
 )
 
 const internalTraffic = "Internal Submariner traffic"
 
-func (ac *awsCloud) getSecurityGroupID(vpcID, name string) (*string, error) {
-	group, err := ac.getSecurityGroup(vpcID, name)
+func (ac *awsCloud) getSecurityGroupID(ctx context.Context, vpcID, name string) (*string, error) {
+	group, err := ac.getSecurityGroup(ctx, vpcID, name)
 	if err != nil {
 		return nil, err
 	}
@@ -44,14 +43,14 @@ func (ac *awsCloud) getSecurityGroupID(vpcID, name string) (*string, error) {
 	return group.GroupId, nil
 }
 
-func (ac *awsCloud) getSecurityGroup(vpcID, name string) (types.SecurityGroup, error) {
+func (ac *awsCloud) getSecurityGroup(ctx context.Context, vpcID, name string) (types.SecurityGroup, error) {
 	filters := []types.Filter{
 		ec2Filter("vpc-id", vpcID),
 		ac.filterByName(name),
 		ac.filterByCurrentCluster(),
 	}
 
-	result, err := ac.client.DescribeSecurityGroups(context.TODO(), &ec2.DescribeSecurityGroupsInput{
+	result, err := ac.client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
 		Filters: filters,
 	})
 	if err != nil {
@@ -65,84 +64,212 @@ func (ac *awsCloud) getSecurityGroup(vpcID, name string) (types.SecurityGroup, e
 	return result.SecurityGroups[0], nil
 }
 
-func (ac *awsCloud) authorizeSecurityGroupIngress(groupID *string, ipPermissions []types.IpPermission) error {
+// authorizeSecurityGroupIngress authorizes the permissions in the group, and records the resulting rules, described by
+// ruleDescription, in the inventory. The rules are revoked if the operation is rolled back.
+func (ac *awsCloud) authorizeSecurityGroupIngress(ctx context.Context, scope api.InventoryScope, groupID *string,
+	ipPermissions []types.IpPermission, ruleDescription string) error {
 	input := &ec2.AuthorizeSecurityGroupIngressInput{
 		GroupId:       groupID,
 		IpPermissions: ipPermissions,
 	}
 
-	_, err := ac.client.AuthorizeSecurityGroupIngress(context.TODO(), input)
+	output, err := ac.client.AuthorizeSecurityGroupIngress(ctx, input)
 	if isAWSError(err, "InvalidPermission.Duplicate") {
+		// AWS doesn't return the existing rule, so it's recorded by the permission it allows; it's never removed.
+		return api.RecordResource(ctx, ac.inventory, sgRuleResource(scope, groupID, ruleDescription, ruleDescription, true))
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "error authorizing AWS security groups ingress")
+	}
+
+	for i := range output.SecurityGroupRules {
+		resource := sgRuleResource(scope, groupID, aws.ToString(output.SecurityGroupRules[i].SecurityGroupRuleId), ruleDescription, false)
+
+		if err := api.RecordResource(ctx, ac.inventory, resource); err != nil {
+			return err // nolint:wrapcheck // No need to wrap here
+		}
+
+		api.OnRollback(ctx, fmt.Sprintf("revoke security group rule %s to %s", resource.ID, ruleDescription),
+			func(ctx context.Context) error {
+				if err := ac.revokeSGRule(ctx, &resource); err != nil {
+					return err
+				}
+
+				return api.ForgetResource(ctx, ac.inventory, resource) // nolint:wrapcheck // No need to wrap here
+			})
+	}
+
+	return nil
+}
+
+func sgRuleResource(scope api.InventoryScope, groupID *string, ruleID, description string, preExisting bool) api.InventoryResource {
+	return api.InventoryResource{
+		Scope:       scope,
+		Kind:        api.SecurityGroupRuleResource,
+		ID:          ruleID,
+		Parent:      aws.ToString(groupID),
+		Description: description,
+		PreExisting: preExisting,
+	}
+}
+
+// revokeSGRule revokes the inventoried security group rule, if it still exists.
+func (ac *awsCloud) revokeSGRule(ctx context.Context, resource *api.InventoryResource) error {
+	_, err := ac.client.RevokeSecurityGroupIngress(ctx, &ec2.RevokeSecurityGroupIngressInput{
+		GroupId:              aws.String(resource.Parent),
+		SecurityGroupRuleIds: []string{resource.ID},
+	})
+	if isAWSError(err, "InvalidSecurityGroupRuleId.NotFound") || isAWSError(err, "InvalidGroup.NotFound") {
 		return nil
 	}
 
-	return errors.Wrap(err, "error authorizing AWS security groups ingress")
+	return errors.Wrapf(err, "error revoking AWS security group rule %q", resource.ID)
 }
 
-func (ac *awsCloud) createClusterSGRule(srcGroup, destGroup *string, port uint16, protocol, description string) error {
-	ipPermissions := []types.IpPermission{
+// createClusterSGRule allows traffic from srcGroup to destGroup. Security group references apply to both IPv4 and IPv6
+// traffic, so no separate rule is needed for dual-stack clusters.
+func (ac *awsCloud) createClusterSGRule(ctx context.Context, srcGroup, destGroup *string, port api.PortSpec,
+	description string) error {
+	ipPermission := newIPPermission(port)
+	ipPermission.UserIdGroupPairs = []types.UserIdGroupPair{
 		{
-			FromPort:   aws.Int32(int32(port)),
-			ToPort:     aws.Int32(int32(port)),
-			IpProtocol: aws.String(protocol),
-			UserIdGroupPairs: []types.UserIdGroupPair{
-				{
-					Description: aws.String(description),
-					GroupId:     srcGroup,
-				},
-			},
+			Description: aws.String(description),
+			GroupId:     srcGroup,
 		},
 	}
 
-	return ac.authorizeSecurityGroupIngress(destGroup, ipPermissions)
+	return ac.authorizeSecurityGroupIngress(ctx, api.CloudScope, destGroup, []types.IpPermission{ipPermission},
+		fmt.Sprintf("allow %s from %s", port, aws.ToString(srcGroup)))
 }
 
-func (ac *awsCloud) allowPortInCluster(vpcID string, port uint16, protocol string) error {
-	workerGroupID, err := ac.getSecurityGroupID(vpcID, "{infraID}-worker-sg")
+// newIPPermission returns a permission for the given port or port range, without any source. Protocols other than
+// TCP and UDP are allowed on all their ports, if any, by AWS.
+func newIPPermission(port api.PortSpec) types.IpPermission {
+	ipPermission := types.IpPermission{
+		IpProtocol: aws.String(port.Protocol),
+	}
+
+	switch {
+	case port.Port != 0 || port.EndPort != 0:
+		ipPermission.FromPort = aws.Int32(int32(port.Port))
+		ipPermission.ToPort = aws.Int32(int32(port.LastPort()))
+	case strings.EqualFold(port.Protocol, "tcp") || strings.EqualFold(port.Protocol, "udp"):
+		ipPermission.FromPort = aws.Int32(0)
+		ipPermission.ToPort = aws.Int32(65535)
+	}
+
+	return ipPermission
+}
+
+// permissionPortSpec returns the ports and protocol allowed by the given permission, without its sources.
+func permissionPortSpec(permission *types.IpPermission) api.PortSpec {
+	port := api.PortSpec{Protocol: aws.ToString(permission.IpProtocol)}
+
+	if permission.FromPort != nil && permission.ToPort != nil && *permission.FromPort >= 0 {
+		port.Port = uint16(*permission.FromPort)
+
+		if *permission.ToPort != *permission.FromPort {
+			port.EndPort = uint16(*permission.ToPort)
+		}
+	}
+
+	return port
+}
+
+// samePorts returns true if both permissions allow the same ports and protocol.
+func samePorts(permission, other *types.IpPermission) bool {
+	return strings.EqualFold(aws.ToString(permission.IpProtocol), aws.ToString(other.IpProtocol)) &&
+		aws.ToInt32(permission.FromPort) == aws.ToInt32(other.FromPort) && aws.ToInt32(permission.ToPort) == aws.ToInt32(other.ToPort)
+}
+
+func (ac *awsCloud) allowPortInCluster(ctx context.Context, vpcID string, port api.PortSpec) error {
+	workerGroupID, err := ac.getSecurityGroupID(ctx, vpcID, "{infraID}-worker-sg")
 	if err != nil {
 		return err
 	}
 
-	masterGroupID, err := ac.getSecurityGroupID(vpcID, "{infraID}-master-sg")
+	masterGroupID, err := ac.getSecurityGroupID(ctx, vpcID, "{infraID}-master-sg")
 	if err != nil {
 		return err
 	}
 
-	err = ac.createClusterSGRule(workerGroupID, workerGroupID, port, protocol, fmt.Sprintf("%s between the workers", internalTraffic))
+	err = ac.createClusterSGRule(ctx, workerGroupID, workerGroupID, port,
+		fmt.Sprintf("%s between the workers", internalTraffic))
 	if err != nil {
 		return err
 	}
 
-	err = ac.createClusterSGRule(workerGroupID, masterGroupID, port, protocol, fmt.Sprintf("%s from worker to master nodes", internalTraffic))
+	err = ac.createClusterSGRule(ctx, workerGroupID, masterGroupID, port,
+		fmt.Sprintf("%s from worker to master nodes", internalTraffic))
 	if err != nil {
 		return err
 	}
 
-	return ac.createClusterSGRule(masterGroupID, workerGroupID, port, protocol, fmt.Sprintf("%s from master to worker nodes", internalTraffic))
+	return ac.createClusterSGRule(ctx, masterGroupID, workerGroupID, port,
+		fmt.Sprintf("%s from master to worker nodes", internalTraffic))
 }
 
-func (ac *awsCloud) createPublicSGRule(groupID *string, port uint16, protocol, description string) error {
-	ipPermissions := []types.IpPermission{
-		{
-			FromPort:   aws.Int32(int32(port)),
-			ToPort:     aws.Int32(int32(port)),
-			IpProtocol: aws.String(protocol),
-			IpRanges: []types.IpRange{
-				{
-					CidrIp:      aws.String("0.0.0.0/0"),
-					Description: aws.String(description),
-				},
-			},
-		},
+func (ac *awsCloud) createPublicSGRule(ctx context.Context, groupID *string, port api.PortSpec, families []api.IPFamily,
+	description string) error {
+	// Each source is authorized separately so that sources which are already allowed don't prevent adding the others.
+	for _, family := range families {
+		for _, cidr := range port.SourcesFor(family) {
+			ipPermission := newIPPermission(port)
+			addSourceCIDR(&ipPermission, cidr, description)
+
+			err := ac.authorizeSecurityGroupIngress(ctx, api.GatewayScope, groupID, []types.IpPermission{ipPermission},
+				fmt.Sprintf("allow %s %s from %s", family, port, cidr))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// addSourceCIDR allows the given IPv4 or IPv6 CIDR in the permission.
+func addSourceCIDR(permission *types.IpPermission, cidr, description string) {
+	if api.CIDRFamily(cidr) == api.IPv6Family {
+		permission.Ipv6Ranges = append(permission.Ipv6Ranges, types.Ipv6Range{
+			CidrIpv6:    aws.String(cidr),
+			Description: aws.String(description),
+		})
+
+		return
+	}
+
+	permission.IpRanges = append(permission.IpRanges, types.IpRange{
+		CidrIp:      aws.String(cidr),
+		Description: aws.String(description),
+	})
+}
+
+// permissionCIDRs returns the IPv4 and IPv6 CIDRs allowed by the permission.
+func permissionCIDRs(permission *types.IpPermission) []string {
+	cidrs := []string{}
+
+	for _, ipRange := range permission.IpRanges {
+		if ipRange.CidrIp != nil {
+			cidrs = append(cidrs, *ipRange.CidrIp)
+		}
+	}
+
+	for _, ipRange := range permission.Ipv6Ranges {
+		if ipRange.CidrIpv6 != nil {
+			cidrs = append(cidrs, *ipRange.CidrIpv6)
+		}
 	}
 
-	return ac.authorizeSecurityGroupIngress(groupID, ipPermissions)
+	return cidrs
 }
 
-func (ac *awsCloud) createGatewaySG(vpcID string, ports []api.PortSpec) (string, error) {
+func (ac *awsCloud) createGatewaySG(ctx context.Context, vpcID string, ports []api.PortSpec, families []api.IPFamily) (string, error) {
 	groupName := ac.withAWSInfo("{infraID}-submariner-gw-sg")
+	created := false
 
-	gatewayGroupID, err := ac.getSecurityGroupID(vpcID, groupName)
+	gatewayGroupID, err := ac.getSecurityGroupID(ctx, vpcID, groupName)
 	if err != nil {
 		if !isNotFoundError(err) {
 			return "", err
@@ -163,16 +290,39 @@ func (ac *awsCloud) createGatewaySG(vpcID string, ports []api.PortSpec) (string,
 			},
 		}
 
-		result, err := ac.client.CreateSecurityGroup(context.TODO(), input)
+		result, err := ac.client.CreateSecurityGroup(ctx, input)
 		if err != nil {
 			return "", errors.Wrap(err, "error creating AWS security group")
 		}
 
 		gatewayGroupID = result.GroupId
+		created = true
+	}
+
+	resource := api.InventoryResource{
+		Scope:       api.GatewayScope,
+		Kind:        api.SecurityGroupResource,
+		ID:          aws.ToString(gatewayGroupID),
+		Description: groupName,
+		PreExisting: !created,
+	}
+
+	if err := api.RecordResource(ctx, ac.inventory, resource); err != nil {
+		return "", err // nolint:wrapcheck // No need to wrap here
+	}
+
+	if created {
+		api.OnRollback(ctx, "delete security group "+groupName, func(ctx context.Context) error {
+			if err := ac.deleteSecurityGroup(ctx, gatewayGroupID); err != nil {
+				return err
+			}
+
+			return api.ForgetResource(ctx, ac.inventory, resource) // nolint:wrapcheck // No need to wrap here
+		})
 	}
 
 	for _, port := range ports {
-		err = ac.createPublicSGRule(gatewayGroupID, port.Port, port.Protocol, "Public Submariner traffic")
+		err = ac.createPublicSGRule(ctx, gatewayGroupID, port, families, "Public Submariner traffic")
 		if err != nil {
 			return "", err
 		}
@@ -185,10 +335,10 @@ func gatewayDeletionRetriable(err error) bool {
 	return isAWSError(err, "DependencyViolation")
 }
 
-func (ac *awsCloud) deleteGatewaySG(vpcID string) error {
+func (ac *awsCloud) deleteGatewaySG(ctx context.Context, vpcID string) error {
 	groupName := ac.withAWSInfo("{infraID}-submariner-gw-sg")
 
-	gatewayGroupID, err := ac.getSecurityGroupID(vpcID, groupName)
+	gatewayGroupID, err := ac.getSecurityGroupID(ctx, vpcID, groupName)
 	if err != nil {
 		if isNotFoundError(err) {
 			return nil
@@ -197,6 +347,11 @@ func (ac *awsCloud) deleteGatewaySG(vpcID string) error {
 		return err
 	}
 
+	return ac.deleteSecurityGroup(ctx, gatewayGroupID)
+}
+
+// deleteSecurityGroup deletes the given security group, retrying while instances using it are being deleted.
+func (ac *awsCloud) deleteSecurityGroup(ctx context.Context, groupID *string) error {
 	backoff := wait.Backoff{
 		Steps:    30,
 		Duration: 500 * time.Millisecond,
@@ -204,52 +359,71 @@ func (ac *awsCloud) deleteGatewaySG(vpcID string) error {
 		Cap:      10 * time.Minute,
 	}
 
-	err = retry.OnError(backoff, gatewayDeletionRetriable, func() error {
-		_, err = ac.client.DeleteSecurityGroup(context.TODO(), &ec2.DeleteSecurityGroupInput{
-			GroupId: gatewayGroupID,
+	var lastErr error
+
+	// This is equivalent to retry.OnError but stops as soon as the context is done.
+	err := wait.ExponentialBackoffWithContext(ctx, backoff, func() (bool, error) {
+		_, err := ac.client.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{
+			GroupId: groupID,
 		})
 
-		return err // nolint:wrapcheck // Let the caller wrap it.
+		if err != nil && gatewayDeletionRetriable(err) {
+			lastErr = err
+			return false, nil
+		}
+
+		return err == nil, err
 	})
 
-	if isAWSError(err, "InvalidPermission.NotFound") {
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		err = lastErr
+	}
+
+	if isAWSError(err, "InvalidPermission.NotFound") || isAWSError(err, "InvalidGroup.NotFound") {
 		return nil
 	}
 
 	return errors.Wrap(err, "error deleting AWS security group")
 }
 
-func (ac *awsCloud) revokePortsInCluster(vpcID string) error {
-	workerGroup, err := ac.getSecurityGroup(vpcID, "{infraID}-worker-sg")
+func (ac *awsCloud) revokePortsInCluster(ctx context.Context, vpcID string) error {
+	workerGroup, err := ac.getSecurityGroup(ctx, vpcID, "{infraID}-worker-sg")
 	if err != nil {
 		return err
 	}
 
-	masterGroup, err := ac.getSecurityGroup(vpcID, "{infraID}-master-sg")
+	masterGroup, err := ac.getSecurityGroup(ctx, vpcID, "{infraID}-master-sg")
 	if err != nil {
 		return err
 	}
 
-	err = ac.revokePortsFromGroup(&workerGroup)
+	err = ac.revokePortsFromGroup(ctx, &workerGroup)
 	if err != nil {
 		return err
 	}
 
-	return ac.revokePortsFromGroup(&masterGroup)
+	return ac.revokePortsFromGroup(ctx, &masterGroup)
 }
 
-func (ac *awsCloud) revokePortsFromGroup(group *types.SecurityGroup) error {
-	var permissionsToRevoke []types.IpPermission
+// internalPermissions returns the permissions in the given group which were created for internal Submariner traffic.
+func internalPermissions(group *types.SecurityGroup) []types.IpPermission {
+	var permissions []types.IpPermission
 
 	for _, permission := range group.IpPermissions {
 		for _, groupPair := range permission.UserIdGroupPairs {
 			if groupPair.Description != nil && strings.Contains(*groupPair.Description, internalTraffic) {
-				permissionsToRevoke = append(permissionsToRevoke, permission)
+				permissions = append(permissions, permission)
 				break
 			}
 		}
 	}
 
+	return permissions
+}
+
+func (ac *awsCloud) revokePortsFromGroup(ctx context.Context, group *types.SecurityGroup) error {
+	permissionsToRevoke := internalPermissions(group)
+
 	if len(permissionsToRevoke) == 0 {
 		return nil
 	}
@@ -259,7 +433,7 @@ func (ac *awsCloud) revokePortsFromGroup(group *types.SecurityGroup) error {
 		IpPermissions: permissionsToRevoke,
 	}
 
-	_, err := ac.client.RevokeSecurityGroupIngress(context.TODO(), input)
+	_, err := ac.client.RevokeSecurityGroupIngress(ctx, input)
 
 	return errors.Wrap(err, "error revoking AWS security group ingress")
 }
@@ -0,0 +1,214 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/stringset"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+func (ac *awsCloud) Status(ctx context.Context) (*api.CloudStatus, error) {
+	status := &api.CloudStatus{InternalPorts: []api.PortSpec{}}
+
+	vpcID, err := ac.getVpcID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	workerGroup, err := ac.getSecurityGroup(ctx, vpcID, "{infraID}-worker-sg")
+	if err != nil {
+		return nil, err
+	}
+
+	masterGroup, err := ac.getSecurityGroup(ctx, vpcID, "{infraID}-master-sg")
+	if err != nil {
+		return nil, err
+	}
+
+	// Internal ports are opened between the workers, from the workers to the masters and from the masters to the workers.
+	pairs := [][]api.PortSpec{
+		groupPermissionPorts(&workerGroup, &workerGroup),
+		groupPermissionPorts(&workerGroup, &masterGroup),
+		groupPermissionPorts(&masterGroup, &workerGroup),
+	}
+
+	for _, port := range unionPorts(pairs...) {
+		if containsPort(pairs[0], port) && containsPort(pairs[1], port) && containsPort(pairs[2], port) {
+			status.InternalPorts = append(status.InternalPorts, port)
+		} else {
+			status.AddIssue("internal port %s is only open between some of the worker and master nodes", port)
+		}
+	}
+
+	return status, nil
+}
+
+func (d *ocpGatewayDeployer) Status(ctx context.Context) (*api.GatewayStatus, error) {
+	status := &api.GatewayStatus{}
+
+	vpcID, err := d.aws.getVpcID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groupName := d.aws.withAWSInfo("{infraID}-submariner-gw-sg")
+
+	group, err := d.aws.getSecurityGroup(ctx, vpcID, groupName)
+	if err == nil {
+		status.Firewall = &api.FirewallStatus{
+			Kind:  api.SecurityGroupResource,
+			Name:  groupName,
+			Ports: publicPermissionPorts(&group),
+		}
+	} else if !isNotFoundError(err) {
+		return nil, err
+	}
+
+	subnets, err := d.aws.getTaggedPublicSubnets(ctx, vpcID)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := d.initMachineSet("", "", &types.Subnet{AvailabilityZone: aws.String("")})
+	if err != nil {
+		return nil, err
+	}
+
+	machineSets, err := d.msDeployer.List(ctx, template, template.GetName())
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the gateway machine sets")
+	}
+
+	machineSetNames := stringset.New()
+
+	for i := range machineSets {
+		status.MachineSets = append(status.MachineSets, machineSets[i].GetName())
+		machineSetNames.Add(machineSets[i].GetName())
+	}
+
+	for i := range subnets {
+		subnetName := extractName(subnets[i].Tags)
+		status.GatewaySubnets = append(status.GatewaySubnets, subnetName)
+
+		machineSet, err := d.initMachineSet("", "", &subnets[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if !machineSetNames.Contains(machineSet.GetName()) {
+			status.AddIssue("public subnet %s is tagged for gateways but its gateway MachineSet %q doesn't exist",
+				subnetName, machineSet.GetName())
+		}
+
+		machineSetNames.Remove(machineSet.GetName())
+	}
+
+	for _, name := range machineSetNames.Elements() {
+		status.AddIssue("gateway MachineSet %q has no corresponding tagged public subnet", name)
+	}
+
+	if d.k8sClient != nil {
+		gwNodes, err := d.k8sClient.ListGatewayNodes(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "error listing the gateway nodes")
+		}
+
+		for i := range gwNodes.Items {
+			status.GatewayNodes = append(status.GatewayNodes, gwNodes.Items[i].Name)
+		}
+	}
+
+	if status.Firewall == nil && (len(subnets) > 0 || len(machineSets) > 0) {
+		status.AddIssue("gateways are deployed but the gateway security group %q doesn't exist", groupName)
+	} else if status.Firewall != nil && len(subnets) == 0 && len(machineSets) == 0 {
+		status.AddIssue("the gateway security group %q exists but no gateways are deployed", groupName)
+	}
+
+	return status, nil
+}
+
+// groupPermissionPorts returns the Submariner internal ports opened in destGroup for traffic from srcGroup.
+func groupPermissionPorts(srcGroup, destGroup *types.SecurityGroup) []api.PortSpec {
+	return permissionPorts(internalPermissions(destGroup), func(permission *types.IpPermission) bool {
+		for _, groupPair := range permission.UserIdGroupPairs {
+			if groupPair.GroupId != nil && *groupPair.GroupId == *srcGroup.GroupId {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// publicPermissionPorts returns the ports opened in the group for traffic from CIDRs.
+func publicPermissionPorts(group *types.SecurityGroup) []api.PortSpec {
+	ports := []api.PortSpec{}
+
+	for i := range group.IpPermissions {
+		port := permissionPortSpec(&group.IpPermissions[i])
+		port.SourceCIDRs = permissionCIDRs(&group.IpPermissions[i])
+
+		if len(port.SourceCIDRs) > 0 {
+			ports = append(ports, port)
+		}
+	}
+
+	return ports
+}
+
+func permissionPorts(permissions []types.IpPermission, sourceMatches func(*types.IpPermission) bool) []api.PortSpec {
+	ports := []api.PortSpec{}
+
+	for i := range permissions {
+		if sourceMatches(&permissions[i]) {
+			ports = append(ports, permissionPortSpec(&permissions[i]))
+		}
+	}
+
+	return ports
+}
+
+func unionPorts(portLists ...[]api.PortSpec) []api.PortSpec {
+	union := []api.PortSpec{}
+
+	for _, ports := range portLists {
+		for _, port := range ports {
+			if !containsPort(union, port) {
+				union = append(union, port)
+			}
+		}
+	}
+
+	return union
+}
+
+func containsPort(ports []api.PortSpec, port api.PortSpec) bool {
+	for _, p := range ports {
+		if p.Matches(port) {
+			return true
+		}
+	}
+
+	return false
+}
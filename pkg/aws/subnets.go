@@ -21,9 +21,11 @@ package aws
 import (
 	"context"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
 )
 
 var (
@@ -54,14 +56,37 @@ func subnetTagged(subnet *types.Subnet) bool {
 	return hasTag(subnet.Tags, tagSubmarinerGateway)
 }
 
-func (ac *awsCloud) findPublicSubnets(vpcID string, filter types.Filter) ([]types.Subnet, error) {
+// selectGatewaySubnets splits the given subnets into those already tagged for Submariner gateways, and those which
+// need to be tagged to satisfy the requested number of gateways.
+func selectGatewaySubnets(subnets []types.Subnet, gateways int) ([]types.Subnet, []types.Subnet) {
+	taggedSubnets, _ := filterSubnets(subnets, func(subnet *types.Subnet) (bool, error) {
+		return subnetTagged(subnet), nil
+	})
+	untaggedSubnets, _ := filterSubnets(subnets, func(subnet *types.Subnet) (bool, error) {
+		return !subnetTagged(subnet), nil
+	})
+
+	toTag := []types.Subnet{}
+
+	for i := range untaggedSubnets {
+		if gateways > 0 && len(taggedSubnets)+len(toTag) >= gateways {
+			break
+		}
+
+		toTag = append(toTag, untaggedSubnets[i])
+	}
+
+	return taggedSubnets, toTag
+}
+
+func (ac *awsCloud) findPublicSubnets(ctx context.Context, vpcID string, filter types.Filter) ([]types.Subnet, error) {
 	filters := []types.Filter{
 		ec2Filter("vpc-id", vpcID),
 		ac.filterByCurrentCluster(),
 		filter,
 	}
 
-	result, err := ac.client.DescribeSubnets(context.TODO(), &ec2.DescribeSubnetsInput{Filters: filters})
+	result, err := ac.client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{Filters: filters})
 	if err != nil {
 		return nil, errors.Wrap(err, "error describing AWS subnets")
 	}
@@ -69,9 +94,10 @@ func (ac *awsCloud) findPublicSubnets(vpcID string, filter types.Filter) ([]type
 	return result.Subnets, nil
 }
 
-func (ac *awsCloud) getSubnetsSupportingInstanceType(subnets []types.Subnet, instanceType string) ([]types.Subnet, error) {
+func (ac *awsCloud) getSubnetsSupportingInstanceType(ctx context.Context, subnets []types.Subnet,
+	instanceType string) ([]types.Subnet, error) {
 	return filterSubnets(subnets, func(subnet *types.Subnet) (bool, error) {
-		output, err := ac.client.DescribeInstanceTypeOfferings(context.TODO(), &ec2.DescribeInstanceTypeOfferingsInput{
+		output, err := ac.client.DescribeInstanceTypeOfferings(ctx, &ec2.DescribeInstanceTypeOfferingsInput{
 			LocationType: types.LocationTypeAvailabilityZone,
 			Filters: []types.Filter{
 				ec2Filter("location", *subnet.AvailabilityZone),
@@ -86,30 +112,90 @@ func (ac *awsCloud) getSubnetsSupportingInstanceType(subnets []types.Subnet, ins
 	})
 }
 
-func (ac *awsCloud) getTaggedPublicSubnets(vpcID string) ([]types.Subnet, error) {
-	return ac.findPublicSubnets(vpcID, ec2FilterByTag(tagSubmarinerGateway))
+func (ac *awsCloud) getTaggedPublicSubnets(ctx context.Context, vpcID string) ([]types.Subnet, error) {
+	return ac.findPublicSubnets(ctx, vpcID, ec2FilterByTag(tagSubmarinerGateway))
 }
 
-func (ac *awsCloud) tagPublicSubnet(subnetID *string) error {
-	_, err := ac.client.CreateTags(context.TODO(), &ec2.CreateTagsInput{
-		Resources: []string{*subnetID},
+func (ac *awsCloud) tagPublicSubnet(ctx context.Context, subnet *types.Subnet) error {
+	_, err := ac.client.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: []string{*subnet.SubnetId},
 		Tags: []types.Tag{
 			tagInternalELB,
 			tagSubmarinerGateway,
 		},
 	})
+	if err != nil {
+		return errors.Wrap(err, "error creating AWS tag")
+	}
+
+	api.OnRollback(ctx, "untag public subnet "+extractName(subnet.Tags), func(ctx context.Context) error {
+		return ac.untagPublicSubnet(ctx, subnet)
+	})
+
+	return api.RecordResource(ctx, ac.inventory, subnetTagResource(subnet, false))
+}
+
+// recordTaggedSubnets records the given subnets, which were tagged before the deployment, as pre-existing.
+func (ac *awsCloud) recordTaggedSubnets(ctx context.Context, subnets []types.Subnet) error {
+	for i := range subnets {
+		if err := api.RecordResource(ctx, ac.inventory, subnetTagResource(&subnets[i], true)); err != nil {
+			return err // nolint:wrapcheck // No need to wrap here
+		}
+	}
 
-	return errors.Wrap(err, "error creating AWS tag")
+	return nil
 }
 
-func (ac *awsCloud) untagPublicSubnet(subnetID *string) error {
-	_, err := ac.client.DeleteTags(context.TODO(), &ec2.DeleteTagsInput{
-		Resources: []string{*subnetID},
+func (ac *awsCloud) untagPublicSubnet(ctx context.Context, subnet *types.Subnet) error {
+	_, err := ac.client.DeleteTags(ctx, &ec2.DeleteTagsInput{
+		Resources: []string{*subnet.SubnetId},
 		Tags: []types.Tag{
 			tagInternalELB,
 			tagSubmarinerGateway,
 		},
 	})
+	if err != nil && !isAWSError(err, "InvalidSubnetID.NotFound") {
+		return errors.Wrap(err, "error deleting AWS tag")
+	}
+
+	return api.ForgetResource(ctx, ac.inventory, subnetTagResource(subnet, false))
+}
+
+func subnetTagResource(subnet *types.Subnet, preExisting bool) api.InventoryResource {
+	return api.InventoryResource{
+		Scope:       api.GatewayScope,
+		Kind:        api.SubnetTagResource,
+		ID:          aws.ToString(subnet.SubnetId),
+		Description: "public subnet " + extractName(subnet.Tags),
+		PreExisting: preExisting,
+	}
+}
+
+// findNodeSubnetID returns the ID of the subnet hosting the instance backing the named node, or an empty string
+// if no node is named. OpenShift names AWS nodes after their instance's private DNS name.
+func (ac *awsCloud) findNodeSubnetID(ctx context.Context, vpcID, nodeName string) (string, error) {
+	if nodeName == "" {
+		return "", nil
+	}
+
+	result, err := ac.client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: []types.Filter{
+			ec2Filter("vpc-id", vpcID),
+			ec2Filter("private-dns-name", nodeName),
+			ac.filterByCurrentCluster(),
+		},
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "error describing AWS instances")
+	}
+
+	for i := range result.Reservations {
+		for j := range result.Reservations[i].Instances {
+			if subnetID := result.Reservations[i].Instances[j].SubnetId; subnetID != nil {
+				return *subnetID, nil
+			}
+		}
+	}
 
-	return errors.Wrap(err, "error deleting AWS tag")
+	return "", newNotFoundError("instance backing node %q", nodeName)
 }
@@ -20,12 +20,12 @@ package aws
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
 )
 
 const permissionsTest = "permissions-test"
@@ -34,13 +34,13 @@ func determinePermissionError(err error, operation string) error {
 	if err == nil || isAWSError(err, "DryRunOperation") {
 		return nil
 	} else if isAWSError(err, "UnauthorizedOperation") {
-		return fmt.Errorf("no permission to %s", operation)
+		return api.Errorf(api.ErrPermissionDenied, "no permission to %s", operation)
 	}
 
 	return errors.Wrapf(err, "error while checking permissions for %s", operation)
 }
 
-func (ac *awsCloud) validateCreateSecGroup(vpcID string) error {
+func (ac *awsCloud) validateCreateSecGroup(ctx context.Context, vpcID string) error {
 	input := &ec2.CreateSecurityGroupInput{
 		DryRun:      aws.Bool(true),
 		GroupName:   aws.String(permissionsTest),
@@ -48,13 +48,13 @@ func (ac *awsCloud) validateCreateSecGroup(vpcID string) error {
 		VpcId:       aws.String(vpcID),
 	}
 
-	_, err := ac.client.CreateSecurityGroup(context.TODO(), input)
+	_, err := ac.client.CreateSecurityGroup(ctx, input)
 
 	return determinePermissionError(err, "create security group")
 }
 
-func (ac *awsCloud) validateCreateSecGroupRule(vpcID string) error {
-	workerGroupID, err := ac.getSecurityGroupID(vpcID, "{infraID}-worker-sg")
+func (ac *awsCloud) validateCreateSecGroupRule(ctx context.Context, vpcID string) error {
+	workerGroupID, err := ac.getSecurityGroupID(ctx, vpcID, "{infraID}-worker-sg")
 	if err != nil {
 		return err
 	}
@@ -64,13 +64,13 @@ func (ac *awsCloud) validateCreateSecGroupRule(vpcID string) error {
 		GroupId: workerGroupID,
 	}
 
-	_, err = ac.client.AuthorizeSecurityGroupIngress(context.TODO(), input)
+	_, err = ac.client.AuthorizeSecurityGroupIngress(ctx, input)
 
 	return determinePermissionError(err, "authorize security group ingress")
 }
 
-func (ac *awsCloud) validateCreateTag(subnetID string) error {
-	_, err := ac.client.CreateTags(context.TODO(), &ec2.CreateTagsInput{
+func (ac *awsCloud) validateCreateTag(ctx context.Context, subnetID string) error {
+	_, err := ac.client.CreateTags(ctx, &ec2.CreateTagsInput{
 		DryRun:    aws.Bool(true),
 		Resources: []string{subnetID},
 		Tags: []types.Tag{
@@ -81,16 +81,16 @@ func (ac *awsCloud) validateCreateTag(subnetID string) error {
 	return determinePermissionError(err, "create tags on subnets")
 }
 
-func (ac *awsCloud) validateDescribeInstanceTypeOfferings() error {
-	_, err := ac.client.DescribeInstanceTypeOfferings(context.TODO(), &ec2.DescribeInstanceTypeOfferingsInput{
+func (ac *awsCloud) validateDescribeInstanceTypeOfferings(ctx context.Context) error {
+	_, err := ac.client.DescribeInstanceTypeOfferings(ctx, &ec2.DescribeInstanceTypeOfferingsInput{
 		DryRun: aws.Bool(true),
 	})
 
 	return determinePermissionError(err, "describe instance type offerings")
 }
 
-func (ac *awsCloud) validateDeleteSecGroup(vpcID string) error {
-	workerGroupID, err := ac.getSecurityGroupID(vpcID, "{infraID}-worker-sg")
+func (ac *awsCloud) validateDeleteSecGroup(ctx context.Context, vpcID string) error {
+	workerGroupID, err := ac.getSecurityGroupID(ctx, vpcID, "{infraID}-worker-sg")
 	if err != nil {
 		return err
 	}
@@ -100,13 +100,13 @@ func (ac *awsCloud) validateDeleteSecGroup(vpcID string) error {
 		GroupId: workerGroupID,
 	}
 
-	_, err = ac.client.DeleteSecurityGroup(context.TODO(), input)
+	_, err = ac.client.DeleteSecurityGroup(ctx, input)
 
 	return determinePermissionError(err, "delete security group")
 }
 
-func (ac *awsCloud) validateDeleteSecGroupRule(vpcID string) error {
-	workerGroupID, err := ac.getSecurityGroupID(vpcID, "{infraID}-worker-sg")
+func (ac *awsCloud) validateDeleteSecGroupRule(ctx context.Context, vpcID string) error {
+	workerGroupID, err := ac.getSecurityGroupID(ctx, vpcID, "{infraID}-worker-sg")
 	if err != nil {
 		return err
 	}
@@ -116,13 +116,13 @@ func (ac *awsCloud) validateDeleteSecGroupRule(vpcID string) error {
 		GroupId: workerGroupID,
 	}
 
-	_, err = ac.client.RevokeSecurityGroupIngress(context.TODO(), input)
+	_, err = ac.client.RevokeSecurityGroupIngress(ctx, input)
 
 	return determinePermissionError(err, "revoke security group ingress")
 }
 
-func (ac *awsCloud) validateRemoveTag(subnetID *string) error {
-	_, err := ac.client.DeleteTags(context.TODO(), &ec2.DeleteTagsInput{
+func (ac *awsCloud) validateRemoveTag(ctx context.Context, subnetID *string) error {
+	_, err := ac.client.DeleteTags(ctx, &ec2.DeleteTagsInput{
 		DryRun:    aws.Bool(true),
 		Resources: []string{*subnetID},
 		Tags: []types.Tag{
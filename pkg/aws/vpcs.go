@@ -26,14 +26,14 @@ import (
 	"github.com/pkg/errors"
 )
 
-func (ac *awsCloud) getVpcID() (string, error) {
+func (ac *awsCloud) getVpcID(ctx context.Context) (string, error) {
 	vpcName := ac.withAWSInfo("{infraID}-vpc")
 	filters := []types.Filter{
 		ac.filterByName(vpcName),
 		ac.filterByCurrentCluster(),
 	}
 
-	result, err := ac.client.DescribeVpcs(context.TODO(), &ec2.DescribeVpcsInput{Filters: filters})
+	result, err := ac.client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{Filters: filters})
 	if err != nil {
 		return "", errors.Wrap(err, "error describing AWS VPCs")
 	}
@@ -0,0 +1,225 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config loads the parameters of a preparation run from a versioned YAML or JSON file, so that they can be
+// kept under version control.
+package config
+
+import (
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/provider"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// APIVersion is the current version of the configuration schema.
+	APIVersion = "cloud-prepare.submariner.io/v1alpha1"
+
+	// Kind identifies a configuration file as a preparation run.
+	Kind = "PreparationConfig"
+)
+
+// supportedVersions are the schema versions which can be loaded.
+var supportedVersions = []string{APIVersion}
+
+// Config is the configuration of a preparation run, e.g.:
+//
+//	apiVersion: cloud-prepare.submariner.io/v1alpha1
+//	kind: PreparationConfig
+//	provider: gcp
+//	infraID: cluster1-x7b2k
+//	region: us-east1
+//	projectID: my-project
+//	internalPorts:
+//	  - port: 4800
+//	    protocol: udp
+//	gateways:
+//	  count: 1
+//	  publicPorts:
+//	    - port: 4500
+//	      protocol: udp
+type Config struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+
+	// Provider is the name of the provider in the registry, e.g. "aws" or "gcp".
+	Provider string `json:"provider"`
+
+	InfraID   string `json:"infraID,omitempty"`
+	Region    string `json:"region,omitempty"`
+	ProjectID string `json:"projectID,omitempty"`
+
+	// CloudName is the entry in the cluster's clouds.yaml used by the dedicated gateway MachineSets, on OpenStack.
+	CloudName string `json:"cloudName,omitempty"`
+
+	// CredentialsFile and Profile select the cloud credentials; the provider's defaults are used if empty.
+	CredentialsFile string `json:"credentialsFile,omitempty"`
+	Profile         string `json:"profile,omitempty"`
+
+	// IPFamilies are the IP families used by the cluster network. IPv4 only if empty.
+	IPFamilies []api.IPFamily `json:"ipFamilies,omitempty"`
+
+	// InternalPorts are opened for intra-cluster communication.
+	InternalPorts []api.PortSpec `json:"internalPorts,omitempty"`
+
+	// Rollback reverts the changes already made if the preparation or the gateway deployment fails.
+	Rollback bool `json:"rollback,omitempty"`
+
+	Gateways GatewayConfig `json:"gateways,omitempty"`
+}
+
+type GatewayConfig struct {
+	// Count is the number of gateways to deploy, 0 to use the provider's default.
+	Count int `json:"count,omitempty"`
+
+	// InstanceType and Image are used for dedicated gateway instances.
+	InstanceType string `json:"instanceType,omitempty"`
+	Image        string `json:"image,omitempty"`
+
+	// Dedicated deploys dedicated gateway instances instead of using existing worker nodes.
+	Dedicated bool `json:"dedicated,omitempty"`
+
+	// PublicPorts are opened on the gateways.
+	PublicPorts []api.PortSpec `json:"publicPorts,omitempty"`
+
+	// SourceCIDRs restricts the sources allowed to reach the public ports which don't specify their own.
+	SourceCIDRs []string `json:"sourceCIDRs,omitempty"`
+}
+
+// configKeys maps the paths of the fields which configure the provider to their keys in its configuration.
+var configKeys = map[string]string{
+	"infraID":               "infraID",
+	"region":                "region",
+	"projectID":             "projectID",
+	"cloudName":             "cloudName",
+	"credentialsFile":       "credentialsFile",
+	"profile":               "profile",
+	"gateways.instanceType": "instanceType",
+	"gateways.image":        "image",
+	"gateways.dedicated":    "dedicatedGateway",
+}
+
+// Load reads the configuration from the given YAML or JSON file. The configuration isn't validated.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading the configuration file %q", path)
+	}
+
+	config, err := Parse(data)
+
+	return config, errors.Wrapf(err, "error loading the configuration file %q", path)
+}
+
+// Parse decodes the configuration from YAML or JSON. Unknown fields are rejected, as well as unsupported versions of
+// the schema. The configuration isn't validated.
+func Parse(data []byte) (*Config, error) {
+	header := &Config{}
+	if err := yaml.Unmarshal(data, header); err != nil {
+		return nil, errors.Wrap(err, "error decoding the configuration")
+	}
+
+	// The version is checked first since a different version might have different fields.
+	if errs := validateVersion(header); len(errs) > 0 {
+		return nil, errs.ToAggregate()
+	}
+
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, errors.Wrap(err, "error decoding the configuration")
+	}
+
+	return config, nil
+}
+
+// NewProvider validates the configuration and creates the provider's Cloud and GatewayDeployer from the given
+// registry, or the default registry if nil.
+func (c *Config) NewProvider(registry *provider.Registry, deps provider.Dependencies) (api.Cloud, api.GatewayDeployer, error) {
+	if registry == nil {
+		registry = provider.NewDefaultRegistry()
+	}
+
+	if err := c.Validate(registry); err != nil {
+		return nil, nil, err
+	}
+
+	cloud, gwDeployer, err := registry.New(c.Provider, c.ProviderConfig(), deps)
+
+	return cloud, gwDeployer, errors.Wrapf(err, "error creating the %s provider", c.Provider)
+}
+
+// ProviderConfig returns the provider's configuration, using the same keys as its JSON representation. Only the
+// fields which are set are included.
+func (c *Config) ProviderConfig() map[string]interface{} {
+	config := map[string]interface{}{}
+
+	for path, value := range c.providerFields() {
+		config[configKeys[path]] = value
+	}
+
+	return config
+}
+
+// providerFields returns the values of the fields which configure the provider and are set, by their path.
+func (c *Config) providerFields() map[string]interface{} {
+	fields := map[string]interface{}{}
+
+	for path, value := range map[string]string{
+		"infraID":               c.InfraID,
+		"region":                c.Region,
+		"projectID":             c.ProjectID,
+		"cloudName":             c.CloudName,
+		"credentialsFile":       c.CredentialsFile,
+		"profile":               c.Profile,
+		"gateways.instanceType": c.Gateways.InstanceType,
+		"gateways.image":        c.Gateways.Image,
+	} {
+		if value != "" {
+			fields[path] = value
+		}
+	}
+
+	if c.Gateways.Dedicated {
+		fields["gateways.dedicated"] = true
+	}
+
+	return fields
+}
+
+// PrepareInput returns the input of the cloud preparation.
+func (c *Config) PrepareInput() api.PrepareForSubmarinerInput {
+	return api.PrepareForSubmarinerInput{
+		InternalPorts: c.InternalPorts,
+		IPFamilies:    c.IPFamilies,
+		Rollback:      c.Rollback,
+	}
+}
+
+// DeployInput returns the input of the gateway deployment.
+func (c *Config) DeployInput() api.GatewayDeployInput {
+	return api.GatewayDeployInput{
+		PublicPorts:       c.Gateways.PublicPorts,
+		PublicSourceCIDRs: c.Gateways.SourceCIDRs,
+		IPFamilies:        c.IPFamilies,
+		Gateways:          c.Gateways.Count,
+		Rollback:          c.Rollback,
+	}
+}
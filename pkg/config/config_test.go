@@ -0,0 +1,264 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/config"
+	"github.com/submariner-io/cloud-prepare/pkg/provider"
+)
+
+const gcpConfig = `
+apiVersion: cloud-prepare.submariner.io/v1alpha1
+kind: PreparationConfig
+provider: gcp
+infraID: cluster1-x7b2k
+region: us-east1
+projectID: my-project
+ipFamilies: [IPv4, IPv6]
+internalPorts:
+  - port: 4800
+    protocol: udp
+rollback: true
+gateways:
+  count: 2
+  dedicated: true
+  instanceType: n1-standard-4
+  publicPorts:
+    - port: 4490
+      endPort: 4500
+      protocol: udp
+    - protocol: esp
+      sourceCIDRs: [10.1.0.0/16]
+  sourceCIDRs: [10.0.0.0/16]
+`
+
+var _ = Describe("Config", func() {
+	Describe("Parse", func() {
+		It("should decode YAML", func() {
+			c, err := config.Parse([]byte(gcpConfig))
+			Expect(err).To(Succeed())
+			Expect(c.Validate(nil)).To(Succeed())
+
+			Expect(c.ProviderConfig()).To(Equal(map[string]interface{}{
+				"infraID":          "cluster1-x7b2k",
+				"region":           "us-east1",
+				"projectID":        "my-project",
+				"dedicatedGateway": true,
+				"instanceType":     "n1-standard-4",
+			}))
+
+			Expect(c.PrepareInput()).To(Equal(api.PrepareForSubmarinerInput{
+				InternalPorts: []api.PortSpec{{Port: 4800, Protocol: "udp"}},
+				IPFamilies:    []api.IPFamily{api.IPv4Family, api.IPv6Family},
+				Rollback:      true,
+			}))
+
+			Expect(c.DeployInput()).To(Equal(api.GatewayDeployInput{
+				PublicPorts: []api.PortSpec{
+					{Port: 4490, EndPort: 4500, Protocol: "udp"},
+					{Protocol: "esp", SourceCIDRs: []string{"10.1.0.0/16"}},
+				},
+				PublicSourceCIDRs: []string{"10.0.0.0/16"},
+				IPFamilies:        []api.IPFamily{api.IPv4Family, api.IPv6Family},
+				Gateways:          2,
+				Rollback:          true,
+			}))
+		})
+
+		It("should decode JSON", func() {
+			c, err := config.Parse([]byte(`{"apiVersion": "cloud-prepare.submariner.io/v1alpha1", "kind": "PreparationConfig",
+				"provider": "generic", "gateways": {"count": 1}}`))
+			Expect(err).To(Succeed())
+			Expect(c.Validate(nil)).To(Succeed())
+			Expect(c.DeployInput().Gateways).To(Equal(1))
+		})
+
+		It("should reject unknown fields", func() {
+			_, err := config.Parse([]byte(gcpConfig + "zone: us-east1-b\n"))
+			Expect(err).To(MatchError(ContainSubstring(`unknown field "zone"`)))
+		})
+
+		It("should reject unsupported versions before decoding the fields", func() {
+			_, err := config.Parse([]byte("apiVersion: cloud-prepare.submariner.io/v2\nkind: PreparationConfig\nzone: us-east1-b\n"))
+			Expect(err).To(MatchError(ContainSubstring(`apiVersion: Unsupported value: "cloud-prepare.submariner.io/v2"`)))
+
+			_, err = config.Parse([]byte("kind: PreparationConfig\n"))
+			Expect(err).To(MatchError(ContainSubstring("apiVersion: Required value")))
+		})
+	})
+
+	Describe("Load", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+
+			dir, err = ioutil.TempDir("", "config")
+			Expect(err).To(Succeed())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("should load the file", func() {
+			path := filepath.Join(dir, "gcp.yaml")
+			Expect(ioutil.WriteFile(path, []byte(gcpConfig), 0o600)).To(Succeed())
+
+			c, err := config.Load(path)
+			Expect(err).To(Succeed())
+			Expect(c.Provider).To(Equal(provider.GCP))
+		})
+
+		It("should fail if the file doesn't exist", func() {
+			_, err := config.Load(filepath.Join(dir, "missing.yaml"))
+			Expect(err).ToNot(Succeed())
+		})
+	})
+
+	Describe("Validate", func() {
+		var c *config.Config
+
+		BeforeEach(func() {
+			c = &config.Config{
+				APIVersion: config.APIVersion,
+				Kind:       config.Kind,
+				Provider:   provider.AWS,
+				InfraID:    "cluster1-x7b2k",
+				Region:     "us-east-1",
+				Gateways:   config.GatewayConfig{InstanceType: "c5d.large"},
+			}
+		})
+
+		It("should accept a valid configuration", func() {
+			Expect(c.Validate(nil)).To(Succeed())
+		})
+
+		It("should report an unknown provider", func() {
+			c.Provider = "azure"
+			Expect(c.Validate(nil)).To(MatchError(ContainSubstring(`provider: Unsupported value: "azure"`)))
+		})
+
+		It("should check the fields against the provider", func() {
+			c.Region = ""
+			c.ProjectID = "my-project"
+			c.Gateways.Dedicated = true
+
+			err := c.Validate(nil)
+			Expect(err).To(MatchError(ContainSubstring("region: Required value: required by the aws provider")))
+			Expect(err).To(MatchError(ContainSubstring("gateways.dedicated: Forbidden: not supported by the aws provider")))
+			Expect(err).To(MatchError(ContainSubstring("projectID: Forbidden: not supported by the aws provider")))
+		})
+
+		It("should not require the instance type on AWS", func() {
+			c.Gateways.InstanceType = ""
+			Expect(c.Validate(nil)).To(Succeed())
+		})
+
+		It("should check the fields of a provider added to the registry with its validator", func() {
+			registry := provider.NewRegistry()
+			Expect(registry.Register("custom", func(_ interface{}, _ provider.Dependencies) (api.Cloud, api.GatewayDeployer, error) {
+				return nil, nil, nil
+			})).To(Succeed())
+			Expect(registry.RegisterValidator("custom", func(_ interface{}) error {
+				missing := &api.MissingFieldsError{Provider: "custom"}
+				missing.Add("projectID", "")
+
+				return missing
+			})).To(Succeed())
+
+			c.Provider = "custom"
+			Expect(c.Validate(registry)).To(MatchError("projectID: Required value: required by the custom provider"))
+		})
+
+		It("should require the instance type of dedicated gateways", func() {
+			c.Provider = provider.GCP
+			c.ProjectID = "my-project"
+			c.Gateways = config.GatewayConfig{Dedicated: true}
+
+			Expect(c.Validate(nil)).To(MatchError("gateways.instanceType: Required value: required for dedicated gateways"))
+		})
+
+		It("should point at the invalid ports", func() {
+			c.InternalPorts = []api.PortSpec{{Port: 4800}, {Port: 4800, Protocol: "udp", SourceCIDRs: []string{"10.0.0.0/8"}}}
+			c.Gateways.PublicPorts = []api.PortSpec{
+				{Port: 4500, Protocol: "udp"},
+				{Port: 4500, EndPort: 4490, Protocol: "udp", SourceCIDRs: []string{"10.0.0.0/8", "10.0.0.0"}},
+			}
+			c.Gateways.SourceCIDRs = []string{"bogus"}
+			c.Gateways.Count = -1
+
+			err := c.Validate(nil)
+			Expect(err).To(MatchError(ContainSubstring("internalPorts[0].protocol: Required value")))
+			Expect(err).To(MatchError(ContainSubstring("internalPorts[1].sourceCIDRs: Forbidden")))
+			Expect(err).To(MatchError(ContainSubstring("gateways.publicPorts[1].endPort: Invalid value: 4490")))
+			Expect(err).To(MatchError(ContainSubstring(`gateways.publicPorts[1].sourceCIDRs[1]: Invalid value: "10.0.0.0"`)))
+			Expect(err).To(MatchError(ContainSubstring(`gateways.sourceCIDRs[0]: Invalid value: "bogus"`)))
+			Expect(err).To(MatchError(ContainSubstring("gateways.count: Invalid value: -1")))
+			Expect(err).ToNot(MatchError(ContainSubstring("publicPorts[0]")))
+		})
+
+		It("should report unsupported IP families", func() {
+			c.IPFamilies = []api.IPFamily{api.IPv4Family, "IPv5"}
+			Expect(c.Validate(nil)).To(MatchError(`ipFamilies[1]: Unsupported value: "IPv5": supported values: "IPv4", "IPv6"`))
+		})
+
+		It("should accept the providers of the given registry", func() {
+			registry := provider.NewRegistry()
+			Expect(registry.Register("custom", func(_ interface{}, _ provider.Dependencies) (api.Cloud, api.GatewayDeployer, error) {
+				return nil, nil, nil
+			})).To(Succeed())
+
+			c.Provider = "custom"
+			Expect(c.Validate(registry)).To(Succeed())
+		})
+	})
+
+	Describe("NewProvider", func() {
+		It("should not create the provider if the configuration is invalid", func() {
+			c := &config.Config{APIVersion: config.APIVersion, Kind: config.Kind, Provider: provider.AWS}
+
+			_, _, err := c.NewProvider(nil, provider.Dependencies{})
+			Expect(err).To(MatchError(ContainSubstring("infraID: Required value")))
+		})
+
+		It("should pass the provider configuration to the registry", func() {
+			var received interface{}
+
+			registry := provider.NewRegistry()
+			Expect(registry.Register("custom", func(config interface{}, _ provider.Dependencies) (api.Cloud, api.GatewayDeployer, error) {
+				received = config
+				return nil, nil, nil
+			})).To(Succeed())
+
+			c := &config.Config{APIVersion: config.APIVersion, Kind: config.Kind, Provider: "custom", InfraID: "cluster1-x7b2k"}
+
+			_, _, err := c.NewProvider(registry, provider.Dependencies{})
+			Expect(err).To(Succeed())
+			Expect(received).To(Equal(map[string]interface{}{"infraID": "cluster1-x7b2k"}))
+		})
+	})
+})
@@ -0,0 +1,197 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/provider"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Validate checks the configuration for the given registry, or the default registry if nil. The returned error lists
+// all the problems found, each prefixed with the path of the offending field, e.g.
+// "gateways.publicPorts[0].protocol: Required value".
+func (c *Config) Validate(registry *provider.Registry) error {
+	if registry == nil {
+		registry = provider.NewDefaultRegistry()
+	}
+
+	errs := validateVersion(c)
+
+	providerPath := field.NewPath("provider")
+	providers := registry.Providers()
+
+	switch {
+	case c.Provider == "":
+		errs = append(errs, field.Required(providerPath, fmt.Sprintf("must be one of %s", strings.Join(providers, ", "))))
+	case !contains(providers, c.Provider):
+		errs = append(errs, field.NotSupported(providerPath, c.Provider, providers))
+	default:
+		errs = append(errs, c.validateProviderConfig(registry)...)
+	}
+
+	for i, family := range c.IPFamilies {
+		if family != api.IPv4Family && family != api.IPv6Family {
+			errs = append(errs, field.NotSupported(field.NewPath("ipFamilies").Index(i), family,
+				[]string{string(api.IPv4Family), string(api.IPv6Family)}))
+		}
+	}
+
+	for i := range c.InternalPorts {
+		errs = append(errs, validatePort(field.NewPath("internalPorts").Index(i), &c.InternalPorts[i], true)...)
+	}
+
+	gatewaysPath := field.NewPath("gateways")
+
+	if c.Gateways.Count < 0 {
+		errs = append(errs, field.Invalid(gatewaysPath.Child("count"), c.Gateways.Count, "must be 0 or more"))
+	}
+
+	for i := range c.Gateways.PublicPorts {
+		errs = append(errs, validatePort(gatewaysPath.Child("publicPorts").Index(i), &c.Gateways.PublicPorts[i], false)...)
+	}
+
+	errs = append(errs, validateCIDRs(gatewaysPath.Child("sourceCIDRs"), c.Gateways.SourceCIDRs)...)
+
+	return errs.ToAggregate()
+}
+
+func validateVersion(c *Config) field.ErrorList {
+	errs := field.ErrorList{}
+
+	switch {
+	case c.APIVersion == "":
+		errs = append(errs, field.Required(field.NewPath("apiVersion"), fmt.Sprintf("must be %s", APIVersion)))
+	case !contains(supportedVersions, c.APIVersion):
+		errs = append(errs, field.NotSupported(field.NewPath("apiVersion"), c.APIVersion, supportedVersions))
+	}
+
+	if c.Kind != Kind {
+		errs = append(errs, field.NotSupported(field.NewPath("kind"), c.Kind, []string{Kind}))
+	}
+
+	return errs
+}
+
+// validateProviderConfig validates the provider's configuration through the registry, and reports the problems found
+// by the path of the offending field.
+func (c *Config) validateProviderConfig(registry *provider.Registry) field.ErrorList {
+	err := registry.Validate(c.Provider, c.ProviderConfig())
+	if err == nil {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+
+	providerErrs := []error{err}
+
+	var aggregate utilerrors.Aggregate
+	if errors.As(err, &aggregate) {
+		providerErrs = aggregate.Errors()
+	}
+
+	for _, err := range providerErrs {
+		missing := &api.MissingFieldsError{}
+		unsupported := &provider.UnsupportedFieldsError{}
+
+		switch {
+		case errors.As(err, &unsupported):
+			for _, key := range unsupported.Keys {
+				errs = append(errs, field.Forbidden(configPath(key), fmt.Sprintf("not supported by the %s provider", c.Provider)))
+			}
+		case errors.As(err, &missing):
+			for _, f := range missing.Fields {
+				detail := fmt.Sprintf("required by the %s provider", c.Provider)
+				if f.Condition != "" {
+					detail = "required " + f.Condition
+				}
+
+				errs = append(errs, field.Required(configPath(f.Key), detail))
+			}
+		default:
+			errs = append(errs, field.Invalid(field.NewPath("provider"), c.Provider, err.Error()))
+		}
+	}
+
+	return errs
+}
+
+// configPath returns the path of the field configuring the given key of the provider's configuration.
+func configPath(key string) *field.Path {
+	for path, k := range configKeys {
+		if k == key {
+			return toPath(path)
+		}
+	}
+
+	return field.NewPath(key)
+}
+
+func validatePort(path *field.Path, port *api.PortSpec, internal bool) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if port.Protocol == "" {
+		errs = append(errs, field.Required(path.Child("protocol"), ""))
+	}
+
+	if port.EndPort != 0 && port.EndPort < port.Port {
+		errs = append(errs, field.Invalid(path.Child("endPort"), int(port.EndPort), "must not be lower than port"))
+	}
+
+	if internal && len(port.SourceCIDRs) > 0 {
+		return append(errs, field.Forbidden(path.Child("sourceCIDRs"), "internal ports are only reachable from within the cluster"))
+	}
+
+	return append(errs, validateCIDRs(path.Child("sourceCIDRs"), port.SourceCIDRs)...)
+}
+
+func validateCIDRs(path *field.Path, cidrs []string) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for i, cidr := range cidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, field.Invalid(path.Index(i), cidr, "must be a valid CIDR"))
+		}
+	}
+
+	return errs
+}
+
+// toPath converts a dotted path, e.g. "gateways.image", to a field path.
+func toPath(path string) *field.Path {
+	elements := strings.Split(path, ".")
+
+	return field.NewPath(elements[0], elements[1:]...)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,325 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/finalizer"
+	"github.com/submariner-io/admiral/pkg/resource"
+	"github.com/submariner-io/admiral/pkg/watcher"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/audit"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/metrics"
+	"github.com/submariner-io/cloud-prepare/pkg/provider"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+)
+
+// Config configures a Controller.
+type Config struct {
+	// Client accesses the CloudPreparation resources.
+	Client dynamic.Interface
+
+	// RestConfig is used by Start to watch the CloudPreparation resources.
+	RestConfig *rest.Config
+
+	// RestMapper is optional and is provided for unit testing in lieu of the RestConfig.
+	RestMapper meta.RESTMapper
+
+	// Namespace restricts the watched CloudPreparation resources, all namespaces are watched if it is empty.
+	Namespace string
+
+	// Registry creates the providers named by the CloudPreparation resources, the default registry if nil.
+	Registry *provider.Registry
+
+	// Dependencies are passed to the provider factories.
+	Dependencies provider.Dependencies
+
+	// TracerProvider creates the spans tracing the steps of the operations and their cloud API calls, the global one
+	// if nil.
+	TracerProvider trace.TracerProvider
+
+	// Metrics, if set, are updated by the operations and their cloud API calls, see metrics.New.
+	Metrics *metrics.Metrics
+
+	// AuditLog, if set, records the mutating calls made by the operations, see audit.NewLog.
+	AuditLog *audit.Log
+}
+
+// Controller prepares clouds declaratively, from CloudPreparation resources. It prepares the cloud and deploys the
+// gateways when a CloudPreparation is created or its spec changes, recording the progress in its status conditions, and
+// cleans up using a finalizer when it is deleted.
+type Controller struct {
+	config Config
+	client dynamic.NamespaceableResourceInterface
+}
+
+// New creates a Controller from the given configuration.
+func New(config *Config) *Controller {
+	c := &Controller{config: *config}
+
+	if c.config.Registry == nil {
+		c.config.Registry = provider.NewDefaultRegistry()
+	}
+
+	c.client = config.Client.Resource(CloudPreparationGVR)
+
+	return c
+}
+
+// Start watches the CloudPreparation resources, reconciling them until the stop channel is closed. Failed
+// reconciliations are retried.
+func (c *Controller) Start(stopCh <-chan struct{}) error {
+	resourceType := &unstructured.Unstructured{}
+	resourceType.SetGroupVersionKind(CloudPreparationGVK)
+
+	onChange := func(obj runtime.Object, _ int) bool {
+		return c.Reconcile(context.TODO(), obj.(*unstructured.Unstructured)) != nil
+	}
+
+	resourceWatcher, err := watcher.New(&watcher.Config{
+		RestConfig: c.config.RestConfig,
+		RestMapper: c.config.RestMapper,
+		Client:     c.config.Client,
+		ResourceConfigs: []watcher.ResourceConfig{
+			{
+				Name:                "CloudPreparation controller",
+				ResourceType:        resourceType,
+				SourceNamespace:     c.config.Namespace,
+				ResourcesEquivalent: resourcesEquivalent,
+				Handler: watcher.EventHandlerFuncs{
+					OnCreateFunc: onChange,
+					OnUpdateFunc: onChange,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "error creating the CloudPreparation watcher")
+	}
+
+	return errors.Wrap(resourceWatcher.Start(stopCh), "error starting the CloudPreparation watcher")
+}
+
+// Reconcile applies the given CloudPreparation: it cleans up if the resource is being deleted, and otherwise prepares
+// the cloud and deploys the gateways unless its current spec has already been applied.
+func (c *Controller) Reconcile(ctx context.Context, obj *unstructured.Unstructured) error {
+	preparation := &CloudPreparation{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, preparation); err != nil {
+		return errors.Wrapf(err, "error converting CloudPreparation %q", obj.GetName())
+	}
+
+	client := resource.ForDynamic(c.client.Namespace(obj.GetNamespace()))
+
+	if obj.GetDeletionTimestamp() != nil {
+		return c.cleanup(ctx, client, obj, preparation)
+	}
+
+	if preparation.Status.ObservedGeneration == preparation.Generation &&
+		meta.IsStatusConditionTrue(preparation.Status.Conditions, ConditionCloudPrepared) &&
+		meta.IsStatusConditionTrue(preparation.Status.Conditions, ConditionGatewaysDeployed) {
+		return nil
+	}
+
+	cloud, gwDeployer, err := c.newProvider(ctx, obj, preparation, ConditionCloudPrepared)
+	if err != nil {
+		return err
+	}
+
+	// The finalizer is only added once the provider can be built, since it's needed to clean up.
+	if _, err := finalizer.Add(ctx, client, obj, FinalizerName); err != nil {
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	err = c.run(ctx, obj, preparation, ConditionCloudPrepared, "The cloud is prepared for Submariner",
+		func(ctx context.Context, reporter api.Reporter) error {
+			input := preparation.Spec.prepareInput()
+
+			families, err := k8s.IPFamiliesOrDetected(ctx, c.config.Dependencies.K8sClient, input.IPFamilies)
+			if err != nil {
+				return err // nolint:wrapcheck // No need to wrap here
+			}
+
+			input.IPFamilies = families
+
+			return cloud.PrepareForSubmarinerWithContext(ctx, input, reporter)
+		})
+	if err != nil {
+		return err
+	}
+
+	err = c.run(ctx, obj, preparation, ConditionGatewaysDeployed, "The gateways are deployed",
+		func(ctx context.Context, reporter api.Reporter) error {
+			input := preparation.Spec.deployInput(c.activeGatewayNode())
+
+			families, err := k8s.IPFamiliesOrDetected(ctx, c.config.Dependencies.K8sClient, input.IPFamilies)
+			if err != nil {
+				return err // nolint:wrapcheck // No need to wrap here
+			}
+
+			input.IPFamilies = families
+
+			return gwDeployer.DeployWithContext(ctx, input, reporter)
+		})
+	if err != nil {
+		return err
+	}
+
+	preparation.Status.ObservedGeneration = preparation.Generation
+
+	return c.updateStatus(ctx, obj, &preparation.Status)
+}
+
+func (c *Controller) cleanup(ctx context.Context, client resource.Interface, obj *unstructured.Unstructured,
+	preparation *CloudPreparation) error {
+	if !hasFinalizer(obj) {
+		return nil
+	}
+
+	cloud, gwDeployer, err := c.newProvider(ctx, obj, preparation, ConditionCleanedUp)
+	if err != nil {
+		return err
+	}
+
+	err = c.run(ctx, obj, preparation, ConditionCleanedUp, "The cloud is cleaned up", func(ctx context.Context, reporter api.Reporter) error {
+		if err := gwDeployer.CleanupWithContext(ctx, reporter); err != nil {
+			return err // nolint:wrapcheck // No need to wrap here
+		}
+
+		return cloud.CleanupAfterSubmarinerWithContext(ctx, reporter)
+	})
+	if err != nil {
+		return err
+	}
+
+	return finalizer.Remove(ctx, client, obj, FinalizerName) // nolint:wrapcheck // No need to wrap here
+}
+
+// newProvider creates the CloudPreparation's provider, recording any failure in the given condition.
+func (c *Controller) newProvider(ctx context.Context, obj *unstructured.Unstructured, preparation *CloudPreparation,
+	conditionType string) (api.Cloud, api.GatewayDeployer, error) {
+	cloud, gwDeployer, err := c.config.Registry.New(preparation.Spec.Provider, preparation.Spec.providerConfig(),
+		c.config.Dependencies)
+	if err != nil {
+		c.reporter(ctx, obj, preparation, conditionType).Failed(err)
+		return nil, nil, err // nolint:wrapcheck // No need to wrap here
+	}
+
+	return cloud, gwDeployer, nil
+}
+
+// activeGatewayNode returns the function finding the node hosting the active gateway through the cluster, if the
+// controller has access to it.
+func (c *Controller) activeGatewayNode() api.ActiveGatewayNodeFunc {
+	if c.config.Dependencies.K8sClient == nil {
+		return nil
+	}
+
+	return c.config.Dependencies.K8sClient.GetActiveGatewayNode
+}
+
+// run runs the operation, reporting its progress in the given condition, which is only set to true, with the given
+// message, once the operation succeeds, and false if it fails. The steps of the operation are traced, see
+// api.TraceSteps, and recorded in the metrics and the audit log if any.
+func (c *Controller) run(ctx context.Context, obj *unstructured.Unstructured, preparation *CloudPreparation,
+	conditionType, successMessage string, operation func(ctx context.Context, reporter api.Reporter) error) error {
+	conditions := c.reporter(ctx, obj, preparation, conditionType)
+	ctx = audit.NewContext(metrics.NewContext(ctx, c.config.Metrics), c.config.AuditLog)
+	tracedCtx, reporter := api.TraceSteps(ctx, conditions, c.config.TracerProvider)
+
+	if err := operation(tracedCtx, reporter); err != nil {
+		// The failure is only reported if the operation didn't report it already, to avoid another status update.
+		if !conditions.failed {
+			reporter.Failed(err)
+		}
+
+		return err
+	}
+
+	conditions.completed(successMessage)
+
+	return nil
+}
+
+func (c *Controller) reporter(ctx context.Context, obj *unstructured.Unstructured, preparation *CloudPreparation,
+	conditionType string) *conditionReporter {
+	return &conditionReporter{
+		conditionType: conditionType,
+		status:        &preparation.Status,
+		generation:    preparation.Generation,
+		publish: func() {
+			// Publishing the progress is best effort, the final status is updated by Reconcile.
+			_ = c.updateStatus(ctx, obj, &preparation.Status)
+		},
+	}
+}
+
+// updateStatus replaces the status of the CloudPreparation in the cluster.
+func (c *Controller) updateStatus(ctx context.Context, obj *unstructured.Unstructured, status *CloudPreparationStatus) error {
+	rawStatus, err := runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+	if err != nil {
+		return errors.Wrap(err, "error converting the CloudPreparation status")
+	}
+
+	client := c.client.Namespace(obj.GetNamespace())
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err // nolint:wrapcheck // No need to wrap here
+		}
+
+		existing.Object["status"] = rawStatus
+
+		_, err = client.UpdateStatus(ctx, existing, metav1.UpdateOptions{})
+
+		return err // nolint:wrapcheck // No need to wrap here
+	})
+
+	return errors.Wrapf(err, "error updating the status of CloudPreparation %q", obj.GetName())
+}
+
+// resourcesEquivalent returns true if the CloudPreparations only differ by changes which don't need to be reconciled,
+// such as the status updates made while reconciling them: only the changes to the spec, which increase the generation,
+// the deletion and the changes to the finalizers need to be.
+func resourcesEquivalent(obj1, obj2 *unstructured.Unstructured) bool {
+	return obj1.GetGeneration() == obj2.GetGeneration() &&
+		equality.Semantic.DeepEqual(obj1.GetDeletionTimestamp(), obj2.GetDeletionTimestamp()) &&
+		equality.Semantic.DeepEqual(obj1.GetFinalizers(), obj2.GetFinalizers())
+}
+
+func hasFinalizer(obj *unstructured.Unstructured) bool {
+	for _, f := range obj.GetFinalizers() {
+		if f == FinalizerName {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,433 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/admiral/pkg/syncer/test"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/controller"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/provider"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	fakeClient "k8s.io/client-go/dynamic/fake"
+	kubeFake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+const (
+	namespace       = "submariner-operator"
+	preparationName = "cluster1"
+)
+
+var _ = Describe("Controller", func() {
+	var (
+		cloudProvider *fakeProvider
+		dynClient     *fakeClient.FakeDynamicClient
+		client        dynamic.ResourceInterface
+		ctrl          *controller.Controller
+		preparation   *unstructured.Unstructured
+	)
+
+	BeforeEach(func() {
+		cloudProvider = &fakeProvider{}
+		preparation = newCloudPreparation()
+	})
+
+	JustBeforeEach(func() {
+		dynClient = fakeClient.NewSimpleDynamicClient(scheme.Scheme, preparation)
+		client = dynClient.Resource(controller.CloudPreparationGVR).Namespace(namespace)
+
+		ctrl = controller.New(&controller.Config{Client: dynClient, Registry: cloudProvider.registry()})
+	})
+
+	reconcile := func() error {
+		obj, err := client.Get(context.TODO(), preparationName, metav1.GetOptions{})
+		Expect(err).To(Succeed())
+
+		return ctrl.Reconcile(context.TODO(), obj)
+	}
+
+	getPreparation := func() *controller.CloudPreparation {
+		obj, err := client.Get(context.TODO(), preparationName, metav1.GetOptions{})
+		Expect(err).To(Succeed())
+
+		result := &controller.CloudPreparation{}
+		Expect(runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, result)).To(Succeed())
+
+		return result
+	}
+
+	When("a CloudPreparation is created", func() {
+		It("should prepare the cloud and deploy the gateways", func() {
+			Expect(reconcile()).To(Succeed())
+
+			Expect(cloudProvider.config).To(HaveKeyWithValue("region", "test-region"))
+			Expect(cloudProvider.config).To(HaveKeyWithValue("instanceType", "m5n.large"))
+			Expect(cloudProvider.cloud.prepared).To(Equal([]api.PrepareForSubmarinerInput{{
+				InternalPorts: []api.PortSpec{{Port: 4800, Protocol: "udp"}},
+				IPFamilies:    []api.IPFamily{api.IPv4Family, api.IPv6Family},
+			}}))
+			Expect(cloudProvider.gwDeployer.deployed).To(Equal([]api.GatewayDeployInput{{
+				PublicPorts:       []api.PortSpec{{Port: 4500, Protocol: "udp", SourceCIDRs: []string{"1.2.3.0/24"}}},
+				PublicSourceCIDRs: []string{"5.6.7.0/24"},
+				IPFamilies:        []api.IPFamily{api.IPv4Family, api.IPv6Family},
+				Gateways:          2,
+			}}))
+
+			result := getPreparation()
+			Expect(result.Finalizers).To(ContainElement(controller.FinalizerName))
+			Expect(meta.IsStatusConditionTrue(result.Status.Conditions, controller.ConditionCloudPrepared)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(result.Status.Conditions, controller.ConditionGatewaysDeployed)).To(BeTrue())
+		})
+
+		It("should only set the conditions to true once the operations complete", func() {
+			var prepared *metav1.Condition
+
+			cloudProvider.cloud.afterStep = func() {
+				prepared = meta.FindStatusCondition(getPreparation().Status.Conditions, controller.ConditionCloudPrepared)
+			}
+
+			Expect(reconcile()).To(Succeed())
+			Expect(prepared).ToNot(BeNil())
+			Expect(prepared.Status).To(Equal(metav1.ConditionUnknown))
+			Expect(prepared.Message).To(Equal("Prepared the internal ports"))
+
+			condition := meta.FindStatusCondition(getPreparation().Status.Conditions, controller.ConditionCloudPrepared)
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Message).To(Equal("The cloud is prepared for Submariner"))
+		})
+
+		Context("and the controller has access to the cluster", func() {
+			JustBeforeEach(func() {
+				ctrl = controller.New(&controller.Config{
+					Client:       dynClient,
+					Registry:     cloudProvider.registry(),
+					Dependencies: provider.Dependencies{K8sClient: k8s.NewInterface(kubeFake.NewSimpleClientset(dualStackNode()))},
+				})
+			})
+
+			It("should find the active gateway node through the cluster", func() {
+				Expect(reconcile()).To(Succeed())
+				Expect(cloudProvider.gwDeployer.deployed).To(HaveLen(1))
+				Expect(cloudProvider.gwDeployer.deployed[0].ActiveGatewayNode).ToNot(BeNil())
+			})
+
+			Context("and the IP families aren't set", func() {
+				BeforeEach(func() {
+					unstructured.RemoveNestedField(preparation.Object, "spec", "ipFamilies")
+				})
+
+				It("should use the IP families of the cluster network", func() {
+					Expect(reconcile()).To(Succeed())
+					Expect(cloudProvider.cloud.prepared).To(HaveLen(1))
+					Expect(cloudProvider.cloud.prepared[0].IPFamilies).To(Equal([]api.IPFamily{api.IPv4Family, api.IPv6Family}))
+					Expect(cloudProvider.gwDeployer.deployed).To(HaveLen(1))
+					Expect(cloudProvider.gwDeployer.deployed[0].IPFamilies).To(Equal([]api.IPFamily{api.IPv4Family, api.IPv6Family}))
+				})
+			})
+		})
+
+		Context("and it's reconciled again", func() {
+			It("should not prepare the cloud again", func() {
+				Expect(reconcile()).To(Succeed())
+				Expect(reconcile()).To(Succeed())
+				Expect(cloudProvider.cloud.prepared).To(HaveLen(1))
+			})
+		})
+
+		Context("and preparing the cloud fails", func() {
+			BeforeEach(func() {
+				cloudProvider.cloud.err = errors.New("fake prepare error")
+			})
+
+			It("should record the failure in the status and return an error", func() {
+				Expect(reconcile()).ToNot(Succeed())
+				Expect(cloudProvider.gwDeployer.deployed).To(BeEmpty())
+
+				condition := meta.FindStatusCondition(getPreparation().Status.Conditions, controller.ConditionCloudPrepared)
+				Expect(condition).ToNot(BeNil())
+				Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+				Expect(condition.Message).To(ContainSubstring("fake prepare error"))
+			})
+
+			Context("and the failure was reported by the provider", func() {
+				BeforeEach(func() {
+					cloudProvider.cloud.reportedErr = errors.New("fake reported error")
+				})
+
+				It("should keep the failure reported by the provider", func() {
+					Expect(reconcile()).ToNot(Succeed())
+
+					condition := meta.FindStatusCondition(getPreparation().Status.Conditions, controller.ConditionCloudPrepared)
+					Expect(condition).ToNot(BeNil())
+					Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+					Expect(condition.Message).To(Equal("fake reported error"))
+				})
+			})
+		})
+
+		Context("with an unknown provider", func() {
+			BeforeEach(func() {
+				Expect(unstructured.SetNestedField(preparation.Object, "unknown", "spec", "provider")).To(Succeed())
+			})
+
+			It("should record the failure in the status and return an error", func() {
+				Expect(reconcile()).ToNot(Succeed())
+
+				result := getPreparation()
+				Expect(meta.IsStatusConditionFalse(result.Status.Conditions, controller.ConditionCloudPrepared)).To(BeTrue())
+				Expect(result.Finalizers).To(BeEmpty())
+			})
+		})
+	})
+
+	When("a prepared CloudPreparation is deleted", func() {
+		BeforeEach(func() {
+			now := metav1.Now()
+			preparation.SetDeletionTimestamp(&now)
+			preparation.SetFinalizers([]string{controller.FinalizerName})
+		})
+
+		It("should clean up and remove the finalizer", func() {
+			Expect(reconcile()).To(Succeed())
+			Expect(cloudProvider.gwDeployer.cleanedUp).To(BeTrue())
+			Expect(cloudProvider.cloud.cleanedUp).To(BeTrue())
+			Expect(getPreparation().Finalizers).To(BeEmpty())
+		})
+
+		Context("and cleaning up fails", func() {
+			BeforeEach(func() {
+				cloudProvider.gwDeployer.err = errors.New("fake cleanup error")
+			})
+
+			It("should keep the finalizer and return an error", func() {
+				Expect(reconcile()).ToNot(Succeed())
+				Expect(cloudProvider.cloud.cleanedUp).To(BeFalse())
+
+				result := getPreparation()
+				Expect(result.Finalizers).To(ContainElement(controller.FinalizerName))
+				Expect(meta.IsStatusConditionFalse(result.Status.Conditions, controller.ConditionCleanedUp)).To(BeTrue())
+			})
+		})
+	})
+
+	When("the controller is started", func() {
+		var stopCh chan struct{}
+
+		JustBeforeEach(func() {
+			restMapper, _ := test.GetRESTMapperAndGroupVersionResourceFor(preparation)
+			ctrl = controller.New(&controller.Config{
+				Client:     dynClient,
+				RestMapper: restMapper,
+				Registry:   cloudProvider.registry(),
+			})
+
+			stopCh = make(chan struct{})
+			Expect(ctrl.Start(stopCh)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			close(stopCh)
+		})
+
+		It("should reconcile the existing CloudPreparations", func() {
+			Eventually(func() int {
+				return len(cloudProvider.gwDeployer.deployInputs())
+			}).Should(Equal(1))
+		})
+
+		Context("and the status of a reconciled CloudPreparation is updated", func() {
+			It("should not reconcile it again", func() {
+				Eventually(func() int {
+					return len(cloudProvider.gwDeployer.deployInputs())
+				}).Should(Equal(1))
+
+				// The conditions no longer being true would otherwise cause the cloud to be prepared again.
+				obj, err := client.Get(context.TODO(), preparationName, metav1.GetOptions{})
+				Expect(err).To(Succeed())
+				Expect(unstructured.SetNestedSlice(obj.Object, []interface{}{}, "status", "conditions")).To(Succeed())
+				_, err = client.UpdateStatus(context.TODO(), obj, metav1.UpdateOptions{})
+				Expect(err).To(Succeed())
+
+				Consistently(func() int {
+					return len(cloudProvider.gwDeployer.deployInputs())
+				}, "300ms").Should(Equal(1))
+			})
+		})
+
+		Context("and the spec of a reconciled CloudPreparation changes", func() {
+			It("should reconcile it again", func() {
+				Eventually(func() int {
+					return len(cloudProvider.gwDeployer.deployInputs())
+				}).Should(Equal(1))
+
+				obj, err := client.Get(context.TODO(), preparationName, metav1.GetOptions{})
+				Expect(err).To(Succeed())
+				obj.SetGeneration(obj.GetGeneration() + 1)
+				_, err = client.Update(context.TODO(), obj, metav1.UpdateOptions{})
+				Expect(err).To(Succeed())
+
+				Eventually(func() int {
+					return len(cloudProvider.gwDeployer.deployInputs())
+				}).Should(Equal(2))
+			})
+		})
+	})
+})
+
+func newCloudPreparation() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"provider": "fake",
+			"config": map[string]interface{}{
+				"region": "test-region",
+			},
+			"internalPorts": []interface{}{
+				map[string]interface{}{"port": int64(4800), "protocol": "udp"},
+			},
+			"publicPorts": []interface{}{
+				map[string]interface{}{"port": int64(4500), "protocol": "udp", "sourceCIDRs": []interface{}{"1.2.3.0/24"}},
+			},
+			"publicSourceCIDRs": []interface{}{"5.6.7.0/24"},
+			"ipFamilies":        []interface{}{"IPv4", "IPv6"},
+			"gateways":          int64(2),
+			"instanceType":      "m5n.large",
+		},
+	}}
+
+	obj.SetGroupVersionKind(controller.CloudPreparationGVK)
+	obj.SetName(preparationName)
+	obj.SetNamespace(namespace)
+
+	return obj
+}
+
+type fakeProvider struct {
+	config     map[string]interface{}
+	cloud      fakeCloud
+	gwDeployer fakeGatewayDeployer
+}
+
+func (p *fakeProvider) factory(config interface{}, _ provider.Dependencies) (api.Cloud, api.GatewayDeployer, error) {
+	p.config = config.(map[string]interface{})
+
+	return &p.cloud, &p.gwDeployer, nil
+}
+
+func (p *fakeProvider) registry() *provider.Registry {
+	registry := provider.NewRegistry()
+	Expect(registry.Register("fake", p.factory)).To(Succeed())
+
+	return registry
+}
+
+type fakeCloud struct {
+	api.Cloud
+	err error
+
+	// reportedErr, if set, is reported as the failure of the preparation when it fails with err.
+	reportedErr error
+
+	prepared  []api.PrepareForSubmarinerInput
+	cleanedUp bool
+	afterStep func()
+}
+
+func (c *fakeCloud) PrepareForSubmarinerWithContext(ctx context.Context, input api.PrepareForSubmarinerInput,
+	reporter api.Reporter) error {
+	reporter.Started("Preparing the internal ports")
+
+	if c.err != nil {
+		if c.reportedErr != nil {
+			reporter.Failed(c.reportedErr)
+		}
+
+		return c.err
+	}
+
+	reporter.Succeeded("Prepared the internal ports")
+
+	if c.afterStep != nil {
+		c.afterStep()
+	}
+
+	c.prepared = append(c.prepared, input)
+
+	return nil
+}
+
+func (c *fakeCloud) CleanupAfterSubmarinerWithContext(ctx context.Context, reporter api.Reporter) error {
+	c.cleanedUp = true
+	return c.err
+}
+
+type fakeGatewayDeployer struct {
+	api.GatewayDeployer
+	mutex     sync.Mutex
+	err       error
+	deployed  []api.GatewayDeployInput
+	cleanedUp bool
+}
+
+func (d *fakeGatewayDeployer) DeployWithContext(ctx context.Context, input api.GatewayDeployInput, reporter api.Reporter) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.deployed = append(d.deployed, input)
+
+	return d.err
+}
+
+func (d *fakeGatewayDeployer) CleanupWithContext(ctx context.Context, reporter api.Reporter) error {
+	if d.err != nil {
+		return d.err
+	}
+
+	d.cleanedUp = true
+
+	return nil
+}
+
+func (d *fakeGatewayDeployer) deployInputs() []api.GatewayDeployInput {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.deployed
+}
+
+func dualStackNode() *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+			{Type: corev1.NodeInternalIP, Address: "fd00::1"},
+		}},
+	}
+}
@@ -0,0 +1,77 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+const (
+	reasonInProgress = "InProgress"
+	reasonSucceeded  = "Succeeded"
+	reasonFailed     = "Failed"
+)
+
+// conditionReporter is an api.Reporter which records each report in a status condition of a CloudPreparation, and
+// publishes the updated status so that the progress of long operations is visible. The steps which succeed leave the
+// condition in progress; it's only set to true when the whole operation completes.
+type conditionReporter struct {
+	conditionType string
+	status        *CloudPreparationStatus
+	generation    int64
+	publish       func()
+
+	// failed is true if the last report was a failure.
+	failed bool
+}
+
+func (r *conditionReporter) Started(message string, args ...interface{}) {
+	r.set(metav1.ConditionUnknown, reasonInProgress, fmt.Sprintf(message, args...))
+}
+
+func (r *conditionReporter) Succeeded(message string, args ...interface{}) {
+	r.set(metav1.ConditionUnknown, reasonInProgress, fmt.Sprintf(message, args...))
+}
+
+// completed records the success of the whole operation.
+func (r *conditionReporter) completed(message string) {
+	r.set(metav1.ConditionTrue, reasonSucceeded, message)
+}
+
+func (r *conditionReporter) Failed(errs ...error) {
+	r.set(metav1.ConditionFalse, reasonFailed, utilerrors.NewAggregate(errs).Error())
+}
+
+func (r *conditionReporter) set(status metav1.ConditionStatus, reason, message string) {
+	r.failed = status == metav1.ConditionFalse
+
+	meta.SetStatusCondition(&r.status.Conditions, metav1.Condition{
+		Type:               r.conditionType,
+		Status:             status,
+		ObservedGeneration: r.generation,
+		Reason:             reason,
+		Message:            message,
+	})
+
+	r.publish()
+}
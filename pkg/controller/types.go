@@ -0,0 +1,129 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// FinalizerName is the finalizer which ensures the cloud is cleaned up before its CloudPreparation is deleted.
+	FinalizerName = "submariner.io/cloud-preparation-cleanup"
+
+	// ConditionCloudPrepared reports the progress of the cloud preparation.
+	ConditionCloudPrepared = "CloudPrepared"
+
+	// ConditionGatewaysDeployed reports the progress of the gateway deployment.
+	ConditionGatewaysDeployed = "GatewaysDeployed"
+
+	// ConditionCleanedUp reports the progress of the clean up, once the CloudPreparation is being deleted.
+	ConditionCleanedUp = "CleanedUp"
+)
+
+// CloudPreparationGVR is the group, version and resource of the CloudPreparation custom resource.
+var CloudPreparationGVR = schema.GroupVersionResource{
+	Group:    "submariner.io",
+	Version:  "v1alpha1",
+	Resource: "cloudpreparations",
+}
+
+// CloudPreparationGVK is the group, version and kind of the CloudPreparation custom resource.
+var CloudPreparationGVK = schema.GroupVersionKind{
+	Group:   CloudPreparationGVR.Group,
+	Version: CloudPreparationGVR.Version,
+	Kind:    "CloudPreparation",
+}
+
+// CloudPreparation declares how a cloud is prepared for Submariner.
+type CloudPreparation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CloudPreparationSpec   `json:"spec"`
+	Status CloudPreparationStatus `json:"status,omitempty"`
+}
+
+type CloudPreparationSpec struct {
+	// Provider is the name of the provider in the controller's registry, e.g. "aws" or "gcp".
+	Provider string `json:"provider"`
+
+	// Config is the provider's configuration, using the same keys as its JSON representation.
+	Config map[string]interface{} `json:"config,omitempty"`
+
+	// InternalPorts are opened for intra-cluster communication.
+	InternalPorts []api.PortSpec `json:"internalPorts,omitempty"`
+
+	// PublicPorts are opened on the gateways.
+	PublicPorts []api.PortSpec `json:"publicPorts,omitempty"`
+
+	// PublicSourceCIDRs restricts the sources allowed to reach the public ports which don't specify their own.
+	PublicSourceCIDRs []string `json:"publicSourceCIDRs,omitempty"`
+
+	// IPFamilies are the IP families used by the cluster network. If empty, they are detected from its nodes when the
+	// controller has access to the cluster, and are IPv4 only otherwise.
+	IPFamilies []api.IPFamily `json:"ipFamilies,omitempty"`
+
+	// Gateways is the number of gateways to deploy, 0 to use the provider's default.
+	Gateways int `json:"gateways,omitempty"`
+
+	// InstanceType overrides the instance type of dedicated gateways in the provider's configuration.
+	InstanceType string `json:"instanceType,omitempty"`
+}
+
+type CloudPreparationStatus struct {
+	// ObservedGeneration is the generation of the spec which was last applied successfully.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+func (s *CloudPreparationSpec) prepareInput() api.PrepareForSubmarinerInput {
+	return api.PrepareForSubmarinerInput{
+		InternalPorts: s.InternalPorts,
+		IPFamilies:    s.IPFamilies,
+	}
+}
+
+// deployInput returns the gateway deployment input, finding the active gateway node with the given function when
+// gateways are removed.
+func (s *CloudPreparationSpec) deployInput(activeGatewayNode api.ActiveGatewayNodeFunc) api.GatewayDeployInput {
+	return api.GatewayDeployInput{
+		PublicPorts:       s.PublicPorts,
+		PublicSourceCIDRs: s.PublicSourceCIDRs,
+		IPFamilies:        s.IPFamilies,
+		Gateways:          s.Gateways,
+		ActiveGatewayNode: activeGatewayNode,
+	}
+}
+
+// providerConfig returns the provider's configuration, with the instance type override applied.
+func (s *CloudPreparationSpec) providerConfig() map[string]interface{} {
+	config := map[string]interface{}{}
+	for key, value := range s.Config {
+		config[key] = value
+	}
+
+	if s.InstanceType != "" {
+		config["instanceType"] = s.InstanceType
+	}
+
+	return config
+}
@@ -25,7 +25,14 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
-
+	"time"
+
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/audit"
+	"github.com/submariner-io/cloud-prepare/pkg/metrics"
+	"github.com/submariner-io/cloud-prepare/pkg/retry"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"google.golang.org/api/cloudresourcemanager/v1"
 	compute "google.golang.org/api/compute/v1"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
@@ -35,41 +42,61 @@ import (
 
 // Interface wraps an actual GCP library client to allow for easier testing.
 type Interface interface {
-	InsertFirewallRule(projectID string, rule *compute.Firewall) error
-	GetFirewallRule(projectID, name string) (*compute.Firewall, error)
-	DeleteFirewallRule(projectID, name string) error
-	UpdateFirewallRule(projectID, name string, rule *compute.Firewall) error
-	GetInstance(zone string, instance string) (*compute.Instance, error)
-	ListInstances(zone string) (*compute.InstanceList, error)
-	ListZones() (*compute.ZoneList, error)
+	InsertFirewallRule(ctx context.Context, projectID string, rule *compute.Firewall) error
+	GetFirewallRule(ctx context.Context, projectID, name string) (*compute.Firewall, error)
+	DeleteFirewallRule(ctx context.Context, projectID, name string) error
+	UpdateFirewallRule(ctx context.Context, projectID, name string, rule *compute.Firewall) error
+	GetInstance(ctx context.Context, zone string, instance string) (*compute.Instance, error)
+	ListInstances(ctx context.Context, zone string) (*compute.InstanceList, error)
+	ListZones(ctx context.Context) (*compute.ZoneList, error)
+	GetRegion(ctx context.Context, region string) (*compute.Region, error)
+	GetMachineType(ctx context.Context, zone, machineType string) (*compute.MachineType, error)
 	InstanceHasPublicIP(instance *compute.Instance) (bool, error)
-	UpdateInstanceNetworkTags(project, zone, instance string, tags *compute.Tags) error
-	ConfigurePublicIPOnInstance(instance *compute.Instance) error
-	DeletePublicIPOnInstance(instance *compute.Instance) error
+	UpdateInstanceNetworkTags(ctx context.Context, project, zone, instance string, tags *compute.Tags) error
+	ConfigurePublicIPOnInstance(ctx context.Context, instance *compute.Instance) error
+	DeletePublicIPOnInstance(ctx context.Context, instance *compute.Instance) error
+
+	// TestIamPermissions returns the subset of the given permissions which the caller has on the project.
+	TestIamPermissions(ctx context.Context, permissions []string) ([]string, error)
 }
 
 type gcpClient struct {
-	projectID     string
-	computeClient *compute.Service
+	projectID             string
+	computeClient         *compute.Service
+	resourceManagerClient *cloudresourcemanager.Service
+	retryPolicy           *retry.Policy
 }
 
-func (g *gcpClient) InsertFirewallRule(projectID string, rule *compute.Firewall) error {
-	_, err := g.computeClient.Firewalls.Insert(projectID, rule).Context(context.TODO()).Do()
-	return err
+func (g *gcpClient) InsertFirewallRule(ctx context.Context, projectID string, rule *compute.Firewall) error {
+	return g.insert(ctx, "compute.firewalls.insert", firewallResource(projectID, rule.Name), rule, func() error {
+		_, err := g.computeClient.Firewalls.Insert(projectID, rule).Context(ctx).Do()
+		return err
+	})
 }
 
-func (g *gcpClient) GetFirewallRule(projectID, name string) (*compute.Firewall, error) {
-	return g.computeClient.Firewalls.Get(projectID, name).Context(context.TODO()).Do()
+func (g *gcpClient) GetFirewallRule(ctx context.Context, projectID, name string) (*compute.Firewall, error) {
+	var result *compute.Firewall
+
+	err := g.call(ctx, "compute.firewalls.get", func() (err error) {
+		result, err = g.computeClient.Firewalls.Get(projectID, name).Context(ctx).Do()
+		return err
+	})
+
+	return result, err
 }
 
-func (g *gcpClient) DeleteFirewallRule(projectID, name string) error {
-	_, err := g.computeClient.Firewalls.Delete(projectID, name).Context(context.TODO()).Do()
-	return err
+func (g *gcpClient) DeleteFirewallRule(ctx context.Context, projectID, name string) error {
+	return g.mutate(ctx, "compute.firewalls.delete", firewallResource(projectID, name), nil, func() error {
+		_, err := g.computeClient.Firewalls.Delete(projectID, name).Context(ctx).Do()
+		return err
+	})
 }
 
-func (g *gcpClient) UpdateFirewallRule(projectID, name string, rule *compute.Firewall) error {
-	_, err := g.computeClient.Firewalls.Update(projectID, name, rule).Context(context.TODO()).Do()
-	return err
+func (g *gcpClient) UpdateFirewallRule(ctx context.Context, projectID, name string, rule *compute.Firewall) error {
+	return g.mutate(ctx, "compute.firewalls.update", firewallResource(projectID, name), rule, func() error {
+		_, err := g.computeClient.Firewalls.Update(projectID, name, rule).Context(ctx).Do()
+		return err
+	})
 }
 
 func NewClient(projectID string, options []option.ClientOption) (Interface, error) {
@@ -80,12 +107,89 @@ func NewClient(projectID string, options []option.ClientOption) (Interface, erro
 		return nil, err
 	}
 
+	resourceManagerClient, err := cloudresourcemanager.NewService(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
 	return &gcpClient{
-		projectID:     projectID,
-		computeClient: computeClient,
+		projectID:             projectID,
+		computeClient:         computeClient,
+		resourceManagerClient: resourceManagerClient,
+		retryPolicy:           retry.Default(),
 	}, nil
 }
 
+// UseRetryPolicy replaces the policy used to retry and rate limit the calls made by the given client, which must have
+// been created by NewClient; a nil policy disables both.
+func UseRetryPolicy(client Interface, policy *retry.Policy) error {
+	g, ok := client.(*gcpClient)
+	if !ok {
+		return errors.New("the client must be created by NewClient")
+	}
+
+	g.retryPolicy = policy
+
+	return nil
+}
+
+// mutate makes a GCP API call which changes the given resource, see call, and records it in the audit log.
+func (g *gcpClient) mutate(ctx context.Context, method, resource string, request interface{}, apiCall func() error) error {
+	err := g.call(ctx, method, apiCall)
+
+	audit.Record(ctx, "gcp", method, resource, request, err)
+
+	return err
+}
+
+// insert makes a GCP API call which creates the given resource, see mutate. Inserts aren't idempotent: if an attempt
+// which failed with a transient error was nevertheless applied, the retry conflicts with it, and is then successful.
+func (g *gcpClient) insert(ctx context.Context, method, resource string, request interface{}, apiCall func() error) error {
+	attempts := 0
+
+	return g.mutate(ctx, method, resource, request, func() error {
+		attempts++
+
+		err := apiCall()
+		if attempts > 1 && errors.Is(categorizeError(err), api.ErrConflict) {
+			return nil
+		}
+
+		return err
+	})
+}
+
+func firewallResource(project, name string) string {
+	return "projects/" + project + "/global/firewalls/" + name
+}
+
+func instanceResource(project, zone, instance string) string {
+	return "projects/" + project + "/zones/" + zone + "/instances/" + instance
+}
+
+// call makes a GCP API call, identified by its method, e.g. "compute.firewalls.get", according to the retry policy.
+// Each attempt is traced in a span and recorded in the metrics, and its error is put in the api error categories.
+func (g *gcpClient) call(ctx context.Context, method string, apiCall func() error) error {
+	service := strings.SplitN(method, ".", 2)[0]
+
+	return g.retryPolicy.Do(ctx, isRetriable, func() error {
+		_, span := api.StartCall(ctx, method,
+			semconv.CloudProviderGCP,
+			semconv.CloudAccountIDKey.String(g.projectID),
+			semconv.RPCSystemKey.String("google-api"),
+			semconv.RPCServiceKey.String(service),
+			semconv.RPCMethodKey.String(strings.TrimPrefix(method, service+".")))
+
+		start := time.Now()
+		err := categorizeError(apiCall())
+
+		api.EndCall(span, err)
+		metrics.ObserveCall(ctx, "gcp", method, start, err)
+
+		return err
+	})
+}
+
 func IsGCPNotFoundError(err error) bool {
 	var gerr *googleapi.Error
 	if errors.As(err, &gerr) {
@@ -95,16 +199,59 @@ func IsGCPNotFoundError(err error) bool {
 	return false
 }
 
-func (g *gcpClient) GetInstance(zone, instance string) (*compute.Instance, error) {
-	return g.computeClient.Instances.Get(g.projectID, zone, instance).Context(context.TODO()).Do()
+func (g *gcpClient) GetInstance(ctx context.Context, zone, instance string) (*compute.Instance, error) {
+	var result *compute.Instance
+
+	err := g.call(ctx, "compute.instances.get", func() (err error) {
+		result, err = g.computeClient.Instances.Get(g.projectID, zone, instance).Context(ctx).Do()
+		return err
+	})
+
+	return result, err
+}
+
+func (g *gcpClient) ListInstances(ctx context.Context, zone string) (*compute.InstanceList, error) {
+	var result *compute.InstanceList
+
+	err := g.call(ctx, "compute.instances.list", func() (err error) {
+		result, err = g.computeClient.Instances.List(g.projectID, zone).Context(ctx).Do()
+		return err
+	})
+
+	return result, err
+}
+
+func (g *gcpClient) ListZones(ctx context.Context) (*compute.ZoneList, error) {
+	var result *compute.ZoneList
+
+	err := g.call(ctx, "compute.zones.list", func() (err error) {
+		result, err = g.computeClient.Zones.List(g.projectID).Context(ctx).Do()
+		return err
+	})
+
+	return result, err
 }
 
-func (g *gcpClient) ListInstances(zone string) (*compute.InstanceList, error) {
-	return g.computeClient.Instances.List(g.projectID, zone).Context(context.TODO()).Do()
+func (g *gcpClient) GetRegion(ctx context.Context, region string) (*compute.Region, error) {
+	var result *compute.Region
+
+	err := g.call(ctx, "compute.regions.get", func() (err error) {
+		result, err = g.computeClient.Regions.Get(g.projectID, region).Context(ctx).Do()
+		return err
+	})
+
+	return result, err
 }
 
-func (g *gcpClient) ListZones() (*compute.ZoneList, error) {
-	return g.computeClient.Zones.List(g.projectID).Context(context.TODO()).Do()
+func (g *gcpClient) GetMachineType(ctx context.Context, zone, machineType string) (*compute.MachineType, error) {
+	var result *compute.MachineType
+
+	err := g.call(ctx, "compute.machineTypes.get", func() (err error) {
+		result, err = g.computeClient.MachineTypes.Get(g.projectID, zone, machineType).Context(ctx).Do()
+		return err
+	})
+
+	return result, err
 }
 
 func (g *gcpClient) InstanceHasPublicIP(instance *compute.Instance) (bool, error) {
@@ -117,13 +264,14 @@ func (g *gcpClient) InstanceHasPublicIP(instance *compute.Instance) (bool, error
 	return len(networkInterface.AccessConfigs) > 0, nil
 }
 
-func (g *gcpClient) UpdateInstanceNetworkTags(project, zone, instance string, tags *compute.Tags) error {
-	_, err := g.computeClient.Instances.SetTags(project, zone, instance, tags).Context(context.TODO()).Do()
-
-	return err
+func (g *gcpClient) UpdateInstanceNetworkTags(ctx context.Context, project, zone, instance string, tags *compute.Tags) error {
+	return g.mutate(ctx, "compute.instances.setTags", instanceResource(project, zone, instance), tags, func() error {
+		_, err := g.computeClient.Instances.SetTags(project, zone, instance, tags).Context(ctx).Do()
+		return err
+	})
 }
 
-func (g *gcpClient) ConfigurePublicIPOnInstance(instance *compute.Instance) error {
+func (g *gcpClient) ConfigurePublicIPOnInstance(ctx context.Context, instance *compute.Instance) error {
 	if len(instance.NetworkInterfaces) == 0 {
 		return fmt.Errorf("there are no network interfaces for instance %s", instance.Name)
 	}
@@ -136,14 +284,16 @@ func (g *gcpClient) ConfigurePublicIPOnInstance(instance *compute.Instance) erro
 		return nil
 	}
 
-	_, err := g.computeClient.Instances.AddAccessConfig(g.projectID, zone, instance.Name,
-		networkInterface.Name, &compute.AccessConfig{}).
-		Context(context.TODO()).Do()
-
-	return err
+	return g.insert(ctx, "compute.instances.addAccessConfig", instanceResource(g.projectID, zone, instance.Name),
+		map[string]string{"networkInterface": networkInterface.Name}, func() error {
+			_, err := g.computeClient.Instances.AddAccessConfig(g.projectID, zone, instance.Name,
+				networkInterface.Name, &compute.AccessConfig{}).
+				Context(ctx).Do()
+			return err
+		})
 }
 
-func (g *gcpClient) DeletePublicIPOnInstance(instance *compute.Instance) error {
+func (g *gcpClient) DeletePublicIPOnInstance(ctx context.Context, instance *compute.Instance) error {
 	if len(instance.NetworkInterfaces) == 0 {
 		return fmt.Errorf("there are no network interfaces for instance %s", instance.Name)
 	}
@@ -151,9 +301,26 @@ func (g *gcpClient) DeletePublicIPOnInstance(instance *compute.Instance) error {
 	// The zone of an instance is on URL, so we just need the latest value
 	zone := instance.Zone[strings.LastIndex(instance.Zone, "/")+1:]
 	networkInterface := instance.NetworkInterfaces[0]
-	_, err := g.computeClient.Instances.DeleteAccessConfig(
-		g.projectID, zone, instance.Name, "External NAT", networkInterface.Name).
-		Context(context.TODO()).Do()
+	return g.mutate(ctx, "compute.instances.deleteAccessConfig", instanceResource(g.projectID, zone, instance.Name),
+		map[string]string{"networkInterface": networkInterface.Name, "accessConfig": "External NAT"}, func() error {
+			_, err := g.computeClient.Instances.DeleteAccessConfig(
+				g.projectID, zone, instance.Name, "External NAT", networkInterface.Name).
+				Context(ctx).Do()
+			return err
+		})
+}
 
-	return err
+func (g *gcpClient) TestIamPermissions(ctx context.Context, permissions []string) ([]string, error) {
+	var response *cloudresourcemanager.TestIamPermissionsResponse
+
+	err := g.call(ctx, "cloudresourcemanager.projects.testIamPermissions", func() (err error) {
+		response, err = g.resourceManagerClient.Projects.TestIamPermissions(g.projectID,
+			&cloudresourcemanager.TestIamPermissionsRequest{Permissions: permissions}).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Permissions, nil
 }
@@ -0,0 +1,141 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/gcp/client"
+	"github.com/submariner-io/cloud-prepare/pkg/retry"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var _ = Describe("Retries", func() {
+	var (
+		server    *httptest.Server
+		mutex     sync.Mutex
+		responses []int
+		requests  int
+		gcpClient client.Interface
+	)
+
+	BeforeEach(func() {
+		responses = nil
+		requests = 0
+
+		// The server replies with the given statuses in turn, then with 200.
+		server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			status := http.StatusOK
+			if requests < len(responses) {
+				status = responses[requests]
+			}
+
+			requests++
+
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(status)
+
+			if status == http.StatusOK {
+				Expect(json.NewEncoder(writer).Encode(map[string]interface{}{"name": "operation"})).To(Succeed())
+				return
+			}
+
+			Expect(json.NewEncoder(writer).Encode(map[string]interface{}{
+				"error": map[string]interface{}{"code": status, "message": "fake error"},
+			})).To(Succeed())
+		}))
+
+		var err error
+
+		gcpClient, err = client.NewClient("test-project", []option.ClientOption{
+			option.WithEndpoint(server.URL + "/"), option.WithoutAuthentication(),
+		})
+		Expect(err).To(Succeed())
+		Expect(client.UseRetryPolicy(gcpClient, &retry.Policy{
+			Backoff: wait.Backoff{Steps: 2, Duration: time.Millisecond, Factor: 1},
+		})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	sentRequests := func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return requests
+	}
+
+	insertFirewallRule := func() error {
+		return gcpClient.InsertFirewallRule(context.TODO(), "test-project", &compute.Firewall{Name: "test-rule"})
+	}
+
+	When("a call fails with a transient error", func() {
+		It("should retry it as many times as the policy allows", func() {
+			responses = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable}
+
+			_, err := gcpClient.GetFirewallRule(context.TODO(), "test-project", "test-rule")
+			Expect(errors.Is(err, api.ErrTransient)).To(BeTrue())
+			Expect(sentRequests()).To(Equal(3))
+		})
+	})
+
+	When("a call fails with an error which isn't transient", func() {
+		It("should not retry it", func() {
+			responses = []int{http.StatusNotFound}
+
+			_, err := gcpClient.GetFirewallRule(context.TODO(), "test-project", "test-rule")
+			Expect(errors.Is(err, api.ErrNotFound)).To(BeTrue())
+			Expect(sentRequests()).To(Equal(1))
+		})
+	})
+
+	When("a retried insert conflicts with the attempt which failed", func() {
+		It("should succeed", func() {
+			responses = []int{http.StatusServiceUnavailable, http.StatusConflict}
+
+			Expect(insertFirewallRule()).To(Succeed())
+			Expect(sentRequests()).To(Equal(2))
+		})
+	})
+
+	When("an insert conflicts on its first attempt", func() {
+		It("should fail", func() {
+			responses = []int{http.StatusConflict}
+
+			Expect(errors.Is(insertFirewallRule(), api.ErrConflict)).To(BeTrue())
+			Expect(sentRequests()).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,76 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"google.golang.org/api/googleapi"
+)
+
+var reasonCategories = map[string]error{
+	"quotaExceeded":                  api.ErrQuotaExceeded,
+	"limitExceeded":                  api.ErrQuotaExceeded,
+	"rateLimitExceeded":              api.ErrTransient,
+	"userRateLimitExceeded":          api.ErrTransient,
+	"alreadyExists":                  api.ErrConflict,
+	"resourceInUseByAnotherResource": api.ErrConflict,
+}
+
+var statusCategories = map[int]error{
+	http.StatusUnauthorized:        api.ErrPermissionDenied,
+	http.StatusForbidden:           api.ErrPermissionDenied,
+	http.StatusNotFound:            api.ErrNotFound,
+	http.StatusConflict:            api.ErrConflict,
+	http.StatusPreconditionFailed:  api.ErrConflict,
+	http.StatusTooManyRequests:     api.ErrTransient,
+	http.StatusInternalServerError: api.ErrTransient,
+	http.StatusBadGateway:          api.ErrTransient,
+	http.StatusServiceUnavailable:  api.ErrTransient,
+	http.StatusGatewayTimeout:      api.ErrTransient,
+	http.StatusNotImplemented:      api.ErrUnsupported,
+}
+
+// isRetriable returns true for rate limiting errors, such as 429 or rateLimitExceeded, and the other transient errors.
+func isRetriable(err error) bool {
+	return errors.Is(err, api.ErrTransient)
+}
+
+// categorizeError puts the errors returned by the GCP API in the api error categories, based on the reason given by
+// the API if any, otherwise on the HTTP status.
+func categorizeError(err error) error {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		return err
+	}
+
+	for _, item := range gerr.Errors {
+		if category, ok := reasonCategories[item.Reason]; ok {
+			return api.NewError(category, err)
+		}
+	}
+
+	if category, ok := statusCategories[gerr.Code]; ok {
+		return api.NewError(category, err)
+	}
+
+	return err
+}
@@ -0,0 +1,114 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/gcp/client"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+var _ = Describe("Error categories", func() {
+	var (
+		server *httptest.Server
+		status int
+		reason string
+	)
+
+	BeforeEach(func() {
+		reason = ""
+
+		server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+			item := map[string]interface{}{"message": "fake error"}
+			if reason != "" {
+				item["reason"] = reason
+			}
+
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(status)
+			Expect(json.NewEncoder(writer).Encode(map[string]interface{}{
+				"error": map[string]interface{}{"code": status, "message": "fake error", "errors": []interface{}{item}},
+			})).To(Succeed())
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// getFirewallRule makes a call to the fake endpoint, which fails with the given status and reason.
+	getFirewallRule := func(withStatus int, withReason string) error {
+		status = withStatus
+		reason = withReason
+
+		gcpClient, err := client.NewClient("test-project", []option.ClientOption{
+			option.WithEndpoint(server.URL + "/"), option.WithoutAuthentication(),
+		})
+		Expect(err).To(Succeed())
+		Expect(client.UseRetryPolicy(gcpClient, nil)).To(Succeed())
+
+		_, err = gcpClient.GetFirewallRule(context.TODO(), "test-project", "test-rule")
+
+		return err
+	}
+
+	table.DescribeTable("should put the GCP API errors in their category",
+		func(status int, reason string, category error) {
+			err := getFirewallRule(status, reason)
+
+			Expect(errors.Is(err, category)).To(BeTrue())
+			Expect(errors.As(err, new(*googleapi.Error))).To(BeTrue())
+		},
+		table.Entry("quota exceeded", http.StatusForbidden, "quotaExceeded", api.ErrQuotaExceeded),
+		table.Entry("limit exceeded", http.StatusForbidden, "limitExceeded", api.ErrQuotaExceeded),
+		table.Entry("rate limit exceeded", http.StatusForbidden, "rateLimitExceeded", api.ErrTransient),
+		table.Entry("user rate limit exceeded", http.StatusForbidden, "userRateLimitExceeded", api.ErrTransient),
+		table.Entry("already exists", http.StatusConflict, "alreadyExists", api.ErrConflict),
+		table.Entry("in use", http.StatusBadRequest, "resourceInUseByAnotherResource", api.ErrConflict),
+		table.Entry("unauthorized", http.StatusUnauthorized, "", api.ErrPermissionDenied),
+		table.Entry("forbidden", http.StatusForbidden, "forbidden", api.ErrPermissionDenied),
+		table.Entry("not found", http.StatusNotFound, "notFound", api.ErrNotFound),
+		table.Entry("conflict", http.StatusConflict, "", api.ErrConflict),
+		table.Entry("precondition failed", http.StatusPreconditionFailed, "conditionNotMet", api.ErrConflict),
+		table.Entry("too many requests", http.StatusTooManyRequests, "", api.ErrTransient),
+		table.Entry("internal error", http.StatusInternalServerError, "backendError", api.ErrTransient),
+		table.Entry("bad gateway", http.StatusBadGateway, "", api.ErrTransient),
+		table.Entry("service unavailable", http.StatusServiceUnavailable, "", api.ErrTransient),
+		table.Entry("gateway timeout", http.StatusGatewayTimeout, "", api.ErrTransient),
+		table.Entry("not implemented", http.StatusNotImplemented, "", api.ErrUnsupported),
+	)
+
+	It("should return the other errors as is", func() {
+		err := getFirewallRule(http.StatusBadRequest, "invalid")
+
+		var gerr *googleapi.Error
+		Expect(errors.As(err, &gerr)).To(BeTrue())
+		Expect(err).To(Equal(gerr))
+	})
+})
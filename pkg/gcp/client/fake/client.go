@@ -22,6 +22,7 @@ limitations under the License.
 package fake
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -52,89 +53,119 @@ func (m *MockInterface) EXPECT() *MockInterfaceMockRecorder {
 }
 
 // ConfigurePublicIPOnInstance mocks base method.
-func (m *MockInterface) ConfigurePublicIPOnInstance(instance *compute.Instance) error {
+func (m *MockInterface) ConfigurePublicIPOnInstance(ctx context.Context, instance *compute.Instance) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ConfigurePublicIPOnInstance", instance)
+	ret := m.ctrl.Call(m, "ConfigurePublicIPOnInstance", ctx, instance)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // ConfigurePublicIPOnInstance indicates an expected call of ConfigurePublicIPOnInstance.
-func (mr *MockInterfaceMockRecorder) ConfigurePublicIPOnInstance(instance interface{}) *gomock.Call {
+func (mr *MockInterfaceMockRecorder) ConfigurePublicIPOnInstance(ctx, instance interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfigurePublicIPOnInstance", reflect.TypeOf((*MockInterface)(nil).ConfigurePublicIPOnInstance), instance)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfigurePublicIPOnInstance", reflect.TypeOf((*MockInterface)(nil).ConfigurePublicIPOnInstance), ctx, instance)
 }
 
 // DeleteFirewallRule mocks base method.
-func (m *MockInterface) DeleteFirewallRule(projectID, name string) error {
+func (m *MockInterface) DeleteFirewallRule(ctx context.Context, projectID, name string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteFirewallRule", projectID, name)
+	ret := m.ctrl.Call(m, "DeleteFirewallRule", ctx, projectID, name)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteFirewallRule indicates an expected call of DeleteFirewallRule.
-func (mr *MockInterfaceMockRecorder) DeleteFirewallRule(projectID, name interface{}) *gomock.Call {
+func (mr *MockInterfaceMockRecorder) DeleteFirewallRule(ctx, projectID, name interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFirewallRule", reflect.TypeOf((*MockInterface)(nil).DeleteFirewallRule), projectID, name)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFirewallRule", reflect.TypeOf((*MockInterface)(nil).DeleteFirewallRule), ctx, projectID, name)
 }
 
 // DeletePublicIPOnInstance mocks base method.
-func (m *MockInterface) DeletePublicIPOnInstance(instance *compute.Instance) error {
+func (m *MockInterface) DeletePublicIPOnInstance(ctx context.Context, instance *compute.Instance) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeletePublicIPOnInstance", instance)
+	ret := m.ctrl.Call(m, "DeletePublicIPOnInstance", ctx, instance)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeletePublicIPOnInstance indicates an expected call of DeletePublicIPOnInstance.
-func (mr *MockInterfaceMockRecorder) DeletePublicIPOnInstance(instance interface{}) *gomock.Call {
+func (mr *MockInterfaceMockRecorder) DeletePublicIPOnInstance(ctx, instance interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePublicIPOnInstance", reflect.TypeOf((*MockInterface)(nil).DeletePublicIPOnInstance), instance)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePublicIPOnInstance", reflect.TypeOf((*MockInterface)(nil).DeletePublicIPOnInstance), ctx, instance)
 }
 
 // GetFirewallRule mocks base method.
-func (m *MockInterface) GetFirewallRule(projectID, name string) (*compute.Firewall, error) {
+func (m *MockInterface) GetFirewallRule(ctx context.Context, projectID, name string) (*compute.Firewall, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetFirewallRule", projectID, name)
+	ret := m.ctrl.Call(m, "GetFirewallRule", ctx, projectID, name)
 	ret0, _ := ret[0].(*compute.Firewall)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetFirewallRule indicates an expected call of GetFirewallRule.
-func (mr *MockInterfaceMockRecorder) GetFirewallRule(projectID, name interface{}) *gomock.Call {
+func (mr *MockInterfaceMockRecorder) GetFirewallRule(ctx, projectID, name interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFirewallRule", reflect.TypeOf((*MockInterface)(nil).GetFirewallRule), projectID, name)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFirewallRule", reflect.TypeOf((*MockInterface)(nil).GetFirewallRule), ctx, projectID, name)
 }
 
 // GetInstance mocks base method.
-func (m *MockInterface) GetInstance(zone, instance string) (*compute.Instance, error) {
+func (m *MockInterface) GetInstance(ctx context.Context, zone, instance string) (*compute.Instance, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetInstance", zone, instance)
+	ret := m.ctrl.Call(m, "GetInstance", ctx, zone, instance)
 	ret0, _ := ret[0].(*compute.Instance)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetInstance indicates an expected call of GetInstance.
-func (mr *MockInterfaceMockRecorder) GetInstance(zone, instance interface{}) *gomock.Call {
+func (mr *MockInterfaceMockRecorder) GetInstance(ctx, zone, instance interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInstance", reflect.TypeOf((*MockInterface)(nil).GetInstance), zone, instance)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInstance", reflect.TypeOf((*MockInterface)(nil).GetInstance), ctx, zone, instance)
+}
+
+// GetMachineType mocks base method.
+func (m *MockInterface) GetMachineType(ctx context.Context, zone, machineType string) (*compute.MachineType, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMachineType", ctx, zone, machineType)
+	ret0, _ := ret[0].(*compute.MachineType)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMachineType indicates an expected call of GetMachineType.
+func (mr *MockInterfaceMockRecorder) GetMachineType(ctx, zone, machineType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMachineType", reflect.TypeOf((*MockInterface)(nil).GetMachineType), ctx, zone, machineType)
+}
+
+// GetRegion mocks base method.
+func (m *MockInterface) GetRegion(ctx context.Context, region string) (*compute.Region, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRegion", ctx, region)
+	ret0, _ := ret[0].(*compute.Region)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRegion indicates an expected call of GetRegion.
+func (mr *MockInterfaceMockRecorder) GetRegion(ctx, region interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRegion", reflect.TypeOf((*MockInterface)(nil).GetRegion), ctx, region)
 }
 
 // InsertFirewallRule mocks base method.
-func (m *MockInterface) InsertFirewallRule(projectID string, rule *compute.Firewall) error {
+func (m *MockInterface) InsertFirewallRule(ctx context.Context, projectID string, rule *compute.Firewall) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InsertFirewallRule", projectID, rule)
+	ret := m.ctrl.Call(m, "InsertFirewallRule", ctx, projectID, rule)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // InsertFirewallRule indicates an expected call of InsertFirewallRule.
-func (mr *MockInterfaceMockRecorder) InsertFirewallRule(projectID, rule interface{}) *gomock.Call {
+func (mr *MockInterfaceMockRecorder) InsertFirewallRule(ctx, projectID, rule interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertFirewallRule", reflect.TypeOf((*MockInterface)(nil).InsertFirewallRule), projectID, rule)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertFirewallRule", reflect.TypeOf((*MockInterface)(nil).InsertFirewallRule), ctx, projectID, rule)
 }
 
 // InstanceHasPublicIP mocks base method.
@@ -153,59 +184,74 @@ func (mr *MockInterfaceMockRecorder) InstanceHasPublicIP(instance interface{}) *
 }
 
 // ListInstances mocks base method.
-func (m *MockInterface) ListInstances(zone string) (*compute.InstanceList, error) {
+func (m *MockInterface) ListInstances(ctx context.Context, zone string) (*compute.InstanceList, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListInstances", zone)
+	ret := m.ctrl.Call(m, "ListInstances", ctx, zone)
 	ret0, _ := ret[0].(*compute.InstanceList)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ListInstances indicates an expected call of ListInstances.
-func (mr *MockInterfaceMockRecorder) ListInstances(zone interface{}) *gomock.Call {
+func (mr *MockInterfaceMockRecorder) ListInstances(ctx, zone interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListInstances", reflect.TypeOf((*MockInterface)(nil).ListInstances), zone)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListInstances", reflect.TypeOf((*MockInterface)(nil).ListInstances), ctx, zone)
 }
 
 // ListZones mocks base method.
-func (m *MockInterface) ListZones() (*compute.ZoneList, error) {
+func (m *MockInterface) ListZones(ctx context.Context) (*compute.ZoneList, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListZones")
+	ret := m.ctrl.Call(m, "ListZones", ctx)
 	ret0, _ := ret[0].(*compute.ZoneList)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ListZones indicates an expected call of ListZones.
-func (mr *MockInterfaceMockRecorder) ListZones() *gomock.Call {
+func (mr *MockInterfaceMockRecorder) ListZones(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListZones", reflect.TypeOf((*MockInterface)(nil).ListZones), ctx)
+}
+
+// TestIamPermissions mocks base method.
+func (m *MockInterface) TestIamPermissions(ctx context.Context, permissions []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TestIamPermissions", ctx, permissions)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TestIamPermissions indicates an expected call of TestIamPermissions.
+func (mr *MockInterfaceMockRecorder) TestIamPermissions(ctx, permissions interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListZones", reflect.TypeOf((*MockInterface)(nil).ListZones))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TestIamPermissions", reflect.TypeOf((*MockInterface)(nil).TestIamPermissions), ctx, permissions)
 }
 
 // UpdateFirewallRule mocks base method.
-func (m *MockInterface) UpdateFirewallRule(projectID, name string, rule *compute.Firewall) error {
+func (m *MockInterface) UpdateFirewallRule(ctx context.Context, projectID, name string, rule *compute.Firewall) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateFirewallRule", projectID, name, rule)
+	ret := m.ctrl.Call(m, "UpdateFirewallRule", ctx, projectID, name, rule)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateFirewallRule indicates an expected call of UpdateFirewallRule.
-func (mr *MockInterfaceMockRecorder) UpdateFirewallRule(projectID, name, rule interface{}) *gomock.Call {
+func (mr *MockInterfaceMockRecorder) UpdateFirewallRule(ctx, projectID, name, rule interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFirewallRule", reflect.TypeOf((*MockInterface)(nil).UpdateFirewallRule), projectID, name, rule)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFirewallRule", reflect.TypeOf((*MockInterface)(nil).UpdateFirewallRule), ctx, projectID, name, rule)
 }
 
 // UpdateInstanceNetworkTags mocks base method.
-func (m *MockInterface) UpdateInstanceNetworkTags(project, zone, instance string, tags *compute.Tags) error {
+func (m *MockInterface) UpdateInstanceNetworkTags(ctx context.Context, project, zone, instance string, tags *compute.Tags) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateInstanceNetworkTags", project, zone, instance, tags)
+	ret := m.ctrl.Call(m, "UpdateInstanceNetworkTags", ctx, project, zone, instance, tags)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateInstanceNetworkTags indicates an expected call of UpdateInstanceNetworkTags.
-func (mr *MockInterfaceMockRecorder) UpdateInstanceNetworkTags(project, zone, instance, tags interface{}) *gomock.Call {
+func (mr *MockInterfaceMockRecorder) UpdateInstanceNetworkTags(ctx, project, zone, instance, tags interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateInstanceNetworkTags", reflect.TypeOf((*MockInterface)(nil).UpdateInstanceNetworkTags), project, zone, instance, tags)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateInstanceNetworkTags", reflect.TypeOf((*MockInterface)(nil).UpdateInstanceNetworkTags), ctx, project, zone, instance, tags)
 }
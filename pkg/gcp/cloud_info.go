@@ -19,6 +19,9 @@ limitations under the License.
 package gcp
 
 import (
+	"context"
+	"strings"
+
 	"github.com/pkg/errors"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	gcpclient "github.com/submariner-io/cloud-prepare/pkg/gcp/client"
@@ -30,38 +33,90 @@ type CloudInfo struct {
 	Region    string
 	ProjectID string
 	Client    gcpclient.Interface
+
+	// Inventory, if set, records the resources which are created, and drives their cleanup.
+	Inventory api.Inventory
 }
 
 // Open expected ports by creating related firewall rule.
 // - if the firewall rule is not found, we will create it.
 // - if the firewall rule is found and changed, we will update it.
-func (c *CloudInfo) openPorts(rules ...*compute.Firewall) error {
+// If the operation is rolled back, created rules are deleted and updated rules are restored.
+func (c *CloudInfo) openPorts(ctx context.Context, scope api.InventoryScope, rules ...*compute.Firewall) error {
 	for _, rule := range rules {
-		_, err := c.Client.GetFirewallRule(c.ProjectID, rule.Name)
-		if gcpclient.IsGCPNotFoundError(err) {
-			if err := c.Client.InsertFirewallRule(c.ProjectID, rule); err != nil {
-				return errors.Wrapf(err, "error inserting firewall rule %#v", rule)
-			}
+		existing, err := c.Client.GetFirewallRule(ctx, c.ProjectID, rule.Name)
+		found := !gcpclient.IsGCPNotFoundError(err)
 
-			continue
+		if found && err != nil {
+			return errors.Wrapf(err, "error retrieving firewall rule %q", rule.Name)
+		}
+
+		if found {
+			err = c.Client.UpdateFirewallRule(ctx, c.ProjectID, rule.Name, rule)
+		} else {
+			err = c.Client.InsertFirewallRule(ctx, c.ProjectID, rule)
 		}
 
 		if err != nil {
-			return errors.Wrapf(err, "error retrieving firewall rule %q", rule.Name)
+			return errors.Wrapf(err, "error applying firewall rule %#v", rule)
 		}
 
-		if err := c.Client.UpdateFirewallRule(c.ProjectID, rule.Name, rule); err != nil {
-			return errors.Wrapf(err, "error updating firewall rule %#v", rule)
+		if err := c.record(ctx, scope, api.FirewallRuleResource, rule.Name, "", found); err != nil {
+			return err
 		}
+
+		if !found {
+			existing = nil
+		}
+
+		c.onFirewallRuleRollback(ctx, scope, rule.Name, existing)
 	}
 
 	return nil
 }
 
-func (c *CloudInfo) deleteFirewallRule(name string, reporter api.Reporter) error {
-	reporter.Started("Deleting firewall rule %q on GCP", name)
+// onFirewallRuleRollback registers the restoration of the named firewall rule to its previous state, or its deletion
+// if it didn't exist, if the operation is rolled back.
+func (c *CloudInfo) onFirewallRuleRollback(ctx context.Context, scope api.InventoryScope, name string, existing *compute.Firewall) {
+	if existing != nil {
+		api.OnRollback(ctx, "restore firewall rule "+name, func(ctx context.Context) error {
+			return errors.Wrapf(c.Client.UpdateFirewallRule(ctx, c.ProjectID, name, existing), "error restoring firewall rule %q", name)
+		})
+
+		return
+	}
 
-	if err := c.Client.DeleteFirewallRule(c.ProjectID, name); err != nil {
+	api.OnRollback(ctx, "delete firewall rule "+name, func(ctx context.Context) error {
+		err := c.Client.DeleteFirewallRule(ctx, c.ProjectID, name)
+		if err != nil && !gcpclient.IsGCPNotFoundError(err) {
+			return errors.Wrapf(err, "error deleting firewall rule %q", name)
+		}
+
+		return c.forget(ctx, scope, api.FirewallRuleResource, name, "")
+	})
+}
+
+// record records the resource in the inventory, if there is one.
+func (c *CloudInfo) record(ctx context.Context, scope api.InventoryScope, kind api.ResourceKind, id, parent string,
+	preExisting bool) error {
+	return api.RecordResource(ctx, c.Inventory, api.InventoryResource{
+		Scope:       scope,
+		Kind:        kind,
+		ID:          id,
+		Parent:      parent,
+		PreExisting: preExisting,
+	})
+}
+
+// forget removes the resource from the inventory, if there is one.
+func (c *CloudInfo) forget(ctx context.Context, scope api.InventoryScope, kind api.ResourceKind, id, parent string) error {
+	return api.ForgetResource(ctx, c.Inventory, api.InventoryResource{Scope: scope, Kind: kind, ID: id, Parent: parent})
+}
+
+func (c *CloudInfo) deleteFirewallRule(ctx context.Context, name string, reporter api.Reporter) error {
+	api.ForResource(reporter, api.FirewallRuleResource, name).Started("Deleting firewall rule %q on GCP", name)
+
+	if err := c.Client.DeleteFirewallRule(ctx, c.ProjectID, name); err != nil {
 		if !gcpclient.IsGCPNotFoundError(err) {
 			reporter.Failed(err)
 			return errors.Wrapf(err, "error deleting firewall rule %q", name)
@@ -72,3 +127,67 @@ func (c *CloudInfo) deleteFirewallRule(name string, reporter api.Reporter) error
 
 	return nil
 }
+
+// resetInventoriedInstance removes the gateway tag or public IP recorded in the inventory from its instance.
+func (c *CloudInfo) resetInventoriedInstance(ctx context.Context, resource *api.InventoryResource) error {
+	instance, err := c.Client.GetInstance(ctx, resource.Parent, resource.ID)
+	if gcpclient.IsGCPNotFoundError(err) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "error retrieving GCP instance %q in zone %q", resource.ID, resource.Parent)
+	}
+
+	if resource.Kind == api.InstanceTagResource {
+		return c.removeGatewayTag(ctx, resource.Parent, instance)
+	}
+
+	return errors.Wrapf(c.Client.DeletePublicIPOnInstance(ctx, instance), "error deleting public IP for GCP instance %q in zone %q",
+		instance.Name, resource.Parent)
+}
+
+func (c *CloudInfo) ignoreZone(zone *compute.Zone) bool {
+	region := zone.Region[strings.LastIndex(zone.Region, "/")+1:]
+
+	return region != c.Region
+}
+
+func (c *CloudInfo) isInstanceGatewayNode(instance *compute.Instance) bool {
+	if instance.Tags == nil {
+		return false
+	}
+
+	for _, tag := range instance.Tags.Items {
+		if tag == submarinerGatewayNodeTag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *CloudInfo) removeGatewayTag(ctx context.Context, zone string, instance *compute.Instance) error {
+	if instance.Tags == nil {
+		return nil
+	}
+
+	for i := range instance.Tags.Items {
+		if instance.Tags.Items[i] == submarinerGatewayNodeTag {
+			instance.Tags.Items = append(instance.Tags.Items[:i], instance.Tags.Items[i+1:]...)
+			break
+		}
+	}
+
+	tags := &compute.Tags{
+		Items:       instance.Tags.Items,
+		Fingerprint: instance.Tags.Fingerprint,
+	}
+
+	err := c.Client.UpdateInstanceNetworkTags(ctx, c.ProjectID, zone, instance.Name, tags)
+	if err != nil {
+		return errors.Wrapf(err, "error updating network tags for GCP instance %q in zode %q", instance.Name, zone)
+	}
+
+	return c.forget(ctx, api.GatewayScope, api.InstanceTagResource, instance.Name, zone)
+}
@@ -0,0 +1,107 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	gcpclient "github.com/submariner-io/cloud-prepare/pkg/gcp/client"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	"google.golang.org/api/option"
+)
+
+// Config is the configuration used to create the GCP Cloud and GatewayDeployer with NewProvider.
+type Config struct {
+	InfraID   string `json:"infraID"`
+	Region    string `json:"region"`
+	ProjectID string `json:"projectID"`
+
+	// CredentialsFile is the service account key file; the application default credentials are used if empty.
+	CredentialsFile string `json:"credentialsFile,omitempty"`
+
+	// DedicatedGateway deploys dedicated gateway nodes of InstanceType, using Image if set, instead of
+	// using existing worker nodes.
+	DedicatedGateway bool   `json:"dedicatedGateway,omitempty"`
+	InstanceType     string `json:"instanceType,omitempty"`
+	Image            string `json:"image,omitempty"`
+
+	// Inventory, if set, records the resources which are created, and drives their cleanup.
+	Inventory api.Inventory `json:"-"`
+}
+
+// Validate returns an api.MissingFieldsError if any required field is missing.
+func (c *Config) Validate() error {
+	missing := &api.MissingFieldsError{Provider: "GCP"}
+
+	if c.InfraID == "" {
+		missing.Add("infraID", "")
+	}
+
+	if c.Region == "" {
+		missing.Add("region", "")
+	}
+
+	if c.ProjectID == "" {
+		missing.Add("projectID", "")
+	}
+
+	if c.DedicatedGateway && c.InstanceType == "" {
+		missing.Add("instanceType", "for dedicated gateways")
+	}
+
+	return missing.OrNil()
+}
+
+// NewProvider creates the GCP Cloud and GatewayDeployer for the given configuration. The MachineSetDeployer is only
+// required for dedicated gateways.
+func NewProvider(config *Config, msDeployer ocp.MachineSetDeployer, k8sClient k8s.Interface) (api.Cloud, api.GatewayDeployer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if k8sClient == nil {
+		return nil, nil, errors.New("the GCP provider requires a Kubernetes client")
+	}
+
+	if config.DedicatedGateway && msDeployer == nil {
+		return nil, nil, errors.New("the GCP provider requires a MachineSetDeployer for dedicated gateways")
+	}
+
+	options := []option.ClientOption{}
+	if config.CredentialsFile != "" {
+		options = append(options, option.WithCredentialsFile(config.CredentialsFile))
+	}
+
+	client, err := gcpclient.NewClient(config.ProjectID, options)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error creating the GCP client")
+	}
+
+	info := CloudInfo{
+		InfraID:   config.InfraID,
+		Region:    config.Region,
+		ProjectID: config.ProjectID,
+		Client:    client,
+		Inventory: config.Inventory,
+	}
+
+	return NewCloud(info), NewOcpGatewayDeployer(info, msDeployer, config.InstanceType, config.Image, config.DedicatedGateway, k8sClient),
+		nil
+}
@@ -21,7 +21,10 @@ package gcp
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/stringset"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	"google.golang.org/api/compute/v1"
 )
@@ -29,27 +32,85 @@ import (
 const (
 	ingressDirection         = "INGRESS"
 	publicPortsRuleName      = "submariner-public-ports"
+	publicPortsV6RuleName    = "submariner-public-ports-v6"
 	internalPortsRuleName    = "submariner-internal-ports"
 	submarinerGatewayNodeTag = "submariner-io-gateway-node"
 )
 
-func newExternalFirewallRules(projectID, infraID string, ports []api.PortSpec) (ingress *compute.Firewall) {
-	ingressName := generateRuleName(infraID, publicPortsRuleName)
+// GatewayNodeTag is the network tag of the gateway instances, which the public firewall rules target.
+const GatewayNodeTag = submarinerGatewayNodeTag
 
-	// We want the external firewall rules to be applied only to Gateway nodes. So, we use the TargetTags
-	// field and include submarinerGatewayNodeTag for selection of Gateway nodes. All the Submariner Gateway
-	// instances will be tagged with submarinerGatewayNodeTag.
-	ingressRule := newFirewallRule(projectID, infraID, ingressName, ingressDirection, ports)
-	ingressRule.TargetTags = []string{
-		submarinerGatewayNodeTag,
+// ValidatePublicPorts checks that the public ports can be opened by the gateway firewall rules: the source ranges apply
+// to the whole rule, so all the ports must be restricted to the same sources.
+func ValidatePublicPorts(infraID string, ports []api.PortSpec) error {
+	for i := range ports {
+		if !sameSources(ports[i].Sources(), ports[0].Sources()) {
+			return fmt.Errorf("the public ports %s and %s have different source CIDRs, which GCP firewall rule %q "+
+				"doesn't support", ports[0], ports[i], PublicRuleName(infraID, api.IPv4Family))
+		}
+	}
+
+	return nil
+}
+
+func newExternalFirewallRules(projectID, infraID string, ports []api.PortSpec, families []api.IPFamily) ([]*compute.Firewall, error) {
+	if err := ValidatePublicPorts(infraID, ports); err != nil {
+		return nil, err
+	}
+
+	var sourcePort api.PortSpec
+	if len(ports) > 0 {
+		sourcePort = ports[0]
+	}
+
+	rules := []*compute.Firewall{}
+
+	for _, family := range families {
+		ingressName := PublicRuleName(infraID, family)
+
+		// We want the external firewall rules to be applied only to Gateway nodes. So, we use the TargetTags
+		// field and include submarinerGatewayNodeTag for selection of Gateway nodes. All the Submariner Gateway
+		// instances will be tagged with submarinerGatewayNodeTag.
+		ingressRule := newFirewallRule(projectID, infraID, ingressName, ingressDirection, ports)
+		ingressRule.TargetTags = []string{
+			submarinerGatewayNodeTag,
+		}
+
+		// Without source ranges, GCP allows all IPv4 sources.
+		if family == api.IPv6Family || len(sourcePort.SourceCIDRs) > 0 {
+			ingressRule.SourceRanges = sourcePort.SourcesFor(family)
+
+			if len(ingressRule.SourceRanges) == 0 {
+				continue
+			}
+		}
+
+		rules = append(rules, ingressRule)
+	}
+
+	return rules, nil
+}
+
+// PublicRuleName returns the name of the gateway firewall rule for the given IP family. GCP doesn't allow IPv4 and IPv6
+// source ranges in the same rule, so each IP family gets its own rule.
+func PublicRuleName(infraID string, family api.IPFamily) string {
+	if family == api.IPv6Family {
+		return generateRuleName(infraID, publicPortsV6RuleName)
 	}
 
-	return ingressRule
+	return generateRuleName(infraID, publicPortsRuleName)
+}
+
+// InternalRuleName returns the name of the firewall rule opening the internal ports.
+func InternalRuleName(infraID string) string {
+	return generateRuleName(infraID, internalPortsRuleName)
 }
 
 func newInternalFirewallRule(projectID, infraID string, ports []api.PortSpec) *compute.Firewall {
-	ingressName := generateRuleName(infraID, internalPortsRuleName)
+	ingressName := InternalRuleName(infraID)
 
+	// The sources are identified by their network tags rather than by address ranges, so this rule applies to both
+	// IPv4 and IPv6 traffic.
 	rule := newFirewallRule(projectID, infraID, ingressName, ingressDirection, ports)
 	rule.TargetTags = []string{
 		fmt.Sprintf("%s-worker", infraID),
@@ -70,8 +131,8 @@ func newFirewallRule(projectID, infraID, name, direction string, ports []api.Por
 		fwRule := &compute.FirewallAllowed{
 			IPProtocol: port.Protocol,
 		}
-		if port.Port != 0 {
-			fwRule.Ports = []string{strconv.Itoa(int(port.Port))}
+		if portString := port.PortString(); portString != "" {
+			fwRule.Ports = []string{portString}
 		}
 
 		allowedPorts = append(allowedPorts, fwRule)
@@ -85,6 +146,66 @@ func newFirewallRule(projectID, infraID, name, direction string, ports []api.Por
 	}
 }
 
+// allowedPorts returns the ports allowed by the given firewall rule, along with its source ranges.
+func allowedPorts(rule *compute.Firewall) []api.PortSpec {
+	ports := []api.PortSpec{}
+
+	for _, allowed := range rule.Allowed {
+		if len(allowed.Ports) == 0 {
+			ports = append(ports, api.PortSpec{Protocol: allowed.IPProtocol, SourceCIDRs: rule.SourceRanges})
+			continue
+		}
+
+		for _, p := range allowed.Ports {
+			port, err := parsePortRange(p)
+			if err == nil {
+				port.Protocol = allowed.IPProtocol
+				port.SourceCIDRs = rule.SourceRanges
+				ports = append(ports, port)
+			}
+		}
+	}
+
+	return ports
+}
+
+func parsePortRange(portRange string) (api.PortSpec, error) {
+	bounds := strings.SplitN(portRange, "-", 2)
+
+	from, err := strconv.ParseUint(bounds[0], 10, 16)
+	if err != nil {
+		return api.PortSpec{}, errors.Wrapf(err, "invalid port range %q", portRange)
+	}
+
+	port := api.PortSpec{Port: uint16(from)}
+
+	if len(bounds) > 1 {
+		to, err := strconv.ParseUint(bounds[1], 10, 16)
+		if err != nil {
+			return api.PortSpec{}, errors.Wrapf(err, "invalid port range %q", portRange)
+		}
+
+		port.EndPort = uint16(to)
+	}
+
+	return port, nil
+}
+
+func sameSources(sources, other []string) bool {
+	otherSet := stringset.New(other...)
+	if stringset.New(sources...).Size() != otherSet.Size() {
+		return false
+	}
+
+	for _, source := range sources {
+		if !otherSet.Contains(source) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func generateRuleName(infraID, name string) (ingressName string) {
 	return fmt.Sprintf("%s-%s-ingress", infraID, name)
 }
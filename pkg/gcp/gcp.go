@@ -19,10 +19,12 @@ limitations under the License.
 package gcp
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/metrics"
 )
 
 type gcpCloud struct {
@@ -36,11 +38,32 @@ func NewCloud(info CloudInfo) api.Cloud {
 
 // PrepareForSubmariner prepares submariner cluster environment on GCP.
 func (gc *gcpCloud) PrepareForSubmariner(input api.PrepareForSubmarinerInput, reporter api.Reporter) error {
+	return gc.PrepareForSubmarinerWithContext(context.TODO(), input, reporter)
+}
+
+// PrepareForSubmarinerWithContext prepares submariner cluster environment on GCP using the given context.
+func (gc *gcpCloud) PrepareForSubmarinerWithContext(ctx context.Context, input api.PrepareForSubmarinerInput,
+	reporter api.Reporter) error {
+	return metrics.ObserveOperation(ctx, "gcp", metrics.PrepareForSubmariner, func() error {
+		return api.RunWithRollback(ctx, input.Rollback, reporter, func(ctx context.Context) error {
+			return gc.prepareForSubmariner(ctx, input, reporter)
+		})
+	})
+}
+
+func (gc *gcpCloud) prepareForSubmariner(ctx context.Context, input api.PrepareForSubmarinerInput, reporter api.Reporter) error {
+	err := api.ValidatePrerequisites(reporter, func() error {
+		return gc.validatePermissions(ctx, preparePermissions)
+	})
+	if err != nil {
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
 	// Create the inbound firewall rule for submariner internal ports.
 	reporter.Started("Opening internal ports %q for intra-cluster communications on GCP", formatPorts(input.InternalPorts))
 
 	internalIngress := newInternalFirewallRule(gc.ProjectID, gc.InfraID, input.InternalPorts)
-	if err := gc.openPorts(internalIngress); err != nil {
+	if err := gc.openPorts(ctx, api.CloudScope, internalIngress); err != nil {
 		reporter.Failed(err)
 		return err
 	}
@@ -53,16 +76,38 @@ func (gc *gcpCloud) PrepareForSubmariner(input api.PrepareForSubmarinerInput, re
 
 // CleanupAfterSubmariner clean up submariner cluster environment on GCP.
 func (gc *gcpCloud) CleanupAfterSubmariner(reporter api.Reporter) error {
+	return gc.CleanupAfterSubmarinerWithContext(context.TODO(), reporter)
+}
+
+// CleanupAfterSubmarinerWithContext clean up submariner cluster environment on GCP using the given context.
+func (gc *gcpCloud) CleanupAfterSubmarinerWithContext(ctx context.Context, reporter api.Reporter) error {
+	err := api.ValidatePrerequisites(reporter, func() error {
+		return gc.validatePermissions(ctx, cleanupPermissions)
+	})
+	if err != nil {
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	if gc.Inventory != nil {
+		return api.CleanupInventory(ctx, gc.Inventory, api.CloudScope, func(resource *api.InventoryResource) error {
+			if resource.Kind != api.FirewallRuleResource {
+				return fmt.Errorf("unexpected %s resource in the GCP inventory", resource.Kind)
+			}
+
+			return gc.deleteFirewallRule(ctx, resource.ID, reporter)
+		})
+	}
+
 	// Delete the inbound and outbound firewall rules to close submariner internal ports.
-	internalIngressName := generateRuleName(gc.InfraID, internalPortsRuleName)
+	internalIngressName := InternalRuleName(gc.InfraID)
 
-	return gc.deleteFirewallRule(internalIngressName, reporter)
+	return gc.deleteFirewallRule(ctx, internalIngressName, reporter)
 }
 
 func formatPorts(ports []api.PortSpec) string {
 	portStrs := []string{}
 	for _, port := range ports {
-		portStrs = append(portStrs, fmt.Sprintf("%d/%s", port.Port, port.Protocol))
+		portStrs = append(portStrs, port.String())
 	}
 
 	return strings.Join(portStrs, ", ")
@@ -19,6 +19,7 @@ limitations under the License.
 package gcp_test
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
@@ -27,6 +28,7 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	"github.com/submariner-io/cloud-prepare/pkg/gcp"
+	"github.com/submariner-io/cloud-prepare/pkg/inventory"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/googleapi"
 )
@@ -35,7 +37,12 @@ const ingressRuleName = "test-infraID-submariner-internal-ports-ingress"
 
 var _ = Describe("Cloud", func() {
 	Describe("PrepareForSubmariner", testPrepareForSubmariner)
+	Describe("PrepareForSubmarinerWithContext", testPrepareForSubmarinerWithContext)
 	Describe("CleanupAfterSubmariner", testCleanupAfterSubmariner)
+	Describe("PlanPrepareForSubmariner", testPlanPrepareForSubmariner)
+	Describe("Status", testCloudStatus)
+	Describe("with an inventory", testCloudInventory)
+	Describe("ScanOrphans", testScanOrphans)
 })
 
 func testPrepareForSubmariner() {
@@ -60,17 +67,18 @@ func testPrepareForSubmariner() {
 
 	When("the firewall rule doesn't exist", func() {
 		BeforeEach(func() {
-			t.gcpClient.EXPECT().GetFirewallRule(projectID, ingressRuleName).Return(nil, &googleapi.Error{Code: http.StatusNotFound})
+			t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, ingressRuleName).Return(nil, &googleapi.Error{Code: http.StatusNotFound})
 		})
 
 		Context("", func() {
 			var actualRule *compute.Firewall
 
 			BeforeEach(func() {
-				t.gcpClient.EXPECT().InsertFirewallRule(projectID, gomock.Any()).DoAndReturn(func(_ string, rule *compute.Firewall) error {
-					actualRule = rule
-					return nil
-				})
+				t.gcpClient.EXPECT().InsertFirewallRule(gomock.Any(), projectID, gomock.Any()).DoAndReturn(
+					func(_ context.Context, _ string, rule *compute.Firewall) error {
+						actualRule = rule
+						return nil
+					})
 			})
 
 			It("should correctly insert it", func() {
@@ -83,7 +91,7 @@ func testPrepareForSubmariner() {
 
 		Context("and insertion fails", func() {
 			BeforeEach(func() {
-				t.gcpClient.EXPECT().InsertFirewallRule(projectID, gomock.Any()).Return(errors.New("fake insert error"))
+				t.gcpClient.EXPECT().InsertFirewallRule(gomock.Any(), projectID, gomock.Any()).Return(errors.New("fake insert error"))
 			})
 
 			It("should return an error", func() {
@@ -94,17 +102,18 @@ func testPrepareForSubmariner() {
 
 	When("the firewall rule already exists", func() {
 		BeforeEach(func() {
-			t.gcpClient.EXPECT().GetFirewallRule(projectID, ingressRuleName).DoAndReturn(func(_, ruleName string) (*compute.Firewall, error) {
-				return &compute.Firewall{Name: ruleName}, nil
-			})
+			t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, ingressRuleName).DoAndReturn(
+				func(_ context.Context, _, ruleName string) (*compute.Firewall, error) {
+					return &compute.Firewall{Name: ruleName}, nil
+				})
 		})
 
 		Context("", func() {
 			var actualRule *compute.Firewall
 
 			BeforeEach(func() {
-				t.gcpClient.EXPECT().UpdateFirewallRule(projectID, ingressRuleName, gomock.Any()).DoAndReturn(
-					func(_, _ string, rule *compute.Firewall) error {
+				t.gcpClient.EXPECT().UpdateFirewallRule(gomock.Any(), projectID, ingressRuleName, gomock.Any()).DoAndReturn(
+					func(_ context.Context, _, _ string, rule *compute.Firewall) error {
 						actualRule = rule
 						return nil
 					})
@@ -120,7 +129,7 @@ func testPrepareForSubmariner() {
 
 		Context("and update fails", func() {
 			BeforeEach(func() {
-				t.gcpClient.EXPECT().UpdateFirewallRule(projectID, ingressRuleName, gomock.Any()).Return(errors.New("fake update error"))
+				t.gcpClient.EXPECT().UpdateFirewallRule(gomock.Any(), projectID, ingressRuleName, gomock.Any()).Return(errors.New("fake update error"))
 			})
 
 			It("should return an error", func() {
@@ -131,13 +140,64 @@ func testPrepareForSubmariner() {
 
 	When("retrieval of the firewall rule fails", func() {
 		BeforeEach(func() {
-			t.gcpClient.EXPECT().GetFirewallRule(projectID, ingressRuleName).Return(nil, errors.New("fake get error"))
+			t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, ingressRuleName).Return(nil, errors.New("fake get error"))
 		})
 
 		It("should return an error", func() {
 			Expect(retError).ToNot(Succeed())
 		})
 	})
+
+	When("permissions are missing", func() {
+		BeforeEach(func() {
+			t.deniedPermissions["compute.firewalls.update"] = true
+			t.deniedPermissions["compute.networks.updatePolicy"] = true
+		})
+
+		It("should report them without changing the firewall rules", func() {
+			Expect(retError).To(MatchError(ContainSubstring(
+				`no permission to update firewall rules, the compute.firewalls.update permission is required on project "` +
+					projectID + `"`)))
+			Expect(retError).To(MatchError(ContainSubstring("the compute.networks.updatePolicy permission is required")))
+		})
+	})
+}
+
+func testPrepareForSubmarinerWithContext() {
+	t := newCloudTestDriver()
+
+	type ctxKey struct{}
+
+	var (
+		ctx      context.Context
+		retError error
+	)
+
+	BeforeEach(func() {
+		ctx = context.WithValue(context.Background(), ctxKey{}, "test")
+
+		t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, ingressRuleName).DoAndReturn(
+			func(actual context.Context, _, _ string) (*compute.Firewall, error) {
+				Expect(actual.Value(ctxKey{})).To(Equal("test"))
+				return nil, &googleapi.Error{Code: http.StatusNotFound}
+			})
+
+		t.gcpClient.EXPECT().InsertFirewallRule(gomock.Any(), projectID, gomock.Any()).DoAndReturn(
+			func(actual context.Context, _ string, _ *compute.Firewall) error {
+				Expect(actual.Value(ctxKey{})).To(Equal("test"))
+				return nil
+			})
+	})
+
+	JustBeforeEach(func() {
+		retError = t.cloud.PrepareForSubmarinerWithContext(ctx, api.PrepareForSubmarinerInput{
+			InternalPorts: []api.PortSpec{{Port: 100, Protocol: "TCP"}},
+		}, api.NewLoggingReporter())
+	})
+
+	It("should pass the context to the GCP client", func() {
+		Expect(retError).To(Succeed())
+	})
 }
 
 func testCleanupAfterSubmariner() {
@@ -151,7 +211,7 @@ func testCleanupAfterSubmariner() {
 
 	Context("on success", func() {
 		BeforeEach(func() {
-			t.gcpClient.EXPECT().DeleteFirewallRule(projectID, ingressRuleName).Return(nil)
+			t.gcpClient.EXPECT().DeleteFirewallRule(gomock.Any(), projectID, ingressRuleName).Return(nil)
 		})
 
 		It("should delete the firewall rule", func() {
@@ -161,7 +221,7 @@ func testCleanupAfterSubmariner() {
 
 	When("the firewall rule doesn't exist", func() {
 		BeforeEach(func() {
-			t.gcpClient.EXPECT().DeleteFirewallRule(projectID, ingressRuleName).Return(&googleapi.Error{Code: http.StatusNotFound})
+			t.gcpClient.EXPECT().DeleteFirewallRule(gomock.Any(), projectID, ingressRuleName).Return(&googleapi.Error{Code: http.StatusNotFound})
 		})
 
 		It("should succeed", func() {
@@ -171,7 +231,57 @@ func testCleanupAfterSubmariner() {
 
 	When("deletion fails", func() {
 		BeforeEach(func() {
-			t.gcpClient.EXPECT().DeleteFirewallRule(projectID, ingressRuleName).Return(errors.New("fake delete error"))
+			t.gcpClient.EXPECT().DeleteFirewallRule(gomock.Any(), projectID, ingressRuleName).Return(errors.New("fake delete error"))
+		})
+
+		It("should return an error", func() {
+			Expect(retError).ToNot(Succeed())
+		})
+	})
+}
+
+func testPlanPrepareForSubmariner() {
+	t := newCloudTestDriver()
+
+	var (
+		plan     *api.Plan
+		retError error
+	)
+
+	JustBeforeEach(func() {
+		plan, retError = t.cloud.PlanPrepareForSubmariner(context.TODO(), api.PrepareForSubmarinerInput{
+			InternalPorts: []api.PortSpec{{Port: 100, Protocol: "TCP"}},
+		})
+	})
+
+	When("the firewall rule doesn't exist", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, ingressRuleName).Return(nil, &googleapi.Error{Code: http.StatusNotFound})
+		})
+
+		It("should plan its creation", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(Equal([]api.Change{{
+				Action: api.ChangeCreate, Kind: api.FirewallRuleResource, Resource: ingressRuleName, Description: "allow 100/TCP",
+			}}))
+		})
+	})
+
+	When("the firewall rule already exists", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, ingressRuleName).Return(&compute.Firewall{}, nil)
+		})
+
+		It("should plan its update", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(HaveLen(1))
+			Expect(plan.Changes[0].Action).To(Equal(api.ChangeUpdate))
+		})
+	})
+
+	When("retrieval of the firewall rule fails", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, ingressRuleName).Return(nil, errors.New("fake get error"))
 		})
 
 		It("should return an error", func() {
@@ -180,9 +290,181 @@ func testCleanupAfterSubmariner() {
 	})
 }
 
+func testCloudStatus() {
+	t := newCloudTestDriver()
+
+	var (
+		status   *api.CloudStatus
+		retError error
+	)
+
+	JustBeforeEach(func() {
+		status, retError = t.cloud.Status(context.TODO())
+	})
+
+	When("the firewall rule exists", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, ingressRuleName).Return(&compute.Firewall{
+				Name: ingressRuleName,
+				Allowed: []*compute.FirewallAllowed{
+					{IPProtocol: "TCP", Ports: []string{"100"}},
+					{IPProtocol: "UDP", Ports: []string{"200"}},
+				},
+			}, nil)
+		})
+
+		It("should report the open internal ports", func() {
+			Expect(retError).To(Succeed())
+			Expect(status.InternalPorts).To(Equal([]api.PortSpec{{Port: 100, Protocol: "TCP"}, {Port: 200, Protocol: "UDP"}}))
+			Expect(status.IsConsistent()).To(BeTrue())
+		})
+	})
+
+	When("the firewall rule doesn't exist", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, ingressRuleName).Return(nil, &googleapi.Error{Code: http.StatusNotFound})
+		})
+
+		It("should report no open internal ports", func() {
+			Expect(retError).To(Succeed())
+			Expect(status.InternalPorts).To(BeEmpty())
+		})
+	})
+
+	When("retrieval of the firewall rule fails", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, ingressRuleName).Return(nil, errors.New("fake get error"))
+		})
+
+		It("should return an error", func() {
+			Expect(retError).ToNot(Succeed())
+		})
+	})
+}
+
+func testCloudInventory() {
+	t := newCloudTestDriver()
+
+	BeforeEach(func() {
+		t.inventory = inventory.New(inventory.NewMemoryStore())
+	})
+
+	JustBeforeEach(func() {
+		Expect(t.cloud.PrepareForSubmariner(api.PrepareForSubmarinerInput{
+			InternalPorts: []api.PortSpec{{Port: 100, Protocol: "TCP"}},
+		}, api.NewLoggingReporter())).To(Succeed())
+	})
+
+	When("the firewall rule is created", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, ingressRuleName).Return(nil, &googleapi.Error{Code: http.StatusNotFound})
+			t.gcpClient.EXPECT().InsertFirewallRule(gomock.Any(), projectID, gomock.Any()).Return(nil)
+		})
+
+		It("should record it and delete it on clean up", func() {
+			Expect(t.inventory.Resources(context.TODO())).To(Equal([]api.InventoryResource{{
+				Scope: api.CloudScope, Kind: api.FirewallRuleResource, ID: ingressRuleName,
+			}}))
+
+			plan, err := t.cloud.PlanCleanupAfterSubmariner(context.TODO())
+			Expect(err).To(Succeed())
+			Expect(plan.Changes).To(HaveLen(1))
+			Expect(plan.Changes[0].Resource).To(Equal(ingressRuleName))
+
+			t.gcpClient.EXPECT().DeleteFirewallRule(gomock.Any(), projectID, ingressRuleName).Return(nil)
+			Expect(t.cloud.CleanupAfterSubmariner(api.NewLoggingReporter())).To(Succeed())
+			Expect(t.inventory.Resources(context.TODO())).To(BeEmpty())
+		})
+	})
+
+	When("the firewall rule already exists", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, ingressRuleName).Return(&compute.Firewall{}, nil)
+			t.gcpClient.EXPECT().UpdateFirewallRule(gomock.Any(), projectID, ingressRuleName, gomock.Any()).Return(nil)
+		})
+
+		It("should record it as pre-existing and not delete it on clean up", func() {
+			resources, err := t.inventory.Resources(context.TODO())
+			Expect(err).To(Succeed())
+			Expect(resources).To(HaveLen(1))
+			Expect(resources[0].PreExisting).To(BeTrue())
+
+			Expect(t.cloud.CleanupAfterSubmariner(api.NewLoggingReporter())).To(Succeed())
+			Expect(t.inventory.Resources(context.TODO())).To(BeEmpty())
+		})
+	})
+}
+
+func testScanOrphans() {
+	t := newCloudTestDriver()
+
+	const (
+		publicRuleName   = "test-infraID-submariner-public-ports-ingress"
+		publicV6RuleName = "test-infraID-submariner-public-ports-v6-ingress"
+	)
+
+	var (
+		orphans  []api.InventoryResource
+		retError error
+	)
+
+	BeforeEach(func() {
+		t.gcpClient.EXPECT().ListZones(gomock.Any()).Return(&compute.ZoneList{Items: []*compute.Zone{
+			{Name: zone1, Region: "east/" + region},
+			{Name: "other-zone", Region: "other-region"},
+		}}, nil)
+
+		t.gcpClient.EXPECT().ListInstances(gomock.Any(), zone1).Return(&compute.InstanceList{Items: []*compute.Instance{
+			{Name: instance1, Tags: &compute.Tags{Items: []string{"submariner-io-gateway-node"}}},
+			{Name: instance2, Tags: &compute.Tags{}},
+			{Name: infraID + "-submariner-gw-" + zone1, Tags: &compute.Tags{Items: []string{"submariner-io-gateway-node"}}},
+		}}, nil)
+
+		t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, publicRuleName).Return(&compute.Firewall{Name: publicRuleName}, nil)
+		t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, publicV6RuleName).Return(nil,
+			&googleapi.Error{Code: http.StatusNotFound})
+		t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, ingressRuleName).Return(&compute.Firewall{Name: ingressRuleName}, nil)
+	})
+
+	JustBeforeEach(func() {
+		orphans, retError = t.cloud.ScanOrphans(context.TODO())
+	})
+
+	It("should report the tagged instances and firewall rules", func() {
+		Expect(retError).To(Succeed())
+		Expect(orphans).To(HaveLen(3))
+		Expect(orphans[0].Kind).To(Equal(api.InstanceTagResource))
+		Expect(orphans[0].ID).To(Equal(instance1))
+		Expect(orphans[0].Parent).To(Equal(zone1))
+		Expect(orphans[1]).To(Equal(api.InventoryResource{Scope: api.GatewayScope, Kind: api.FirewallRuleResource, ID: publicRuleName}))
+		Expect(orphans[2]).To(Equal(api.InventoryResource{Scope: api.CloudScope, Kind: api.FirewallRuleResource, ID: ingressRuleName}))
+	})
+
+	Context("and they're deleted", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetInstance(gomock.Any(), zone1, instance1).Return(&compute.Instance{
+				Name: instance1, Tags: &compute.Tags{Items: []string{"submariner-io-gateway-node"}},
+			}, nil)
+			t.gcpClient.EXPECT().UpdateInstanceNetworkTags(gomock.Any(), projectID, zone1, instance1, &compute.Tags{Items: []string{}}).
+				Return(nil)
+			t.gcpClient.EXPECT().DeleteFirewallRule(gomock.Any(), projectID, publicRuleName).Return(errors.New("fake delete error"))
+			t.gcpClient.EXPECT().DeleteFirewallRule(gomock.Any(), projectID, ingressRuleName).Return(nil)
+		})
+
+		It("should delete all of them and return the failures", func() {
+			Expect(retError).To(Succeed())
+
+			err := t.cloud.DeleteOrphans(context.TODO(), orphans, api.NewLoggingReporter())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(publicRuleName))
+		})
+	})
+}
+
 type cloudTestDriver struct {
 	fakeGCPClientBase
-	cloud api.Cloud
+	cloud     api.Cloud
+	inventory api.Inventory
 }
 
 func newCloudTestDriver() *cloudTestDriver {
@@ -190,12 +472,16 @@ func newCloudTestDriver() *cloudTestDriver {
 
 	BeforeEach(func() {
 		t.beforeEach()
+		t.inventory = nil
+	})
 
+	JustBeforeEach(func() {
 		t.cloud = gcp.NewCloud(gcp.CloudInfo{
 			InfraID:   infraID,
 			Region:    region,
 			ProjectID: projectID,
 			Client:    t.gcpClient,
+			Inventory: t.inventory,
 		})
 	})
 
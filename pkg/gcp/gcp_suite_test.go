@@ -19,6 +19,7 @@ limitations under the License.
 package gcp_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -44,13 +45,34 @@ func TestGCP(t *testing.T) {
 }
 
 type fakeGCPClientBase struct {
-	gcpClient *fake.MockInterface
-	mockCtrl  *gomock.Controller
+	gcpClient         *fake.MockInterface
+	mockCtrl          *gomock.Controller
+	deniedPermissions map[string]bool
+	permissionsErr    error
 }
 
 func (f *fakeGCPClientBase) beforeEach() {
 	f.mockCtrl = gomock.NewController(GinkgoT())
 	f.gcpClient = fake.NewMockInterface(f.mockCtrl)
+	f.deniedPermissions = map[string]bool{}
+	f.permissionsErr = nil
+
+	f.gcpClient.EXPECT().TestIamPermissions(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, permissions []string) ([]string, error) {
+			if f.permissionsErr != nil {
+				return nil, f.permissionsErr
+			}
+
+			granted := []string{}
+
+			for _, p := range permissions {
+				if !f.deniedPermissions[p] {
+					granted = append(granted, p)
+				}
+			}
+
+			return granted, nil
+		}).AnyTimes()
 }
 
 func (f *fakeGCPClientBase) afterEach() {
@@ -20,7 +20,9 @@ package gcp
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"text/template"
 
@@ -28,8 +30,11 @@ import (
 	"github.com/submariner-io/admiral/pkg/stringset"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/metrics"
 	"github.com/submariner-io/cloud-prepare/pkg/ocp"
 	"google.golang.org/api/compute/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 )
@@ -57,45 +62,85 @@ func NewOcpGatewayDeployer(info CloudInfo, msDeployer ocp.MachineSetDeployer, in
 }
 
 func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, reporter api.Reporter) error {
+	return d.DeployWithContext(context.TODO(), input, reporter)
+}
+
+func (d *ocpGatewayDeployer) DeployWithContext(ctx context.Context, input api.GatewayDeployInput, reporter api.Reporter) error {
+	return metrics.ObserveOperation(ctx, "gcp", metrics.Deploy, func() error {
+		return api.RunWithRollback(ctx, input.Rollback, reporter, func(ctx context.Context) error {
+			return d.deploy(ctx, input, reporter)
+		})
+	})
+}
+
+func (d *ocpGatewayDeployer) deploy(ctx context.Context, input api.GatewayDeployInput, reporter api.Reporter) error {
+	families, err := k8s.IPFamiliesOrDetected(ctx, d.k8sClient, input.IPFamilies)
+	if err != nil {
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	input.IPFamilies = families
+
+	err = api.ValidatePrerequisites(reporter, func() error {
+		if err := d.validatePermissions(ctx, deployPermissions); err != nil {
+			return err
+		}
+
+		return d.validateQuotas(ctx, &input)
+	})
+	if err != nil {
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
 	reporter.Started("Configuring the required firewall rules for inter-cluster traffic")
 
-	externalIngress := newExternalFirewallRules(d.ProjectID, d.InfraID, input.PublicPorts)
-	if err := d.openPorts(externalIngress); err != nil {
-		return reportFailure(reporter, err, "error creating firewall rule %q", externalIngress.Name)
+	publicPorts := input.PublicPortSpecs()
+
+	externalIngress, err := newExternalFirewallRules(d.ProjectID, d.InfraID, publicPorts, input.PublicIPFamilies())
+	if err != nil {
+		return reportFailure(reporter, err, "error configuring the gateway firewall rule")
+	}
+
+	ruleNames := make([]string, 0, len(externalIngress))
+	for _, rule := range externalIngress {
+		ruleNames = append(ruleNames, rule.Name)
 	}
 
-	reporter.Succeeded("Opened External ports %q with firewall rule %q on GCP",
-		formatPorts(input.PublicPorts), externalIngress.Name)
+	if err := d.openPorts(ctx, api.GatewayScope, externalIngress...); err != nil {
+		return reportFailure(reporter, err, "error creating firewall rules %q", ruleNames)
+	}
 
-	numGatewayNodes, eligibleZonesForGW, err := d.parseCurrentGatewayInstances(reporter)
+	reporter.Succeeded("Opened External ports %q with firewall rules %q on GCP",
+		formatPorts(publicPorts), ruleNames)
+
+	numGatewayNodes, eligibleZonesForGW, err := d.parseCurrentGatewayInstances(ctx, reporter)
 	if err != nil {
 		return reportFailure(reporter, err, "error parsing current gateway instances")
 	}
 
+	if gatewaysToRemove := input.GatewaysToRemove(numGatewayNodes); gatewaysToRemove > 0 {
+		return d.removeGateways(ctx, &input, gatewaysToRemove, reporter)
+	}
+
 	gatewayNodesToDeploy := input.Gateways - numGatewayNodes
 
-	if gatewayNodesToDeploy == 0 {
+	if gatewayNodesToDeploy <= 0 {
 		reporter.Succeeded("Current gateways match the required number of gateways")
 		return nil
 	}
 
-	// Currently, we only support increasing the number of Gateway nodes which could be a valid use-case
-	// to convert a non-HA deployment to an HA deployment. We are not supporting decreasing the Gateway
-	// nodes (for now) as it might impact the datapath if we accidentally delete the active GW node.
-	if gatewayNodesToDeploy < 0 {
-		reporter.Failed(fmt.Errorf("decreasing the number of Gateway nodes is not currently supported"))
-		return nil
-	}
-
 	if d.dedicatedGWNode {
 		for _, zone := range eligibleZonesForGW.Elements() {
-			reporter.Started(fmt.Sprintf("Deploying dedicated gateway node in zone %q", zone))
+			api.ForResource(reporter, api.MachineSetResource, d.InfraID+"-submariner-gw-"+zone).
+				Started(fmt.Sprintf("Deploying dedicated gateway node in zone %q", zone))
 
-			err = d.deployGateway(zone)
+			err = d.deployGateway(ctx, zone)
 			if err != nil {
 				return reportFailure(reporter, err, "error deploying gateway for zone %q", zone)
 			}
 
+			metrics.GatewaysDeployed(ctx, "gcp", 1)
+
 			gatewayNodesToDeploy--
 			if gatewayNodesToDeploy <= 0 {
 				reporter.Succeeded("Successfully deployed gateway node")
@@ -106,26 +151,24 @@ func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, reporter api.R
 		// Query the list of instances in the eligibleZones of the current region and if it's a worker node,
 		// configure the instance as Submariner Gateway node.
 		for _, zone := range eligibleZonesForGW.Elements() {
-			workerNodes, err := d.k8sClient.ListNodesWithLabel("topology.kubernetes.io/zone=" + zone + ",node-role.kubernetes.io/worker")
+			workerNodes, err := d.k8sClient.ListNodesWithLabel(ctx,
+				"topology.kubernetes.io/zone="+zone+",node-role.kubernetes.io/worker")
 			if err != nil {
 				return reportFailure(reporter, err, "failed to list k8s nodes in zone %q of project %q", zone, d.ProjectID)
 			}
 
-			for i := range workerNodes.Items {
-				node := &workerNodes.Items[i]
-				machineSetInfo := node.GetAnnotations()["machine.openshift.io/machine"]
-				gcpInstanceInfo := strings.Split(machineSetInfo, "/")
-				if len(gcpInstanceInfo) <= 1 {
-					continue
-				}
-
-				reporter.Started(fmt.Sprintf("Configuring worker node %q in zone %q as gateway node", node.Name, zone))
-				if err := d.configureExistingNodeAsGW(zone, gcpInstanceInfo[1], node.Name); err != nil {
+			if node, gcpInstanceName := selectWorkerNode(workerNodes); node != nil {
+				api.ForResource(reporter, api.NodeLabelResource, node.Name).
+					Started(fmt.Sprintf("Configuring worker node %q in zone %q as gateway node", node.Name, zone))
+				if err := d.configureExistingNodeAsGW(ctx, zone, gcpInstanceName, node.Name); err != nil {
 					return reportFailure(reporter, err, "error configuring gateway node %q", node.Name)
 				}
 
+				metrics.GatewaysDeployed(ctx, "gcp", 1)
+
 				gatewayNodesToDeploy--
-				break
+			} else {
+				api.Warn(reporter, "There is no worker node in zone %q which can be configured as a gateway node", zone)
 			}
 
 			if gatewayNodesToDeploy <= 0 {
@@ -144,14 +187,21 @@ func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, reporter api.R
 	return err
 }
 
-func (d *ocpGatewayDeployer) parseCurrentGatewayInstances(reporter api.Reporter) (int, stringset.Interface, error) {
-	zones, err := d.retrieveZones(reporter)
+func (d *ocpGatewayDeployer) parseCurrentGatewayInstances(ctx context.Context, reporter api.Reporter) (int,
+	stringset.Interface, error) {
+	zones, err := d.retrieveZones(ctx, reporter)
 	if err != nil {
 		return 0, nil, err
 	}
 
 	reporter.Started("Verifying if current gateways match the required number of gateways")
 
+	return d.gatewayZones(ctx, zones)
+}
+
+// gatewayZones returns the number of zones in the region which already have a gateway instance, along with
+// the zones which are eligible for deploying a new one.
+func (d *ocpGatewayDeployer) gatewayZones(ctx context.Context, zones *compute.ZoneList) (int, stringset.Interface, error) {
 	zonesWithSubmarinerGW := stringset.New()
 	eligibleZonesForGW := stringset.New()
 
@@ -162,7 +212,7 @@ func (d *ocpGatewayDeployer) parseCurrentGatewayInstances(reporter api.Reporter)
 			continue
 		}
 
-		instanceList, err := d.Client.ListInstances(zone.Name)
+		instanceList, err := d.Client.ListInstances(ctx, zone.Name)
 		if err != nil {
 			return 0, nil, errors.Wrapf(err, "failed to list instances in zone %q of project %q", zone.Name, d.ProjectID)
 		}
@@ -189,6 +239,31 @@ func (d *ocpGatewayDeployer) parseCurrentGatewayInstances(reporter api.Reporter)
 	return zonesWithSubmarinerGW.Size(), eligibleZonesForGW, nil
 }
 
+// selectWorkerNode returns the first node which is backed by a GCP instance, along with the name of that instance.
+func selectWorkerNode(workerNodes *v1.NodeList) (*v1.Node, string) {
+	for i := range workerNodes.Items {
+		node := &workerNodes.Items[i]
+
+		if instanceName := nodeInstanceName(node); instanceName != "" {
+			return node, instanceName
+		}
+	}
+
+	return nil, ""
+}
+
+// nodeInstanceName returns the name of the GCP instance backing the node, if known.
+func nodeInstanceName(node *v1.Node) string {
+	machineSetInfo := node.GetAnnotations()["machine.openshift.io/machine"]
+
+	gcpInstanceInfo := strings.Split(machineSetInfo, "/")
+	if len(gcpInstanceInfo) <= 1 {
+		return ""
+	}
+
+	return gcpInstanceInfo[1]
+}
+
 type machineSetConfig struct {
 	AZ                  string
 	InfraID             string
@@ -245,7 +320,7 @@ func (d *ocpGatewayDeployer) initMachineSet(zone string) (*unstructured.Unstruct
 	return machineSet, nil
 }
 
-func (d *ocpGatewayDeployer) deployGateway(zone string) error {
+func (d *ocpGatewayDeployer) deployGateway(ctx context.Context, zone string) error {
 	machineSet, err := d.initMachineSet(zone)
 	if err != nil {
 		return err
@@ -255,7 +330,7 @@ func (d *ocpGatewayDeployer) deployGateway(zone string) error {
 		// TODO: use machineSetClient.List() instead of hard coding.
 		workerNodeList := []string{d.InfraID + "-worker-b", d.InfraID + "-worker-c", d.InfraID + "-worker-d"}
 
-		d.image, err = d.msDeployer.GetWorkerNodeImage(workerNodeList, machineSet, d.InfraID)
+		d.image, err = d.msDeployer.GetWorkerNodeImage(ctx, workerNodeList, machineSet, d.InfraID)
 		if err != nil {
 			return errors.Wrap(err, "error retrieving worker node image")
 		}
@@ -266,11 +341,15 @@ func (d *ocpGatewayDeployer) deployGateway(zone string) error {
 		}
 	}
 
-	return errors.Wrapf(d.msDeployer.Deploy(machineSet), "error deploying machine set %q", machineSet.GetName())
+	if err := d.msDeployer.Deploy(ctx, machineSet); err != nil {
+		return errors.Wrapf(err, "error deploying machine set %q", machineSet.GetName())
+	}
+
+	return d.recordCreated(ctx, api.MachineSetResource, machineSet.GetName(), zone, "delete machine set "+machineSet.GetName())
 }
 
-func (d *ocpGatewayDeployer) configureExistingNodeAsGW(zone, gcpInstanceInfo, nodeName string) error {
-	instance, err := d.Client.GetInstance(zone, gcpInstanceInfo)
+func (d *ocpGatewayDeployer) configureExistingNodeAsGW(ctx context.Context, zone, gcpInstanceInfo, nodeName string) error {
+	instance, err := d.Client.GetInstance(ctx, zone, gcpInstanceInfo)
 	if err != nil {
 		return errors.Wrapf(err, "error retrieving GCP instance %q in zode %q", gcpInstanceInfo, zone)
 	}
@@ -282,35 +361,76 @@ func (d *ocpGatewayDeployer) configureExistingNodeAsGW(zone, gcpInstanceInfo, no
 
 	tags.Items = append(tags.Items, submarinerGatewayNodeTag)
 
-	err = d.Client.UpdateInstanceNetworkTags(d.ProjectID, zone, instance.Name, tags)
+	err = d.Client.UpdateInstanceNetworkTags(ctx, d.ProjectID, zone, instance.Name, tags)
 	if err != nil {
 		return errors.Wrapf(err, "error updating network tags for GCP instance %q in zode %q", instance.Name, zone)
 	}
 
-	err = d.Client.ConfigurePublicIPOnInstance(instance)
+	err = d.recordCreated(ctx, api.InstanceTagResource, instance.Name, zone, "remove the gateway tag from instance "+instance.Name)
+	if err != nil {
+		return err
+	}
+
+	err = d.Client.ConfigurePublicIPOnInstance(ctx, instance)
 	if err != nil {
 		return errors.Wrapf(err, "error configuring public IP for GCP instance %q in zode %q", instance.Name, zone)
 	}
 
-	err = d.k8sClient.AddGWLabelOnNode(nodeName)
+	err = d.recordCreated(ctx, api.PublicIPResource, instance.Name, zone, "delete the public IP of instance "+instance.Name)
+	if err != nil {
+		return err
+	}
+
+	err = d.k8sClient.AddGWLabelOnNode(ctx, nodeName)
 	if err != nil {
 		return errors.Wrapf(err, "error labeling node %q", nodeName)
 	}
 
-	return nil
+	return d.recordCreated(ctx, api.NodeLabelResource, nodeName, "", "remove the gateway label from node "+nodeName)
+}
+
+// recordCreated records the gateway resource created by the deployment in the inventory, and registers its removal
+// if the deployment is rolled back.
+func (d *ocpGatewayDeployer) recordCreated(ctx context.Context, kind api.ResourceKind, id, parent, description string) error {
+	resource := api.InventoryResource{Scope: api.GatewayScope, Kind: kind, ID: id, Parent: parent}
+
+	api.OnRollback(ctx, description, func(ctx context.Context) error {
+		if err := d.removeResource(ctx, &resource); err != nil {
+			return err
+		}
+
+		return api.ForgetResource(ctx, d.Inventory, resource)
+	})
+
+	return api.RecordResource(ctx, d.Inventory, resource)
 }
 
 func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
+	return d.CleanupWithContext(context.TODO(), reporter)
+}
+
+func (d *ocpGatewayDeployer) CleanupWithContext(ctx context.Context, reporter api.Reporter) error {
+	err := api.ValidatePrerequisites(reporter, func() error {
+		return d.validatePermissions(ctx, gatewayCleanupPermissions)
+	})
+	if err != nil {
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	if d.Inventory != nil {
+		return d.cleanupInventory(ctx, reporter)
+	}
+
 	reporter.Started("Retrieving the Submariner gateway firewall rules")
 
-	err := d.deleteExternalFWRules(reporter)
+	err = d.deleteExternalFWRules(ctx, reporter)
 	if err != nil {
 		return reportFailure(reporter, err, "failed to delete the gateway firewall rules in the project %q", d.ProjectID)
 	}
 
 	reporter.Succeeded("Successfully deleted the firewall rules")
 
-	zones, err := d.retrieveZones(reporter)
+	zones, err := d.retrieveZones(ctx, reporter)
 	if err != nil {
 		return reportFailure(reporter, err, "error retrieving zones")
 	}
@@ -320,7 +440,7 @@ func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
 			continue
 		}
 
-		instanceList, err := d.Client.ListInstances(zone.Name)
+		instanceList, err := d.Client.ListInstances(ctx, zone.Name)
 		if err != nil {
 			return reportFailure(reporter, err, "failed to list instances in zone %q of project %q", zone.Name, d.ProjectID)
 		}
@@ -339,18 +459,20 @@ func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
 			// the gateway node was deployed using the OCPMachineSet API otherwise it's an existing worker node.
 			prefix := d.InfraID + "-submariner-gw-" + zone.Name
 			if strings.HasPrefix(instance.Name, prefix) {
-				reporter.Started(fmt.Sprintf("Deleting the gateway instance %q", instance.Name))
+				api.ForResource(reporter, api.MachineSetResource, prefix).
+					Started(fmt.Sprintf("Deleting the gateway instance %q", instance.Name))
 
-				err := d.deleteGateway(zone.Name)
+				err := d.deleteGateway(ctx, zone.Name)
 				if err != nil {
 					return reportFailure(reporter, err, "failed to delete dedicated gateway instance %q", instance.Name)
 				}
 
 				reporter.Succeeded("Successfully deleted the instance")
 			} else {
-				reporter.Started(fmt.Sprintf("Removing the gateway configuration from instance %q", instance.Name))
+				api.ForResource(reporter, api.InstanceTagResource, instance.Name).
+					Started(fmt.Sprintf("Removing the gateway configuration from instance %q", instance.Name))
 
-				err = d.resetExistingGWNode(zone.Name, instance)
+				err = d.resetExistingGWNode(ctx, zone.Name, instance)
 				if err != nil {
 					return reportFailure(reporter, err, "failed to delete gateway instance %q", instance.Name)
 				}
@@ -362,7 +484,7 @@ func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
 
 	reporter.Started("Removing the Submariner gateway label from worker nodes")
 
-	err = d.k8sClient.RemoveGWLabelFromWorkerNodes()
+	err = d.k8sClient.RemoveGWLabelFromWorkerNodes(ctx)
 	if err != nil {
 		return reportFailure(reporter, err, "error removing the gateway label from worker nodes")
 	}
@@ -372,81 +494,227 @@ func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
 	return nil
 }
 
-func (d *ocpGatewayDeployer) deleteGateway(zone string) error {
+// cleanupInventory removes the gateway resources recorded in the inventory.
+func (d *ocpGatewayDeployer) cleanupInventory(ctx context.Context, reporter api.Reporter) error {
+	reporter.Started("Removing the Submariner gateway resources recorded in the inventory")
+
+	err := api.CleanupInventory(ctx, d.Inventory, api.GatewayScope, func(resource *api.InventoryResource) error {
+		if resource.Kind == api.FirewallRuleResource {
+			return d.deleteFirewallRule(ctx, resource.ID, reporter)
+		}
+
+		return d.removeResource(ctx, resource)
+	})
+	if err != nil {
+		return reportFailure(reporter, err, "error removing the Submariner gateway resources")
+	}
+
+	reporter.Succeeded("Successfully removed the Submariner gateway resources")
+
+	return nil
+}
+
+// removeResource removes the gateway machine set, instance tag, public IP or node label recorded in the inventory.
+func (d *ocpGatewayDeployer) removeResource(ctx context.Context, resource *api.InventoryResource) error {
+	switch resource.Kind { // nolint:exhaustive // Other kinds aren't created by the GCP gateway deployer
+	case api.MachineSetResource:
+		return d.deleteGateway(ctx, resource.Parent)
+	case api.InstanceTagResource, api.PublicIPResource:
+		return d.resetInventoriedInstance(ctx, resource)
+	case api.NodeLabelResource:
+		return errors.Wrapf(d.k8sClient.RemoveGWLabelFromWorkerNode(ctx, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: resource.ID}}),
+			"error removing the gateway label from node %q", resource.ID)
+	}
+
+	return fmt.Errorf("unexpected %s resource in the GCP inventory", resource.Kind)
+}
+
+func (d *ocpGatewayDeployer) deleteGateway(ctx context.Context, zone string) error {
 	machineSet, err := d.initMachineSet(zone)
 	if err != nil {
 		return err
 	}
 
-	return errors.Wrapf(d.msDeployer.Delete(machineSet), "error deleting machine set %q", machineSet.GetName())
+	if err := d.msDeployer.Delete(ctx, machineSet); err != nil {
+		return errors.Wrapf(err, "error deleting machine set %q", machineSet.GetName())
+	}
+
+	return d.forget(ctx, api.GatewayScope, api.MachineSetResource, machineSet.GetName(), zone)
 }
 
-func (d *ocpGatewayDeployer) deleteExternalFWRules(reporter api.Reporter) error {
-	ingressName := generateRuleName(d.InfraID, publicPortsRuleName)
+// surplusGateway is a zone whose gateway instances are removed when decreasing the number of gateways.
+type surplusGateway struct {
+	zone      string
+	instances []*compute.Instance
+	nodes     []*v1.Node
+}
 
-	if err := d.deleteFirewallRule(ingressName, reporter); err != nil {
-		return errors.Wrapf(err, "error deleting firewall rule %q", ingressName)
+// surplusGateways returns the given number of zones whose gateways should be removed, in zone order. A zone is
+// never selected if one of its instances backs the node hosting the active gateway.
+func (d *ocpGatewayDeployer) surplusGateways(ctx context.Context, input *api.GatewayDeployInput, count int) ([]surplusGateway, error) {
+	activeNode, err := input.FindActiveGatewayNode(ctx, d.k8sClient.GetActiveGatewayNode)
+	if err != nil {
+		return nil, err // nolint:wrapcheck // No need to wrap here
 	}
 
-	return nil
-}
+	gwNodes, err := d.k8sClient.ListGatewayNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the gateway nodes")
+	}
 
-func reportFailure(reporter api.Reporter, failure error, format string, args ...interface{}) error {
-	err := errors.WithMessagef(failure, format, args...)
-	reporter.Failed(err)
+	instances, err := d.gatewayInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	return err
+	zones := make([]string, 0, len(instances))
+	for zone := range instances {
+		zones = append(zones, zone)
+	}
+
+	sort.Strings(zones)
+
+	surplus := []surplusGateway{}
+
+	for _, zone := range zones {
+		if len(surplus) == count {
+			break
+		}
+
+		gateway := surplusGateway{zone: zone, instances: instances[zone]}
+		active := false
+
+		for _, instance := range instances[zone] {
+			node := gatewayNodeForInstance(gwNodes, instance.Name)
+			if node != nil {
+				gateway.nodes = append(gateway.nodes, node)
+			}
+
+			active = active || isActiveGatewayInstance(instance.Name, node, activeNode)
+		}
+
+		if !active {
+			surplus = append(surplus, gateway)
+		}
+	}
+
+	return surplus, nil
 }
 
-func (d *ocpGatewayDeployer) ignoreZone(zone *compute.Zone) bool {
-	region := zone.Region[strings.LastIndex(zone.Region, "/")+1:]
+// gatewayNodeForInstance returns the gateway node backed by the named instance, if any.
+func gatewayNodeForInstance(gwNodes *v1.NodeList, instanceName string) *v1.Node {
+	for i := range gwNodes.Items {
+		if nodeInstanceName(&gwNodes.Items[i]) == instanceName {
+			return &gwNodes.Items[i]
+		}
+	}
 
-	return region != d.Region
+	return nil
 }
 
-func (d *ocpGatewayDeployer) isInstanceGatewayNode(instance *compute.Instance) bool {
-	if instance.Tags == nil {
+// isActiveGatewayInstance returns true if the instance backs the active gateway node. GCP node names are the instance
+// name, optionally followed by the internal domain, so these are matched too when the node isn't annotated.
+func isActiveGatewayInstance(instanceName string, node *v1.Node, activeNode string) bool {
+	if activeNode == "" {
 		return false
 	}
 
-	for _, tag := range instance.Tags.Items {
-		if tag == submarinerGatewayNodeTag {
-			return true
+	if node != nil && node.Name == activeNode {
+		return true
+	}
+
+	return activeNode == instanceName || strings.HasPrefix(activeNode, instanceName+".")
+}
+
+func (d *ocpGatewayDeployer) removeGateways(ctx context.Context, input *api.GatewayDeployInput, count int, reporter api.Reporter) error {
+	reporter.Started(fmt.Sprintf("Removing %d surplus gateways", count))
+
+	surplus, err := d.surplusGateways(ctx, input, count)
+	if err != nil {
+		return reportFailure(reporter, err, "error determining the gateways to remove")
+	}
+
+	for i := range surplus {
+		if err := d.removeGateway(ctx, &surplus[i]); err != nil {
+			return reportFailure(reporter, err, "error removing the gateway in zone %q", surplus[i].zone)
 		}
 	}
 
-	return false
+	// The zone hosting the active gateway is kept even if fewer gateways than requested can then be removed.
+	if len(surplus) < count {
+		api.Warn(reporter, "Only %d of the %d surplus gateways can be removed without removing the active gateway",
+			len(surplus), count)
+	}
+
+	reporter.Succeeded("Successfully removed %d surplus gateways", len(surplus))
+
+	return nil
 }
 
-func (d *ocpGatewayDeployer) resetExistingGWNode(zone string, instance *compute.Instance) error {
-	for i := range instance.Tags.Items {
-		if instance.Tags.Items[i] == submarinerGatewayNodeTag {
-			instance.Tags.Items = append(instance.Tags.Items[:i], instance.Tags.Items[i+1:]...)
+func (d *ocpGatewayDeployer) removeGateway(ctx context.Context, gateway *surplusGateway) error {
+	for _, instance := range gateway.instances {
+		if strings.HasPrefix(instance.Name, d.InfraID+"-submariner-gw-"+gateway.zone) {
+			if err := d.deleteGateway(ctx, gateway.zone); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := d.resetExistingGWNode(ctx, gateway.zone, instance); err != nil {
+			return err
 		}
 	}
 
-	tags := &compute.Tags{
-		Items:       instance.Tags.Items,
-		Fingerprint: instance.Tags.Fingerprint,
+	for _, node := range gateway.nodes {
+		if err := d.k8sClient.RemoveGWLabelFromWorkerNode(ctx, node); err != nil {
+			return errors.Wrapf(err, "error removing the gateway label from node %q", node.Name)
+		}
+
+		if err := d.forget(ctx, api.GatewayScope, api.NodeLabelResource, node.Name, ""); err != nil {
+			return err
+		}
 	}
 
-	err := d.Client.UpdateInstanceNetworkTags(d.ProjectID, zone, instance.Name, tags)
-	if err != nil {
-		return errors.Wrapf(err, "error updating network tags for GCP instance %q in zode %q", instance.Name, zone)
+	return nil
+}
+
+func (d *ocpGatewayDeployer) deleteExternalFWRules(ctx context.Context, reporter api.Reporter) error {
+	for _, family := range []api.IPFamily{api.IPv4Family, api.IPv6Family} {
+		ingressName := PublicRuleName(d.InfraID, family)
+
+		if err := d.deleteFirewallRule(ctx, ingressName, reporter); err != nil {
+			return errors.Wrapf(err, "error deleting firewall rule %q", ingressName)
+		}
+	}
+
+	return nil
+}
+
+func reportFailure(reporter api.Reporter, failure error, format string, args ...interface{}) error {
+	err := errors.WithMessagef(failure, format, args...)
+	reporter.Failed(err)
+
+	return err
+}
+
+func (d *ocpGatewayDeployer) resetExistingGWNode(ctx context.Context, zone string, instance *compute.Instance) error {
+	if err := d.removeGatewayTag(ctx, zone, instance); err != nil {
+		return err
 	}
 
-	err = d.Client.DeletePublicIPOnInstance(instance)
+	err := d.Client.DeletePublicIPOnInstance(ctx, instance)
 	if err != nil {
 		return errors.Wrapf(err, "error deleting public IP for GCP instance %q in zode %q", instance.Name, zone)
 	}
 
-	return nil
+	return d.forget(ctx, api.GatewayScope, api.PublicIPResource, instance.Name, zone)
 }
 
-func (d *ocpGatewayDeployer) retrieveZones(reporter api.Reporter) (*compute.ZoneList, error) {
+func (d *ocpGatewayDeployer) retrieveZones(ctx context.Context, reporter api.Reporter) (*compute.ZoneList, error) {
 	reporter.Started("Retrieving the current zones in the project")
 
-	zones, err := d.Client.ListZones()
+	zones, err := d.Client.ListZones(ctx)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to list the zones in the project %q", d.ProjectID)
 	}
@@ -29,6 +29,7 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	"github.com/submariner-io/cloud-prepare/pkg/gcp"
+	"github.com/submariner-io/cloud-prepare/pkg/inventory"
 	"github.com/submariner-io/cloud-prepare/pkg/k8s"
 	ocpFake "github.com/submariner-io/cloud-prepare/pkg/ocp/fake"
 	"google.golang.org/api/compute/v1"
@@ -41,12 +42,20 @@ import (
 
 const (
 	publicPortsRuleName      = "test-infraID-submariner-public-ports-ingress"
+	publicPortsV6RuleName    = "test-infraID-submariner-public-ports-v6-ingress"
 	submarinerGatewayNodeTag = "submariner-io-gateway-node"
 )
 
 var _ = Describe("OCP GatewayDeployer", func() {
 	Context("on Deploy", testDeploy)
+	Context("on Deploy without permissions", testDeployWithoutPermissions)
 	Context("on Cleanup", testCleanup)
+	Context("on Cleanup with an inventory", testCleanupWithInventory)
+	Context("on Deploy with rollback", testDeployWithRollback)
+	Context("on PlanDeploy", testPlanDeploy)
+	Context("on PlanDeploy with source CIDRs", testPlanDeployWithSourceCIDRs)
+	Context("on PlanCleanup", testPlanCleanup)
+	Context("on Status", testGatewayStatus)
 })
 
 func testDeploy() {
@@ -60,11 +69,13 @@ func testDeploy() {
 	BeforeEach(func() {
 		actualRule = nil
 
-		t.gcpClient.EXPECT().GetFirewallRule(projectID, publicPortsRuleName).Return(nil, &googleapi.Error{Code: http.StatusNotFound})
-		t.gcpClient.EXPECT().InsertFirewallRule(projectID, gomock.Any()).DoAndReturn(func(_ string, rule *compute.Firewall) error {
-			actualRule = rule
-			return nil
-		})
+		t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, publicPortsRuleName).Return(nil,
+			&googleapi.Error{Code: http.StatusNotFound})
+		t.gcpClient.EXPECT().InsertFirewallRule(gomock.Any(), projectID, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ string, rule *compute.Firewall) error {
+				actualRule = rule
+				return nil
+			})
 	})
 
 	JustBeforeEach(func() {
@@ -158,9 +169,79 @@ func testDeploy() {
 			t.numGateways = 1
 		})
 
-		It("should do nothing", func() {
-			Expect(retError).To(Succeed())
-			t.assertLabeledNodes("node-1", "node-2")
+		Context("", func() {
+			BeforeEach(func() {
+				t.expInstanceUntagged(zone1, t.instances[zone1][0])
+			})
+
+			It("should remove the surplus gateway", func() {
+				Expect(retError).To(Succeed())
+				t.assertLabeledNodes("node-2")
+			})
+		})
+
+		Context("and the first gateway is active", func() {
+			BeforeEach(func() {
+				t.activeNode = "node-1"
+				t.expInstanceUntagged(zone2, t.instances[zone2][0])
+			})
+
+			It("should not remove the active gateway", func() {
+				Expect(retError).To(Succeed())
+				t.assertLabeledNodes("node-1")
+				Expect(t.reporter.warnings).To(BeEmpty())
+			})
+		})
+
+		Context("and the other gateway is removed meanwhile", func() {
+			BeforeEach(func() {
+				t.activeNode = "node-1"
+
+				// The gateways are counted when validating the quotas then when deploying them, with the other gateway's
+				// instance, which is no longer one when the gateways to remove are selected.
+				t.gcpClient.EXPECT().ListInstances(gomock.Any(), zone2).Return(&compute.InstanceList{Items: []*compute.Instance{{
+					Name: instance2,
+					Tags: &compute.Tags{Items: []string{submarinerGatewayNodeTag}},
+				}}}, nil).Times(2)
+
+				t.instances[zone2][0].Tags.Items = []string{}
+			})
+
+			It("should keep the zone hosting the active gateway and warn that fewer gateways were removed", func() {
+				Expect(retError).To(Succeed())
+				t.assertLabeledNodes("node-1", "node-2")
+				Expect(t.reporter.warnings).To(HaveLen(1))
+			})
+		})
+
+		Context("and the gateways are dedicated nodes", func() {
+			var machineSets map[string]*unstructured.Unstructured
+
+			BeforeEach(func() {
+				t.instances[zone1][0].Name = infraID + "-submariner-gw-" + zone1
+				t.instances[zone2][0].Name = infraID + "-submariner-gw-" + zone2
+				t.nodes = []*corev1.Node{}
+				t.activeNode = infraID + "-submariner-gw-" + zone1 + ".c.test.internal"
+
+				t.msDeployer.EXPECT().Delete(gomock.Any(), gomock.Any()).DoAndReturn(machineSetFn(&machineSets)).Times(1)
+			})
+
+			It("should delete the surplus gateway's machine set", func() {
+				Expect(retError).To(Succeed())
+				Expect(machineSets).To(HaveLen(1))
+				t.assertMachineSet(machineSets[zone2], "")
+			})
+		})
+
+		Context("and the active gateway node can't be determined", func() {
+			BeforeEach(func() {
+				t.activeNodeErr = errors.New("fake error")
+			})
+
+			It("should return an error and not remove any gateways", func() {
+				Expect(retError).ToNot(Succeed())
+				t.assertLabeledNodes("node-1", "node-2")
+			})
 		})
 	})
 
@@ -192,8 +273,8 @@ func testDeploy() {
 		var machineSets map[string]*unstructured.Unstructured
 
 		BeforeEach(func() {
-			t.msDeployer.EXPECT().GetWorkerNodeImage(gomock.Any(), infraID).Return("test-image", nil).AnyTimes()
-			t.msDeployer.EXPECT().Deploy(gomock.Any()).DoAndReturn(machineSetFn(&machineSets)).Times(2)
+			t.msDeployer.EXPECT().GetWorkerNodeImage(gomock.Any(), gomock.Any(), gomock.Any(), infraID).Return("test-image", nil).AnyTimes()
+			t.msDeployer.EXPECT().Deploy(gomock.Any(), gomock.Any()).DoAndReturn(machineSetFn(&machineSets)).Times(2)
 
 			t.dedicatedGWNode = true
 			t.numGateways = 2
@@ -222,9 +303,22 @@ func testDeploy() {
 		})
 	})
 
+	When("the regional quotas don't allow for the dedicated gateway nodes", func() {
+		BeforeEach(func() {
+			t.dedicatedGWNode = true
+			t.numGateways = 2
+			t.regionQuotas[0].Usage = 20
+		})
+
+		It("should return an error before deploying any gateway node", func() {
+			Expect(retError).To(MatchError(ContainSubstring("insufficient quota for CPUS")))
+			Expect(errors.Is(retError, api.ErrQuotaExceeded)).To(BeTrue())
+		})
+	})
+
 	When("zone retrieval fails", func() {
 		BeforeEach(func() {
-			t.gcpClient.EXPECT().ListZones().Return(nil, errors.New("fake error"))
+			t.gcpClient.EXPECT().ListZones(gomock.Any()).Return(nil, errors.New("fake error"))
 		})
 
 		It("should return an error", func() {
@@ -233,6 +327,34 @@ func testDeploy() {
 	})
 }
 
+func testDeployWithoutPermissions() {
+	t := newGatewayDeployerTestDriver()
+
+	BeforeEach(func() {
+		t.numGateways = 1
+		t.deniedPermissions["compute.firewalls.create"] = true
+		t.deniedPermissions["compute.instances.setTags"] = true
+	})
+
+	It("should report all the missing permissions before making any change", func() {
+		err := t.doDeploy()
+		Expect(err).To(MatchError(ContainSubstring("the compute.firewalls.create permission is required")))
+		Expect(err).To(MatchError(ContainSubstring("the compute.instances.setTags permission is required")))
+		Expect(errors.Is(err, api.ErrPermissionDenied)).To(BeTrue())
+		t.assertLabeledNodes()
+	})
+
+	When("checking the permissions fails", func() {
+		BeforeEach(func() {
+			t.permissionsErr = errors.New("fake error")
+		})
+
+		It("should return an error", func() {
+			Expect(t.doDeploy()).To(MatchError(ContainSubstring("fake error")))
+		})
+	})
+}
+
 func testCleanup() {
 	t := newGatewayDeployerTestDriver()
 
@@ -246,7 +368,13 @@ func testCleanup() {
 	})
 
 	JustBeforeEach(func() {
-		t.gcpClient.EXPECT().DeleteFirewallRule(projectID, publicPortsRuleName).Return(deleteFirewallRule)
+		t.gcpClient.EXPECT().DeleteFirewallRule(gomock.Any(), projectID, publicPortsRuleName).Return(deleteFirewallRule)
+
+		if deleteFirewallRule == nil {
+			t.gcpClient.EXPECT().DeleteFirewallRule(gomock.Any(), projectID, publicPortsV6RuleName).Return(
+				&googleapi.Error{Code: http.StatusNotFound})
+		}
+
 		retError = t.gwDeployer.Cleanup(api.NewLoggingReporter())
 	})
 
@@ -284,7 +412,7 @@ func testCleanup() {
 			t.instances[zone1][0].Tags.Items = []string{submarinerGatewayNodeTag}
 			t.instances[zone2][0].Tags.Items = []string{submarinerGatewayNodeTag}
 
-			t.msDeployer.EXPECT().Delete(gomock.Any()).DoAndReturn(machineSetFn(&machineSets)).Times(2)
+			t.msDeployer.EXPECT().Delete(gomock.Any(), gomock.Any()).DoAndReturn(machineSetFn(&machineSets)).Times(2)
 		})
 
 		It("should delete them", func() {
@@ -298,7 +426,7 @@ func testCleanup() {
 
 	When("zone retrieval fails", func() {
 		BeforeEach(func() {
-			t.gcpClient.EXPECT().ListZones().Return(nil, errors.New("fake error"))
+			t.gcpClient.EXPECT().ListZones(gomock.Any()).Return(nil, errors.New("fake error"))
 		})
 
 		It("should return an error", func() {
@@ -317,9 +445,504 @@ func testCleanup() {
 	})
 }
 
+func testCleanupWithInventory() {
+	t := newGatewayDeployerTestDriver()
+
+	BeforeEach(func() {
+		t.inventory = inventory.New(inventory.NewMemoryStore())
+		t.nodes = []*corev1.Node{
+			newNode("node-1", zone1, instance1),
+			labelNode(newNode("node-2", "other", "other")),
+		}
+
+		t.instances[zone1][0].Tags.Items = []string{}
+		t.numGateways = 1
+
+		t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, publicPortsRuleName).Return(nil,
+			&googleapi.Error{Code: http.StatusNotFound})
+		t.gcpClient.EXPECT().InsertFirewallRule(gomock.Any(), projectID, gomock.Any()).Return(nil)
+		t.expInstanceTagged(zone1, t.instances[zone1][0])
+	})
+
+	JustBeforeEach(func() {
+		Expect(t.doDeploy()).To(Succeed())
+		t.assertLabeledNodes("node-1", "node-2")
+	})
+
+	It("should only remove the recorded resources", func() {
+		resources, err := t.inventory.Resources(context.TODO())
+		Expect(err).To(Succeed())
+		Expect(resources).To(Equal([]api.InventoryResource{
+			{Scope: api.GatewayScope, Kind: api.FirewallRuleResource, ID: publicPortsRuleName},
+			{Scope: api.GatewayScope, Kind: api.InstanceTagResource, ID: instance1, Parent: zone1},
+			{Scope: api.GatewayScope, Kind: api.PublicIPResource, ID: instance1, Parent: zone1},
+			{Scope: api.GatewayScope, Kind: api.NodeLabelResource, ID: "node-1"},
+		}))
+
+		t.gcpClient.EXPECT().DeleteFirewallRule(gomock.Any(), projectID, publicPortsRuleName).Return(nil)
+		t.expInstanceUntagged(zone1, t.instances[zone1][0])
+
+		Expect(t.gwDeployer.Cleanup(api.NewLoggingReporter())).To(Succeed())
+		t.assertLabeledNodes("node-2")
+		Expect(t.inventory.Resources(context.TODO())).To(BeEmpty())
+	})
+
+	It("should plan removing only the recorded resources", func() {
+		plan, err := t.gwDeployer.PlanCleanup(context.TODO())
+		Expect(err).To(Succeed())
+		Expect(plan.Changes).To(HaveLen(4))
+		Expect(plan.Changes[0]).To(Equal(api.Change{Action: api.ChangeDelete, Kind: api.NodeLabelResource, Resource: "node-1"}))
+		Expect(plan.Changes[3]).To(Equal(api.Change{Action: api.ChangeDelete, Kind: api.FirewallRuleResource, Resource: publicPortsRuleName}))
+	})
+}
+
+func testDeployWithRollback() {
+	t := newGatewayDeployerTestDriver()
+
+	var (
+		deployedMachineSets []string
+		retError            error
+	)
+
+	BeforeEach(func() {
+		deployedMachineSets = []string{}
+		t.dedicatedGWNode = true
+		t.numGateways = 2
+
+		t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, publicPortsRuleName).Return(nil,
+			&googleapi.Error{Code: http.StatusNotFound})
+		t.gcpClient.EXPECT().InsertFirewallRule(gomock.Any(), projectID, gomock.Any()).Return(nil)
+		t.msDeployer.EXPECT().GetWorkerNodeImage(gomock.Any(), gomock.Any(), gomock.Any(), infraID).Return("test-image", nil).AnyTimes()
+
+		// The first machine set is deployed, the second fails.
+		t.msDeployer.EXPECT().Deploy(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, ms *unstructured.Unstructured) error {
+				if len(deployedMachineSets) > 0 {
+					return errors.New("fake Deploy error")
+				}
+
+				deployedMachineSets = append(deployedMachineSets, ms.GetName())
+
+				return nil
+			}).Times(2)
+	})
+
+	JustBeforeEach(func() {
+		retError = t.doDeploy()
+	})
+
+	When("rollback is requested", func() {
+		var (
+			deletedMachineSets []string
+			deleteError        error
+		)
+
+		BeforeEach(func() {
+			deletedMachineSets = []string{}
+			deleteError = nil
+			t.rollback = true
+
+			t.msDeployer.EXPECT().Delete(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, ms *unstructured.Unstructured) error {
+					deletedMachineSets = append(deletedMachineSets, ms.GetName())
+					return deleteError
+				})
+			t.gcpClient.EXPECT().DeleteFirewallRule(gomock.Any(), projectID, publicPortsRuleName).Return(nil)
+		})
+
+		It("should return the error and revert the completed steps", func() {
+			Expect(retError).To(MatchError(ContainSubstring("fake Deploy error")))
+			Expect(deletedMachineSets).To(Equal(deployedMachineSets))
+		})
+
+		Context("and reverting a step fails", func() {
+			BeforeEach(func() {
+				deleteError = errors.New("fake Delete error")
+			})
+
+			It("should still revert the other steps and return both errors", func() {
+				Expect(retError).To(MatchError(ContainSubstring("fake Deploy error")))
+				Expect(retError).To(MatchError(ContainSubstring("fake Delete error")))
+			})
+		})
+	})
+
+	When("rollback isn't requested", func() {
+		It("should return the error and leave the completed steps", func() {
+			Expect(retError).To(MatchError(ContainSubstring("fake Deploy error")))
+			Expect(deployedMachineSets).To(HaveLen(1))
+		})
+	})
+}
+
+func testPlanDeploy() {
+	t := newGatewayDeployerTestDriver()
+
+	var (
+		plan     *api.Plan
+		retError error
+	)
+
+	BeforeEach(func() {
+		t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, publicPortsRuleName).Return(nil,
+			&googleapi.Error{Code: http.StatusNotFound})
+	})
+
+	JustBeforeEach(func() {
+		plan, retError = t.gwDeployer.PlanDeploy(context.TODO(), api.GatewayDeployInput{
+			Gateways:          t.numGateways,
+			PublicPorts:       []api.PortSpec{{Port: 100, Protocol: "TCP"}},
+			ActiveGatewayNode: t.findActiveNode,
+		})
+	})
+
+	When("one gateway is requested", func() {
+		BeforeEach(func() {
+			t.nodes = []*corev1.Node{
+				newNode("node-1", zone1, instance1),
+			}
+
+			t.numGateways = 1
+		})
+
+		It("should plan the firewall rule and the gateway node configuration without applying them", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(Equal([]api.Change{
+				{Action: api.ChangeCreate, Kind: api.FirewallRuleResource, Resource: publicPortsRuleName, Description: "allow 100/TCP"},
+				{
+					Action: api.ChangeCreate, Kind: api.InstanceTagResource, Resource: instance1,
+					Description: fmt.Sprintf("tag %q in zone %s", submarinerGatewayNodeTag, zone1),
+				},
+				{Action: api.ChangeCreate, Kind: api.PublicIPResource, Resource: instance1, Description: "in zone " + zone1},
+				{Action: api.ChangeCreate, Kind: api.NodeLabelResource, Resource: "node-1", Description: "gateway label"},
+			}))
+
+			t.assertLabeledNodes()
+		})
+	})
+
+	When("the requested number of gateways is decreased", func() {
+		BeforeEach(func() {
+			t.nodes = []*corev1.Node{
+				labelNode(newNode("node-1", zone1, instance1)),
+				labelNode(newNode("node-2", zone2, instance2)),
+			}
+
+			t.instances[zone1][0].Tags.Items = []string{submarinerGatewayNodeTag}
+			t.instances[zone2][0].Tags.Items = []string{submarinerGatewayNodeTag}
+			t.activeNode = "node-1"
+			t.numGateways = 1
+		})
+
+		It("should plan removing the surplus gateway without applying it", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes[1:]).To(Equal([]api.Change{
+				{
+					Action: api.ChangeDelete, Kind: api.InstanceTagResource, Resource: instance2,
+					Description: fmt.Sprintf("tag %q in zone %s", submarinerGatewayNodeTag, zone2),
+				},
+				{Action: api.ChangeDelete, Kind: api.PublicIPResource, Resource: instance2, Description: "in zone " + zone2},
+				{Action: api.ChangeDelete, Kind: api.NodeLabelResource, Resource: "node-2", Description: "gateway label"},
+			}))
+
+			t.assertLabeledNodes("node-1", "node-2")
+		})
+	})
+
+	When("dedicated gateway nodes are requested", func() {
+		BeforeEach(func() {
+			t.dedicatedGWNode = true
+			t.numGateways = 2
+		})
+
+		It("should plan a machine set per zone", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(HaveLen(3))
+			Expect(plan.Changes[1].Kind).To(Equal(api.MachineSetResource))
+			Expect(plan.Changes[2].Kind).To(Equal(api.MachineSetResource))
+			Expect([]string{plan.Changes[1].Resource, plan.Changes[2].Resource}).To(ConsistOf(
+				infraID+"-submariner-gw-"+zone1, infraID+"-submariner-gw-"+zone2))
+		})
+	})
+
+	When("there's an insufficient number of available nodes", func() {
+		BeforeEach(func() {
+			t.nodes = []*corev1.Node{
+				newNode("node-1", zone1, instance1),
+			}
+
+			t.numGateways = 2
+		})
+
+		It("should return an error", func() {
+			Expect(retError).ToNot(Succeed())
+		})
+	})
+}
+
+func testPlanDeployWithSourceCIDRs() {
+	t := newGatewayDeployerTestDriver()
+
+	var (
+		publicPorts []api.PortSpec
+		sourceCIDRs []string
+		plan        *api.Plan
+		retError    error
+	)
+
+	BeforeEach(func() {
+		t.dedicatedGWNode = true
+		t.numGateways = 1
+		publicPorts = []api.PortSpec{{Port: 4500, EndPort: 4510, Protocol: "UDP"}}
+		sourceCIDRs = []string{"10.0.0.0/8"}
+	})
+
+	JustBeforeEach(func() {
+		plan, retError = t.gwDeployer.PlanDeploy(context.TODO(), api.GatewayDeployInput{
+			Gateways:          t.numGateways,
+			PublicPorts:       publicPorts,
+			PublicSourceCIDRs: sourceCIDRs,
+		})
+	})
+
+	When("all the ports share the same sources", func() {
+		BeforeEach(func() {
+			t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, publicPortsRuleName).Return(nil,
+				&googleapi.Error{Code: http.StatusNotFound})
+		})
+
+		It("should plan a firewall rule restricted to the source CIDRs", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes[0]).To(Equal(api.Change{
+				Action: api.ChangeCreate, Kind: api.FirewallRuleResource, Resource: publicPortsRuleName,
+				Description: "allow 4500-4510/UDP from 10.0.0.0/8",
+			}))
+		})
+	})
+
+	When("the sources include IPv6 CIDRs", func() {
+		BeforeEach(func() {
+			sourceCIDRs = append(sourceCIDRs, "2001:db8::/32")
+
+			t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, publicPortsRuleName).Return(nil,
+				&googleapi.Error{Code: http.StatusNotFound})
+			t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, publicPortsV6RuleName).Return(nil,
+				&googleapi.Error{Code: http.StatusNotFound})
+		})
+
+		It("should plan a separate IPv6 firewall rule", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes[:2]).To(Equal([]api.Change{
+				{
+					Action: api.ChangeCreate, Kind: api.FirewallRuleResource, Resource: publicPortsRuleName,
+					Description: "allow 4500-4510/UDP from 10.0.0.0/8",
+				},
+				{
+					Action: api.ChangeCreate, Kind: api.FirewallRuleResource, Resource: publicPortsV6RuleName,
+					Description: "allow 4500-4510/UDP from 2001:db8::/32",
+				},
+			}))
+		})
+	})
+
+	When("the ports have differing sources", func() {
+		BeforeEach(func() {
+			publicPorts = append(publicPorts, api.PortSpec{Port: 500, Protocol: "UDP", SourceCIDRs: []string{"192.168.0.0/16"}})
+		})
+
+		It("should return an error", func() {
+			Expect(retError).ToNot(Succeed())
+		})
+	})
+}
+
+func testPlanCleanup() {
+	t := newGatewayDeployerTestDriver()
+
+	var (
+		plan     *api.Plan
+		retError error
+	)
+
+	BeforeEach(func() {
+		t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, publicPortsRuleName).Return(&compute.Firewall{}, nil)
+		t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, publicPortsV6RuleName).Return(nil,
+			&googleapi.Error{Code: http.StatusNotFound})
+	})
+
+	JustBeforeEach(func() {
+		plan, retError = t.gwDeployer.PlanCleanup(context.TODO())
+	})
+
+	Context("with preexisting nodes labeled as gateways", func() {
+		BeforeEach(func() {
+			t.nodes = []*corev1.Node{
+				labelNode(newNode("node-1", zone1, instance1)),
+			}
+
+			t.instances[zone1][0].Tags.Items = []string{submarinerGatewayNodeTag}
+		})
+
+		It("should plan the removal of their gateway configuration without applying it", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(HaveLen(4))
+			Expect(plan.Changes[0].Kind).To(Equal(api.FirewallRuleResource))
+			Expect(plan.Changes[1].Kind).To(Equal(api.InstanceTagResource))
+			Expect(plan.Changes[2].Kind).To(Equal(api.PublicIPResource))
+			Expect(plan.Changes[3]).To(Equal(api.Change{
+				Action: api.ChangeDelete, Kind: api.NodeLabelResource, Resource: "node-1", Description: "gateway label",
+			}))
+
+			t.assertLabeledNodes("node-1")
+		})
+	})
+
+	Context("with dedicated nodes deployed as gateways", func() {
+		BeforeEach(func() {
+			t.instances[zone1][0].Name = infraID + "-submariner-gw-" + zone1
+			t.instances[zone1][0].Tags.Items = []string{submarinerGatewayNodeTag}
+		})
+
+		It("should plan their deletion", func() {
+			Expect(retError).To(Succeed())
+			Expect(plan.Changes).To(HaveLen(2))
+			Expect(plan.Changes[1]).To(Equal(api.Change{
+				Action: api.ChangeDelete, Kind: api.MachineSetResource, Resource: infraID + "-submariner-gw-" + zone1,
+				Description: "dedicated gateway node in zone " + zone1,
+			}))
+		})
+	})
+}
+
+func testGatewayStatus() {
+	t := newGatewayDeployerTestDriver()
+
+	var (
+		status      *api.GatewayStatus
+		retError    error
+		machineSets []unstructured.Unstructured
+	)
+
+	var v6Rule *compute.Firewall
+
+	BeforeEach(func() {
+		machineSets = nil
+		v6Rule = nil
+
+		t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, publicPortsRuleName).Return(&compute.Firewall{
+			Name:    publicPortsRuleName,
+			Allowed: []*compute.FirewallAllowed{{IPProtocol: "UDP", Ports: []string{"4500"}}},
+		}, nil)
+	})
+
+	JustBeforeEach(func() {
+		if v6Rule != nil {
+			t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, publicPortsV6RuleName).Return(v6Rule, nil)
+		} else {
+			t.gcpClient.EXPECT().GetFirewallRule(gomock.Any(), projectID, publicPortsV6RuleName).Return(nil,
+				&googleapi.Error{Code: http.StatusNotFound})
+		}
+
+		t.msDeployer.EXPECT().List(gomock.Any(), gomock.Any(), infraID+"-submariner-gw-").Return(machineSets, nil)
+
+		status, retError = t.gwDeployer.Status(context.TODO())
+	})
+
+	Context("with preexisting nodes labeled as gateways", func() {
+		BeforeEach(func() {
+			t.nodes = []*corev1.Node{
+				labelNode(newNode("node-1", zone1, instance1)),
+			}
+
+			t.instances[zone1][0].Tags.Items = []string{submarinerGatewayNodeTag}
+		})
+
+		It("should report the firewall rule and gateway nodes", func() {
+			Expect(retError).To(Succeed())
+			Expect(status.Firewall).To(Equal(&api.FirewallStatus{
+				Kind: api.FirewallRuleResource, Name: publicPortsRuleName, Ports: []api.PortSpec{{Port: 4500, Protocol: "UDP"}},
+			}))
+			Expect(status.GatewayNodes).To(Equal([]string{"node-1"}))
+			Expect(status.Issues).To(BeEmpty())
+		})
+
+		Context("and an IPv6 firewall rule", func() {
+			BeforeEach(func() {
+				v6Rule = &compute.Firewall{
+					Name:         publicPortsV6RuleName,
+					Allowed:      []*compute.FirewallAllowed{{IPProtocol: "UDP", Ports: []string{"4500"}}},
+					SourceRanges: []string{api.AnySourceCIDRv6},
+				}
+			})
+
+			It("should report the ports of both firewall rules", func() {
+				Expect(retError).To(Succeed())
+				Expect(status.Firewall.Ports).To(Equal([]api.PortSpec{
+					{Port: 4500, Protocol: "UDP"},
+					{Port: 4500, Protocol: "UDP", SourceCIDRs: []string{api.AnySourceCIDRv6}},
+				}))
+			})
+		})
+	})
+
+	Context("with a tagged instance whose node isn't labeled", func() {
+		BeforeEach(func() {
+			t.nodes = []*corev1.Node{
+				newNode("node-1", zone1, instance1),
+			}
+
+			t.instances[zone1][0].Tags.Items = []string{submarinerGatewayNodeTag}
+		})
+
+		It("should report an issue", func() {
+			Expect(retError).To(Succeed())
+			Expect(status.Issues).To(HaveLen(1))
+		})
+	})
+
+	Context("with a dedicated gateway instance", func() {
+		BeforeEach(func() {
+			t.instances[zone1][0].Name = infraID + "-submariner-gw-" + zone1
+			t.instances[zone1][0].Tags.Items = []string{submarinerGatewayNodeTag}
+		})
+
+		Context("and its MachineSet", func() {
+			BeforeEach(func() {
+				ms := unstructured.Unstructured{}
+				ms.SetName(infraID + "-submariner-gw-" + zone1)
+				machineSets = []unstructured.Unstructured{ms}
+			})
+
+			It("should report the MachineSet", func() {
+				Expect(retError).To(Succeed())
+				Expect(status.MachineSets).To(Equal([]string{infraID + "-submariner-gw-" + zone1}))
+				Expect(status.Issues).To(BeEmpty())
+			})
+		})
+
+		Context("and no MachineSet", func() {
+			It("should report an issue", func() {
+				Expect(retError).To(Succeed())
+				Expect(status.Issues).To(HaveLen(1))
+			})
+		})
+	})
+
+	Context("with no gateways", func() {
+		It("should report the firewall rule as an issue", func() {
+			Expect(retError).To(Succeed())
+			Expect(status.Issues).To(HaveLen(1))
+		})
+	})
+}
+
 type gatewayDeployerTestDriver struct {
 	fakeGCPClientBase
 	numGateways     int
+	activeNode      string
+	activeNodeErr   error
+	inventory       api.Inventory
+	rollback        bool
 	dedicatedGWNode bool
 	image           string
 	kubeClient      *kubeFake.Clientset
@@ -327,9 +950,21 @@ type gatewayDeployerTestDriver struct {
 	nodes           []*corev1.Node
 	zones           []*compute.Zone
 	instances       map[string][]*compute.Instance
+	regionQuotas    []*compute.Quota
+	reporter        *warningRecorder
 	gwDeployer      api.GatewayDeployer
 }
 
+// warningRecorder is a logging reporter which records the warnings.
+type warningRecorder struct {
+	api.Reporter
+	warnings []string
+}
+
+func (r *warningRecorder) Warning(message string, args ...interface{}) {
+	r.warnings = append(r.warnings, fmt.Sprintf(message, args...))
+}
+
 func newGatewayDeployerTestDriver() *gatewayDeployerTestDriver {
 	t := &gatewayDeployerTestDriver{}
 
@@ -371,33 +1006,54 @@ func newGatewayDeployerTestDriver() *gatewayDeployerTestDriver {
 			},
 		}
 
+		t.regionQuotas = []*compute.Quota{
+			{Metric: "CPUS", Limit: 24, Usage: 8},
+			{Metric: "INSTANCES", Limit: 100, Usage: 2},
+			{Metric: "IN_USE_ADDRESSES", Limit: 8, Usage: 0},
+		}
+
+		t.activeNode = ""
+		t.activeNodeErr = nil
+		t.inventory = nil
+		t.rollback = false
 		t.dedicatedGWNode = false
 		t.image = ""
+		t.reporter = &warningRecorder{Reporter: api.NewLoggingReporter()}
 		t.msDeployer = ocpFake.NewMockMachineSetDeployer(t.mockCtrl)
 		t.kubeClient = kubeFake.NewSimpleClientset()
 	})
 
 	JustBeforeEach(func() {
-		t.gcpClient.EXPECT().ListZones().Return(&compute.ZoneList{Items: t.zones}, nil).AnyTimes()
-		t.gcpClient.EXPECT().ListInstances(gomock.Any()).DoAndReturn(func(zone string) (*compute.InstanceList, error) {
-			list := t.instances[zone]
-			if list != nil {
-				return &compute.InstanceList{Items: list}, nil
-			}
+		t.gcpClient.EXPECT().ListZones(gomock.Any()).Return(&compute.ZoneList{Items: t.zones}, nil).AnyTimes()
+		t.gcpClient.EXPECT().ListInstances(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, zone string) (*compute.InstanceList, error) {
+				list := t.instances[zone]
+				if list != nil {
+					return &compute.InstanceList{Items: list}, nil
+				}
 
-			return &compute.InstanceList{}, nil
-		}).AnyTimes()
+				return &compute.InstanceList{}, nil
+			}).AnyTimes()
 
-		t.gcpClient.EXPECT().GetInstance(gomock.Any(), gomock.Any()).DoAndReturn(func(zone, instance string) (*compute.Instance, error) {
-			list := t.instances[zone]
-			for _, i := range list {
-				if i.Name == instance {
-					return i, nil
+		t.gcpClient.EXPECT().GetRegion(gomock.Any(), region).DoAndReturn(
+			func(_ context.Context, name string) (*compute.Region, error) {
+				return &compute.Region{Name: name, Quotas: t.regionQuotas}, nil
+			}).AnyTimes()
+
+		t.gcpClient.EXPECT().GetMachineType(gomock.Any(), gomock.Any(), instanceType).Return(
+			&compute.MachineType{Name: instanceType, GuestCpus: 4}, nil).AnyTimes()
+
+		t.gcpClient.EXPECT().GetInstance(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, zone, instance string) (*compute.Instance, error) {
+				list := t.instances[zone]
+				for _, i := range list {
+					if i.Name == instance {
+						return i, nil
+					}
 				}
-			}
 
-			return nil, fmt.Errorf("instance %q not found", instance)
-		}).AnyTimes()
+				return nil, fmt.Errorf("instance %q not found", instance)
+			}).AnyTimes()
 
 		for _, node := range t.nodes {
 			_, err := t.kubeClient.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
@@ -411,6 +1067,7 @@ func newGatewayDeployerTestDriver() *gatewayDeployerTestDriver {
 			Region:    region,
 			ProjectID: projectID,
 			Client:    t.gcpClient,
+			Inventory: t.inventory,
 		}, t.msDeployer, instanceType, t.image, t.dedicatedGWNode, k8s.NewInterface(t.kubeClient))
 	})
 
@@ -430,7 +1087,13 @@ func (t *gatewayDeployerTestDriver) doDeploy() error {
 				Protocol: "UDP",
 			},
 		},
-	}, api.NewLoggingReporter())
+		ActiveGatewayNode: t.findActiveNode,
+		Rollback:          t.rollback,
+	}, t.reporter)
+}
+
+func (t *gatewayDeployerTestDriver) findActiveNode(_ context.Context) (string, error) {
+	return t.activeNode, t.activeNodeErr
 }
 
 func (t *gatewayDeployerTestDriver) getLabeledNodes() []string {
@@ -472,19 +1135,19 @@ func (t *gatewayDeployerTestDriver) assertIngressRule(rule *compute.Firewall) {
 }
 
 func (t *gatewayDeployerTestDriver) expInstanceTagged(zone string, instance *compute.Instance) {
-	t.gcpClient.EXPECT().UpdateInstanceNetworkTags(projectID, zone, instance.Name, &compute.Tags{
+	t.gcpClient.EXPECT().UpdateInstanceNetworkTags(gomock.Any(), projectID, zone, instance.Name, &compute.Tags{
 		Items: []string{submarinerGatewayNodeTag},
 	})
 
-	t.gcpClient.EXPECT().ConfigurePublicIPOnInstance(instance)
+	t.gcpClient.EXPECT().ConfigurePublicIPOnInstance(gomock.Any(), instance)
 }
 
 func (t *gatewayDeployerTestDriver) expInstanceUntagged(zone string, instance *compute.Instance) {
-	t.gcpClient.EXPECT().UpdateInstanceNetworkTags(projectID, zone, instance.Name, &compute.Tags{
+	t.gcpClient.EXPECT().UpdateInstanceNetworkTags(gomock.Any(), projectID, zone, instance.Name, &compute.Tags{
 		Items: []string{},
 	})
 
-	t.gcpClient.EXPECT().DeletePublicIPOnInstance(instance)
+	t.gcpClient.EXPECT().DeletePublicIPOnInstance(gomock.Any(), instance)
 }
 
 func (t *gatewayDeployerTestDriver) assertMachineSet(ms *unstructured.Unstructured, expImage string) {
@@ -533,10 +1196,10 @@ func labelNode(node *corev1.Node) *corev1.Node {
 }
 
 // nolint:gocritic // Error: "consider `machineSets' to be of non-pointer type"
-func machineSetFn(machineSets *map[string]*unstructured.Unstructured) func(ms *unstructured.Unstructured) error {
+func machineSetFn(machineSets *map[string]*unstructured.Unstructured) func(context.Context, *unstructured.Unstructured) error {
 	*machineSets = map[string]*unstructured.Unstructured{}
 
-	return func(ms *unstructured.Unstructured) error {
+	return func(_ context.Context, ms *unstructured.Unstructured) error {
 		zone, ok, _ := unstructured.NestedString(ms.Object, "spec", "template", "spec", "providerSpec", "value", "zone")
 		Expect(ok).To(BeTrue())
 
@@ -0,0 +1,102 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	gcpclient "github.com/submariner-io/cloud-prepare/pkg/gcp/client"
+)
+
+// ScanOrphans searches the region for the cluster's instances carrying the gateway network tag, and for the Submariner
+// firewall rules. The instances of dedicated gateways aren't reported since they're removed with their MachineSets.
+func (gc *gcpCloud) ScanOrphans(ctx context.Context) ([]api.InventoryResource, error) {
+	instances, err := gc.gatewayInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	zones := make([]string, 0, len(instances))
+	for zone := range instances {
+		zones = append(zones, zone)
+	}
+
+	sort.Strings(zones)
+
+	orphans := []api.InventoryResource{}
+
+	for _, zone := range zones {
+		for _, instance := range instances[zone] {
+			if !strings.HasPrefix(instance.Name, gc.InfraID+"-submariner-gw-") {
+				orphans = append(orphans, api.InventoryResource{
+					Scope:       api.GatewayScope,
+					Kind:        api.InstanceTagResource,
+					ID:          instance.Name,
+					Parent:      zone,
+					Description: "instance tagged with " + submarinerGatewayNodeTag,
+				})
+			}
+		}
+	}
+
+	rules := []struct {
+		scope api.InventoryScope
+		name  string
+	}{
+		{api.GatewayScope, PublicRuleName(gc.InfraID, api.IPv4Family)},
+		{api.GatewayScope, PublicRuleName(gc.InfraID, api.IPv6Family)},
+		{api.CloudScope, InternalRuleName(gc.InfraID)},
+	}
+
+	for _, rule := range rules {
+		_, found, err := gc.getFirewallRule(ctx, rule.name)
+		if err != nil {
+			return nil, err
+		}
+
+		if found {
+			orphans = append(orphans, api.InventoryResource{Scope: rule.scope, Kind: api.FirewallRuleResource, ID: rule.name})
+		}
+	}
+
+	return orphans, nil
+}
+
+func (gc *gcpCloud) DeleteOrphans(ctx context.Context, orphans []api.InventoryResource, reporter api.Reporter) error {
+	return api.DeleteOrphans(orphans, reporter, func(resource *api.InventoryResource) error {
+		switch resource.Kind { // nolint:exhaustive // Other kinds aren't found by ScanOrphans
+		case api.InstanceTagResource:
+			return gc.resetInventoriedInstance(ctx, resource)
+		case api.FirewallRuleResource:
+			err := gc.Client.DeleteFirewallRule(ctx, gc.ProjectID, resource.ID)
+			if gcpclient.IsGCPNotFoundError(err) {
+				return nil
+			}
+
+			return errors.Wrapf(err, "error deleting firewall rule %q", resource.ID)
+		default:
+			return fmt.Errorf("unexpected orphaned %s resource on GCP", resource.Kind)
+		}
+	})
+}
@@ -0,0 +1,273 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	gcpclient "github.com/submariner-io/cloud-prepare/pkg/gcp/client"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"google.golang.org/api/compute/v1"
+)
+
+func (gc *gcpCloud) PlanPrepareForSubmariner(ctx context.Context, input api.PrepareForSubmarinerInput) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	internalIngress := newInternalFirewallRule(gc.ProjectID, gc.InfraID, input.InternalPorts)
+	if err := gc.planOpenPorts(ctx, plan, internalIngress); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func (gc *gcpCloud) PlanCleanupAfterSubmariner(ctx context.Context) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	if gc.Inventory != nil {
+		if err := api.PlanInventoryCleanup(ctx, gc.Inventory, api.CloudScope, plan); err != nil {
+			return nil, err // nolint:wrapcheck // No need to wrap here
+		}
+
+		return plan, nil
+	}
+
+	if err := gc.planDeleteFirewallRule(ctx, plan, InternalRuleName(gc.InfraID)); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func (d *ocpGatewayDeployer) PlanDeploy(ctx context.Context, input api.GatewayDeployInput) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	families, err := k8s.IPFamiliesOrDetected(ctx, d.k8sClient, input.IPFamilies)
+	if err != nil {
+		return nil, err // nolint:wrapcheck // No need to wrap here
+	}
+
+	input.IPFamilies = families
+
+	externalIngress, err := newExternalFirewallRules(d.ProjectID, d.InfraID, input.PublicPortSpecs(), input.PublicIPFamilies())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.planOpenPorts(ctx, plan, externalIngress...); err != nil {
+		return nil, err
+	}
+
+	zones, err := d.Client.ListZones(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the zones in the project %q", d.ProjectID)
+	}
+
+	numGatewayNodes, eligibleZonesForGW, err := d.gatewayZones(ctx, zones)
+	if err != nil {
+		return nil, err
+	}
+
+	if gatewaysToRemove := input.GatewaysToRemove(numGatewayNodes); gatewaysToRemove > 0 {
+		return d.planRemoveGateways(ctx, plan, &input, gatewaysToRemove)
+	}
+
+	gatewayNodesToDeploy := input.Gateways - numGatewayNodes
+	if gatewayNodesToDeploy <= 0 {
+		return plan, nil
+	}
+
+	for _, zone := range eligibleZonesForGW.Elements() {
+		if d.dedicatedGWNode {
+			machineSet, err := d.initMachineSet(zone)
+			if err != nil {
+				return nil, err
+			}
+
+			plan.Add(api.ChangeApply, api.MachineSetResource, machineSet.GetName(), "dedicated gateway node of type %s in zone %s",
+				d.instanceType, zone)
+
+			gatewayNodesToDeploy--
+		} else {
+			workerNodes, err := d.k8sClient.ListNodesWithLabel(ctx,
+				"topology.kubernetes.io/zone="+zone+",node-role.kubernetes.io/worker")
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to list k8s nodes in zone %q of project %q", zone, d.ProjectID)
+			}
+
+			if node, gcpInstanceName := selectWorkerNode(workerNodes); node != nil {
+				plan.Add(api.ChangeCreate, api.InstanceTagResource, gcpInstanceName, "tag %q in zone %s", submarinerGatewayNodeTag, zone)
+				plan.Add(api.ChangeCreate, api.PublicIPResource, gcpInstanceName, "in zone %s", zone)
+				plan.Add(api.ChangeCreate, api.NodeLabelResource, node.Name, "gateway label")
+
+				gatewayNodesToDeploy--
+			}
+		}
+
+		if gatewayNodesToDeploy <= 0 {
+			return plan, nil
+		}
+	}
+
+	return nil, fmt.Errorf("there are an insufficient number of zones (%d) to deploy the desired number of gateways (%d)",
+		eligibleZonesForGW.Size(), input.Gateways)
+}
+
+func (d *ocpGatewayDeployer) PlanCleanup(ctx context.Context) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	if d.Inventory != nil {
+		if err := api.PlanInventoryCleanup(ctx, d.Inventory, api.GatewayScope, plan); err != nil {
+			return nil, err // nolint:wrapcheck // No need to wrap here
+		}
+
+		return plan, nil
+	}
+
+	for _, family := range []api.IPFamily{api.IPv4Family, api.IPv6Family} {
+		if err := d.planDeleteFirewallRule(ctx, plan, PublicRuleName(d.InfraID, family)); err != nil {
+			return nil, err
+		}
+	}
+
+	zones, err := d.Client.ListZones(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the zones in the project %q", d.ProjectID)
+	}
+
+	for _, zone := range zones.Items {
+		if d.ignoreZone(zone) {
+			continue
+		}
+
+		instanceList, err := d.Client.ListInstances(ctx, zone.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list instances in zone %q of project %q", zone.Name, d.ProjectID)
+		}
+
+		for _, instance := range instanceList.Items {
+			if !strings.HasPrefix(instance.Name, d.InfraID) || !d.isInstanceGatewayNode(instance) {
+				continue
+			}
+
+			if strings.HasPrefix(instance.Name, d.InfraID+"-submariner-gw-"+zone.Name) {
+				machineSet, err := d.initMachineSet(zone.Name)
+				if err != nil {
+					return nil, err
+				}
+
+				plan.Add(api.ChangeDelete, api.MachineSetResource, machineSet.GetName(), "dedicated gateway node in zone %s", zone.Name)
+			} else {
+				plan.Add(api.ChangeDelete, api.InstanceTagResource, instance.Name, "tag %q in zone %s", submarinerGatewayNodeTag,
+					zone.Name)
+				plan.Add(api.ChangeDelete, api.PublicIPResource, instance.Name, "in zone %s", zone.Name)
+			}
+		}
+	}
+
+	gwNodes, err := d.k8sClient.ListNodesWithLabel(ctx, k8s.SubmarinerGatewayLabel)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the gateway nodes")
+	}
+
+	for i := range gwNodes.Items {
+		plan.Add(api.ChangeDelete, api.NodeLabelResource, gwNodes.Items[i].Name, "gateway label")
+	}
+
+	return plan, nil
+}
+
+func (d *ocpGatewayDeployer) planRemoveGateways(ctx context.Context, plan *api.Plan, input *api.GatewayDeployInput,
+	count int) (*api.Plan, error) {
+	surplus, err := d.surplusGateways(ctx, input, count)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range surplus {
+		zone := surplus[i].zone
+
+		for _, instance := range surplus[i].instances {
+			if strings.HasPrefix(instance.Name, d.InfraID+"-submariner-gw-"+zone) {
+				machineSet, err := d.initMachineSet(zone)
+				if err != nil {
+					return nil, err
+				}
+
+				plan.Add(api.ChangeDelete, api.MachineSetResource, machineSet.GetName(), "dedicated gateway node in zone %s", zone)
+			} else {
+				plan.Add(api.ChangeDelete, api.InstanceTagResource, instance.Name, "tag %q in zone %s", submarinerGatewayNodeTag, zone)
+				plan.Add(api.ChangeDelete, api.PublicIPResource, instance.Name, "in zone %s", zone)
+			}
+		}
+
+		for _, node := range surplus[i].nodes {
+			plan.Add(api.ChangeDelete, api.NodeLabelResource, node.Name, "gateway label")
+		}
+	}
+
+	return plan, nil
+}
+
+func (c *CloudInfo) planOpenPorts(ctx context.Context, plan *api.Plan, rules ...*compute.Firewall) error {
+	for _, rule := range rules {
+		_, err := c.Client.GetFirewallRule(ctx, c.ProjectID, rule.Name)
+		if gcpclient.IsGCPNotFoundError(err) {
+			plan.Add(api.ChangeCreate, api.FirewallRuleResource, rule.Name, "allow %s", formatAllowed(rule))
+			continue
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "error retrieving firewall rule %q", rule.Name)
+		}
+
+		plan.Add(api.ChangeUpdate, api.FirewallRuleResource, rule.Name, "allow %s", formatAllowed(rule))
+	}
+
+	return nil
+}
+
+func (c *CloudInfo) planDeleteFirewallRule(ctx context.Context, plan *api.Plan, name string) error {
+	_, err := c.Client.GetFirewallRule(ctx, c.ProjectID, name)
+	if gcpclient.IsGCPNotFoundError(err) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "error retrieving firewall rule %q", name)
+	}
+
+	plan.Add(api.ChangeDelete, api.FirewallRuleResource, name, "")
+
+	return nil
+}
+
+func formatAllowed(rule *compute.Firewall) string {
+	allowed := formatPorts(allowedPorts(rule))
+
+	if len(rule.SourceRanges) > 0 {
+		allowed += " from " + strings.Join(rule.SourceRanges, ", ")
+	}
+
+	return allowed
+}
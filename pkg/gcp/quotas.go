@@ -0,0 +1,82 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+// validateQuotas checks that the regional quotas of the project allow for the gateways which still need to be deployed.
+// Dedicated gateways need new instances, and every gateway needs a public IP.
+func (d *ocpGatewayDeployer) validateQuotas(ctx context.Context, input *api.GatewayDeployInput) error {
+	zones, err := d.Client.ListZones(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list the zones in the project %q", d.ProjectID)
+	}
+
+	numGatewayNodes, eligibleZones, err := d.gatewayZones(ctx, zones)
+	if err != nil {
+		return err
+	}
+
+	newGateways := input.Gateways - numGatewayNodes
+	if newGateways > eligibleZones.Size() {
+		newGateways = eligibleZones.Size()
+	}
+
+	if newGateways <= 0 {
+		return nil
+	}
+
+	required := map[string]int64{"IN_USE_ADDRESSES": int64(newGateways)}
+
+	if d.dedicatedGWNode {
+		machineType, err := d.Client.GetMachineType(ctx, eligibleZones.Elements()[0], d.instanceType)
+		if err != nil {
+			return errors.Wrapf(err, "failed to retrieve the machine type %q", d.instanceType)
+		}
+
+		required["CPUS"] = machineType.GuestCpus * int64(newGateways)
+		required["INSTANCES"] = int64(newGateways)
+	}
+
+	region, err := d.Client.GetRegion(ctx, d.Region)
+	if err != nil {
+		return errors.Wrapf(err, "failed to retrieve the quotas of region %q", d.Region)
+	}
+
+	quotas := []api.Quota{}
+
+	for _, quota := range region.Quotas {
+		if amount, ok := required[quota.Metric]; ok {
+			quotas = append(quotas, api.Quota{
+				Name:     fmt.Sprintf("%s in region %q", quota.Metric, d.Region),
+				Limit:    int64(quota.Limit),
+				Used:     int64(quota.Usage),
+				Required: amount,
+			})
+		}
+	}
+
+	return api.ValidateQuotas(quotas...)
+}
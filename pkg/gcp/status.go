@@ -0,0 +1,189 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/stringset"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	gcpclient "github.com/submariner-io/cloud-prepare/pkg/gcp/client"
+	"google.golang.org/api/compute/v1"
+)
+
+func (gc *gcpCloud) Status(ctx context.Context) (*api.CloudStatus, error) {
+	status := &api.CloudStatus{InternalPorts: []api.PortSpec{}}
+
+	rule, found, err := gc.getFirewallRule(ctx, InternalRuleName(gc.InfraID))
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return status, nil
+	}
+
+	status.InternalPorts = allowedPorts(rule)
+
+	if rule.Disabled {
+		status.AddIssue("the internal firewall rule %q is disabled", rule.Name)
+	}
+
+	return status, nil
+}
+
+func (d *ocpGatewayDeployer) Status(ctx context.Context) (*api.GatewayStatus, error) {
+	status := &api.GatewayStatus{}
+
+	for _, family := range []api.IPFamily{api.IPv4Family, api.IPv6Family} {
+		rule, found, err := d.getFirewallRule(ctx, PublicRuleName(d.InfraID, family))
+		if err != nil {
+			return nil, err
+		}
+
+		if !found {
+			continue
+		}
+
+		// The IPv6 rule's ports are reported along with the IPv4 rule's, distinguished by their source CIDRs.
+		if status.Firewall == nil {
+			status.Firewall = &api.FirewallStatus{Kind: api.FirewallRuleResource, Name: rule.Name}
+		}
+
+		status.Firewall.Ports = append(status.Firewall.Ports, allowedPorts(rule)...)
+
+		if rule.Disabled {
+			status.AddIssue("the gateway firewall rule %q is disabled", rule.Name)
+		}
+	}
+
+	template, err := d.initMachineSet("")
+	if err != nil {
+		return nil, err
+	}
+
+	machineSets, err := d.msDeployer.List(ctx, template, d.InfraID+"-submariner-gw-")
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the gateway machine sets")
+	}
+
+	machineSetNames := stringset.New()
+
+	for i := range machineSets {
+		status.MachineSets = append(status.MachineSets, machineSets[i].GetName())
+		machineSetNames.Add(machineSets[i].GetName())
+	}
+
+	gwNodes, err := d.k8sClient.ListGatewayNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the gateway nodes")
+	}
+
+	labeledInstances := stringset.New()
+
+	for i := range gwNodes.Items {
+		status.GatewayNodes = append(status.GatewayNodes, gwNodes.Items[i].Name)
+		labeledInstances.Add(nodeInstanceName(&gwNodes.Items[i]))
+	}
+
+	instances, err := d.gatewayInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	d.checkGatewayInstances(status, instances, machineSetNames, labeledInstances)
+
+	return status, nil
+}
+
+func (d *ocpGatewayDeployer) checkGatewayInstances(status *api.GatewayStatus, instances map[string][]*compute.Instance,
+	machineSetNames, labeledInstances stringset.Interface) {
+	zones := make([]string, 0, len(instances))
+	for zone := range instances {
+		zones = append(zones, zone)
+	}
+
+	sort.Strings(zones)
+
+	for _, zone := range zones {
+		machineSetName := d.InfraID + "-submariner-gw-" + zone
+
+		for _, instance := range instances[zone] {
+			if strings.HasPrefix(instance.Name, machineSetName) {
+				if !machineSetNames.Contains(machineSetName) {
+					status.AddIssue("dedicated gateway instance %q has no gateway MachineSet", instance.Name)
+				}
+			} else if !labeledInstances.Contains(instance.Name) {
+				status.AddIssue("instance %q is tagged as a gateway but its node isn't labeled as a gateway", instance.Name)
+			}
+		}
+	}
+
+	if status.Firewall == nil && len(instances) > 0 {
+		status.AddIssue("gateways are deployed but the gateway firewall rule doesn't exist")
+	} else if status.Firewall != nil && len(instances) == 0 && len(status.MachineSets) == 0 {
+		status.AddIssue("the gateway firewall rule %q exists but no gateways are deployed", status.Firewall.Name)
+	}
+}
+
+// gatewayInstances returns the cluster's instances tagged as gateways, by zone.
+func (c *CloudInfo) gatewayInstances(ctx context.Context) (map[string][]*compute.Instance, error) {
+	zones, err := c.Client.ListZones(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the zones in the project %q", c.ProjectID)
+	}
+
+	instances := map[string][]*compute.Instance{}
+
+	for _, zone := range zones.Items {
+		if c.ignoreZone(zone) {
+			continue
+		}
+
+		instanceList, err := c.Client.ListInstances(ctx, zone.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list instances in zone %q of project %q", zone.Name, c.ProjectID)
+		}
+
+		for _, instance := range instanceList.Items {
+			if strings.HasPrefix(instance.Name, c.InfraID) && c.isInstanceGatewayNode(instance) {
+				instances[zone.Name] = append(instances[zone.Name], instance)
+			}
+		}
+	}
+
+	return instances, nil
+}
+
+// getFirewallRule returns the named firewall rule, and whether it was found.
+func (c *CloudInfo) getFirewallRule(ctx context.Context, name string) (*compute.Firewall, bool, error) {
+	rule, err := c.Client.GetFirewallRule(ctx, c.ProjectID, name)
+	if gcpclient.IsGCPNotFoundError(err) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "error retrieving firewall rule %q", name)
+	}
+
+	return rule, true, nil
+}
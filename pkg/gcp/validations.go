@@ -0,0 +1,96 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// permission is an IAM permission, along with the operation it allows for the error messages.
+type permission struct {
+	name      string
+	operation string
+}
+
+var (
+	getFirewallRules    = permission{"compute.firewalls.get", "retrieve firewall rules"}
+	createFirewallRules = permission{"compute.firewalls.create", "create firewall rules"}
+	updateFirewallRules = permission{"compute.firewalls.update", "update firewall rules"}
+	deleteFirewallRules = permission{"compute.firewalls.delete", "delete firewall rules"}
+	updateNetworkPolicy = permission{"compute.networks.updatePolicy", "change the firewall rules of the network"}
+	listZones           = permission{"compute.zones.list", "list zones"}
+	getRegions          = permission{"compute.regions.get", "retrieve the quotas of regions"}
+	getMachineTypes     = permission{"compute.machineTypes.get", "retrieve machine types"}
+	listInstances       = permission{"compute.instances.list", "list instances"}
+	getInstances        = permission{"compute.instances.get", "retrieve instances"}
+	setInstanceTags     = permission{"compute.instances.setTags", "set the network tags of instances"}
+	addAccessConfig     = permission{"compute.instances.addAccessConfig", "add public IPs to instances"}
+	useExternalIP       = permission{"compute.subnetworks.useExternalIp", "use public IPs in the subnets"}
+	deleteAccessConfig  = permission{"compute.instances.deleteAccessConfig", "remove public IPs from instances"}
+)
+
+var (
+	preparePermissions = []permission{getFirewallRules, createFirewallRules, updateFirewallRules, updateNetworkPolicy}
+	cleanupPermissions = []permission{deleteFirewallRules, updateNetworkPolicy}
+
+	// Surplus gateways are removed when deploying, so the permissions to reset instances are also required, along with
+	// those to check the quotas.
+	deployPermissions = []permission{
+		getFirewallRules, createFirewallRules, updateFirewallRules, updateNetworkPolicy, listZones, listInstances,
+		getInstances, setInstanceTags, addAccessConfig, useExternalIP, deleteAccessConfig, getRegions, getMachineTypes,
+	}
+
+	gatewayCleanupPermissions = []permission{
+		deleteFirewallRules, updateNetworkPolicy, listZones, listInstances, setInstanceTags, deleteAccessConfig,
+	}
+)
+
+// validatePermissions checks the credentials have the given permissions on the project using testIamPermissions,
+// which doesn't change anything. An error is returned for each missing permission.
+func (c *CloudInfo) validatePermissions(ctx context.Context, permissions []permission) error {
+	names := make([]string, len(permissions))
+	for i := range permissions {
+		names[i] = permissions[i].name
+	}
+
+	granted, err := c.Client.TestIamPermissions(ctx, names)
+	if err != nil {
+		return errors.Wrapf(err, "error checking the permissions on project %q", c.ProjectID)
+	}
+
+	isGranted := map[string]bool{}
+	for _, name := range granted {
+		isGranted[name] = true
+	}
+
+	errs := []error{}
+
+	for _, p := range permissions {
+		if !isGranted[p.name] {
+			errs = append(errs, api.Errorf(api.ErrPermissionDenied, "no permission to %s, the %s permission is required on project %q",
+				p.operation, p.name, c.ProjectID))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
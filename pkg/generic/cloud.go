@@ -0,0 +1,76 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generic
+
+import (
+	"context"
+
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+)
+
+type genericCloud struct{}
+
+// NewCloud creates a generic Cloud implementation for clusters without a supported cloud provider. There is nothing
+// to prepare on such clusters, so all its operations succeed without making any changes.
+func NewCloud() api.Cloud {
+	return &genericCloud{}
+}
+
+// NewProvider creates the generic Cloud and GatewayDeployer.
+func NewProvider(k8sClient k8s.Interface) (api.Cloud, api.GatewayDeployer) {
+	return NewCloud(), NewGatewayDeployer(k8sClient)
+}
+
+func (c *genericCloud) PrepareForSubmariner(input api.PrepareForSubmarinerInput, reporter api.Reporter) error {
+	return c.PrepareForSubmarinerWithContext(context.TODO(), input, reporter)
+}
+
+func (c *genericCloud) PrepareForSubmarinerWithContext(ctx context.Context, input api.PrepareForSubmarinerInput,
+	reporter api.Reporter) error {
+	return nil
+}
+
+func (c *genericCloud) CleanupAfterSubmariner(reporter api.Reporter) error {
+	return c.CleanupAfterSubmarinerWithContext(context.TODO(), reporter)
+}
+
+func (c *genericCloud) CleanupAfterSubmarinerWithContext(ctx context.Context, reporter api.Reporter) error {
+	return nil
+}
+
+func (c *genericCloud) PlanPrepareForSubmariner(ctx context.Context, input api.PrepareForSubmarinerInput) (*api.Plan, error) {
+	return &api.Plan{}, nil
+}
+
+func (c *genericCloud) PlanCleanupAfterSubmariner(ctx context.Context) (*api.Plan, error) {
+	return &api.Plan{}, nil
+}
+
+func (c *genericCloud) Status(ctx context.Context) (*api.CloudStatus, error) {
+	return &api.CloudStatus{InternalPorts: []api.PortSpec{}}, nil
+}
+
+func (c *genericCloud) ScanOrphans(ctx context.Context) ([]api.InventoryResource, error) {
+	return []api.InventoryResource{}, nil
+}
+
+func (c *genericCloud) DeleteOrphans(ctx context.Context, orphans []api.InventoryResource, reporter api.Reporter) error {
+	return nil
+}
@@ -19,16 +19,20 @@ limitations under the License.
 package generic
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/pkg/errors"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/metrics"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type gatewayDeployer struct {
 	k8sClient k8s.Interface
+	inventory api.Inventory
 }
 
 // NewGatewayDeployer creates a generic GatewayDeployer implementation.
@@ -36,29 +40,57 @@ func NewGatewayDeployer(k8sClient k8s.Interface) api.GatewayDeployer {
 	return &gatewayDeployer{k8sClient: k8sClient}
 }
 
+// UseInventory makes the given generic GatewayDeployer record the gateway labels it adds in the inventory, and
+// only remove those when cleaning up. If the supplied deployer is not a generic one, an error is returned.
+func UseInventory(gwDeployer api.GatewayDeployer, inventory api.Inventory) error {
+	g, ok := gwDeployer.(*gatewayDeployer)
+	if !ok {
+		return errors.New("the gateway deployer must be generic")
+	}
+
+	g.inventory = inventory
+
+	return nil
+}
+
 func (g *gatewayDeployer) Deploy(input api.GatewayDeployInput, reporter api.Reporter) error {
-	gwNodes, err := g.k8sClient.ListGatewayNodes()
+	return g.DeployWithContext(context.TODO(), input, reporter)
+}
+
+func (g *gatewayDeployer) DeployWithContext(ctx context.Context, input api.GatewayDeployInput, reporter api.Reporter) error {
+	return metrics.ObserveOperation(ctx, "generic", metrics.Deploy, func() error {
+		return api.RunWithRollback(ctx, input.Rollback, reporter, func(ctx context.Context) error {
+			return g.deploy(ctx, input, reporter)
+		})
+	})
+}
+
+func (g *gatewayDeployer) deploy(ctx context.Context, input api.GatewayDeployInput, reporter api.Reporter) error {
+	gwNodes, err := g.k8sClient.ListGatewayNodes(ctx)
 	if err != nil {
 		reporter.Failed(err)
 		return errors.Wrap(err, "error listing the gateway nodes")
 	}
 
-	gatewayNodesToDeploy := input.Gateways - len(gwNodes.Items)
+	for i := range gwNodes.Items {
+		if err := g.recordGatewayLabel(ctx, gwNodes.Items[i].Name, true); err != nil {
+			reporter.Failed(err)
+			return err
+		}
+	}
 
-	if gatewayNodesToDeploy == 0 {
-		reporter.Succeeded("Current gateways match the desired number of gateways")
-		return nil
+	if gatewaysToRemove := input.GatewaysToRemove(len(gwNodes.Items)); gatewaysToRemove > 0 {
+		return g.removeGateways(ctx, &input, gwNodes.Items, gatewaysToRemove, reporter)
 	}
 
-	// Currently, we only support increasing the number of Gateway nodes which could be a valid use-case
-	// to convert a non-HA deployment to an HA deployment. We are not supporting decreasing the Gateway
-	// nodes (for now) as it might impact the datapath if we accidentally delete the active GW node.
-	if gatewayNodesToDeploy < 0 {
-		reporter.Failed(fmt.Errorf("decreasing the number of Gateway nodes is not currently supported"))
+	gatewayNodesToDeploy := input.Gateways - len(gwNodes.Items)
+
+	if gatewayNodesToDeploy <= 0 {
+		reporter.Succeeded("Current gateways match the desired number of gateways")
 		return nil
 	}
 
-	nonGWNodes, err := g.k8sClient.ListNodesWithLabel("!submariner.io/gateway")
+	nonGWNodes, err := g.k8sClient.ListNodesWithLabel(ctx, "!submariner.io/gateway")
 	if err != nil {
 		reporter.Failed(err)
 		return errors.Wrap(err, "error listing the gateway nodes")
@@ -71,12 +103,27 @@ func (g *gatewayDeployer) Deploy(input api.GatewayDeployInput, reporter api.Repo
 			continue
 		}
 
-		err = g.k8sClient.AddGWLabelOnNode(node.Name)
+		err = g.k8sClient.AddGWLabelOnNode(ctx, node.Name)
 		if err != nil {
 			reporter.Failed(err)
 			return errors.Wrapf(err, "error adding the gateway label on node %q", node.Name)
 		}
 
+		api.OnRollback(ctx, "remove the gateway label from node "+node.Name, func(ctx context.Context) error {
+			if err := g.k8sClient.RemoveGWLabelFromWorkerNode(ctx, node); err != nil {
+				return errors.Wrapf(err, "error removing the gateway label from node %q", node.Name)
+			}
+
+			return api.ForgetResource(ctx, g.inventory, gatewayLabelResource(node.Name, false))
+		})
+
+		if err := g.recordGatewayLabel(ctx, node.Name, false); err != nil {
+			reporter.Failed(err)
+			return err
+		}
+
+		metrics.GatewaysDeployed(ctx, "generic", 1)
+
 		gatewayNodesToDeploy--
 
 		if gatewayNodesToDeploy <= 0 {
@@ -92,8 +139,65 @@ func (g *gatewayDeployer) Deploy(input api.GatewayDeployInput, reporter api.Repo
 	return err
 }
 
+// removeGateways unlabels the given number of gateway nodes, but never the node hosting the active gateway.
+func (g *gatewayDeployer) removeGateways(ctx context.Context, input *api.GatewayDeployInput, gwNodes []v1.Node, count int,
+	reporter api.Reporter) error {
+	activeNode, err := input.FindActiveGatewayNode(ctx, g.k8sClient.GetActiveGatewayNode)
+	if err != nil {
+		reporter.Failed(err)
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	for _, node := range surplusGatewayNodes(gwNodes, count, activeNode) {
+		err = g.k8sClient.RemoveGWLabelFromWorkerNode(ctx, node)
+		if err != nil {
+			reporter.Failed(err)
+			return errors.Wrapf(err, "error removing the gateway label from node %q", node.Name)
+		}
+
+		if err := api.ForgetResource(ctx, g.inventory, gatewayLabelResource(node.Name, false)); err != nil {
+			reporter.Failed(err)
+			return err
+		}
+	}
+
+	reporter.Succeeded("Successfully removed %d surplus gateway nodes", count)
+
+	return nil
+}
+
+// surplusGatewayNodes returns the given number of gateway nodes to remove, skipping the node hosting the active gateway.
+func surplusGatewayNodes(gwNodes []v1.Node, count int, activeNode string) []*v1.Node {
+	surplus := []*v1.Node{}
+
+	for i := range gwNodes {
+		if len(surplus) == count {
+			break
+		}
+
+		if gwNodes[i].Name != activeNode {
+			surplus = append(surplus, &gwNodes[i])
+		}
+	}
+
+	return surplus
+}
+
 func (g *gatewayDeployer) Cleanup(reporter api.Reporter) error {
-	err := g.k8sClient.RemoveGWLabelFromWorkerNodes()
+	return g.CleanupWithContext(context.TODO(), reporter)
+}
+
+func (g *gatewayDeployer) CleanupWithContext(ctx context.Context, reporter api.Reporter) error {
+	var err error
+
+	if g.inventory != nil {
+		err = api.CleanupInventory(ctx, g.inventory, api.GatewayScope, func(resource *api.InventoryResource) error {
+			return g.k8sClient.RemoveGWLabelFromWorkerNode(ctx, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: resource.ID}})
+		})
+	} else {
+		err = g.k8sClient.RemoveGWLabelFromWorkerNodes(ctx)
+	}
+
 	if err != nil {
 		reporter.Failed(err)
 		return errors.Wrap(err, "error removing the gateway label from all worker nodes")
@@ -104,6 +208,20 @@ func (g *gatewayDeployer) Cleanup(reporter api.Reporter) error {
 	return nil
 }
 
+func (g *gatewayDeployer) recordGatewayLabel(ctx context.Context, nodeName string, preExisting bool) error {
+	return api.RecordResource(ctx, g.inventory, gatewayLabelResource(nodeName, preExisting))
+}
+
+func gatewayLabelResource(nodeName string, preExisting bool) api.InventoryResource {
+	return api.InventoryResource{
+		Scope:       api.GatewayScope,
+		Kind:        api.NodeLabelResource,
+		ID:          nodeName,
+		Description: k8s.SubmarinerGatewayLabel,
+		PreExisting: preExisting,
+	}
+}
+
 func isMasterNode(node *v1.Node) bool {
 	for _, taint := range node.Spec.Taints {
 		if taint.Key == "node-role.kubernetes.io/master" && taint.Effect == v1.TaintEffectNoSchedule {
@@ -20,13 +20,18 @@ package generic_test
 import (
 	"context"
 	"errors"
+	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/submariner-io/admiral/pkg/fake"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	"github.com/submariner-io/cloud-prepare/pkg/generic"
+	"github.com/submariner-io/cloud-prepare/pkg/inventory"
 	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/metrics"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubeFake "k8s.io/client-go/kubernetes/fake"
@@ -89,6 +94,17 @@ var _ = Describe("GatewayDeployer", func() {
 				Expect(t.doDeploy()).ToNot(Succeed())
 				t.awaitLabeledNodes(1)
 			})
+
+			Context("and rollback is requested", func() {
+				BeforeEach(func() {
+					t.rollback = true
+				})
+
+				It("should remove the labels it added and return an error", func() {
+					Expect(t.doDeploy()).ToNot(Succeed())
+					t.awaitLabeledNodes(0)
+				})
+			})
 		})
 	})
 
@@ -107,6 +123,21 @@ var _ = Describe("GatewayDeployer", func() {
 			Expect(t.doDeploy()).To(Succeed())
 			t.awaitLabeledNodes(2)
 		})
+
+		It("should record the additional gateway nodes in the metrics", func() {
+			registry := prometheus.NewRegistry()
+			m, err := metrics.New(registry)
+			Expect(err).To(Succeed())
+
+			Expect(t.gwDeployer.DeployWithContext(metrics.NewContext(context.TODO(), m), t.deployInput(),
+				api.NewLoggingReporter())).To(Succeed())
+			Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP cloud_prepare_gateways_deployed_total Number of gateways deployed, by provider.
+# TYPE cloud_prepare_gateways_deployed_total counter
+cloud_prepare_gateways_deployed_total{provider="generic"} 1
+`), "cloud_prepare_gateways_deployed_total")).To(Succeed())
+			Expect(testutil.GatherAndCount(registry, "cloud_prepare_operation_duration_seconds")).To(Equal(1))
+		})
 	})
 
 	When("the requested number of gateway nodes are already labeled", func() {
@@ -162,9 +193,43 @@ var _ = Describe("GatewayDeployer", func() {
 			t.numGateways = 1
 		})
 
-		It("should not unlabel the subtracted gateway nodes", func() {
+		It("should unlabel the surplus gateway node", func() {
 			Expect(t.doDeploy()).To(Succeed())
-			t.awaitLabeledNodes(2)
+			t.awaitLabeledNodes(1)
+		})
+
+		Context("and a node hosts the active gateway", func() {
+			BeforeEach(func() {
+				t.activeNode = "node-1"
+			})
+
+			It("should not unlabel the active gateway node", func() {
+				Expect(t.doDeploy()).To(Succeed())
+				t.awaitLabeledNodes(1)
+				Expect(t.getLabeledWorkerNodes()[0].Name).To(Equal("node-1"))
+			})
+		})
+
+		Context("and the active gateway node can't be determined", func() {
+			BeforeEach(func() {
+				t.activeNodeErr = errors.New("fake error")
+			})
+
+			It("should return an error and not unlabel any nodes", func() {
+				Expect(t.doDeploy()).ToNot(Succeed())
+				t.awaitLabeledNodes(2)
+			})
+		})
+
+		Context("to zero", func() {
+			BeforeEach(func() {
+				t.numGateways = 0
+			})
+
+			It("should not unlabel any nodes", func() {
+				Expect(t.doDeploy()).To(Succeed())
+				t.awaitLabeledNodes(2)
+			})
 		})
 	})
 
@@ -194,14 +259,170 @@ var _ = Describe("GatewayDeployer", func() {
 				Expect(t.gwDeployer.Cleanup(api.NewLoggingReporter())).ToNot(Succeed())
 			})
 		})
+
+		Context("with an inventory", func() {
+			BeforeEach(func() {
+				t.nodes = []*corev1.Node{
+					newNonMasterNode("node-1"),
+					newNonMasterNode("node-2"),
+				}
+
+				setGWLabel(t.nodes[0])
+				t.numGateways = 2
+				t.inventory = inventory.New(inventory.NewMemoryStore())
+			})
+
+			JustBeforeEach(func() {
+				Expect(t.doDeploy()).To(Succeed())
+				t.awaitLabeledNodes(2)
+			})
+
+			It("should only unlabel the nodes it labeled", func() {
+				Expect(t.gwDeployer.Cleanup(api.NewLoggingReporter())).To(Succeed())
+				t.awaitLabeledNodes(1)
+				Expect(t.getLabeledWorkerNodes()[0].Name).To(Equal("node-1"))
+				Expect(t.inventory.Resources(context.TODO())).To(BeEmpty())
+			})
+
+			It("should plan unlabeling only the nodes it labeled", func() {
+				plan, err := t.gwDeployer.PlanCleanup(context.TODO())
+				Expect(err).To(Succeed())
+				Expect(plan.Changes).To(Equal([]api.Change{{
+					Action: api.ChangeDelete, Kind: api.NodeLabelResource, Resource: "node-2", Description: k8s.SubmarinerGatewayLabel,
+				}}))
+			})
+		})
+	})
+})
+
+var _ = Describe("GatewayDeployer plan", func() {
+	t := newGatewayDeployerTestDriver()
+
+	BeforeEach(func() {
+		t.nodes = []*corev1.Node{
+			newNonMasterNode("node-1"),
+			newMasterNode("master-node"),
+			newNonMasterNode("node-2"),
+		}
+
+		setGWLabel(t.nodes[0])
+	})
+
+	Context("on deploy", func() {
+		BeforeEach(func() {
+			t.numGateways = 2
+		})
+
+		It("should plan labeling the additional gateway nodes without changing them", func() {
+			plan, err := t.gwDeployer.PlanDeploy(context.TODO(), api.GatewayDeployInput{Gateways: t.numGateways})
+			Expect(err).To(Succeed())
+			Expect(plan.Changes).To(HaveLen(1))
+			Expect(plan.Changes[0].Action).To(Equal(api.ChangeCreate))
+			Expect(plan.Changes[0].Kind).To(Equal(api.NodeLabelResource))
+			Expect(plan.Changes[0].Resource).To(Equal("node-2"))
+
+			t.awaitLabeledNodes(1)
+		})
+
+		When("there's an insufficient number of worker nodes", func() {
+			BeforeEach(func() {
+				t.numGateways = 3
+			})
+
+			It("should return an error", func() {
+				_, err := t.gwDeployer.PlanDeploy(context.TODO(), api.GatewayDeployInput{Gateways: t.numGateways})
+				Expect(err).ToNot(Succeed())
+			})
+		})
+
+		When("the requested number of gateways is decreased", func() {
+			BeforeEach(func() {
+				setGWLabel(t.nodes[2])
+				t.numGateways = 1
+				t.activeNode = "node-1"
+			})
+
+			It("should plan unlabeling the surplus gateway node without changing it", func() {
+				plan, err := t.gwDeployer.PlanDeploy(context.TODO(), t.deployInput())
+				Expect(err).To(Succeed())
+				Expect(plan.Changes).To(Equal([]api.Change{{
+					Action: api.ChangeDelete, Kind: api.NodeLabelResource, Resource: "node-2", Description: k8s.SubmarinerGatewayLabel,
+				}}))
+
+				t.awaitLabeledNodes(2)
+			})
+		})
+
+		When("the requested number of gateway nodes are already labeled", func() {
+			BeforeEach(func() {
+				t.numGateways = 1
+			})
+
+			It("should return an empty plan", func() {
+				plan, err := t.gwDeployer.PlanDeploy(context.TODO(), api.GatewayDeployInput{Gateways: t.numGateways})
+				Expect(err).To(Succeed())
+				Expect(plan.IsEmpty()).To(BeTrue())
+			})
+		})
+	})
+
+	Context("on clean up", func() {
+		It("should plan unlabeling the gateway nodes without changing them", func() {
+			plan, err := t.gwDeployer.PlanCleanup(context.TODO())
+			Expect(err).To(Succeed())
+			Expect(plan.Changes).To(HaveLen(1))
+			Expect(plan.Changes[0].Action).To(Equal(api.ChangeDelete))
+			Expect(plan.Changes[0].Resource).To(Equal("node-1"))
+
+			t.awaitLabeledNodes(1)
+		})
+	})
+})
+
+var _ = Describe("GatewayDeployer status", func() {
+	t := newGatewayDeployerTestDriver()
+
+	BeforeEach(func() {
+		t.nodes = []*corev1.Node{
+			newNonMasterNode("node-1"),
+			newNonMasterNode("node-2"),
+		}
+
+		setGWLabel(t.nodes[0])
+	})
+
+	It("should report the gateway nodes", func() {
+		status, err := t.gwDeployer.Status(context.TODO())
+		Expect(err).To(Succeed())
+		Expect(status.GatewayNodes).To(Equal([]string{"node-1"}))
+		Expect(status.IsConsistent()).To(BeTrue())
+	})
+
+	When("a master node is labeled as a gateway", func() {
+		BeforeEach(func() {
+			master := newMasterNode("master-node")
+			master.Labels = map[string]string{}
+			setGWLabel(master)
+			t.nodes = append(t.nodes, master)
+		})
+
+		It("should report an issue", func() {
+			status, err := t.gwDeployer.Status(context.TODO())
+			Expect(err).To(Succeed())
+			Expect(status.Issues).To(HaveLen(1))
+		})
 	})
 })
 
 type gatewayDeployerTestDriver struct {
-	numGateways int
-	kubeClient  *kubeFake.Clientset
-	nodes       []*corev1.Node
-	gwDeployer  api.GatewayDeployer
+	numGateways   int
+	activeNode    string
+	activeNodeErr error
+	inventory     api.Inventory
+	rollback      bool
+	kubeClient    *kubeFake.Clientset
+	nodes         []*corev1.Node
+	gwDeployer    api.GatewayDeployer
 }
 
 func newGatewayDeployerTestDriver() *gatewayDeployerTestDriver {
@@ -209,6 +430,10 @@ func newGatewayDeployerTestDriver() *gatewayDeployerTestDriver {
 
 	BeforeEach(func() {
 		t.nodes = []*corev1.Node{}
+		t.activeNode = ""
+		t.activeNodeErr = nil
+		t.inventory = nil
+		t.rollback = false
 
 		t.kubeClient = kubeFake.NewSimpleClientset()
 	})
@@ -222,6 +447,10 @@ func newGatewayDeployerTestDriver() *gatewayDeployerTestDriver {
 		t.kubeClient.ClearActions()
 
 		t.gwDeployer = generic.NewGatewayDeployer(k8s.NewInterface(t.kubeClient))
+
+		if t.inventory != nil {
+			Expect(generic.UseInventory(t.gwDeployer, t.inventory)).To(Succeed())
+		}
 	})
 
 	return t
@@ -253,7 +482,17 @@ func (t *gatewayDeployerTestDriver) awaitLabeledNodes(expCount int) {
 }
 
 func (t *gatewayDeployerTestDriver) doDeploy() error {
-	return t.gwDeployer.Deploy(api.GatewayDeployInput{Gateways: t.numGateways}, api.NewLoggingReporter())
+	return t.gwDeployer.Deploy(t.deployInput(), api.NewLoggingReporter())
+}
+
+func (t *gatewayDeployerTestDriver) deployInput() api.GatewayDeployInput {
+	return api.GatewayDeployInput{
+		Gateways: t.numGateways,
+		ActiveGatewayNode: func(_ context.Context) (string, error) {
+			return t.activeNode, t.activeNodeErr
+		},
+		Rollback: t.rollback,
+	}
 }
 
 func newNonMasterNode(name string) *corev1.Node {
@@ -0,0 +1,100 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generic
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+)
+
+func (g *gatewayDeployer) PlanDeploy(ctx context.Context, input api.GatewayDeployInput) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	gwNodes, err := g.k8sClient.ListGatewayNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the gateway nodes")
+	}
+
+	if gatewaysToRemove := input.GatewaysToRemove(len(gwNodes.Items)); gatewaysToRemove > 0 {
+		activeNode, err := input.FindActiveGatewayNode(ctx, g.k8sClient.GetActiveGatewayNode)
+		if err != nil {
+			return nil, err // nolint:wrapcheck // No need to wrap here
+		}
+
+		for _, node := range surplusGatewayNodes(gwNodes.Items, gatewaysToRemove, activeNode) {
+			plan.Add(api.ChangeDelete, api.NodeLabelResource, node.Name, k8s.SubmarinerGatewayLabel)
+		}
+
+		return plan, nil
+	}
+
+	gatewayNodesToDeploy := input.Gateways - len(gwNodes.Items)
+	if gatewayNodesToDeploy <= 0 {
+		return plan, nil
+	}
+
+	nonGWNodes, err := g.k8sClient.ListNodesWithLabel(ctx, "!submariner.io/gateway")
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the gateway nodes")
+	}
+
+	for i := range nonGWNodes.Items {
+		node := &nonGWNodes.Items[i]
+		if isMasterNode(node) {
+			continue
+		}
+
+		plan.Add(api.ChangeCreate, api.NodeLabelResource, node.Name, "%s=true", k8s.SubmarinerGatewayLabel)
+
+		gatewayNodesToDeploy--
+		if gatewayNodesToDeploy <= 0 {
+			return plan, nil
+		}
+	}
+
+	return nil, fmt.Errorf("there are an insufficient number of worker nodes (%d) to satisfy the desired number of gateways (%d)",
+		len(nonGWNodes.Items), input.Gateways)
+}
+
+func (g *gatewayDeployer) PlanCleanup(ctx context.Context) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	if g.inventory != nil {
+		if err := api.PlanInventoryCleanup(ctx, g.inventory, api.GatewayScope, plan); err != nil {
+			return nil, err // nolint:wrapcheck // No need to wrap here
+		}
+
+		return plan, nil
+	}
+
+	gwNodes, err := g.k8sClient.ListNodesWithLabel(ctx, k8s.SubmarinerGatewayLabel)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the gateway nodes")
+	}
+
+	for i := range gwNodes.Items {
+		plan.Add(api.ChangeDelete, api.NodeLabelResource, gwNodes.Items[i].Name, k8s.SubmarinerGatewayLabel)
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,46 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generic
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+func (g *gatewayDeployer) Status(ctx context.Context) (*api.GatewayStatus, error) {
+	status := &api.GatewayStatus{}
+
+	gwNodes, err := g.k8sClient.ListGatewayNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the gateway nodes")
+	}
+
+	for i := range gwNodes.Items {
+		node := &gwNodes.Items[i]
+		status.GatewayNodes = append(status.GatewayNodes, node.Name)
+
+		if isMasterNode(node) {
+			status.AddIssue("master node %q is labeled as a gateway", node.Name)
+		}
+	}
+
+	return status, nil
+}
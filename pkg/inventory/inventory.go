@@ -0,0 +1,97 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+// Store persists the resources recorded in an inventory.
+type Store interface {
+	// Load returns the stored resources, or none if nothing has been stored yet.
+	Load(ctx context.Context) ([]api.InventoryResource, error)
+
+	// Save replaces the stored resources.
+	Save(ctx context.Context, resources []api.InventoryResource) error
+}
+
+type inventory struct {
+	sync.Mutex
+	store Store
+}
+
+// New returns an Inventory which persists its resources in the given store. Every change is saved immediately, so
+// that the inventory remains accurate if an operation fails part way.
+func New(store Store) api.Inventory {
+	return &inventory{store: store}
+}
+
+func (i *inventory) Record(ctx context.Context, resource api.InventoryResource) error {
+	return i.update(ctx, func(resources []api.InventoryResource) []api.InventoryResource {
+		for j := range resources {
+			if resources[j].Key() == resource.Key() {
+				resource.PreExisting = resources[j].PreExisting
+				resources[j] = resource
+
+				return resources
+			}
+		}
+
+		return append(resources, resource)
+	})
+}
+
+func (i *inventory) Forget(ctx context.Context, resource api.InventoryResource) error {
+	return i.update(ctx, func(resources []api.InventoryResource) []api.InventoryResource {
+		remaining := []api.InventoryResource{}
+
+		for j := range resources {
+			if resources[j].Key() != resource.Key() {
+				remaining = append(remaining, resources[j])
+			}
+		}
+
+		return remaining
+	})
+}
+
+func (i *inventory) Resources(ctx context.Context) ([]api.InventoryResource, error) {
+	i.Lock()
+	defer i.Unlock()
+
+	resources, err := i.store.Load(ctx)
+
+	return resources, errors.Wrap(err, "error loading the inventory")
+}
+
+func (i *inventory) update(ctx context.Context, mutate func([]api.InventoryResource) []api.InventoryResource) error {
+	i.Lock()
+	defer i.Unlock()
+
+	resources, err := i.store.Load(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error loading the inventory")
+	}
+
+	return errors.Wrap(i.store.Save(ctx, mutate(resources)), "error saving the inventory")
+}
@@ -0,0 +1,203 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/admiral/pkg/fake"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/inventory"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeFake "k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	namespace     = "submariner-operator"
+	configMapName = "cloud-prepare-inventory"
+)
+
+var (
+	group = api.InventoryResource{Scope: api.GatewayScope, Kind: api.SecurityGroupResource, ID: "sg-1"}
+	rule  = api.InventoryResource{Scope: api.GatewayScope, Kind: api.SecurityGroupRuleResource, ID: "sgr-1", Parent: "sg-1"}
+	tag   = api.InventoryResource{Scope: api.GatewayScope, Kind: api.SubnetTagResource, ID: "subnet-1", PreExisting: true}
+)
+
+var _ = Describe("Inventory", func() {
+	Context("with a memory store", func() {
+		testInventory(func() inventory.Store {
+			return inventory.NewMemoryStore()
+		})
+	})
+
+	Context("with a file store", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+
+			dir, err = ioutil.TempDir("", "inventory")
+			Expect(err).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		testInventory(func() inventory.Store {
+			return inventory.NewFileStore(filepath.Join(dir, "inventory.json"))
+		})
+	})
+
+	Context("with a ConfigMap store", func() {
+		var kubeClient *kubeFake.Clientset
+
+		BeforeEach(func() {
+			kubeClient = kubeFake.NewSimpleClientset()
+		})
+
+		testInventory(func() inventory.Store {
+			return inventory.NewConfigMapStore(kubeClient, namespace, configMapName)
+		})
+
+		It("should store the inventory in the ConfigMap", func() {
+			inv := inventory.New(inventory.NewConfigMapStore(kubeClient, namespace, configMapName))
+			Expect(inv.Record(context.TODO(), group)).To(Succeed())
+
+			configMap, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), configMapName, metav1.GetOptions{})
+			Expect(err).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue(inventory.ConfigMapKey, ContainSubstring(`"id": "sg-1"`)))
+		})
+
+		When("updating the ConfigMap fails", func() {
+			It("should return an error", func() {
+				inv := inventory.New(inventory.NewConfigMapStore(kubeClient, namespace, configMapName))
+				Expect(inv.Record(context.TODO(), group)).To(Succeed())
+
+				reactor := fake.NewFailingReactorForResource(&kubeClient.Fake, "configmaps")
+				reactor.SetFailOnUpdate(errors.New("fake error"))
+
+				Expect(inv.Record(context.TODO(), rule)).ToNot(Succeed())
+			})
+		})
+	})
+})
+
+func testInventory(newStore func() inventory.Store) {
+	var inv api.Inventory
+
+	BeforeEach(func() {
+		inv = inventory.New(newStore())
+	})
+
+	It("should initially be empty", func() {
+		Expect(inv.Resources(context.TODO())).To(BeEmpty())
+	})
+
+	It("should return the recorded resources in order", func() {
+		Expect(inv.Record(context.TODO(), group)).To(Succeed())
+		Expect(inv.Record(context.TODO(), rule)).To(Succeed())
+		Expect(inv.Record(context.TODO(), tag)).To(Succeed())
+
+		Expect(inv.Resources(context.TODO())).To(Equal([]api.InventoryResource{group, rule, tag}))
+	})
+
+	It("should persist the resources in the store", func() {
+		store := newStore()
+		Expect(inventory.New(store).Record(context.TODO(), group)).To(Succeed())
+
+		Expect(inventory.New(store).Resources(context.TODO())).To(Equal([]api.InventoryResource{group}))
+	})
+
+	When("a resource is recorded again", func() {
+		It("should keep its original position and pre-existing state", func() {
+			Expect(inv.Record(context.TODO(), group)).To(Succeed())
+			Expect(inv.Record(context.TODO(), rule)).To(Succeed())
+
+			updated := group
+			updated.PreExisting = true
+			updated.Description = "updated"
+			Expect(inv.Record(context.TODO(), updated)).To(Succeed())
+
+			updated.PreExisting = false
+			Expect(inv.Resources(context.TODO())).To(Equal([]api.InventoryResource{updated, rule}))
+		})
+	})
+
+	When("a resource is forgotten", func() {
+		It("should no longer return it", func() {
+			Expect(inv.Record(context.TODO(), group)).To(Succeed())
+			Expect(inv.Record(context.TODO(), rule)).To(Succeed())
+			Expect(inv.Forget(context.TODO(), group)).To(Succeed())
+
+			Expect(inv.Resources(context.TODO())).To(Equal([]api.InventoryResource{rule}))
+		})
+	})
+
+	Context("on clean up", func() {
+		var removed []string
+
+		BeforeEach(func() {
+			removed = []string{}
+
+			Expect(inv.Record(context.TODO(), group)).To(Succeed())
+			Expect(inv.Record(context.TODO(), rule)).To(Succeed())
+			Expect(inv.Record(context.TODO(), tag)).To(Succeed())
+			Expect(inv.Record(context.TODO(), api.InventoryResource{Scope: api.CloudScope, Kind: api.FirewallRuleResource, ID: "fw"})).
+				To(Succeed())
+		})
+
+		It("should remove the resources of the scope which weren't pre-existing, in reverse order", func() {
+			Expect(api.CleanupInventory(context.TODO(), inv, api.GatewayScope, func(resource *api.InventoryResource) error {
+				removed = append(removed, resource.ID)
+				return nil
+			})).To(Succeed())
+
+			Expect(removed).To(Equal([]string{"sgr-1", "sg-1"}))
+
+			resources, err := inv.Resources(context.TODO())
+			Expect(err).To(Succeed())
+			Expect(resources).To(HaveLen(1))
+			Expect(resources[0].Scope).To(Equal(api.CloudScope))
+		})
+
+		When("removing a resource fails", func() {
+			It("should return an error and keep the remaining resources", func() {
+				Expect(api.CleanupInventory(context.TODO(), inv, api.GatewayScope, func(resource *api.InventoryResource) error {
+					if resource.ID == "sg-1" {
+						return errors.New("fake error")
+					}
+
+					return nil
+				})).ToNot(Succeed())
+
+				resources, err := inv.Resources(context.TODO())
+				Expect(err).To(Succeed())
+				Expect(resources).To(HaveLen(2))
+				Expect(resources[0].ID).To(Equal("sg-1"))
+			})
+		})
+	})
+}
@@ -0,0 +1,176 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ConfigMapKey is the key holding the inventory in the data of its ConfigMap.
+const ConfigMapKey = "inventory.json"
+
+type contents struct {
+	Resources []api.InventoryResource `json:"resources"`
+}
+
+func decode(data []byte) ([]api.InventoryResource, error) {
+	if len(data) == 0 {
+		return []api.InventoryResource{}, nil
+	}
+
+	c := &contents{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding the inventory")
+	}
+
+	if c.Resources == nil {
+		return []api.InventoryResource{}, nil
+	}
+
+	return c.Resources, nil
+}
+
+func encode(resources []api.InventoryResource) ([]byte, error) {
+	data, err := json.MarshalIndent(&contents{Resources: resources}, "", "  ")
+	return data, errors.Wrap(err, "error encoding the inventory")
+}
+
+type fileStore struct {
+	path string
+}
+
+// NewFileStore returns a Store which keeps the inventory in the given local file, as JSON.
+func NewFileStore(path string) Store {
+	return &fileStore{path: path}
+}
+
+func (s *fileStore) Load(_ context.Context) ([]api.InventoryResource, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return []api.InventoryResource{}, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading the inventory file %q", s.path)
+	}
+
+	return decode(data)
+}
+
+func (s *fileStore) Save(_ context.Context, resources []api.InventoryResource) error {
+	data, err := encode(resources)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrapf(ioutil.WriteFile(s.path, data, 0o600), "error writing the inventory file %q", s.path)
+}
+
+type configMapStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewConfigMapStore returns a Store which keeps the inventory in the given ConfigMap, under ConfigMapKey. The ConfigMap
+// is created when the inventory is first saved.
+func NewConfigMapStore(client kubernetes.Interface, namespace, name string) Store {
+	return &configMapStore{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+func (s *configMapStore) Load(ctx context.Context) ([]api.InventoryResource, error) {
+	configMap, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return []api.InventoryResource{}, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "error retrieving the inventory ConfigMap %s/%s", s.namespace, s.name)
+	}
+
+	return decode([]byte(configMap.Data[ConfigMapKey]))
+}
+
+func (s *configMapStore) Save(ctx context.Context, resources []api.InventoryResource) error {
+	data, err := encode(resources)
+	if err != nil {
+		return err
+	}
+
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+
+	configMap, err := configMaps.Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.name,
+				Namespace: s.namespace,
+			},
+			Data: map[string]string{ConfigMapKey: string(data)},
+		}, metav1.CreateOptions{})
+
+		return errors.Wrapf(err, "error creating the inventory ConfigMap %s/%s", s.namespace, s.name)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "error retrieving the inventory ConfigMap %s/%s", s.namespace, s.name)
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+
+	configMap.Data[ConfigMapKey] = string(data)
+
+	_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+
+	return errors.Wrapf(err, "error updating the inventory ConfigMap %s/%s", s.namespace, s.name)
+}
+
+type memoryStore struct {
+	resources []api.InventoryResource
+}
+
+// NewMemoryStore returns a Store which only keeps the inventory in memory, for example for dry runs.
+func NewMemoryStore() Store {
+	return &memoryStore{}
+}
+
+func (s *memoryStore) Load(_ context.Context) ([]api.InventoryResource, error) {
+	return append([]api.InventoryResource{}, s.resources...), nil
+}
+
+func (s *memoryStore) Save(_ context.Context, resources []api.InventoryResource) error {
+	s.resources = append([]api.InventoryResource{}, resources...)
+	return nil
+}
@@ -0,0 +1,58 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"strings"
+
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var reasonCategories = map[metav1.StatusReason]error{
+	metav1.StatusReasonUnauthorized:       api.ErrPermissionDenied,
+	metav1.StatusReasonForbidden:          api.ErrPermissionDenied,
+	metav1.StatusReasonNotFound:           api.ErrNotFound,
+	metav1.StatusReasonAlreadyExists:      api.ErrConflict,
+	metav1.StatusReasonConflict:           api.ErrConflict,
+	metav1.StatusReasonTooManyRequests:    api.ErrTransient,
+	metav1.StatusReasonServerTimeout:      api.ErrTransient,
+	metav1.StatusReasonTimeout:            api.ErrTransient,
+	metav1.StatusReasonServiceUnavailable: api.ErrTransient,
+	metav1.StatusReasonInternalError:      api.ErrTransient,
+	metav1.StatusReasonMethodNotAllowed:   api.ErrUnsupported,
+}
+
+// CategorizeError puts the errors returned by the Kubernetes API, through the typed or the dynamic clients, in the api
+// error categories, based on the reason of their status.
+func CategorizeError(err error) error {
+	reason := apierrors.ReasonForError(err)
+
+	// Resource quotas are enforced by an admission plugin, which forbids the requests exceeding them.
+	if reason == metav1.StatusReasonForbidden && strings.Contains(err.Error(), "exceeded quota") {
+		return api.NewError(api.ErrQuotaExceeded, err)
+	}
+
+	if category, ok := reasonCategories[reason]; ok {
+		return api.NewError(category, err)
+	}
+
+	return err
+}
@@ -0,0 +1,85 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/admiral/pkg/fake"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("CategorizeError", func() {
+	nodes := schema.GroupResource{Resource: "nodes"}
+
+	table.DescribeTable("should put the Kubernetes API errors in their category",
+		func(err, category error) {
+			categorized := k8s.CategorizeError(err)
+
+			if category == nil {
+				Expect(categorized).To(Equal(err))
+				return
+			}
+
+			Expect(errors.Is(categorized, category)).To(BeTrue())
+			Expect(categorized).To(MatchError(err.Error()))
+		},
+		table.Entry("unauthorized", apierrors.NewUnauthorized("fake"), api.ErrPermissionDenied),
+		table.Entry("forbidden", apierrors.NewForbidden(nodes, "node-1", errors.New("fake")), api.ErrPermissionDenied),
+		table.Entry("forbidden by a resource quota",
+			apierrors.NewForbidden(nodes, "node-1", errors.New("exceeded quota: compute-resources")), api.ErrQuotaExceeded),
+		table.Entry("not found", apierrors.NewNotFound(nodes, "node-1"), api.ErrNotFound),
+		table.Entry("already exists", apierrors.NewAlreadyExists(nodes, "node-1"), api.ErrConflict),
+		table.Entry("conflict", apierrors.NewConflict(nodes, "node-1", errors.New("fake")), api.ErrConflict),
+		table.Entry("too many requests", apierrors.NewTooManyRequests("fake", 1), api.ErrTransient),
+		table.Entry("server timeout", apierrors.NewServerTimeout(nodes, "list", 1), api.ErrTransient),
+		table.Entry("timeout", apierrors.NewTimeoutError("fake", 1), api.ErrTransient),
+		table.Entry("service unavailable", apierrors.NewServiceUnavailable("fake"), api.ErrTransient),
+		table.Entry("internal error", apierrors.NewInternalError(errors.New("fake")), api.ErrTransient),
+		table.Entry("method not allowed", apierrors.NewMethodNotSupported(nodes, "patch"), api.ErrUnsupported),
+		table.Entry("bad request", apierrors.NewBadRequest("fake"), nil),
+		table.Entry("invalid", apierrors.NewInvalid(schema.GroupKind{Kind: "Node"}, "node-1", nil), nil),
+		table.Entry("not an API error", errors.New("fake"), nil),
+	)
+
+	It("should return nil if there is no error", func() {
+		Expect(k8s.CategorizeError(nil)).To(BeNil())
+	})
+
+	When("a call of the Interface fails", func() {
+		t := newInterfaceTestDriver()
+
+		BeforeEach(func() {
+			fake.NewFailingReactorForResource(&t.kubeClient.Fake, "nodes").SetFailOnList(
+				apierrors.NewForbidden(nodes, "", errors.New("fake")))
+		})
+
+		It("should return the error in its category", func() {
+			_, err := t.client.ListGatewayNodes(context.TODO())
+			Expect(errors.Is(err, api.ErrPermissionDenied)).To(BeTrue())
+		})
+	})
+})
@@ -25,6 +25,10 @@ import (
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/resource"
 	"github.com/submariner-io/admiral/pkg/util"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/audit"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -33,14 +37,22 @@ import (
 
 const (
 	SubmarinerGatewayLabel = "submariner.io/gateway"
+
+	// GatewayStatusLabel is set on the Submariner gateway pods, with the value "active" on the active gateway.
+	GatewayStatusLabel = "gateway.submariner.io/status"
 )
 
+// labelSelectorKey is the attribute of the spans of the calls listing resources by label.
+const labelSelectorKey = attribute.Key("k8s.label_selector")
+
 type Interface interface {
-	ListNodesWithLabel(labelSelector string) (*v1.NodeList, error)
-	ListGatewayNodes() (*v1.NodeList, error)
-	AddGWLabelOnNode(nodeName string) error
-	RemoveGWLabelFromWorkerNodes() error
-	RemoveGWLabelFromWorkerNode(node *v1.Node) error
+	ListNodesWithLabel(ctx context.Context, labelSelector string) (*v1.NodeList, error)
+	ListGatewayNodes(ctx context.Context) (*v1.NodeList, error)
+	AddGWLabelOnNode(ctx context.Context, nodeName string) error
+	RemoveGWLabelFromWorkerNodes(ctx context.Context) error
+	RemoveGWLabelFromWorkerNode(ctx context.Context, node *v1.Node) error
+	GetIPFamilies(ctx context.Context) ([]api.IPFamily, error)
+	GetActiveGatewayNode(ctx context.Context) (string, error)
 }
 
 type k8sIface struct {
@@ -51,27 +63,33 @@ func NewInterface(clientSet kubernetes.Interface) Interface {
 	return &k8sIface{clientSet: clientSet}
 }
 
-func (k *k8sIface) ListNodesWithLabel(labelSelector string) (*v1.NodeList, error) {
-	nodes, err := k.clientSet.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: labelSelector})
+func (k *k8sIface) ListNodesWithLabel(ctx context.Context, labelSelector string) (_ *v1.NodeList, err error) {
+	ctx, span := api.StartCall(ctx, "Kubernetes.ListNodesWithLabel", labelSelectorKey.String(labelSelector))
+	defer func() { api.EndCall(span, err) }()
+
+	nodes, err := k.clientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
 	if err != nil {
-		return nil, errors.Wrap(err, "unable to list the nodes in the cluster")
+		return nil, errors.Wrap(CategorizeError(err), "unable to list the nodes in the cluster")
 	}
 
 	return nodes, nil
 }
 
-func (k *k8sIface) ListGatewayNodes() (*v1.NodeList, error) {
+func (k *k8sIface) ListGatewayNodes(ctx context.Context) (_ *v1.NodeList, err error) {
 	labelSelector := SubmarinerGatewayLabel + "=true"
 
-	nodes, err := k.clientSet.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: labelSelector})
+	ctx, span := api.StartCall(ctx, "Kubernetes.ListGatewayNodes", labelSelectorKey.String(labelSelector))
+	defer func() { api.EndCall(span, err) }()
+
+	nodes, err := k.clientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
 	if err != nil {
-		return nil, errors.Wrap(err, "unable to list the Gateway nodes in the cluster")
+		return nil, errors.Wrap(CategorizeError(err), "unable to list the Gateway nodes in the cluster")
 	}
 
 	return nodes, nil
 }
 
-func (k *k8sIface) updateLabel(nodeName string, mutate func(existing *v1.Node)) error {
+func (k *k8sIface) updateLabel(ctx context.Context, nodeName string, mutate func(existing *v1.Node)) error {
 	// nolint:wrapcheck // Let the caller wrap these errors.
 	client := &resource.InterfaceFuncs{
 		GetFunc: func(ctx context.Context, name string, options metav1.GetOptions) (runtime.Object, error) {
@@ -82,18 +100,24 @@ func (k *k8sIface) updateLabel(nodeName string, mutate func(existing *v1.Node))
 		},
 	}
 
-	return errors.Wrap(util.Update(context.TODO(), client, &v1.Node{
+	return errors.Wrap(CategorizeError(util.Update(ctx, client, &v1.Node{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: nodeName,
 		},
 	}, func(existing runtime.Object) (runtime.Object, error) {
 		mutate(existing.(*v1.Node))
 		return existing, nil
-	}), "error updating node")
+	})), "error updating node")
 }
 
-func (k *k8sIface) AddGWLabelOnNode(nodeName string) error {
-	return k.updateLabel(nodeName, func(existing *v1.Node) {
+func (k *k8sIface) AddGWLabelOnNode(ctx context.Context, nodeName string) (err error) {
+	ctx, span := api.StartCall(ctx, "Kubernetes.AddGWLabelOnNode", semconv.K8SNodeNameKey.String(nodeName))
+	defer func() {
+		api.EndCall(span, err)
+		audit.Record(ctx, "k8s", "Kubernetes.AddGWLabelOnNode", nodeName, map[string]string{SubmarinerGatewayLabel: "true"}, err)
+	}()
+
+	return k.updateLabel(ctx, nodeName, func(existing *v1.Node) {
 		labels := existing.GetLabels()
 		if labels == nil {
 			labels = map[string]string{}
@@ -104,15 +128,18 @@ func (k *k8sIface) AddGWLabelOnNode(nodeName string) error {
 	})
 }
 
-func (k *k8sIface) RemoveGWLabelFromWorkerNodes() error {
-	gwNodeList, err := k.clientSet.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: SubmarinerGatewayLabel})
+func (k *k8sIface) RemoveGWLabelFromWorkerNodes(ctx context.Context) (err error) {
+	ctx, span := api.StartCall(ctx, "Kubernetes.RemoveGWLabelFromWorkerNodes", labelSelectorKey.String(SubmarinerGatewayLabel))
+	defer func() { api.EndCall(span, err) }()
+
+	gwNodeList, err := k.clientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: SubmarinerGatewayLabel})
 	if err != nil {
-		return errors.Wrap(err, "error listing submariner gateway nodes")
+		return errors.Wrap(CategorizeError(err), "error listing submariner gateway nodes")
 	}
 
 	gwNodes := gwNodeList.Items
 	for i := range gwNodes {
-		err = k.RemoveGWLabelFromWorkerNode(&gwNodes[i])
+		err = k.RemoveGWLabelFromWorkerNode(ctx, &gwNodes[i])
 		if err != nil {
 			return errors.Wrap(err, fmt.Sprintf("error removing the label from the gateway node %q", gwNodes[i].Name))
 		}
@@ -121,8 +148,80 @@ func (k *k8sIface) RemoveGWLabelFromWorkerNodes() error {
 	return nil
 }
 
-func (k *k8sIface) RemoveGWLabelFromWorkerNode(node *v1.Node) error {
-	return k.updateLabel(node.Name, func(existing *v1.Node) {
+func (k *k8sIface) RemoveGWLabelFromWorkerNode(ctx context.Context, node *v1.Node) (err error) {
+	ctx, span := api.StartCall(ctx, "Kubernetes.RemoveGWLabelFromWorkerNode", semconv.K8SNodeNameKey.String(node.Name))
+	defer func() {
+		api.EndCall(span, err)
+		audit.Record(ctx, "k8s", "Kubernetes.RemoveGWLabelFromWorkerNode", node.Name, map[string]interface{}{SubmarinerGatewayLabel: nil}, err)
+	}()
+
+	return k.updateLabel(ctx, node.Name, func(existing *v1.Node) {
 		delete(existing.Labels, SubmarinerGatewayLabel)
 	})
 }
+
+// GetIPFamilies returns the IP families used by the cluster network, based on the internal addresses of its nodes.
+func (k *k8sIface) GetIPFamilies(ctx context.Context) (_ []api.IPFamily, err error) {
+	ctx, span := api.StartCall(ctx, "Kubernetes.GetIPFamilies")
+	defer func() { api.EndCall(span, err) }()
+
+	nodes, err := k.clientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(CategorizeError(err), "unable to list the nodes in the cluster")
+	}
+
+	hasFamily := map[api.IPFamily]bool{}
+
+	for i := range nodes.Items {
+		for _, address := range nodes.Items[i].Status.Addresses {
+			if address.Type == v1.NodeInternalIP {
+				hasFamily[api.CIDRFamily(address.Address)] = true
+			}
+		}
+	}
+
+	families := []api.IPFamily{}
+
+	for _, family := range []api.IPFamily{api.IPv4Family, api.IPv6Family} {
+		if hasFamily[family] {
+			families = append(families, family)
+		}
+	}
+
+	return families, nil
+}
+
+// IPFamiliesOrDetected returns the requested IP families or, if none are requested and a client is given, the
+// families used by the cluster network, see GetIPFamilies. The result is only empty, meaning IPv4 only, if neither
+// gives any family.
+func IPFamiliesOrDetected(ctx context.Context, client Interface, requested []api.IPFamily) ([]api.IPFamily, error) {
+	if len(requested) > 0 || client == nil {
+		return requested, nil
+	}
+
+	families, err := client.GetIPFamilies(ctx)
+
+	return families, errors.Wrap(err, "error detecting the IP families of the cluster network")
+}
+
+// GetActiveGatewayNode returns the name of the node running the active Submariner gateway pod, or an empty string
+// if there is none.
+func (k *k8sIface) GetActiveGatewayNode(ctx context.Context) (_ string, err error) {
+	ctx, span := api.StartCall(ctx, "Kubernetes.GetActiveGatewayNode", labelSelectorKey.String(GatewayStatusLabel+"=active"))
+	defer func() { api.EndCall(span, err) }()
+
+	pods, err := k.clientSet.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: GatewayStatusLabel + "=active",
+	})
+	if err != nil {
+		return "", errors.Wrap(CategorizeError(err), "unable to list the active gateway pods")
+	}
+
+	for i := range pods.Items {
+		if pods.Items[i].Spec.NodeName != "" {
+			return pods.Items[i].Spec.NodeName, nil
+		}
+	}
+
+	return "", nil
+}
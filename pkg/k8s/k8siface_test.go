@@ -18,6 +18,7 @@ limitations under the License.
 package k8s_test
 
 import (
+	"bytes"
 	"context"
 	"sort"
 
@@ -25,7 +26,13 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/fake"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/audit"
 	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/manifests"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubeFake "k8s.io/client-go/kubernetes/fake"
@@ -36,8 +43,307 @@ var _ = Describe("Interface", func() {
 	Describe("ListGatewayNodes", testListGatewayNodes)
 	Describe("AddGWLabelOnNode", testAddGWLabelOnNode)
 	Describe("RemoveGWLabelFromWorkerNodes", testRemoveGWLabelFromWorkerNodes)
+	Describe("GetIPFamilies", testGetIPFamilies)
+	Describe("IPFamiliesOrDetected", testIPFamiliesOrDetected)
+	Describe("GetActiveGatewayNode", testGetActiveGatewayNode)
+	Describe("tracing", testTracing)
+	Describe("auditing", testAuditing)
+	Describe("manifests", testManifests)
 })
 
+func testManifests() {
+	t := newInterfaceTestDriver()
+
+	var (
+		out    *bytes.Buffer
+		client k8s.Interface
+	)
+
+	BeforeEach(func() {
+		t.nodes = []*corev1.Node{
+			newNode("node-1", map[string]string{k8s.SubmarinerGatewayLabel: "true"}),
+			newNode("node-2", map[string]string{}),
+		}
+
+		out = &bytes.Buffer{}
+	})
+
+	JustBeforeEach(func() {
+		client = k8s.NewManifestInterface(t.client, manifests.NewStreamWriter(out))
+	})
+
+	It("should write the added gateway label instead of updating the node", func() {
+		Expect(client.AddGWLabelOnNode(context.TODO(), "node-2")).To(Succeed())
+		t.assertNoLabel("node-2", k8s.SubmarinerGatewayLabel)
+		Expect(out.String()).To(Equal(`---
+apiVersion: v1
+kind: Node
+metadata:
+  labels:
+    submariner.io/gateway: "true"
+  name: node-2
+`))
+	})
+
+	It("should write the gateway nodes without the label instead of updating them", func() {
+		Expect(client.RemoveGWLabelFromWorkerNodes(context.TODO())).To(Succeed())
+		t.assertLabel("node-1", k8s.SubmarinerGatewayLabel, "true")
+		Expect(out.String()).To(Equal(`---
+apiVersion: v1
+kind: Node
+metadata:
+  name: node-1
+`))
+	})
+
+	It("should read the nodes from the cluster", func() {
+		nodes, err := client.ListGatewayNodes(context.TODO())
+		Expect(err).To(Succeed())
+		Expect(nodes.Items).To(HaveLen(1))
+	})
+}
+
+func testAuditing() {
+	t := newInterfaceTestDriver()
+
+	var (
+		sink *audit.MemorySink
+		log  *audit.Log
+		ctx  context.Context
+	)
+
+	BeforeEach(func() {
+		t.nodes = []*corev1.Node{newNode("node-1", map[string]string{})}
+
+		sink = audit.NewMemorySink()
+		log = audit.NewLog([]byte("key"), sink)
+		ctx = audit.NewContext(context.TODO(), log)
+	})
+
+	It("should record the node label updates", func() {
+		Expect(t.client.AddGWLabelOnNode(ctx, "node-1")).To(Succeed())
+		Expect(t.client.RemoveGWLabelFromWorkerNode(ctx, t.nodes[0])).To(Succeed())
+
+		entries := sink.Entries()
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Operation).To(Equal("Kubernetes.AddGWLabelOnNode"))
+		Expect(entries[0].Resource).To(Equal("node-1"))
+		Expect(entries[0].Request).To(Equal(`{"submariner.io/gateway":"true"}`))
+		Expect(entries[1].Operation).To(Equal("Kubernetes.RemoveGWLabelFromWorkerNode"))
+		Expect(audit.Verify([]byte("key"), entries, log.LastHash())).To(Succeed())
+	})
+}
+
+func testTracing() {
+	t := newInterfaceTestDriver()
+
+	var (
+		exporter *tracetest.InMemoryExporter
+		ctx      context.Context
+		reporter api.Reporter
+	)
+
+	BeforeEach(func() {
+		t.nodes = []*corev1.Node{
+			newNode("node-1", map[string]string{k8s.SubmarinerGatewayLabel: "true"}),
+			newNode("node-2", map[string]string{k8s.SubmarinerGatewayLabel: "true"}),
+		}
+
+		exporter = tracetest.NewInMemoryExporter()
+		ctx, reporter = api.TraceSteps(context.TODO(), api.NewLoggingReporter(),
+			sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	})
+
+	It("should trace each step in a span which is the parent of the spans of its calls", func() {
+		reporter.Started("Removing the gateway labels")
+		Expect(t.client.RemoveGWLabelFromWorkerNodes(ctx)).To(Succeed())
+		reporter.Succeeded("Removed the gateway labels")
+
+		step := findSpan(exporter, "Removing the gateway labels")
+		Expect(step.Parent.IsValid()).To(BeFalse())
+		Expect(step.Status.Code).To(Equal(codes.Ok))
+
+		call := findSpan(exporter, "Kubernetes.RemoveGWLabelFromWorkerNodes")
+		Expect(call.Parent.SpanID()).To(Equal(step.SpanContext.SpanID()))
+
+		nodeCalls := 0
+
+		for _, span := range exporter.GetSpans() {
+			if span.Name == "Kubernetes.RemoveGWLabelFromWorkerNode" {
+				Expect(span.Parent.SpanID()).To(Equal(call.SpanContext.SpanID()))
+
+				nodeCalls++
+			}
+		}
+
+		Expect(nodeCalls).To(Equal(2))
+	})
+
+	When("a call fails", func() {
+		BeforeEach(func() {
+			fake.NewFailingReactorForResource(&t.kubeClient.Fake, "nodes").SetFailOnGet(errors.New("fake error"))
+		})
+
+		It("should record the error in the spans of the call and of the step", func() {
+			reporter.Started("Adding the gateway label")
+
+			err := t.client.AddGWLabelOnNode(ctx, "node-1")
+			Expect(err).ToNot(Succeed())
+
+			reporter.Failed(err)
+
+			call := findSpan(exporter, "Kubernetes.AddGWLabelOnNode")
+			Expect(call.Status.Code).To(Equal(codes.Error))
+			Expect(call.Events).To(HaveLen(1))
+
+			step := findSpan(exporter, "Adding the gateway label")
+			Expect(call.Parent.SpanID()).To(Equal(step.SpanContext.SpanID()))
+			Expect(step.Status.Code).To(Equal(codes.Error))
+			Expect(step.Status.Description).To(ContainSubstring("fake error"))
+		})
+	})
+}
+
+func findSpan(exporter *tracetest.InMemoryExporter, name string) *tracetest.SpanStub {
+	spans := exporter.GetSpans()
+
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+
+	Fail("span " + name + " not found")
+
+	return nil
+}
+
+func testGetActiveGatewayNode() {
+	t := newInterfaceTestDriver()
+
+	var pods []*corev1.Pod
+
+	BeforeEach(func() {
+		pods = []*corev1.Pod{
+			newGatewayPod("gateway-1", "node-1", "passive"),
+		}
+	})
+
+	JustBeforeEach(func() {
+		for _, pod := range pods {
+			_, err := t.kubeClient.CoreV1().Pods(pod.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+			Expect(err).To(Succeed())
+		}
+	})
+
+	When("there's an active gateway pod", func() {
+		BeforeEach(func() {
+			pods = append(pods, newGatewayPod("gateway-2", "node-2", "active"))
+		})
+
+		It("should return its node", func() {
+			Expect(t.client.GetActiveGatewayNode(context.TODO())).To(Equal("node-2"))
+		})
+	})
+
+	When("there's no active gateway pod", func() {
+		It("should return an empty node name", func() {
+			Expect(t.client.GetActiveGatewayNode(context.TODO())).To(BeEmpty())
+		})
+	})
+
+	Context("on failure", func() {
+		BeforeEach(func() {
+			fake.NewFailingReactorForResource(&t.kubeClient.Fake, "pods").SetFailOnList(errors.New("fake error"))
+		})
+
+		It("should return an error", func() {
+			_, err := t.client.GetActiveGatewayNode(context.TODO())
+			Expect(err).ToNot(Succeed())
+		})
+	})
+}
+
+func testGetIPFamilies() {
+	t := newInterfaceTestDriver()
+
+	var (
+		families []api.IPFamily
+		err      error
+	)
+
+	BeforeEach(func() {
+		t.nodes = []*corev1.Node{
+			withInternalIPs(newNode("node-1", nil), "10.0.0.1"),
+			withInternalIPs(newNode("node-2", nil), "10.0.0.2"),
+		}
+	})
+
+	JustBeforeEach(func() {
+		families, err = t.client.GetIPFamilies(context.TODO())
+	})
+
+	When("the nodes only have IPv4 addresses", func() {
+		It("should return IPv4", func() {
+			Expect(err).To(Succeed())
+			Expect(families).To(Equal([]api.IPFamily{api.IPv4Family}))
+		})
+	})
+
+	When("the nodes have IPv4 and IPv6 addresses", func() {
+		BeforeEach(func() {
+			t.nodes = append(t.nodes, withInternalIPs(newNode("node-3", nil), "fd00::3", "10.0.0.3"))
+		})
+
+		It("should return both families", func() {
+			Expect(err).To(Succeed())
+			Expect(families).To(Equal([]api.IPFamily{api.IPv4Family, api.IPv6Family}))
+		})
+	})
+
+	Context("on failure", func() {
+		BeforeEach(func() {
+			fake.NewFailingReactorForResource(&t.kubeClient.Fake, "nodes").SetFailOnList(errors.New("fake error"))
+		})
+
+		It("should return an error", func() {
+			Expect(err).ToNot(Succeed())
+		})
+	})
+}
+
+func testIPFamiliesOrDetected() {
+	t := newInterfaceTestDriver()
+
+	BeforeEach(func() {
+		t.nodes = []*corev1.Node{withInternalIPs(newNode("node-1", nil), "fd00::1", "10.0.0.1")}
+	})
+
+	When("families are requested", func() {
+		It("should return them", func() {
+			families, err := k8s.IPFamiliesOrDetected(context.TODO(), t.client, []api.IPFamily{api.IPv4Family})
+			Expect(err).To(Succeed())
+			Expect(families).To(Equal([]api.IPFamily{api.IPv4Family}))
+		})
+	})
+
+	When("no families are requested", func() {
+		It("should return the cluster's families", func() {
+			families, err := k8s.IPFamiliesOrDetected(context.TODO(), t.client, nil)
+			Expect(err).To(Succeed())
+			Expect(families).To(Equal([]api.IPFamily{api.IPv4Family, api.IPv6Family}))
+		})
+	})
+
+	When("there is no client", func() {
+		It("should return no families", func() {
+			families, err := k8s.IPFamiliesOrDetected(context.TODO(), nil, nil)
+			Expect(err).To(Succeed())
+			Expect(families).To(BeEmpty())
+		})
+	})
+}
+
 func testRemoveGWLabelFromWorkerNodes() {
 	t := newInterfaceTestDriver()
 
@@ -50,7 +356,7 @@ func testRemoveGWLabelFromWorkerNodes() {
 	})
 
 	It("should remove the label from all nodes", func() {
-		Expect(t.client.RemoveGWLabelFromWorkerNodes()).To(Succeed())
+		Expect(t.client.RemoveGWLabelFromWorkerNodes(context.TODO())).To(Succeed())
 		t.assertNoLabel(t.nodes[0].Name, k8s.SubmarinerGatewayLabel)
 		t.assertNoLabel(t.nodes[1].Name, k8s.SubmarinerGatewayLabel)
 		t.assertNoLabel(t.nodes[2].Name, k8s.SubmarinerGatewayLabel)
@@ -63,7 +369,7 @@ func testRemoveGWLabelFromWorkerNodes() {
 		})
 
 		It("should return an error", func() {
-			Expect(t.client.RemoveGWLabelFromWorkerNodes()).ToNot(Succeed())
+			Expect(t.client.RemoveGWLabelFromWorkerNodes(context.TODO())).ToNot(Succeed())
 		})
 	})
 }
@@ -77,7 +383,7 @@ func testAddGWLabelOnNode() {
 
 	When("the gateway label isn't present", func() {
 		It("should add it", func() {
-			Expect(t.client.AddGWLabelOnNode("node")).To(Succeed())
+			Expect(t.client.AddGWLabelOnNode(context.TODO(), "node")).To(Succeed())
 			t.assertLabel(t.nodes[0].Name, k8s.SubmarinerGatewayLabel, "true")
 			t.assertLabel(t.nodes[0].Name, "foo", "bar")
 		})
@@ -89,7 +395,7 @@ func testAddGWLabelOnNode() {
 		})
 
 		It("should set it to true", func() {
-			Expect(t.client.AddGWLabelOnNode(t.nodes[0].Name)).To(Succeed())
+			Expect(t.client.AddGWLabelOnNode(context.TODO(), t.nodes[0].Name)).To(Succeed())
 			t.assertLabel(t.nodes[0].Name, k8s.SubmarinerGatewayLabel, "true")
 		})
 	})
@@ -100,7 +406,7 @@ func testAddGWLabelOnNode() {
 		})
 
 		It("should not try to update it", func() {
-			Expect(t.client.AddGWLabelOnNode(t.nodes[0].Name)).To(Succeed())
+			Expect(t.client.AddGWLabelOnNode(context.TODO(), t.nodes[0].Name)).To(Succeed())
 
 			actualActions := t.kubeClient.Fake.Actions()
 			for i := range actualActions {
@@ -117,7 +423,7 @@ func testAddGWLabelOnNode() {
 		})
 
 		It("should add the gateway label", func() {
-			Expect(t.client.AddGWLabelOnNode(t.nodes[0].Name)).To(Succeed())
+			Expect(t.client.AddGWLabelOnNode(context.TODO(), t.nodes[0].Name)).To(Succeed())
 			t.assertLabel(t.nodes[0].Name, k8s.SubmarinerGatewayLabel, "true")
 		})
 	})
@@ -128,7 +434,7 @@ func testAddGWLabelOnNode() {
 		})
 
 		It("should not return an error", func() {
-			Expect(t.client.AddGWLabelOnNode("node")).To(Succeed())
+			Expect(t.client.AddGWLabelOnNode(context.TODO(), "node")).To(Succeed())
 		})
 	})
 
@@ -138,7 +444,7 @@ func testAddGWLabelOnNode() {
 		})
 
 		It("should return an error", func() {
-			Expect(t.client.AddGWLabelOnNode(t.nodes[0].Name)).ToNot(Succeed())
+			Expect(t.client.AddGWLabelOnNode(context.TODO(), t.nodes[0].Name)).ToNot(Succeed())
 		})
 	})
 }
@@ -156,7 +462,7 @@ func testListGatewayNodes() {
 	})
 
 	It("should return the correct nodes", func() {
-		list, err := t.client.ListGatewayNodes()
+		list, err := t.client.ListGatewayNodes(context.TODO())
 		Expect(err).To(Succeed())
 
 		assertNodeNames(list, "node-1", "node-2")
@@ -168,7 +474,7 @@ func testListGatewayNodes() {
 		})
 
 		It("should return an error", func() {
-			_, err := t.client.ListGatewayNodes()
+			_, err := t.client.ListGatewayNodes(context.TODO())
 			Expect(err).ToNot(Succeed())
 		})
 	})
@@ -198,7 +504,7 @@ func testListNodesWithLabel() {
 		})
 
 		It("should return an error", func() {
-			_, err := t.client.ListNodesWithLabel("")
+			_, err := t.client.ListNodesWithLabel(context.TODO(), "")
 			Expect(err).ToNot(Succeed())
 		})
 	})
@@ -232,7 +538,7 @@ func newInterfaceTestDriver() *interfaceTestDriver {
 }
 
 func (t *interfaceTestDriver) testListNodesWithLabel(labelSelector string, expNodes ...string) {
-	list, err := t.client.ListNodesWithLabel(labelSelector)
+	list, err := t.client.ListNodesWithLabel(context.TODO(), labelSelector)
 	Expect(err).To(Succeed())
 
 	assertNodeNames(list, expNodes...)
@@ -275,3 +581,22 @@ func newNode(name string, labels map[string]string) *corev1.Node {
 		},
 	}
 }
+
+func withInternalIPs(node *corev1.Node, addresses ...string) *corev1.Node {
+	for _, address := range addresses {
+		node.Status.Addresses = append(node.Status.Addresses, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: address})
+	}
+
+	return node
+}
+
+func newGatewayPod(name, nodeName, status string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "submariner-operator",
+			Labels:    map[string]string{k8s.GatewayStatusLabel: status},
+		},
+		Spec: corev1.PodSpec{NodeName: nodeName},
+	}
+}
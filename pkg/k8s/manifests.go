@@ -0,0 +1,76 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"context"
+
+	"github.com/submariner-io/cloud-prepare/pkg/manifests"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type manifestIface struct {
+	Interface
+	writer *manifests.Writer
+}
+
+// NewManifestInterface returns an Interface which writes the changes to the gateway labels of the nodes as manifests,
+// rather than applying them. Each manifest only holds the node's name and the labels set by Submariner, so that applying
+// it merges them into the node; removing the gateway label writes the manifest without it, which removes the label when
+// applied over the manifest which added it. The nodes and pods are still read from the cluster with the given reader.
+func NewManifestInterface(reader Interface, writer *manifests.Writer) Interface {
+	return &manifestIface{
+		Interface: reader,
+		writer:    writer,
+	}
+}
+
+func (m *manifestIface) AddGWLabelOnNode(_ context.Context, nodeName string) error {
+	return m.writeNode(nodeName, map[string]string{SubmarinerGatewayLabel: "true"})
+}
+
+func (m *manifestIface) RemoveGWLabelFromWorkerNodes(ctx context.Context) error {
+	gwNodeList, err := m.ListNodesWithLabel(ctx, SubmarinerGatewayLabel)
+	if err != nil {
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	for i := range gwNodeList.Items {
+		if err := m.RemoveGWLabelFromWorkerNode(ctx, &gwNodeList.Items[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *manifestIface) RemoveGWLabelFromWorkerNode(_ context.Context, node *v1.Node) error {
+	return m.writeNode(node.Name, nil)
+}
+
+func (m *manifestIface) writeNode(nodeName string, labels map[string]string) error {
+	node := &unstructured.Unstructured{}
+	node.SetAPIVersion("v1")
+	node.SetKind("Node")
+	node.SetName(nodeName)
+	node.SetLabels(labels)
+
+	return m.writer.Write(node) // nolint:wrapcheck // No need to wrap here
+}
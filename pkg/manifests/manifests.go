@@ -0,0 +1,148 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package manifests writes the Kubernetes resources which would otherwise be applied to the cluster as manifests, so
+// that they can be applied by a GitOps pipeline instead.
+package manifests
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// KustomizationFile is the name of the kustomization listing the manifests written to a directory.
+const KustomizationFile = "kustomization.yaml"
+
+// Writer writes manifests, either as the files of a kustomize-ready directory or as a stream of YAML documents.
+type Writer struct {
+	mutex sync.Mutex
+	dir   string
+	out   io.Writer
+}
+
+// NewDirectoryWriter returns a Writer which writes each manifest to a file in the given directory, creating it if
+// needed, and lists all the manifests of the directory, including those written previously, in its kustomization.
+func NewDirectoryWriter(dir string) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrapf(err, "error creating the manifests directory %q", dir)
+	}
+
+	return &Writer{dir: dir}, nil
+}
+
+// NewStreamWriter returns a Writer which writes the manifests to out as a stream of YAML documents.
+func NewStreamWriter(out io.Writer) *Writer {
+	return &Writer{out: out}
+}
+
+// Write writes the manifest of the given object, replacing any manifest previously written for it in a directory.
+func (w *Writer) Write(obj *unstructured.Unstructured) error {
+	manifest, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return errors.Wrapf(err, "error marshalling the manifest of %s %q", obj.GetKind(), obj.GetName())
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.out != nil {
+		_, err = fmt.Fprintf(w.out, "---\n%s", manifest)
+
+		return errors.Wrapf(err, "error writing the manifest of %s %q", obj.GetKind(), obj.GetName())
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(w.dir, fileName(obj)), manifest, 0o600); err != nil {
+		return errors.Wrapf(err, "error writing the manifest of %s %q", obj.GetKind(), obj.GetName())
+	}
+
+	return w.writeKustomization()
+}
+
+// Remove removes the manifest of the given object from the directory, if it exists. Manifests can't be removed from a
+// stream, so an error is returned for streams.
+func (w *Writer) Remove(obj *unstructured.Unstructured) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.out != nil {
+		return fmt.Errorf("the manifest of %s %q can't be removed from a stream", obj.GetKind(), obj.GetName())
+	}
+
+	err := os.Remove(filepath.Join(w.dir, fileName(obj)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "error removing the manifest of %s %q", obj.GetKind(), obj.GetName())
+	}
+
+	return w.writeKustomization()
+}
+
+// writeKustomization lists the manifests of the directory, sorted by name, in its kustomization.
+func (w *Writer) writeKustomization() error {
+	files, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		return errors.Wrapf(err, "error reading the manifests directory %q", w.dir)
+	}
+
+	resources := []string{}
+
+	for _, file := range files {
+		if !file.IsDir() && file.Name() != KustomizationFile && strings.HasSuffix(file.Name(), ".yaml") {
+			resources = append(resources, file.Name())
+		}
+	}
+
+	sort.Strings(resources)
+
+	kustomization, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "kustomize.config.k8s.io/v1beta1",
+		"kind":       "Kustomization",
+		"resources":  resources,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error marshalling the kustomization")
+	}
+
+	err = ioutil.WriteFile(filepath.Join(w.dir, KustomizationFile), kustomization, 0o600)
+
+	return errors.Wrapf(err, "error writing the kustomization of %q", w.dir)
+}
+
+// fileName returns the name of the object's manifest, e.g. "machineset-openshift-machine-api-gw.yaml".
+func fileName(obj *unstructured.Unstructured) string {
+	parts := []string{strings.ToLower(obj.GetKind())}
+
+	if obj.GetNamespace() != "" {
+		parts = append(parts, obj.GetNamespace())
+	}
+
+	return strings.Join(append(parts, obj.GetName()), "-") + ".yaml"
+}
@@ -0,0 +1,153 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifests_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/manifests"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Writer", func() {
+	var machineSet, node *unstructured.Unstructured
+
+	BeforeEach(func() {
+		machineSet = &unstructured.Unstructured{}
+		machineSet.SetAPIVersion("machine.openshift.io/v1beta1")
+		machineSet.SetKind("MachineSet")
+		machineSet.SetNamespace("openshift-machine-api")
+		machineSet.SetName("test-gw")
+
+		node = &unstructured.Unstructured{}
+		node.SetAPIVersion("v1")
+		node.SetKind("Node")
+		node.SetName("test-node")
+	})
+
+	Context("writing to a directory", func() {
+		var (
+			dir    string
+			writer *manifests.Writer
+		)
+
+		BeforeEach(func() {
+			var err error
+
+			dir, err = ioutil.TempDir("", "manifests")
+			Expect(err).To(Succeed())
+
+			writer, err = manifests.NewDirectoryWriter(filepath.Join(dir, "gateways"))
+			Expect(err).To(Succeed())
+
+			Expect(writer.Write(node)).To(Succeed())
+			Expect(writer.Write(machineSet)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		readFile := func(name string) string {
+			content, err := ioutil.ReadFile(filepath.Join(dir, "gateways", name))
+			Expect(err).To(Succeed())
+
+			return string(content)
+		}
+
+		It("should write each manifest to a file", func() {
+			Expect(readFile("machineset-openshift-machine-api-test-gw.yaml")).To(Equal(`apiVersion: machine.openshift.io/v1beta1
+kind: MachineSet
+metadata:
+  name: test-gw
+  namespace: openshift-machine-api
+`))
+			Expect(readFile("node-test-node.yaml")).To(ContainSubstring("name: test-node"))
+		})
+
+		It("should list the manifests in the kustomization", func() {
+			Expect(readFile(manifests.KustomizationFile)).To(Equal(`apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- machineset-openshift-machine-api-test-gw.yaml
+- node-test-node.yaml
+`))
+		})
+
+		When("a manifest is removed", func() {
+			It("should remove its file and unlist it", func() {
+				Expect(writer.Remove(machineSet)).To(Succeed())
+
+				_, err := os.Stat(filepath.Join(dir, "gateways", "machineset-openshift-machine-api-test-gw.yaml"))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+				Expect(readFile(manifests.KustomizationFile)).ToNot(ContainSubstring("machineset"))
+			})
+		})
+
+		When("a new writer uses the directory", func() {
+			It("should keep the manifests written previously", func() {
+				writer, err := manifests.NewDirectoryWriter(filepath.Join(dir, "gateways"))
+				Expect(err).To(Succeed())
+
+				node.SetName("other-node")
+				Expect(writer.Write(node)).To(Succeed())
+				Expect(readFile(manifests.KustomizationFile)).To(And(ContainSubstring("- machineset-openshift-machine-api-test-gw.yaml"),
+					ContainSubstring("- node-test-node.yaml"), ContainSubstring("- node-other-node.yaml")))
+			})
+		})
+	})
+
+	Context("writing to a stream", func() {
+		var (
+			out    *bytes.Buffer
+			writer *manifests.Writer
+		)
+
+		BeforeEach(func() {
+			out = &bytes.Buffer{}
+			writer = manifests.NewStreamWriter(out)
+		})
+
+		It("should write the manifests as YAML documents", func() {
+			Expect(writer.Write(node)).To(Succeed())
+			Expect(writer.Write(machineSet)).To(Succeed())
+			Expect(out.String()).To(Equal(`---
+apiVersion: v1
+kind: Node
+metadata:
+  name: test-node
+---
+apiVersion: machine.openshift.io/v1beta1
+kind: MachineSet
+metadata:
+  name: test-gw
+  namespace: openshift-machine-api
+`))
+		})
+
+		It("should fail to remove a manifest", func() {
+			Expect(writer.Remove(machineSet)).ToNot(Succeed())
+		})
+	})
+})
@@ -0,0 +1,182 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics provides the Prometheus metrics of the cloud API calls made by the providers, and of the outcome of
+// the preparations and gateway deployments.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+const (
+	namespace = "cloud_prepare"
+
+	providerLabel  = "provider"
+	operationLabel = "operation"
+	categoryLabel  = "category"
+	resultLabel    = "result"
+)
+
+// The operations whose duration is observed by ObserveOperation.
+const (
+	PrepareForSubmariner = "PrepareForSubmariner"
+	Deploy               = "Deploy"
+)
+
+// Metrics are the collectors of the metrics, created and registered by New. The providers update them for the
+// operations run with a context carrying them, see NewContext.
+type Metrics struct {
+	apiCalls          *prometheus.CounterVec
+	apiCallDuration   *prometheus.HistogramVec
+	apiCallFailures   *prometheus.CounterVec
+	operationDuration *prometheus.HistogramVec
+	gatewaysDeployed  *prometheus.CounterVec
+}
+
+type metricsKey struct{}
+
+// The error categories, as they appear in the category label of the API call failures.
+var categories = []struct {
+	category error
+	label    string
+}{
+	{api.ErrPermissionDenied, "permission_denied"},
+	{api.ErrNotFound, "not_found"},
+	{api.ErrQuotaExceeded, "quota_exceeded"},
+	{api.ErrConflict, "conflict"},
+	{api.ErrTransient, "transient"},
+	{api.ErrUnsupported, "unsupported"},
+}
+
+// New creates the metrics and registers them with the given registerer, e.g. prometheus.DefaultRegisterer or a new
+// registry in tests.
+func New(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		apiCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "api_calls_total",
+			Help:      "Number of cloud API calls, by provider and operation.",
+		}, []string{providerLabel, operationLabel}),
+		apiCallDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "api_call_duration_seconds",
+			Help:      "Latency of the cloud API calls, by provider and operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{providerLabel, operationLabel}),
+		apiCallFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "api_call_failures_total",
+			Help:      "Number of failed cloud API calls, by provider, operation and error category.",
+		}, []string{providerLabel, operationLabel, categoryLabel}),
+		operationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "operation_duration_seconds",
+			Help:      "Time spent preparing the clouds and deploying the gateways, by provider, operation and result.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+		}, []string{providerLabel, operationLabel, resultLabel}),
+		gatewaysDeployed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "gateways_deployed_total",
+			Help:      "Number of gateways deployed, by provider.",
+		}, []string{providerLabel}),
+	}
+
+	for _, collector := range []prometheus.Collector{
+		m.apiCalls, m.apiCallDuration, m.apiCallFailures, m.operationDuration, m.gatewaysDeployed,
+	} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err // nolint:wrapcheck // No need to wrap here
+		}
+	}
+
+	return m, nil
+}
+
+// NewContext returns a context carrying the given metrics, which the providers update for the operations run with it.
+// No metrics are updated for the operations run with a context carrying none, which is the default.
+func NewContext(ctx context.Context, m *Metrics) context.Context {
+	if m == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, metricsKey{}, m)
+}
+
+func fromContext(ctx context.Context) *Metrics {
+	m, _ := ctx.Value(metricsKey{}).(*Metrics)
+	return m
+}
+
+// ObserveCall records a cloud API call of the given provider, e.g. "aws", and operation, e.g. "EC2.DescribeVpcs", which
+// started at the given time and returned the given error, in the metrics carried by the context. Failures are counted
+// by error category, see api.Error.
+func ObserveCall(ctx context.Context, provider, operation string, start time.Time, err error) {
+	c := fromContext(ctx)
+	if c == nil {
+		return
+	}
+
+	c.apiCalls.WithLabelValues(provider, operation).Inc()
+	c.apiCallDuration.WithLabelValues(provider, operation).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		c.apiCallFailures.WithLabelValues(provider, operation, category(err)).Inc()
+	}
+}
+
+// ObserveOperation runs the given operation of the given provider, e.g. PrepareForSubmariner, recording how long it
+// took and whether it succeeded in the metrics carried by the context. The operation's error is returned.
+func ObserveOperation(ctx context.Context, provider, operation string, run func() error) error {
+	start := time.Now()
+	err := run()
+
+	if c := fromContext(ctx); c != nil {
+		result := "success"
+		if err != nil {
+			result = "failure"
+		}
+
+		c.operationDuration.WithLabelValues(provider, operation, result).Observe(time.Since(start).Seconds())
+	}
+
+	return err
+}
+
+// GatewaysDeployed records the given number of gateways deployed by the given provider in the metrics carried by the
+// context.
+func GatewaysDeployed(ctx context.Context, provider string, count int) {
+	if c := fromContext(ctx); c != nil {
+		c.gatewaysDeployed.WithLabelValues(provider).Add(float64(count))
+	}
+}
+
+func category(err error) string {
+	for _, c := range categories {
+		if errors.Is(err, c.category) {
+			return c.label
+		}
+	}
+
+	return "other"
+}
@@ -0,0 +1,162 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/metrics"
+)
+
+var _ = Describe("Metrics", func() {
+	var (
+		registry *prometheus.Registry
+		ctx      context.Context
+	)
+
+	BeforeEach(func() {
+		registry = prometheus.NewRegistry()
+
+		m, err := metrics.New(registry)
+		Expect(err).To(Succeed())
+
+		ctx = metrics.NewContext(context.TODO(), m)
+	})
+
+	When("cloud API calls are observed", func() {
+		BeforeEach(func() {
+			start := time.Now()
+
+			metrics.ObserveCall(ctx, "aws", "EC2.DescribeVpcs", start, nil)
+			metrics.ObserveCall(ctx, "aws", "EC2.DescribeVpcs", start, api.Errorf(api.ErrQuotaExceeded, "fake error"))
+			metrics.ObserveCall(ctx, "gcp", "compute.firewalls.get", start, errors.New("fake error"))
+		})
+
+		It("should count them by provider and operation", func() {
+			Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP cloud_prepare_api_calls_total Number of cloud API calls, by provider and operation.
+# TYPE cloud_prepare_api_calls_total counter
+cloud_prepare_api_calls_total{operation="EC2.DescribeVpcs",provider="aws"} 2
+cloud_prepare_api_calls_total{operation="compute.firewalls.get",provider="gcp"} 1
+`), "cloud_prepare_api_calls_total")).To(Succeed())
+		})
+
+		It("should count the failures by error category", func() {
+			Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP cloud_prepare_api_call_failures_total Number of failed cloud API calls, by provider, operation and error category.
+# TYPE cloud_prepare_api_call_failures_total counter
+cloud_prepare_api_call_failures_total{category="quota_exceeded",operation="EC2.DescribeVpcs",provider="aws"} 1
+cloud_prepare_api_call_failures_total{category="other",operation="compute.firewalls.get",provider="gcp"} 1
+`), "cloud_prepare_api_call_failures_total")).To(Succeed())
+		})
+
+		It("should observe their latencies", func() {
+			Expect(histogramCount(registry, "cloud_prepare_api_call_duration_seconds", "operation", "EC2.DescribeVpcs")).To(Equal(2))
+		})
+	})
+
+	When("an operation is observed", func() {
+		It("should run it and observe its duration by result", func() {
+			Expect(metrics.ObserveOperation(ctx, "gcp", metrics.Deploy, func() error { return nil })).To(Succeed())
+			Expect(metrics.ObserveOperation(ctx, "gcp", metrics.Deploy, func() error {
+				return errors.New("fake error")
+			})).To(MatchError("fake error"))
+
+			Expect(histogramCount(registry, "cloud_prepare_operation_duration_seconds", "result", "success")).To(Equal(1))
+			Expect(histogramCount(registry, "cloud_prepare_operation_duration_seconds", "result", "failure")).To(Equal(1))
+		})
+	})
+
+	When("gateways are deployed", func() {
+		It("should count them by provider", func() {
+			metrics.GatewaysDeployed(ctx, "rhos", 2)
+			metrics.GatewaysDeployed(ctx, "rhos", 1)
+
+			Expect(testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP cloud_prepare_gateways_deployed_total Number of gateways deployed, by provider.
+# TYPE cloud_prepare_gateways_deployed_total counter
+cloud_prepare_gateways_deployed_total{provider="rhos"} 3
+`), "cloud_prepare_gateways_deployed_total")).To(Succeed())
+		})
+	})
+
+	When("the context carries no metrics", func() {
+		It("should not update them", func() {
+			metrics.ObserveCall(context.TODO(), "aws", "EC2.DescribeVpcs", time.Now(), nil)
+			metrics.GatewaysDeployed(context.TODO(), "aws", 1)
+			Expect(metrics.ObserveOperation(context.TODO(), "aws", metrics.Deploy, func() error { return nil })).To(Succeed())
+
+			Expect(testutil.GatherAndCount(registry)).To(BeZero())
+		})
+	})
+
+	When("the context carries other metrics", func() {
+		It("should only update those", func() {
+			other := prometheus.NewRegistry()
+
+			m, err := metrics.New(other)
+			Expect(err).To(Succeed())
+
+			metrics.GatewaysDeployed(metrics.NewContext(context.TODO(), m), "aws", 1)
+
+			Expect(testutil.GatherAndCount(registry)).To(BeZero())
+			Expect(testutil.GatherAndCount(other, "cloud_prepare_gateways_deployed_total")).To(Equal(1))
+		})
+	})
+
+	When("the metrics are already registered", func() {
+		It("should return an error", func() {
+			_, err := metrics.New(registry)
+			Expect(err).ToNot(Succeed())
+		})
+	})
+})
+
+// histogramCount returns the number of observations of the histogram with the given name, in the series with the
+// given label value.
+func histogramCount(registry *prometheus.Registry, name, label, value string) int {
+	families, err := registry.Gather()
+	Expect(err).To(Succeed())
+
+	count := 0
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			for _, pair := range metric.GetLabel() {
+				if pair.GetName() == label && pair.GetValue() == value {
+					count += int(metric.GetHistogram().GetSampleCount())
+				}
+			}
+		}
+	}
+
+	return count
+}
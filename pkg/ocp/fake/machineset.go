@@ -22,6 +22,7 @@ limitations under the License.
 package fake
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -52,44 +53,59 @@ func (m *MockMachineSetDeployer) EXPECT() *MockMachineSetDeployerMockRecorder {
 }
 
 // Delete mocks base method.
-func (m *MockMachineSetDeployer) Delete(machineSet *unstructured.Unstructured) error {
+func (m *MockMachineSetDeployer) Delete(ctx context.Context, machineSet *unstructured.Unstructured) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", machineSet)
+	ret := m.ctrl.Call(m, "Delete", ctx, machineSet)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockMachineSetDeployerMockRecorder) Delete(machineSet interface{}) *gomock.Call {
+func (mr *MockMachineSetDeployerMockRecorder) Delete(ctx, machineSet interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockMachineSetDeployer)(nil).Delete), machineSet)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockMachineSetDeployer)(nil).Delete), ctx, machineSet)
 }
 
 // Deploy mocks base method.
-func (m *MockMachineSetDeployer) Deploy(machineSet *unstructured.Unstructured) error {
+func (m *MockMachineSetDeployer) Deploy(ctx context.Context, machineSet *unstructured.Unstructured) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Deploy", machineSet)
+	ret := m.ctrl.Call(m, "Deploy", ctx, machineSet)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Deploy indicates an expected call of Deploy.
-func (mr *MockMachineSetDeployerMockRecorder) Deploy(machineSet interface{}) *gomock.Call {
+func (mr *MockMachineSetDeployerMockRecorder) Deploy(ctx, machineSet interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deploy", reflect.TypeOf((*MockMachineSetDeployer)(nil).Deploy), machineSet)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deploy", reflect.TypeOf((*MockMachineSetDeployer)(nil).Deploy), ctx, machineSet)
 }
 
 // GetWorkerNodeImage mocks base method.
-func (m *MockMachineSetDeployer) GetWorkerNodeImage(workerNodeList []string, machineSet *unstructured.Unstructured, infraID string) (string, error) {
+func (m *MockMachineSetDeployer) GetWorkerNodeImage(ctx context.Context, workerNodeList []string, machineSet *unstructured.Unstructured, infraID string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetWorkerNodeImage", machineSet, infraID)
+	ret := m.ctrl.Call(m, "GetWorkerNodeImage", ctx, workerNodeList, machineSet, infraID)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetWorkerNodeImage indicates an expected call of GetWorkerNodeImage.
-func (mr *MockMachineSetDeployerMockRecorder) GetWorkerNodeImage(machineSet, infraID interface{}) *gomock.Call {
+func (mr *MockMachineSetDeployerMockRecorder) GetWorkerNodeImage(ctx, workerNodeList, machineSet, infraID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWorkerNodeImage", reflect.TypeOf((*MockMachineSetDeployer)(nil).GetWorkerNodeImage), machineSet, infraID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWorkerNodeImage", reflect.TypeOf((*MockMachineSetDeployer)(nil).GetWorkerNodeImage), ctx, workerNodeList, machineSet, infraID)
+}
+
+// List mocks base method.
+func (m *MockMachineSetDeployer) List(ctx context.Context, machineSet *unstructured.Unstructured, namePrefix string) ([]unstructured.Unstructured, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, machineSet, namePrefix)
+	ret0, _ := ret[0].([]unstructured.Unstructured)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockMachineSetDeployerMockRecorder) List(ctx, machineSet, namePrefix interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockMachineSetDeployer)(nil).List), ctx, machineSet, namePrefix)
 }
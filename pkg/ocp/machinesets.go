@@ -21,10 +21,17 @@ package ocp
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/resource"
 	"github.com/submariner-io/admiral/pkg/util"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/audit"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -38,15 +45,23 @@ import (
 // MachineSetDeployer can deploy and delete machinesets from OCP.
 type MachineSetDeployer interface {
 	// Deploy makes sure to deploy the given machine set (creating or updating it).
-	Deploy(machineSet *unstructured.Unstructured) error
+	Deploy(ctx context.Context, machineSet *unstructured.Unstructured) error
 
 	// GetWorkerNodeImage returns the image used by OCP worker nodes.
-	GetWorkerNodeImage(workerNodeList []string, machineSet *unstructured.Unstructured, infraID string) (string, error)
+	GetWorkerNodeImage(ctx context.Context, workerNodeList []string, machineSet *unstructured.Unstructured,
+		infraID string) (string, error)
 
 	// Delete will remove the given machineset.
-	Delete(machineSet *unstructured.Unstructured) error
+	Delete(ctx context.Context, machineSet *unstructured.Unstructured) error
+
+	// List returns the machine sets of the same kind and in the same namespace as the given machine set, whose
+	// names start with the given prefix.
+	List(ctx context.Context, machineSet *unstructured.Unstructured, namePrefix string) ([]unstructured.Unstructured, error)
 }
 
+// machineSetNameKey is the attribute of the spans of the calls on a machine set.
+const machineSetNameKey = attribute.Key("k8s.machineset.name")
+
 type k8sMachineSetDeployer struct {
 	restMapper    meta.RESTMapper
 	dynamicClient dynamic.Interface
@@ -69,21 +84,24 @@ func (msd *k8sMachineSetDeployer) clientFor(obj runtime.Object) (dynamic.Resourc
 	return msd.dynamicClient.Resource(*gvr).Namespace(machineSet.GetNamespace()), nil
 }
 
-func (msd *k8sMachineSetDeployer) GetWorkerNodeImage(workerNodeList []string, machineSet *unstructured.Unstructured,
-	infraID string) (string, error) {
+func (msd *k8sMachineSetDeployer) GetWorkerNodeImage(ctx context.Context, workerNodeList []string,
+	machineSet *unstructured.Unstructured, infraID string) (_ string, err error) {
+	ctx, span := startCall(ctx, "GetWorkerNodeImage", machineSet)
+	defer func() { api.EndCall(span, err) }()
+
 	machineSetClient, err := msd.clientFor(machineSet)
 	if err != nil {
 		return "", err
 	}
 
 	for _, nodeName := range workerNodeList {
-		existing, err := machineSetClient.Get(context.TODO(), nodeName, metav1.GetOptions{})
+		existing, err := machineSetClient.Get(ctx, nodeName, metav1.GetOptions{})
 		if apierrors.IsNotFound(err) {
 			continue
 		}
 
 		if err != nil {
-			return "", errors.Wrapf(err, "error retrieving machine set %q", nodeName)
+			return "", errors.Wrapf(k8s.CategorizeError(err), "error retrieving machine set %q", nodeName)
 		}
 
 		disks, _, _ := unstructured.NestedSlice(existing.Object, "spec", "template", "spec", "providerSpec", "value", "disks")
@@ -107,27 +125,75 @@ func (msd *k8sMachineSetDeployer) GetWorkerNodeImage(workerNodeList []string, ma
 	return "", fmt.Errorf("could not retrieve the image of one of the worker nodes from the infra %q", infraID)
 }
 
-func (msd *k8sMachineSetDeployer) Deploy(machineSet *unstructured.Unstructured) error {
+func (msd *k8sMachineSetDeployer) Deploy(ctx context.Context, machineSet *unstructured.Unstructured) (err error) {
+	ctx, span := startCall(ctx, "Deploy", machineSet)
+	defer func() { api.EndCall(span, err) }()
+
 	machineSetClient, err := msd.clientFor(machineSet)
 	if err != nil {
 		return err
 	}
 
-	_, err = util.CreateOrUpdate(context.TODO(), resource.ForDynamic(machineSetClient), machineSet, util.Replace(machineSet))
+	_, err = util.CreateOrUpdate(ctx, resource.ForDynamic(machineSetClient), machineSet, util.Replace(machineSet))
 
-	return errors.Wrapf(err, "error creating machine set %#v", machineSet)
+	audit.Record(ctx, "ocp", "MachineSet.Deploy", machineSetResource(machineSet), machineSet, err)
+
+	return errors.Wrapf(k8s.CategorizeError(err), "error creating machine set %#v", machineSet)
 }
 
-func (msd *k8sMachineSetDeployer) Delete(machineSet *unstructured.Unstructured) error {
+func (msd *k8sMachineSetDeployer) Delete(ctx context.Context, machineSet *unstructured.Unstructured) (err error) {
+	ctx, span := startCall(ctx, "Delete", machineSet)
+	defer func() { api.EndCall(span, err) }()
+
 	machineSetClient, err := msd.clientFor(machineSet)
 	if err != nil {
 		return err
 	}
 
-	err = machineSetClient.Delete(context.TODO(), machineSet.GetName(), metav1.DeleteOptions{})
+	err = machineSetClient.Delete(ctx, machineSet.GetName(), metav1.DeleteOptions{})
 	if apierrors.IsNotFound(err) {
 		return nil
 	}
 
-	return errors.Wrapf(err, "error deleting machine set %q", machineSet.GetName())
+	audit.Record(ctx, "ocp", "MachineSet.Delete", machineSetResource(machineSet), nil, err)
+
+	return errors.Wrapf(k8s.CategorizeError(err), "error deleting machine set %q", machineSet.GetName())
+}
+
+func (msd *k8sMachineSetDeployer) List(ctx context.Context, machineSet *unstructured.Unstructured,
+	namePrefix string) (_ []unstructured.Unstructured, err error) {
+	ctx, span := startCall(ctx, "List", machineSet)
+	defer func() { api.EndCall(span, err) }()
+
+	machineSetClient, err := msd.clientFor(machineSet)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := machineSetClient.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(k8s.CategorizeError(err), "error listing machine sets")
+	}
+
+	machineSets := []unstructured.Unstructured{}
+
+	for i := range list.Items {
+		if strings.HasPrefix(list.Items[i].GetName(), namePrefix) {
+			machineSets = append(machineSets, list.Items[i])
+		}
+	}
+
+	return machineSets, nil
+}
+
+// startCall starts the span of a call on the given machine set, see api.StartCall.
+func startCall(ctx context.Context, operation string, machineSet *unstructured.Unstructured) (context.Context, trace.Span) {
+	return api.StartCall(ctx, "MachineSet."+operation,
+		semconv.K8SNamespaceNameKey.String(machineSet.GetNamespace()),
+		machineSetNameKey.String(machineSet.GetName()))
+}
+
+// machineSetResource returns the namespaced name of the given machine set, which identifies it in the audit log.
+func machineSetResource(machineSet *unstructured.Unstructured) string {
+	return machineSet.GetNamespace() + "/" + machineSet.GetName()
 }
@@ -21,13 +21,24 @@ package ocp_test
 import (
 	"context"
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/submariner-io/admiral/pkg/fake"
 	. "github.com/submariner-io/admiral/pkg/gomega"
 	"github.com/submariner-io/admiral/pkg/syncer/test"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/audit"
+	"github.com/submariner-io/cloud-prepare/pkg/manifests"
 	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -63,7 +74,7 @@ var _ = Describe("K8s MachineSetDeployer", func() {
 	Context("on GetWorkerNodeImage", func() {
 		When("no worker node exists", func() {
 			It("should return an error", func() {
-				_, err := deployer.GetWorkerNodeImage(workerNodeList, machineSet, infraID)
+				_, err := deployer.GetWorkerNodeImage(context.TODO(), workerNodeList, machineSet, infraID)
 				Expect(err).ToNot(Succeed())
 			})
 		})
@@ -90,7 +101,7 @@ var _ = Describe("K8s MachineSetDeployer", func() {
 				})
 
 				It("should return its disk image", func() {
-					image, err := deployer.GetWorkerNodeImage(workerNodeList, machineSet, infraID)
+					image, err := deployer.GetWorkerNodeImage(context.TODO(), workerNodeList, machineSet, infraID)
 					Expect(err).To(Succeed())
 					Expect(image).To(Equal("some-image"))
 				})
@@ -98,7 +109,7 @@ var _ = Describe("K8s MachineSetDeployer", func() {
 
 			Context("and has no disks", func() {
 				It("should return an error", func() {
-					_, err := deployer.GetWorkerNodeImage(workerNodeList, machineSet, infraID)
+					_, err := deployer.GetWorkerNodeImage(context.TODO(), workerNodeList, machineSet, infraID)
 					Expect(err).ToNot(Succeed())
 				})
 			})
@@ -112,10 +123,22 @@ var _ = Describe("K8s MachineSetDeployer", func() {
 				})
 
 				It("should return an error", func() {
-					_, err := deployer.GetWorkerNodeImage(workerNodeList, machineSet, infraID)
+					_, err := deployer.GetWorkerNodeImage(context.TODO(), workerNodeList, machineSet, infraID)
 					Expect(err).To(ContainErrorSubstring(expectedErr))
 				})
 			})
+
+			Context("and retrieval is forbidden", func() {
+				BeforeEach(func() {
+					fake.NewFailingReactor(&dynClient.Fake).SetFailOnGet(
+						apierrors.NewForbidden(schema.GroupResource{Resource: "machinesets"}, workerNodeList[0], errors.New("fake")))
+				})
+
+				It("should return a permission denied error", func() {
+					_, err := deployer.GetWorkerNodeImage(context.TODO(), workerNodeList, machineSet, infraID)
+					Expect(errors.Is(err, api.ErrPermissionDenied)).To(BeTrue())
+				})
+			})
 		})
 	})
 
@@ -125,11 +148,22 @@ var _ = Describe("K8s MachineSetDeployer", func() {
 		})
 
 		It("should successfully create the machine set", func() {
-			Expect(deployer.Deploy(machineSet)).To(Succeed())
+			Expect(deployer.Deploy(context.TODO(), machineSet)).To(Succeed())
 
 			_, err := msClient.Get(context.TODO(), machineSetName, metav1.GetOptions{})
 			Expect(err).To(Succeed())
 		})
+
+		It("should record it in the audit log", func() {
+			sink := audit.NewMemorySink()
+
+			Expect(deployer.Deploy(audit.NewContext(context.TODO(), audit.NewLog([]byte("key"), sink)), machineSet)).To(Succeed())
+
+			entries := sink.Entries()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Operation).To(Equal("MachineSet.Deploy"))
+			Expect(entries[0].Resource).To(Equal(machineSet.GetNamespace() + "/" + machineSetName))
+		})
 	})
 
 	Context("on Delete", func() {
@@ -144,7 +178,7 @@ var _ = Describe("K8s MachineSetDeployer", func() {
 			})
 
 			It("should successfully delete the machine set", func() {
-				Expect(deployer.Delete(machineSet)).To(Succeed())
+				Expect(deployer.Delete(context.TODO(), machineSet)).To(Succeed())
 
 				_, err := msClient.Get(context.TODO(), machineSetName, metav1.GetOptions{})
 				Expect(apierrors.IsNotFound(err)).To(BeTrue())
@@ -156,19 +190,183 @@ var _ = Describe("K8s MachineSetDeployer", func() {
 				})
 
 				It("should return an error", func() {
-					Expect(deployer.Delete(machineSet)).ToNot(Succeed())
+					Expect(deployer.Delete(context.TODO(), machineSet)).ToNot(Succeed())
+				})
+			})
+
+			Context("and the machine set is being modified", func() {
+				BeforeEach(func() {
+					fake.NewFailingReactor(&dynClient.Fake).SetFailOnDelete(
+						apierrors.NewConflict(schema.GroupResource{Resource: "machinesets"}, machineSetName, errors.New("fake")))
+				})
+
+				It("should return a conflict error", func() {
+					Expect(errors.Is(deployer.Delete(context.TODO(), machineSet), api.ErrConflict)).To(BeTrue())
 				})
 			})
 		})
 
 		When("the machine set does not exist", func() {
 			It("should not return an error", func() {
-				Expect(deployer.Delete(machineSet)).To(Succeed())
+				Expect(deployer.Delete(context.TODO(), machineSet)).To(Succeed())
+			})
+		})
+	})
+
+	Context("on List", func() {
+		BeforeEach(func() {
+			for _, name := range []string{infraID + "-submariner-gw-a", infraID + "-worker-a", infraID + "-submariner-gw-b"} {
+				ms := newMachineSet()
+				ms.SetName(name)
+
+				_, err := msClient.Create(context.TODO(), ms, metav1.CreateOptions{})
+				Expect(err).To(Succeed())
+			}
+		})
+
+		It("should return the machine sets with the given name prefix", func() {
+			machineSets, err := deployer.List(context.TODO(), machineSet, infraID+"-submariner-gw-")
+			Expect(err).To(Succeed())
+
+			names := []string{}
+			for i := range machineSets {
+				names = append(names, machineSets[i].GetName())
+			}
+
+			Expect(names).To(ConsistOf(infraID+"-submariner-gw-a", infraID+"-submariner-gw-b"))
+		})
+
+		When("listing fails", func() {
+			BeforeEach(func() {
+				fake.NewFailingReactor(&dynClient.Fake).SetFailOnList(errors.New("fake List error"))
+			})
+
+			It("should return an error", func() {
+				_, err := deployer.List(context.TODO(), machineSet, infraID)
+				Expect(err).ToNot(Succeed())
+			})
+		})
+	})
+
+	Context("when traced", func() {
+		var (
+			exporter *tracetest.InMemoryExporter
+			ctx      context.Context
+		)
+
+		BeforeEach(func() {
+			machineSet.SetName(machineSetName)
+
+			exporter = tracetest.NewInMemoryExporter()
+			ctx, _ = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)).Tracer("test").Start(context.TODO(), "test")
+		})
+
+		It("should trace each call in a child span of the context's span", func() {
+			Expect(deployer.Deploy(ctx, machineSet)).To(Succeed())
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Name).To(Equal("MachineSet.Deploy"))
+			Expect(spans[0].Parent.SpanID()).To(Equal(trace.SpanContextFromContext(ctx).SpanID()))
+			Expect(spans[0].Attributes).To(ContainElement(attribute.String("k8s.machineset.name", machineSetName)))
+			Expect(spans[0].Status.Code).ToNot(Equal(codes.Error))
+		})
+
+		When("the call fails", func() {
+			BeforeEach(func() {
+				fake.NewFailingReactor(&dynClient.Fake).SetFailOnDelete(errors.New("fake Delete error"))
+			})
+
+			It("should record the error in its span", func() {
+				Expect(deployer.Delete(ctx, machineSet)).ToNot(Succeed())
+
+				spans := exporter.GetSpans()
+				Expect(spans).To(HaveLen(1))
+				Expect(spans[0].Name).To(Equal("MachineSet.Delete"))
+				Expect(spans[0].Status.Code).To(Equal(codes.Error))
+				Expect(spans[0].Status.Description).To(ContainSubstring("fake Delete error"))
+				Expect(spans[0].Events).To(HaveLen(1))
 			})
 		})
 	})
 })
 
+var _ = Describe("Manifest MachineSetDeployer", func() {
+	const machineSetName = "test-machineset"
+
+	var (
+		msClient   dynamic.ResourceInterface
+		dir        string
+		deployer   ocp.MachineSetDeployer
+		machineSet *unstructured.Unstructured
+	)
+
+	BeforeEach(func() {
+		machineSet = newMachineSet()
+		machineSet.SetName(machineSetName)
+		restMapper, gvr := test.GetRESTMapperAndGroupVersionResourceFor(machineSet)
+
+		dynClient := fakeClient.NewSimpleDynamicClient(scheme.Scheme)
+		msClient = dynClient.Resource(*gvr).Namespace(machineSet.GetNamespace())
+
+		var err error
+
+		dir, err = ioutil.TempDir("", "manifests")
+		Expect(err).To(Succeed())
+
+		writer, err := manifests.NewDirectoryWriter(dir)
+		Expect(err).To(Succeed())
+
+		deployer = ocp.NewManifestMachineSetDeployer(ocp.NewK8sMachinesetDeployer(restMapper, dynClient), writer)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	manifestPath := func() string {
+		return filepath.Join(dir, "machineset-"+machineSet.GetNamespace()+"-"+machineSetName+".yaml")
+	}
+
+	Context("on Deploy", func() {
+		It("should write the machine set's manifest instead of creating it", func() {
+			Expect(deployer.Deploy(context.TODO(), machineSet)).To(Succeed())
+
+			_, err := msClient.Get(context.TODO(), machineSetName, metav1.GetOptions{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+			manifest, err := ioutil.ReadFile(manifestPath())
+			Expect(err).To(Succeed())
+			Expect(string(manifest)).To(ContainSubstring("name: " + machineSetName))
+
+			kustomization, err := ioutil.ReadFile(filepath.Join(dir, manifests.KustomizationFile))
+			Expect(err).To(Succeed())
+			Expect(string(kustomization)).To(ContainSubstring(filepath.Base(manifestPath())))
+		})
+	})
+
+	Context("on Delete", func() {
+		It("should remove the machine set's manifest", func() {
+			Expect(deployer.Deploy(context.TODO(), machineSet)).To(Succeed())
+			Expect(deployer.Delete(context.TODO(), machineSet)).To(Succeed())
+
+			_, err := os.Stat(manifestPath())
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("on List", func() {
+		It("should list the machine sets in the cluster", func() {
+			_, err := msClient.Create(context.TODO(), machineSet, metav1.CreateOptions{})
+			Expect(err).To(Succeed())
+
+			machineSets, err := deployer.List(context.TODO(), machineSet, "test-")
+			Expect(err).To(Succeed())
+			Expect(machineSets).To(HaveLen(1))
+		})
+	})
+})
+
 func newMachineSet() *unstructured.Unstructured {
 	ms := &unstructured.Unstructured{}
 	ms.SetGroupVersionKind(schema.GroupVersionKind{
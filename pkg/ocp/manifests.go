@@ -0,0 +1,59 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ocp
+
+import (
+	"context"
+
+	"github.com/submariner-io/cloud-prepare/pkg/manifests"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type manifestMachineSetDeployer struct {
+	reader MachineSetDeployer
+	writer *manifests.Writer
+}
+
+// NewManifestMachineSetDeployer returns a MachineSetDeployer which writes the machine sets it deploys as manifests,
+// rather than applying them, and removes the manifests of the machine sets it deletes. The worker node images and the
+// existing machine sets are still read from the cluster with the given reader, e.g. a K8s MachineSetDeployer.
+func NewManifestMachineSetDeployer(reader MachineSetDeployer, writer *manifests.Writer) MachineSetDeployer {
+	return &manifestMachineSetDeployer{
+		reader: reader,
+		writer: writer,
+	}
+}
+
+func (msd *manifestMachineSetDeployer) Deploy(_ context.Context, machineSet *unstructured.Unstructured) error {
+	return msd.writer.Write(machineSet) // nolint:wrapcheck // No need to wrap here
+}
+
+func (msd *manifestMachineSetDeployer) GetWorkerNodeImage(ctx context.Context, workerNodeList []string,
+	machineSet *unstructured.Unstructured, infraID string) (string, error) {
+	return msd.reader.GetWorkerNodeImage(ctx, workerNodeList, machineSet, infraID)
+}
+
+func (msd *manifestMachineSetDeployer) Delete(_ context.Context, machineSet *unstructured.Unstructured) error {
+	return msd.writer.Remove(machineSet) // nolint:wrapcheck // No need to wrap here
+}
+
+func (msd *manifestMachineSetDeployer) List(ctx context.Context, machineSet *unstructured.Unstructured,
+	namePrefix string) ([]unstructured.Unstructured, error) {
+	return msd.reader.List(ctx, machineSet, namePrefix)
+}
@@ -0,0 +1,121 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/aws"
+	"github.com/submariner-io/cloud-prepare/pkg/gcp"
+	"github.com/submariner-io/cloud-prepare/pkg/generic"
+	"github.com/submariner-io/cloud-prepare/pkg/rhos"
+)
+
+// The names of the providers in NewDefaultRegistry.
+const (
+	AWS     = "aws"
+	GCP     = "gcp"
+	RHOS    = "rhos"
+	Generic = "generic"
+)
+
+var builtinFactories = map[string]Factory{
+	AWS:     newAWS,
+	GCP:     newGCP,
+	RHOS:    newRHOS,
+	Generic: newGeneric,
+}
+
+var builtinValidators = map[string]Validator{
+	AWS: func(config interface{}) error {
+		return validateConfig(config, &aws.Config{})
+	},
+	GCP: func(config interface{}) error {
+		return validateConfig(config, &gcp.Config{})
+	},
+	RHOS: func(config interface{}) error {
+		return validateConfig(config, &rhos.Config{})
+	},
+	Generic: func(config interface{}) error {
+		return validateConfig(config, &genericConfig{})
+	},
+}
+
+// genericConfig is the configuration of the generic provider, which has no fields.
+type genericConfig struct{}
+
+func (genericConfig) Validate() error {
+	return nil
+}
+
+func newAWS(config interface{}, deps Dependencies) (api.Cloud, api.GatewayDeployer, error) {
+	awsConfig := &aws.Config{}
+	if err := DecodeConfig(config, awsConfig); err != nil {
+		return nil, nil, err
+	}
+
+	if deps.Inventory != nil {
+		awsConfig.Inventory = deps.Inventory
+	}
+
+	return aws.NewProvider(awsConfig, deps.MachineSetDeployer, deps.K8sClient)
+}
+
+func newGCP(config interface{}, deps Dependencies) (api.Cloud, api.GatewayDeployer, error) {
+	gcpConfig := &gcp.Config{}
+	if err := DecodeConfig(config, gcpConfig); err != nil {
+		return nil, nil, err
+	}
+
+	if deps.Inventory != nil {
+		gcpConfig.Inventory = deps.Inventory
+	}
+
+	return gcp.NewProvider(gcpConfig, deps.MachineSetDeployer, deps.K8sClient)
+}
+
+func newRHOS(config interface{}, deps Dependencies) (api.Cloud, api.GatewayDeployer, error) {
+	rhosConfig := &rhos.Config{}
+	if err := DecodeConfig(config, rhosConfig); err != nil {
+		return nil, nil, err
+	}
+
+	if deps.Inventory != nil {
+		rhosConfig.Inventory = deps.Inventory
+	}
+
+	return rhos.NewProvider(rhosConfig, deps.MachineSetDeployer, deps.K8sClient)
+}
+
+// newGeneric ignores the configuration, since the generic provider has none.
+func newGeneric(_ interface{}, deps Dependencies) (api.Cloud, api.GatewayDeployer, error) {
+	if deps.K8sClient == nil {
+		return nil, nil, errors.New("the generic provider requires a Kubernetes client")
+	}
+
+	cloud, gwDeployer := generic.NewProvider(deps.K8sClient)
+
+	if deps.Inventory != nil {
+		if err := generic.UseInventory(gwDeployer, deps.Inventory); err != nil {
+			return nil, nil, err // nolint:wrapcheck // No need to wrap here
+		}
+	}
+
+	return cloud, gwDeployer, nil
+}
@@ -0,0 +1,265 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// Dependencies are the cluster clients a provider may need, in addition to its configuration.
+type Dependencies struct {
+	K8sClient          k8s.Interface
+	MachineSetDeployer ocp.MachineSetDeployer
+
+	// Inventory, if set, records the resources created by the provider, and drives their cleanup.
+	Inventory api.Inventory
+}
+
+// Factory creates the Cloud and GatewayDeployer of a provider. The configuration is either the provider's typed
+// configuration, or a map using the same keys as its JSON representation.
+type Factory func(config interface{}, deps Dependencies) (api.Cloud, api.GatewayDeployer, error)
+
+// Validator checks the configuration of a provider without creating it, see Registry.Validate.
+type Validator func(config interface{}) error
+
+// Registry holds the provider factories, and their optional validators, by name.
+type Registry struct {
+	mutex      sync.RWMutex
+	factories  map[string]Factory
+	validators map[string]Validator
+}
+
+// UnsupportedFieldsError is returned by Validate when a configuration map has keys which the provider doesn't support.
+type UnsupportedFieldsError struct {
+	// Keys are the unsupported keys, sorted.
+	Keys []string
+}
+
+func (e *UnsupportedFieldsError) Error() string {
+	return fmt.Sprintf("the configuration fields %s aren't supported", strings.Join(e.Keys, ", "))
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{factories: map[string]Factory{}, validators: map[string]Validator{}}
+}
+
+// NewDefaultRegistry creates a Registry containing all the providers in this module.
+func NewDefaultRegistry() *Registry {
+	registry := NewRegistry()
+
+	for name, factory := range builtinFactories {
+		registry.factories[name] = factory
+	}
+
+	for name, validator := range builtinValidators {
+		registry.validators[name] = validator
+	}
+
+	return registry
+}
+
+// Register adds the factory for the named provider. Each name can only be registered once.
+func (r *Registry) Register(name string, factory Factory) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, found := r.factories[name]; found {
+		return fmt.Errorf("provider %q is already registered", name)
+	}
+
+	r.factories[name] = factory
+
+	return nil
+}
+
+// RegisterValidator adds the validator of the named provider, which must already be registered.
+func (r *Registry) RegisterValidator(name string, validator Validator) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, found := r.factories[name]; !found {
+		return fmt.Errorf("provider %q isn't registered", name)
+	}
+
+	r.validators[name] = validator
+
+	return nil
+}
+
+// Providers returns the names of the registered providers, sorted.
+func (r *Registry) Providers() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// New creates the Cloud and GatewayDeployer of the named provider, using its factory.
+func (r *Registry) New(name string, config interface{}, deps Dependencies) (api.Cloud, api.GatewayDeployer, error) {
+	r.mutex.RLock()
+	factory, found := r.factories[name]
+	r.mutex.RUnlock()
+
+	if !found {
+		return nil, nil, api.Errorf(api.ErrUnsupported, "unknown provider %q, the registered providers are %q", name, r.Providers())
+	}
+
+	cloud, gwDeployer, err := factory(config, deps)
+
+	return cloud, gwDeployer, errors.WithMessagef(err, "error creating provider %q", name)
+}
+
+// Validate checks the configuration of the named provider without creating it, using its validator. The validators
+// of the built-in providers return an UnsupportedFieldsError for the unsupported keys of a configuration map, and the
+// provider's api.MissingFieldsError for its missing fields, combined in an aggregate if there are both. The
+// configuration of a provider without a validator is only checked when it's created.
+func (r *Registry) Validate(name string, config interface{}) error {
+	r.mutex.RLock()
+	_, found := r.factories[name]
+	validator := r.validators[name]
+	r.mutex.RUnlock()
+
+	if !found {
+		return api.Errorf(api.ErrUnsupported, "unknown provider %q, the registered providers are %q", name, r.Providers())
+	}
+
+	if validator == nil {
+		return nil
+	}
+
+	return validator(config)
+}
+
+// DecodeConfig stores the given configuration in target, which must be a pointer to the provider's typed
+// configuration. The configuration is either a value of, or a pointer to, the same type, or a map which is decoded
+// using the target's JSON representation; unknown keys are rejected.
+func DecodeConfig(config, target interface{}) error {
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Ptr || targetValue.IsNil() {
+		return fmt.Errorf("the configuration target must be a non-nil pointer, not %T", target)
+	}
+
+	if configMap, ok := config.(map[string]interface{}); ok {
+		data, err := json.Marshal(configMap)
+		if err != nil {
+			return errors.Wrap(err, "error encoding the configuration")
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+
+		return errors.Wrap(decoder.Decode(target), "error decoding the configuration")
+	}
+
+	configValue := reflect.ValueOf(config)
+	if configValue.Kind() == reflect.Ptr && !configValue.IsNil() {
+		configValue = configValue.Elem()
+	}
+
+	if !configValue.IsValid() || configValue.Type() != targetValue.Elem().Type() {
+		return api.Errorf(api.ErrUnsupported, "unsupported configuration type %T, expected %s or a map", config,
+			targetValue.Elem().Type())
+	}
+
+	targetValue.Elem().Set(configValue)
+
+	return nil
+}
+
+// validatedConfig is a typed provider configuration which can check its fields.
+type validatedConfig interface {
+	Validate() error
+}
+
+// validateConfig decodes the configuration into target, a pointer to the provider's typed configuration, and validates
+// it. The unsupported keys of a configuration map are reported along with the missing fields.
+func validateConfig(config interface{}, target validatedConfig) error {
+	errs := []error{}
+
+	if configMap, ok := config.(map[string]interface{}); ok {
+		supported := jsonKeys(reflect.TypeOf(target).Elem())
+		unsupported := []string{}
+		supportedMap := map[string]interface{}{}
+
+		for key, value := range configMap {
+			if supported[key] {
+				supportedMap[key] = value
+			} else {
+				unsupported = append(unsupported, key)
+			}
+		}
+
+		if len(unsupported) > 0 {
+			sort.Strings(unsupported)
+			errs = append(errs, &UnsupportedFieldsError{Keys: unsupported})
+		}
+
+		config = supportedMap
+	}
+
+	if config != nil {
+		if err := DecodeConfig(config, target); err != nil {
+			return err
+		}
+	}
+
+	if err := target.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// jsonKeys returns the keys of the fields of the given struct type in its JSON representation.
+func jsonKeys(structType reflect.Type) map[string]bool {
+	keys := map[string]bool{}
+
+	for i := 0; i < structType.NumField(); i++ {
+		key := strings.Split(structType.Field(i).Tag.Get("json"), ",")[0]
+		if key == "" {
+			key = structType.Field(i).Name
+		}
+
+		if key != "-" {
+			keys[key] = true
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,275 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/gcp"
+	"github.com/submariner-io/cloud-prepare/pkg/generic"
+	"github.com/submariner-io/cloud-prepare/pkg/inventory"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/provider"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	kubeFake "k8s.io/client-go/kubernetes/fake"
+)
+
+type testConfig struct {
+	Name  string `json:"name"`
+	Count int    `json:"count,omitempty"`
+}
+
+var _ = Describe("Registry", func() {
+	Describe("NewDefaultRegistry", testDefaultRegistry)
+	Describe("Register", testRegister)
+	Describe("New", testNew)
+	Describe("Validate", testValidate)
+	Describe("DecodeConfig", testDecodeConfig)
+})
+
+func testDefaultRegistry() {
+	var registry *provider.Registry
+
+	BeforeEach(func() {
+		registry = provider.NewDefaultRegistry()
+	})
+
+	It("should contain the built-in providers", func() {
+		Expect(registry.Providers()).To(Equal([]string{provider.AWS, provider.GCP, provider.Generic, provider.RHOS}))
+	})
+
+	When("the generic provider is created with a Kubernetes client", func() {
+		It("should return its Cloud and GatewayDeployer", func() {
+			cloud, gwDeployer, err := registry.New(provider.Generic, nil, provider.Dependencies{
+				K8sClient: k8s.NewInterface(kubeFake.NewSimpleClientset()),
+			})
+			Expect(err).To(Succeed())
+			Expect(cloud).ToNot(BeNil())
+			Expect(gwDeployer).ToNot(BeNil())
+		})
+	})
+
+	When("the generic provider is created with an inventory", func() {
+		It("should plan its cleanup from the inventory", func() {
+			gwInventory := inventory.New(inventory.NewMemoryStore())
+			Expect(gwInventory.Record(context.TODO(), api.InventoryResource{
+				Scope: api.GatewayScope,
+				Kind:  api.NodeLabelResource,
+				ID:    "node-1",
+			})).To(Succeed())
+
+			_, gwDeployer, err := registry.New(provider.Generic, nil, provider.Dependencies{
+				K8sClient: k8s.NewInterface(kubeFake.NewSimpleClientset()),
+				Inventory: gwInventory,
+			})
+			Expect(err).To(Succeed())
+
+			plan, err := gwDeployer.PlanCleanup(context.TODO())
+			Expect(err).To(Succeed())
+			Expect(plan.Changes).To(HaveLen(1))
+			Expect(plan.Changes[0].Resource).To(Equal("node-1"))
+		})
+	})
+
+	When("the generic provider is created without a Kubernetes client", func() {
+		It("should return an error", func() {
+			_, _, err := registry.New(provider.Generic, nil, provider.Dependencies{})
+			Expect(err).ToNot(Succeed())
+		})
+	})
+
+	When("a provider's configuration is missing required fields", func() {
+		It("should return an error listing them", func() {
+			_, _, err := registry.New(provider.GCP, map[string]interface{}{"infraID": "test-infraID"}, provider.Dependencies{})
+			Expect(err).To(MatchError(ContainSubstring("region, projectID")))
+
+			_, _, err = registry.New(provider.GCP, &gcp.Config{InfraID: "test-infraID", Region: "test-region"},
+				provider.Dependencies{})
+			Expect(err).To(MatchError(ContainSubstring("projectID")))
+		})
+	})
+
+	When("a provider's configuration has an unknown field", func() {
+		It("should return an error", func() {
+			_, _, err := registry.New(provider.AWS, map[string]interface{}{"infraID": "test-infraID", "zone": "a"},
+				provider.Dependencies{})
+			Expect(err).ToNot(Succeed())
+		})
+	})
+}
+
+func testRegister() {
+	var registry *provider.Registry
+
+	BeforeEach(func() {
+		registry = provider.NewRegistry()
+		Expect(registry.Register("test", newTestFactory(nil))).To(Succeed())
+	})
+
+	It("should add the provider", func() {
+		Expect(registry.Providers()).To(Equal([]string{"test"}))
+	})
+
+	When("the provider is already registered", func() {
+		It("should return an error", func() {
+			Expect(registry.Register("test", newTestFactory(nil))).ToNot(Succeed())
+		})
+	})
+}
+
+func testNew() {
+	var (
+		registry *provider.Registry
+		config   testConfig
+	)
+
+	BeforeEach(func() {
+		registry = provider.NewRegistry()
+		config = testConfig{}
+		Expect(registry.Register("test", newTestFactory(&config))).To(Succeed())
+	})
+
+	It("should create the provider with the given configuration", func() {
+		cloud, gwDeployer, err := registry.New("test", map[string]interface{}{"name": "foo", "count": 2}, provider.Dependencies{})
+		Expect(err).To(Succeed())
+		Expect(cloud).ToNot(BeNil())
+		Expect(gwDeployer).ToNot(BeNil())
+		Expect(config).To(Equal(testConfig{Name: "foo", Count: 2}))
+	})
+
+	When("the factory fails", func() {
+		It("should return an error", func() {
+			_, _, err := registry.New("test", "invalid", provider.Dependencies{})
+			Expect(err).ToNot(Succeed())
+		})
+	})
+
+	When("the provider isn't registered", func() {
+		It("should return an unsupported error", func() {
+			_, _, err := registry.New("unknown", nil, provider.Dependencies{})
+			Expect(err).ToNot(Succeed())
+			Expect(errors.Is(err, api.ErrUnsupported)).To(BeTrue())
+		})
+	})
+}
+
+func testValidate() {
+	var registry *provider.Registry
+
+	BeforeEach(func() {
+		registry = provider.NewDefaultRegistry()
+	})
+
+	It("should accept a valid configuration", func() {
+		Expect(registry.Validate(provider.AWS, map[string]interface{}{"infraID": "test-infraID", "region": "test-region"})).
+			To(Succeed())
+		Expect(registry.Validate(provider.Generic, nil)).To(Succeed())
+	})
+
+	It("should report the missing and unsupported fields", func() {
+		err := registry.Validate(provider.GCP, map[string]interface{}{
+			"infraID":          "test-infraID",
+			"region":           "test-region",
+			"dedicatedGateway": true,
+			"zone":             "a",
+			"profile":          "default",
+		})
+
+		var aggregate utilerrors.Aggregate
+		Expect(errors.As(err, &aggregate)).To(BeTrue())
+		Expect(aggregate.Errors()).To(HaveLen(2))
+
+		unsupported := &provider.UnsupportedFieldsError{}
+		Expect(errors.As(aggregate.Errors()[0], &unsupported)).To(BeTrue())
+		Expect(unsupported.Keys).To(Equal([]string{"profile", "zone"}))
+
+		missing := &api.MissingFieldsError{}
+		Expect(errors.As(aggregate.Errors()[1], &missing)).To(BeTrue())
+		Expect(missing.Fields).To(Equal([]api.MissingField{{Key: "projectID"}, {Key: "instanceType", Condition: "for dedicated gateways"}}))
+	})
+
+	It("should accept the configuration of a provider without a validator", func() {
+		Expect(registry.Register("test", newTestFactory(nil))).To(Succeed())
+		Expect(registry.Validate("test", "invalid")).To(Succeed())
+	})
+
+	It("should use the provider's validator", func() {
+		Expect(registry.Register("test", newTestFactory(nil))).To(Succeed())
+		Expect(registry.RegisterValidator("test", func(config interface{}) error {
+			return errors.New("invalid")
+		})).To(Succeed())
+
+		Expect(registry.Validate("test", nil)).To(MatchError("invalid"))
+	})
+
+	When("the provider isn't registered", func() {
+		It("should return an unsupported error", func() {
+			Expect(errors.Is(registry.Validate("unknown", nil), api.ErrUnsupported)).To(BeTrue())
+			Expect(registry.RegisterValidator("unknown", nil)).ToNot(Succeed())
+		})
+	})
+}
+
+func testDecodeConfig() {
+	var config testConfig
+
+	BeforeEach(func() {
+		config = testConfig{}
+	})
+
+	It("should accept a value of the target type", func() {
+		Expect(provider.DecodeConfig(testConfig{Name: "foo"}, &config)).To(Succeed())
+		Expect(config).To(Equal(testConfig{Name: "foo"}))
+	})
+
+	It("should accept a pointer to the target type", func() {
+		Expect(provider.DecodeConfig(&testConfig{Name: "foo"}, &config)).To(Succeed())
+		Expect(config).To(Equal(testConfig{Name: "foo"}))
+	})
+
+	It("should decode a map", func() {
+		Expect(provider.DecodeConfig(map[string]interface{}{"name": "foo"}, &config)).To(Succeed())
+		Expect(config).To(Equal(testConfig{Name: "foo"}))
+	})
+
+	It("should reject a map with unknown keys", func() {
+		Expect(provider.DecodeConfig(map[string]interface{}{"other": "foo"}, &config)).ToNot(Succeed())
+	})
+
+	It("should reject another type", func() {
+		Expect(provider.DecodeConfig(errors.New("foo"), &config)).ToNot(Succeed())
+		Expect(provider.DecodeConfig(nil, &config)).ToNot(Succeed())
+	})
+}
+
+func newTestFactory(target *testConfig) provider.Factory {
+	return func(config interface{}, deps provider.Dependencies) (api.Cloud, api.GatewayDeployer, error) {
+		if target != nil {
+			if err := provider.DecodeConfig(config, target); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		return generic.NewCloud(), generic.NewGatewayDeployer(deps.K8sClient), nil
+	}
+}
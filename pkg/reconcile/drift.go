@@ -0,0 +1,222 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"fmt"
+
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+// DriftType describes how the live state differs from the desired state.
+type DriftType string
+
+const (
+	// Missing is used when something which was requested doesn't exist.
+	Missing DriftType = "missing"
+
+	// Unexpected is used when something exists which wasn't requested.
+	Unexpected DriftType = "unexpected"
+
+	// Inconsistent is used for the partial or inconsistent states reported by the provider.
+	Inconsistent DriftType = "inconsistent"
+)
+
+// Drift is a single difference between the live state of a cloud and the desired preparation.
+type Drift struct {
+	Type DriftType `json:"type"`
+
+	// Kind is the kind of resource which drifted, if known.
+	Kind api.ResourceKind `json:"kind,omitempty"`
+
+	// Resource identifies the resource which drifted, if known.
+	Resource string `json:"resource,omitempty"`
+
+	// Description provides human-readable details about the difference.
+	Description string `json:"description"`
+
+	// Manual is true if repairing doesn't fix the drift, which must then be fixed by hand. Preparing and deploying only
+	// open the ports which are missing, so this is the case of the unexpected public ports, e.g. opened by hand.
+	Manual bool `json:"manual,omitempty"`
+}
+
+func (d *Drift) String() string {
+	if d.Resource == "" {
+		return fmt.Sprintf("%s: %s", d.Type, d.Description)
+	}
+
+	return fmt.Sprintf("%s %s %q: %s", d.Type, d.Kind, d.Resource, d.Description)
+}
+
+// CloudDrift compares the cloud preparation reported by status with the desired input.
+func CloudDrift(status *api.CloudStatus, input *api.PrepareForSubmarinerInput) []Drift {
+	drifts := []Drift{}
+
+	for _, port := range input.InternalPorts {
+		if !containsPort(status.InternalPorts, port) {
+			drifts = append(drifts, Drift{
+				Type:        Missing,
+				Description: fmt.Sprintf("internal port %s isn't open", port),
+			})
+		}
+	}
+
+	return append(drifts, issueDrift(status.Issues)...)
+}
+
+// GatewayDrift compares the gateway deployment reported by status with the desired input. The public ports are
+// only compared when the provider manages a firewall for the gateways, and the number of gateways only when a
+// specific number was requested.
+func GatewayDrift(status *api.GatewayStatus, input *api.GatewayDeployInput) []Drift {
+	drifts := []Drift{}
+
+	if status.Firewall != nil {
+		drifts = append(drifts, firewallDrift(status.Firewall, input)...)
+	}
+
+	if input.Gateways > 0 {
+		if deployed := deployedGateways(status); deployed != input.Gateways {
+			driftType := Missing
+			if deployed > input.Gateways {
+				driftType = Unexpected
+			}
+
+			drifts = append(drifts, Drift{
+				Type:        driftType,
+				Description: fmt.Sprintf("%d gateways are deployed but %d were requested", deployed, input.Gateways),
+			})
+		}
+	}
+
+	return append(drifts, issueDrift(status.Issues)...)
+}
+
+// portSource is a port allowed from a single source CIDR.
+type portSource struct {
+	port api.PortSpec
+	cidr string
+}
+
+func (p portSource) String() string {
+	return fmt.Sprintf("%s from %s", p.port, p.cidr)
+}
+
+// firewallDrift compares the firewall's ports with the public ports, for each of their source CIDRs. The desired
+// sources depend on the IP families of the deployment, since unrestricted ports are open to any source of each family.
+func firewallDrift(firewall *api.FirewallStatus, input *api.GatewayDeployInput) []Drift {
+	drifts := []Drift{}
+
+	desired := []portSource{}
+
+	for _, family := range input.PublicIPFamilies() {
+		for _, port := range input.PublicPortSpecs() {
+			for _, cidr := range port.SourcesFor(family) {
+				desired = append(desired, portSource{port: port, cidr: cidr})
+			}
+		}
+	}
+
+	live := []portSource{}
+
+	for _, port := range firewall.Ports {
+		for _, cidr := range port.Sources() {
+			live = append(live, portSource{port: port, cidr: cidr})
+		}
+	}
+
+	for _, source := range desired {
+		if !containsPortSource(live, source) {
+			drifts = append(drifts, Drift{
+				Type:        Missing,
+				Kind:        firewall.Kind,
+				Resource:    firewall.Name,
+				Description: fmt.Sprintf("public port %s isn't open", source),
+			})
+		}
+	}
+
+	for _, source := range live {
+		if !containsPortSource(desired, source) {
+			drifts = append(drifts, Drift{
+				Type:        Unexpected,
+				Kind:        firewall.Kind,
+				Resource:    firewall.Name,
+				Description: fmt.Sprintf("public port %s is open but wasn't requested", source),
+				Manual:      true,
+			})
+		}
+	}
+
+	return drifts
+}
+
+func containsPortSource(sources []portSource, source portSource) bool {
+	for _, s := range sources {
+		if s.port.Matches(source.port) && s.cidr == source.cidr {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deployedGateways returns the number of gateways from the most precise information the provider reports.
+func deployedGateways(status *api.GatewayStatus) int {
+	switch {
+	case len(status.GatewayNodes) > 0:
+		return len(status.GatewayNodes)
+	case len(status.GatewaySubnets) > 0:
+		return len(status.GatewaySubnets)
+	default:
+		return len(status.MachineSets)
+	}
+}
+
+// repairable returns the drift which repairing can fix.
+func repairable(drifts []Drift) []Drift {
+	result := []Drift{}
+
+	for i := range drifts {
+		if !drifts[i].Manual {
+			result = append(result, drifts[i])
+		}
+	}
+
+	return result
+}
+
+func issueDrift(issues []string) []Drift {
+	drifts := make([]Drift, 0, len(issues))
+
+	for _, issue := range issues {
+		drifts = append(drifts, Drift{Type: Inconsistent, Description: issue})
+	}
+
+	return drifts
+}
+
+func containsPort(ports []api.PortSpec, port api.PortSpec) bool {
+	for i := range ports {
+		if ports[i].Matches(port) {
+			return true
+		}
+	}
+
+	return false
+}
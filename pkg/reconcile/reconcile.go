@@ -0,0 +1,150 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Config describes the desired preparation a Reconciler maintains.
+type Config struct {
+	Cloud        api.Cloud
+	PrepareInput api.PrepareForSubmarinerInput
+
+	// GatewayDeployer, if set, has its gateway deployment compared with DeployInput.
+	GatewayDeployer api.GatewayDeployer
+	DeployInput     api.GatewayDeployInput
+
+	// Repair, if true, re-applies the desired preparation when drift which it can fix is found, see Drift.Manual.
+	Repair bool
+}
+
+// Result is the outcome of a single reconciliation.
+type Result struct {
+	CloudDrift   []Drift `json:"cloudDrift"`
+	GatewayDrift []Drift `json:"gatewayDrift"`
+
+	// Repaired is true if drift which repairing can fix was found, the desired preparation was re-applied, and no such
+	// drift remained afterwards. The drift which must be fixed by hand, see Drift.Manual, is never repaired.
+	Repaired bool `json:"repaired"`
+
+	// RemainingDrift lists the drift which was still found after the repair, including the drift which must be fixed
+	// by hand.
+	RemainingDrift []Drift `json:"remainingDrift,omitempty"`
+}
+
+// HasDrift returns true if the live state differed from the desired preparation.
+func (r *Result) HasDrift() bool {
+	return len(r.CloudDrift) > 0 || len(r.GatewayDrift) > 0
+}
+
+// Reconciler detects, and optionally repairs, drift between a prepared cloud and its desired preparation.
+type Reconciler struct {
+	config Config
+}
+
+// New creates a Reconciler maintaining the given preparation.
+func New(config *Config) *Reconciler {
+	return &Reconciler{config: *config}
+}
+
+// Reconcile compares the live state with the desired preparation once, repairing it if requested. The cloud and
+// gateways are only repaired if they drifted in a way repairing can fix; since preparing and deploying are idempotent,
+// this re-applies the missing rules, attachments and gateways, and removes surplus gateways, without touching what's
+// already correct. Unexpected public ports aren't closed. The live state is then compared again, and whatever drift
+// remains is returned in the result.
+func (r *Reconciler) Reconcile(ctx context.Context, reporter api.Reporter) (*Result, error) {
+	result := &Result{}
+
+	var err error
+
+	result.CloudDrift, result.GatewayDrift, err = r.detectDrift(ctx, "Checking the cloud preparation for drift", reporter)
+	if err != nil {
+		return nil, err
+	}
+
+	cloudRepairs, gatewayRepairs := repairable(result.CloudDrift), repairable(result.GatewayDrift)
+
+	if !r.config.Repair || len(cloudRepairs)+len(gatewayRepairs) == 0 {
+		return result, nil
+	}
+
+	if len(cloudRepairs) > 0 {
+		err = r.config.Cloud.PrepareForSubmarinerWithContext(ctx, r.config.PrepareInput, reporter)
+		if err != nil {
+			return result, errors.Wrap(err, "error repairing the cloud preparation")
+		}
+	}
+
+	if len(gatewayRepairs) > 0 {
+		err = r.config.GatewayDeployer.DeployWithContext(ctx, r.config.DeployInput, reporter)
+		if err != nil {
+			return result, errors.Wrap(err, "error repairing the gateway deployment")
+		}
+	}
+
+	cloudDrift, gatewayDrift, err := r.detectDrift(ctx, "Checking the repaired cloud preparation for drift", reporter)
+	if err != nil {
+		return result, err
+	}
+
+	result.RemainingDrift = append(cloudDrift, gatewayDrift...)
+	result.Repaired = len(repairable(result.RemainingDrift)) == 0
+
+	return result, nil
+}
+
+func (r *Reconciler) detectDrift(ctx context.Context, message string, reporter api.Reporter) ([]Drift, []Drift, error) {
+	reporter.Started(message)
+
+	cloudStatus, err := r.config.Cloud.Status(ctx)
+	if err != nil {
+		reporter.Failed(err)
+		return nil, nil, errors.Wrap(err, "error retrieving the cloud status")
+	}
+
+	cloudDrift := CloudDrift(cloudStatus, &r.config.PrepareInput)
+	gatewayDrift := []Drift{}
+
+	if r.config.GatewayDeployer != nil {
+		gwStatus, err := r.config.GatewayDeployer.Status(ctx)
+		if err != nil {
+			reporter.Failed(err)
+			return nil, nil, errors.Wrap(err, "error retrieving the gateway status")
+		}
+
+		gatewayDrift = GatewayDrift(gwStatus, &r.config.DeployInput)
+	}
+
+	reporter.Succeeded("Found %d differences", len(cloudDrift)+len(gatewayDrift))
+
+	return cloudDrift, gatewayDrift, nil
+}
+
+// Run reconciles every interval until the context is done, passing each outcome to the handler.
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration, reporter api.Reporter, handler func(*Result, error)) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		handler(r.Reconcile(ctx, reporter))
+	}, interval)
+}
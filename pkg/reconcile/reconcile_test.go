@@ -0,0 +1,351 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/generic"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/reconcile"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeFake "k8s.io/client-go/kubernetes/fake"
+)
+
+var (
+	vxlanPort   = api.PortSpec{Port: 4800, Protocol: "udp"}
+	metricsPort = api.PortSpec{Port: 8080, Protocol: "tcp"}
+)
+
+var _ = Describe("Drift", func() {
+	When("the internal ports are open", func() {
+		It("should report no cloud drift", func() {
+			Expect(reconcile.CloudDrift(&api.CloudStatus{InternalPorts: []api.PortSpec{vxlanPort, metricsPort}},
+				&api.PrepareForSubmarinerInput{InternalPorts: []api.PortSpec{vxlanPort}})).To(BeEmpty())
+		})
+	})
+
+	When("an internal port was closed and the status has issues", func() {
+		It("should report both", func() {
+			drifts := reconcile.CloudDrift(&api.CloudStatus{InternalPorts: []api.PortSpec{vxlanPort}, Issues: []string{"issue"}},
+				&api.PrepareForSubmarinerInput{InternalPorts: []api.PortSpec{vxlanPort, metricsPort}})
+			Expect(drifts).To(HaveLen(2))
+			Expect(drifts[0].Type).To(Equal(reconcile.Missing))
+			Expect(drifts[0].Description).To(ContainSubstring(metricsPort.String()))
+			Expect(drifts[1]).To(Equal(reconcile.Drift{Type: reconcile.Inconsistent, Description: "issue"}))
+		})
+	})
+
+	When("the gateway firewall doesn't match the public ports", func() {
+		It("should report the missing and unexpected ports", func() {
+			input := &api.GatewayDeployInput{PublicPorts: []api.PortSpec{{Port: 4500, Protocol: "udp"}}}
+			drifts := reconcile.GatewayDrift(&api.GatewayStatus{
+				Firewall: &api.FirewallStatus{Kind: api.FirewallRuleResource, Name: "gw-rule", Ports: []api.PortSpec{metricsPort}},
+			}, input)
+
+			Expect(drifts).To(HaveLen(2))
+			Expect(drifts[0].Type).To(Equal(reconcile.Missing))
+			Expect(drifts[0].Kind).To(Equal(api.FirewallRuleResource))
+			Expect(drifts[0].Resource).To(Equal("gw-rule"))
+			Expect(drifts[1].Type).To(Equal(reconcile.Unexpected))
+			Expect(drifts[1].Description).To(ContainSubstring(metricsPort.String()))
+			Expect(drifts[0].Manual).To(BeFalse())
+			Expect(drifts[1].Manual).To(BeTrue())
+		})
+	})
+
+	When("the gateway firewall allows the public ports from other sources", func() {
+		It("should report the missing and unexpected sources", func() {
+			input := &api.GatewayDeployInput{
+				PublicPorts: []api.PortSpec{{Port: 4500, Protocol: "udp", SourceCIDRs: []string{"10.0.0.0/8"}}},
+				IPFamilies:  []api.IPFamily{api.IPv4Family, api.IPv6Family},
+			}
+
+			drifts := reconcile.GatewayDrift(&api.GatewayStatus{
+				Firewall: &api.FirewallStatus{Kind: api.FirewallRuleResource, Name: "gw-rule", Ports: []api.PortSpec{
+					{Port: 4500, Protocol: "UDP", SourceCIDRs: []string{"10.0.0.0/8"}},
+					{Port: 4500, Protocol: "UDP", SourceCIDRs: []string{"192.168.0.0/16"}},
+				}},
+			}, input)
+
+			Expect(drifts).To(HaveLen(1))
+			Expect(drifts[0].Type).To(Equal(reconcile.Unexpected))
+			Expect(drifts[0].Description).To(ContainSubstring("192.168.0.0/16"))
+		})
+	})
+
+	When("the gateway firewall only allows the public ports from IPv4 sources", func() {
+		It("should report the missing IPv6 sources", func() {
+			input := &api.GatewayDeployInput{
+				PublicPorts: []api.PortSpec{{Port: 4500, Protocol: "udp"}},
+				IPFamilies:  []api.IPFamily{api.IPv4Family, api.IPv6Family},
+			}
+
+			drifts := reconcile.GatewayDrift(&api.GatewayStatus{
+				Firewall: &api.FirewallStatus{Kind: api.FirewallRuleResource, Name: "gw-rule", Ports: []api.PortSpec{
+					{Port: 4500, Protocol: "UDP"},
+				}},
+			}, input)
+
+			Expect(drifts).To(HaveLen(1))
+			Expect(drifts[0].Type).To(Equal(reconcile.Missing))
+			Expect(drifts[0].Description).To(ContainSubstring(api.AnySourceCIDRv6))
+		})
+	})
+
+	When("there are more gateways than requested", func() {
+		It("should report unexpected gateways", func() {
+			drifts := reconcile.GatewayDrift(&api.GatewayStatus{GatewayNodes: []string{"node-1", "node-2"}},
+				&api.GatewayDeployInput{Gateways: 1})
+			Expect(drifts).To(HaveLen(1))
+			Expect(drifts[0].Type).To(Equal(reconcile.Unexpected))
+		})
+	})
+})
+
+var _ = Describe("Reconciler", func() {
+	var (
+		cloud      *fakeCloud
+		kubeClient *kubeFake.Clientset
+		config     *reconcile.Config
+	)
+
+	BeforeEach(func() {
+		cloud = &fakeCloud{status: &api.CloudStatus{InternalPorts: []api.PortSpec{vxlanPort}}}
+		kubeClient = kubeFake.NewSimpleClientset(newNode("node-1"), newNode("node-2"))
+
+		config = &reconcile.Config{
+			Cloud:           cloud,
+			PrepareInput:    api.PrepareForSubmarinerInput{InternalPorts: []api.PortSpec{vxlanPort}},
+			GatewayDeployer: generic.NewGatewayDeployer(k8s.NewInterface(kubeClient)),
+			DeployInput:     api.GatewayDeployInput{Gateways: 1},
+		}
+	})
+
+	reconcileOnce := func() *reconcile.Result {
+		result, err := reconcile.New(config).Reconcile(context.TODO(), api.NewLoggingReporter())
+		Expect(err).To(Succeed())
+
+		return result
+	}
+
+	When("the internal port was closed and the gateway unlabeled", func() {
+		BeforeEach(func() {
+			cloud.status.InternalPorts = nil
+		})
+
+		It("should report the drift without repairing it", func() {
+			result := reconcileOnce()
+			Expect(result.HasDrift()).To(BeTrue())
+			Expect(result.CloudDrift).To(HaveLen(1))
+			Expect(result.GatewayDrift).To(HaveLen(1))
+			Expect(result.Repaired).To(BeFalse())
+			Expect(cloud.prepared).To(BeEmpty())
+			Expect(gatewayNodes(kubeClient)).To(BeEmpty())
+		})
+
+		Context("and repair is requested", func() {
+			BeforeEach(func() {
+				config.Repair = true
+			})
+
+			It("should re-apply the desired preparation", func() {
+				result := reconcileOnce()
+				Expect(result.Repaired).To(BeTrue())
+				Expect(result.RemainingDrift).To(BeEmpty())
+				Expect(cloud.prepared).To(Equal([]api.PrepareForSubmarinerInput{config.PrepareInput}))
+				Expect(gatewayNodes(kubeClient)).To(HaveLen(1))
+
+				Expect(reconcileOnce().HasDrift()).To(BeFalse())
+			})
+		})
+
+		Context("and repair is requested but can't resolve all the drift", func() {
+			BeforeEach(func() {
+				config.Repair = true
+				cloud.status.Issues = []string{"issue"}
+			})
+
+			It("should return the remaining drift without reporting it repaired", func() {
+				result := reconcileOnce()
+				Expect(result.Repaired).To(BeFalse())
+				Expect(result.RemainingDrift).To(Equal([]reconcile.Drift{{Type: reconcile.Inconsistent, Description: "issue"}}))
+				Expect(cloud.prepared).To(HaveLen(1))
+				Expect(gatewayNodes(kubeClient)).To(HaveLen(1))
+			})
+		})
+
+		Context("and repairing the cloud fails", func() {
+			BeforeEach(func() {
+				config.Repair = true
+				cloud.prepareErr = errors.New("fake error")
+			})
+
+			It("should return an error", func() {
+				_, err := reconcile.New(config).Reconcile(context.TODO(), api.NewLoggingReporter())
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	When("a public port was opened by hand", func() {
+		var gwDeployer *fakeGatewayDeployer
+
+		BeforeEach(func() {
+			config.Repair = true
+			config.DeployInput = api.GatewayDeployInput{PublicPorts: []api.PortSpec{vxlanPort}}
+
+			gwDeployer = &fakeGatewayDeployer{status: &api.GatewayStatus{Firewall: &api.FirewallStatus{
+				Kind:  api.SecurityGroupResource,
+				Name:  "gw-sg",
+				Ports: []api.PortSpec{vxlanPort, metricsPort},
+			}}}
+			config.GatewayDeployer = gwDeployer
+		})
+
+		It("should report the drift without re-applying the preparation", func() {
+			result := reconcileOnce()
+			Expect(result.GatewayDrift).To(HaveLen(1))
+			Expect(result.GatewayDrift[0].Manual).To(BeTrue())
+			Expect(result.Repaired).To(BeFalse())
+			Expect(gwDeployer.deployed).To(BeEmpty())
+		})
+
+		Context("and an internal port was closed", func() {
+			BeforeEach(func() {
+				cloud.status.InternalPorts = nil
+			})
+
+			It("should repair the internal port and return the public port as remaining drift", func() {
+				result := reconcileOnce()
+				Expect(result.Repaired).To(BeTrue())
+				Expect(cloud.prepared).To(HaveLen(1))
+				Expect(gwDeployer.deployed).To(BeEmpty())
+				Expect(result.RemainingDrift).To(HaveLen(1))
+				Expect(result.RemainingDrift[0].Manual).To(BeTrue())
+			})
+		})
+	})
+
+	When("nothing drifted", func() {
+		BeforeEach(func() {
+			config.Repair = true
+			config.GatewayDeployer = nil
+		})
+
+		It("should not re-apply the preparation", func() {
+			result := reconcileOnce()
+			Expect(result.HasDrift()).To(BeFalse())
+			Expect(result.Repaired).To(BeFalse())
+			Expect(cloud.prepared).To(BeEmpty())
+		})
+	})
+
+	When("retrieving the cloud status fails", func() {
+		BeforeEach(func() {
+			cloud.statusErr = errors.New("fake error")
+		})
+
+		It("should return an error", func() {
+			_, err := reconcile.New(config).Reconcile(context.TODO(), api.NewLoggingReporter())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("run periodically", func() {
+		It("should reconcile until the context is done", func() {
+			ctx, cancel := context.WithCancel(context.TODO())
+			results := make(chan *reconcile.Result, 10)
+
+			go reconcile.New(config).Run(ctx, time.Millisecond, api.NewLoggingReporter(), func(result *reconcile.Result, err error) {
+				Expect(err).To(Succeed())
+				results <- result
+			})
+
+			Eventually(results).Should(Receive())
+			Eventually(results).Should(Receive())
+			cancel()
+		})
+	})
+})
+
+type fakeCloud struct {
+	api.Cloud
+	status     *api.CloudStatus
+	statusErr  error
+	prepareErr error
+	prepared   []api.PrepareForSubmarinerInput
+}
+
+func (c *fakeCloud) Status(ctx context.Context) (*api.CloudStatus, error) {
+	return c.status, c.statusErr
+}
+
+func (c *fakeCloud) PrepareForSubmarinerWithContext(ctx context.Context, input api.PrepareForSubmarinerInput, reporter api.Reporter) error {
+	if c.prepareErr != nil {
+		return c.prepareErr
+	}
+
+	c.prepared = append(c.prepared, input)
+	c.status.InternalPorts = input.InternalPorts
+
+	return nil
+}
+
+type fakeGatewayDeployer struct {
+	api.GatewayDeployer
+	status   *api.GatewayStatus
+	deployed []api.GatewayDeployInput
+}
+
+func (d *fakeGatewayDeployer) Status(ctx context.Context) (*api.GatewayStatus, error) {
+	return d.status, nil
+}
+
+func (d *fakeGatewayDeployer) DeployWithContext(ctx context.Context, input api.GatewayDeployInput, reporter api.Reporter) error {
+	d.deployed = append(d.deployed, input)
+	return nil
+}
+
+func newNode(name string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{},
+		},
+	}
+}
+
+func gatewayNodes(kubeClient *kubeFake.Clientset) []string {
+	nodes, err := kubeClient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: k8s.SubmarinerGatewayLabel})
+	Expect(err).To(Succeed())
+
+	names := []string{}
+	for i := range nodes.Items {
+		names = append(names, nodes.Items[i].Name)
+	}
+
+	return names
+}
@@ -0,0 +1,152 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package retry provides the policy used by the providers to retry the cloud API calls which fail with transient errors,
+// such as throttling, and to limit the rate of the calls on the client side.
+package retry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Policy determines how the cloud API calls are retried and rate limited. A Policy must be used by pointer, since it
+// holds the state of its rate limiter, which is shared by all the calls using it. A nil Policy doesn't retry or limit
+// any call.
+type Policy struct {
+	// Backoff determines the delays between the retries of a call. Backoff.Steps is the maximum number of retries.
+	Backoff wait.Backoff
+
+	// QPS is the sustained number of calls allowed per second, and Burst the number of calls allowed at once. The rate
+	// isn't limited if QPS is 0.
+	QPS   float64
+	Burst int
+
+	// Clock is used to wait between retries and for the rate limiter; it defaults to the real clock.
+	Clock clock.Clock
+
+	initLimiter sync.Once
+	limiter     *rate.Limiter
+}
+
+// Default returns the policy used by the providers unless another one is specified: up to 5 retries, starting after
+// one second and doubling the delay each time, with at most 10 calls per second and bursts of 20 calls.
+func Default() *Policy {
+	return &Policy{
+		Backoff: wait.Backoff{
+			Steps:    5,
+			Duration: time.Second,
+			Factor:   2,
+			Jitter:   0.1,
+			Cap:      30 * time.Second,
+		},
+		QPS:   10,
+		Burst: 20,
+	}
+}
+
+// Do calls the given function once the rate limiter allows it, and retries it as long as it fails with an error which
+// is retriable according to the given function and the policy allows for more retries. The last error is returned.
+func (p *Policy) Do(ctx context.Context, retriable func(error) bool, call func() error) error {
+	for retry := 1; ; retry++ {
+		if err := p.Wait(ctx); err != nil {
+			return err
+		}
+
+		err := call()
+		if err == nil || !p.ShouldRetry(ctx, retriable, err, retry) {
+			return err
+		}
+	}
+}
+
+// Wait blocks until the rate limiter allows another call, or the context is done.
+func (p *Policy) Wait(ctx context.Context) error {
+	if p == nil || p.QPS <= 0 {
+		return nil
+	}
+
+	p.initLimiter.Do(func() {
+		burst := p.Burst
+		if burst < 1 {
+			burst = 1
+		}
+
+		p.limiter = rate.NewLimiter(rate.Limit(p.QPS), burst)
+	})
+
+	now := p.clock().Now()
+	reservation := p.limiter.ReserveN(now, 1)
+
+	if err := p.sleep(ctx, reservation.DelayFrom(now)); err != nil {
+		reservation.CancelAt(p.clock().Now())
+		return err
+	}
+
+	return nil
+}
+
+// ShouldRetry determines whether a call which failed with the given error should be retried, given the number of the
+// retry, starting at 1. If so, it waits for the backoff delay before returning true. It returns false without waiting
+// if the error isn't retriable, if there are no retries left, or if the context is done.
+func (p *Policy) ShouldRetry(ctx context.Context, retriable func(error) bool, err error, retry int) bool {
+	if p == nil || retry > p.Backoff.Steps || !retriable(err) {
+		return false
+	}
+
+	return p.sleep(ctx, p.Delay(retry)) == nil
+}
+
+// Delay returns the backoff delay before the given retry, starting at 1.
+func (p *Policy) Delay(retry int) time.Duration {
+	backoff := p.Backoff
+
+	var delay time.Duration
+
+	for i := 0; i < retry; i++ {
+		delay = backoff.Step()
+	}
+
+	return delay
+}
+
+func (p *Policy) sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.clock().After(delay):
+		return nil
+	}
+}
+
+func (p *Policy) clock() clock.Clock {
+	if p.Clock == nil {
+		return clock.RealClock{}
+	}
+
+	return p.Clock
+}
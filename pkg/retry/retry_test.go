@@ -0,0 +1,194 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/retry"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var (
+	errTransient = errors.New("transient")
+	errFatal     = errors.New("fatal")
+)
+
+func isTransient(err error) bool {
+	return errors.Is(err, errTransient)
+}
+
+var _ = Describe("Policy", func() {
+	var (
+		fakeClock *clock.FakeClock
+		policy    *retry.Policy
+		errs      []error
+		calls     int
+		done      chan error
+		ctx       context.Context
+		cancel    context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		fakeClock = clock.NewFakeClock(time.Now())
+		policy = &retry.Policy{
+			Backoff: wait.Backoff{Steps: 3, Duration: time.Second, Factor: 2, Cap: 3 * time.Second},
+			Clock:   fakeClock,
+		}
+		errs = nil
+		calls = 0
+		done = make(chan error, 1)
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	call := func() error {
+		calls++
+
+		if calls <= len(errs) {
+			return errs[calls-1]
+		}
+
+		return nil
+	}
+
+	doAsync := func() {
+		go func() {
+			done <- policy.Do(ctx, isTransient, call)
+		}()
+	}
+
+	// awaitDelay waits for the policy to wait on the clock, then lets the given delay pass.
+	awaitDelay := func(delay time.Duration) {
+		Eventually(fakeClock.HasWaiters).Should(BeTrue())
+		Consistently(done).ShouldNot(Receive())
+		fakeClock.Step(delay)
+	}
+
+	It("should compute exponential delays up to the cap", func() {
+		Expect(policy.Delay(1)).To(Equal(time.Second))
+		Expect(policy.Delay(2)).To(Equal(2 * time.Second))
+		Expect(policy.Delay(3)).To(Equal(3 * time.Second))
+	})
+
+	When("a call fails with retriable errors", func() {
+		BeforeEach(func() {
+			errs = []error{errTransient, errTransient}
+		})
+
+		It("should retry it after the backoff delays until it succeeds", func() {
+			doAsync()
+			awaitDelay(time.Second)
+			awaitDelay(2 * time.Second)
+			Eventually(done).Should(Receive(BeNil()))
+			Expect(calls).To(Equal(3))
+		})
+	})
+
+	When("a call keeps failing with retriable errors", func() {
+		BeforeEach(func() {
+			errs = []error{errTransient, errTransient, errTransient, errTransient, errTransient}
+		})
+
+		It("should return the last error once there are no retries left", func() {
+			doAsync()
+			awaitDelay(time.Second)
+			awaitDelay(2 * time.Second)
+			awaitDelay(3 * time.Second)
+			Eventually(done).Should(Receive(MatchError(errTransient)))
+			Expect(calls).To(Equal(4))
+		})
+	})
+
+	When("a call fails with an error which isn't retriable", func() {
+		BeforeEach(func() {
+			errs = []error{errFatal}
+		})
+
+		It("should return it without retrying", func() {
+			Expect(policy.Do(ctx, isTransient, call)).To(MatchError(errFatal))
+			Expect(calls).To(Equal(1))
+		})
+	})
+
+	When("the context is done while waiting to retry", func() {
+		BeforeEach(func() {
+			errs = []error{errTransient}
+		})
+
+		It("should return the error without retrying", func() {
+			doAsync()
+			Eventually(fakeClock.HasWaiters).Should(BeTrue())
+			cancel()
+			Eventually(done).Should(Receive(MatchError(errTransient)))
+			Expect(calls).To(Equal(1))
+		})
+	})
+
+	When("the rate is limited", func() {
+		BeforeEach(func() {
+			policy.QPS = 1
+			policy.Burst = 2
+		})
+
+		It("should only delay the calls beyond the burst", func() {
+			Expect(policy.Do(ctx, isTransient, call)).To(Succeed())
+			Expect(policy.Do(ctx, isTransient, call)).To(Succeed())
+
+			doAsync()
+			awaitDelay(time.Second)
+			Eventually(done).Should(Receive(BeNil()))
+			Expect(calls).To(Equal(3))
+		})
+
+		It("should stop waiting when the context is done", func() {
+			Expect(policy.Wait(ctx)).To(Succeed())
+			Expect(policy.Wait(ctx)).To(Succeed())
+
+			go func() {
+				done <- policy.Wait(ctx)
+			}()
+
+			Eventually(fakeClock.HasWaiters).Should(BeTrue())
+			cancel()
+			Eventually(done).Should(Receive(Equal(context.Canceled)))
+		})
+	})
+
+	When("the policy is nil", func() {
+		BeforeEach(func() {
+			errs = []error{errTransient}
+		})
+
+		It("should call once without retrying", func() {
+			var nilPolicy *retry.Policy
+
+			Expect(nilPolicy.Do(ctx, isTransient, call)).To(MatchError(errTransient))
+			Expect(calls).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,67 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"context"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/secgroups"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
+	"github.com/submariner-io/cloud-prepare/pkg/audit"
+)
+
+// audited makes a mutating call with the given client, identified by its gophercloud operation, e.g.
+// "secgroups.AddServer", and records it in the audit log carried by the client's context, see CloudInfo.withContext.
+func audited(client *gophercloud.ServiceClient, operation, resource string, request interface{}, call func() error) error {
+	err := call()
+
+	ctx := client.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	audit.Record(ctx, "rhos", operation, resource, request, err)
+
+	return err
+}
+
+func addServer(client *gophercloud.ServiceClient, serverID, groupName string) error {
+	return audited(client, "secgroups.AddServer", serverID, map[string]string{"securityGroup": groupName}, func() error {
+		return secgroups.AddServer(client, serverID, groupName).ExtractErr()
+	})
+}
+
+func removeServer(client *gophercloud.ServiceClient, serverID, groupName string) error {
+	return audited(client, "secgroups.RemoveServer", serverID, map[string]string{"securityGroup": groupName}, func() error {
+		return secgroups.RemoveServer(client, serverID, groupName).ExtractErr()
+	})
+}
+
+func deleteSecurityGroup(client *gophercloud.ServiceClient, groupID string) error {
+	return audited(client, "secgroups.Delete", groupID, nil, func() error {
+		return secgroups.Delete(client, groupID).ExtractErr()
+	})
+}
+
+func deleteRule(client *gophercloud.ServiceClient, ruleID string) error {
+	return audited(client, "rules.Delete", ruleID, nil, func() error {
+		return rules.Delete(client, ruleID).ExtractErr()
+	})
+}
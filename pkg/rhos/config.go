@@ -0,0 +1,119 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/ocp"
+	"github.com/submariner-io/cloud-prepare/pkg/retry"
+)
+
+// Config is the configuration used to create the RHOS Cloud and GatewayDeployer with NewProvider.
+type Config struct {
+	InfraID   string `json:"infraID"`
+	Region    string `json:"region"`
+	ProjectID string `json:"projectID"`
+
+	// CloudName is the entry in the cluster's clouds.yaml used by the dedicated gateway MachineSets.
+	CloudName string `json:"cloudName,omitempty"`
+
+	// DedicatedGateway deploys dedicated gateway nodes of InstanceType, using Image if set, instead of
+	// using existing worker nodes.
+	DedicatedGateway bool   `json:"dedicatedGateway,omitempty"`
+	InstanceType     string `json:"instanceType,omitempty"`
+	Image            string `json:"image,omitempty"`
+
+	// Client is an authenticated OpenStack client. If nil, one is authenticated using the OS_* environment variables.
+	Client *gophercloud.ProviderClient `json:"-"`
+
+	// Inventory, if set, records the resources which are created, and drives their cleanup.
+	Inventory api.Inventory `json:"-"`
+}
+
+// Validate returns an api.MissingFieldsError if any required field is missing.
+func (c *Config) Validate() error {
+	missing := &api.MissingFieldsError{Provider: "RHOS"}
+
+	if c.InfraID == "" {
+		missing.Add("infraID", "")
+	}
+
+	if c.Region == "" {
+		missing.Add("region", "")
+	}
+
+	if c.ProjectID == "" {
+		missing.Add("projectID", "")
+	}
+
+	if c.DedicatedGateway && c.InstanceType == "" {
+		missing.Add("instanceType", "for dedicated gateways")
+	}
+
+	if c.DedicatedGateway && c.CloudName == "" {
+		missing.Add("cloudName", "for dedicated gateways")
+	}
+
+	return missing.OrNil()
+}
+
+// NewProvider creates the RHOS Cloud and GatewayDeployer for the given configuration. The MachineSetDeployer is only
+// required for dedicated gateways.
+func NewProvider(config *Config, msDeployer ocp.MachineSetDeployer, k8sClient k8s.Interface) (api.Cloud, api.GatewayDeployer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if k8sClient == nil {
+		return nil, nil, errors.New("the RHOS provider requires a Kubernetes client")
+	}
+
+	if config.DedicatedGateway && msDeployer == nil {
+		return nil, nil, errors.New("the RHOS provider requires a MachineSetDeployer for dedicated gateways")
+	}
+
+	client := config.Client
+	if client == nil {
+		authOptions, err := openstack.AuthOptionsFromEnv()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error reading the OpenStack authentication options")
+		}
+
+		client, err = openstack.AuthenticatedClient(authOptions)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error authenticating the OpenStack client")
+		}
+	}
+
+	info := CloudInfo{
+		Client:      client,
+		InfraID:     config.InfraID,
+		Region:      config.Region,
+		K8sClient:   k8sClient,
+		Inventory:   config.Inventory,
+		RetryPolicy: retry.Default(),
+	}
+
+	return NewCloud(info), NewOcpGatewayDeployer(info, msDeployer, config.ProjectID, config.InstanceType, config.Image,
+		config.CloudName, config.DedicatedGateway), nil
+}
@@ -0,0 +1,75 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+var statusCategories = map[int]error{
+	http.StatusUnauthorized:          api.ErrPermissionDenied,
+	http.StatusForbidden:             api.ErrPermissionDenied,
+	http.StatusNotFound:              api.ErrNotFound,
+	http.StatusConflict:              api.ErrConflict,
+	http.StatusRequestEntityTooLarge: api.ErrQuotaExceeded,
+	http.StatusTooManyRequests:       api.ErrTransient,
+	http.StatusInternalServerError:   api.ErrTransient,
+	http.StatusBadGateway:            api.ErrTransient,
+	http.StatusServiceUnavailable:    api.ErrTransient,
+	http.StatusGatewayTimeout:        api.ErrTransient,
+	http.StatusNotImplemented:        api.ErrUnsupported,
+}
+
+func categorizeError(err error) error {
+	var codeErr gophercloud.StatusCodeError
+	if !errors.As(err, &codeErr) {
+		return err
+	}
+
+	// Neutron reports exceeded quotas as conflicts, and Nova as forbidden requests.
+	body := responseBody(err)
+	if bytes.Contains(body, []byte("OverQuota")) || bytes.Contains(body, []byte("Quota exceeded")) {
+		return api.NewError(api.ErrQuotaExceeded, err)
+	}
+
+	if category, ok := statusCategories[codeErr.GetStatusCode()]; ok {
+		return api.NewError(category, err)
+	}
+
+	return err
+}
+
+func responseBody(err error) []byte {
+	var forbidden gophercloud.ErrDefault403
+	if errors.As(err, &forbidden) {
+		return forbidden.Body
+	}
+
+	var conflict gophercloud.ErrDefault409
+	if errors.As(err, &conflict) {
+		return conflict.Body
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos_test
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gophercloud/gophercloud"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/rhos"
+	kubeFake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Error categories", func() {
+	var t *fakeOpenStack
+
+	BeforeEach(func() {
+		t = &fakeOpenStack{}
+		t.beforeEach()
+	})
+
+	AfterEach(func() {
+		t.afterEach()
+	})
+
+	// createGatewayGroup deploys the gateways, which creates the missing gateway security group. Nova responds to the
+	// request with the given status and body.
+	createGatewayGroup := func(status int, body string) error {
+		t.failures["POST /os-security-groups"] = errorResponse{status: status, body: body}
+
+		gwDeployer := rhos.NewOcpGatewayDeployer(rhos.CloudInfo{
+			Client:    t.providerClient(),
+			InfraID:   infraID,
+			Region:    region,
+			K8sClient: k8s.NewInterface(kubeFake.NewSimpleClientset()),
+		}, nil, projectID, flavor, "rhcos", "openstack", false)
+
+		return gwDeployer.Deploy(api.GatewayDeployInput{
+			PublicPorts: []api.PortSpec{{Port: 4500, Protocol: "udp"}},
+			Gateways:    1,
+		}, api.NewLoggingReporter())
+	}
+
+	table.DescribeTable("should put the OpenStack API errors in their category",
+		func(status int, body string, category error) {
+			err := createGatewayGroup(status, body)
+
+			Expect(errors.Is(err, category)).To(BeTrue())
+			Expect(errors.As(err, new(gophercloud.StatusCodeError))).To(BeTrue())
+		},
+		table.Entry("Nova quota exceeded", http.StatusForbidden, `{"forbidden": {"message": "Quota exceeded for resources"}}`,
+			api.ErrQuotaExceeded),
+		table.Entry("Neutron quota exceeded", http.StatusConflict, `{"NeutronError": {"type": "OverQuota"}}`, api.ErrQuotaExceeded),
+		table.Entry("request entity too large", http.StatusRequestEntityTooLarge, "", api.ErrQuotaExceeded),
+		table.Entry("unauthorized", http.StatusUnauthorized, "", api.ErrPermissionDenied),
+		table.Entry("forbidden", http.StatusForbidden, `{"forbidden": {"message": "Policy doesn't allow it"}}`, api.ErrPermissionDenied),
+		table.Entry("not found", http.StatusNotFound, "", api.ErrNotFound),
+		table.Entry("conflict", http.StatusConflict, `{"NeutronError": {"type": "SecurityGroupRuleExists"}}`, api.ErrConflict),
+		table.Entry("too many requests", http.StatusTooManyRequests, "", api.ErrTransient),
+		table.Entry("internal error", http.StatusInternalServerError, "", api.ErrTransient),
+		table.Entry("bad gateway", http.StatusBadGateway, "", api.ErrTransient),
+		table.Entry("service unavailable", http.StatusServiceUnavailable, "", api.ErrTransient),
+		table.Entry("gateway timeout", http.StatusGatewayTimeout, "", api.ErrTransient),
+		table.Entry("not implemented", http.StatusNotImplemented, "", api.ErrUnsupported),
+	)
+
+	It("should return the other errors as is", func() {
+		err := createGatewayGroup(http.StatusBadRequest, "")
+
+		Expect(errors.As(err, new(gophercloud.ErrDefault400))).To(BeTrue())
+		Expect(errors.As(err, new(*api.Error))).To(BeFalse())
+	})
+})
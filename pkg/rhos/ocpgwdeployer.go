@@ -20,6 +20,7 @@ package rhos
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -29,8 +30,11 @@ import (
 	"github.com/gophercloud/gophercloud/openstack"
 	"github.com/pkg/errors"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/metrics"
 	"github.com/submariner-io/cloud-prepare/pkg/ocp"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 )
@@ -112,7 +116,7 @@ func (d *ocpGatewayDeployer) initMachineSet(index string) (*unstructured.Unstruc
 	return machineSet, errors.Wrap(err, "error decoding message gateway yaml")
 }
 
-func (d *ocpGatewayDeployer) deployGateway(index string) error {
+func (d *ocpGatewayDeployer) deployGateway(ctx context.Context, index string) error {
 	machineSet, err := d.initMachineSet(index)
 	if err != nil {
 		return err
@@ -122,7 +126,7 @@ func (d *ocpGatewayDeployer) deployGateway(index string) error {
 		// TODO: use machineSetClient.List() instead of hard coding.
 		workerNodeList := []string{d.InfraID + "-worker-0", d.InfraID + "-worker-1", d.InfraID + "-worker-2"}
 
-		d.image, err = d.msDeployer.GetWorkerNodeImage(workerNodeList, machineSet, d.InfraID)
+		d.image, err = d.msDeployer.GetWorkerNodeImage(ctx, workerNodeList, machineSet, d.InfraID)
 		if err != nil {
 			return errors.Wrap(err, "error getting the worker image")
 		}
@@ -133,88 +137,154 @@ func (d *ocpGatewayDeployer) deployGateway(index string) error {
 		}
 	}
 
-	return errors.Wrap(d.msDeployer.Deploy(machineSet), "failed to deploy submariner gateway node")
+	if err := d.msDeployer.Deploy(ctx, machineSet); err != nil {
+		return errors.Wrap(err, "failed to deploy submariner gateway node")
+	}
+
+	api.OnRollback(ctx, "delete machine set "+machineSet.GetName(), func(ctx context.Context) error {
+		return d.deleteGateway(ctx, index)
+	})
+
+	return api.RecordResource(ctx, d.Inventory, machineSetResource(machineSet.GetName(), index))
+}
+
+// machineSetResource returns the inventory resource for the named gateway machine set, deployed with the given index.
+func machineSetResource(name, index string) api.InventoryResource {
+	return api.InventoryResource{
+		Scope:       api.GatewayScope,
+		Kind:        api.MachineSetResource,
+		ID:          name,
+		Parent:      index,
+		Description: "dedicated gateway node",
+	}
+}
+
+func nodeLabelResource(nodeName string) api.InventoryResource {
+	return api.InventoryResource{
+		Scope:       api.GatewayScope,
+		Kind:        api.NodeLabelResource,
+		ID:          nodeName,
+		Description: "gateway label",
+	}
 }
 
 func (d *ocpGatewayDeployer) Deploy(input api.GatewayDeployInput, reporter api.Reporter) error {
-	reporter.Started("Configuring the required firewall rules for inter-cluster traffic")
+	return d.DeployWithContext(context.TODO(), input, reporter)
+}
 
-	computeClient, err := openstack.NewComputeV2(d.Client, gophercloud.EndpointOpts{Region: d.Region})
+func (d *ocpGatewayDeployer) DeployWithContext(ctx context.Context, input api.GatewayDeployInput, reporter api.Reporter) error {
+	return metrics.ObserveOperation(ctx, "rhos", metrics.Deploy, func() error {
+		return api.RunWithRollback(ctx, input.Rollback, reporter, func(ctx context.Context) error {
+			return d.deploy(ctx, input, reporter)
+		})
+	})
+}
+
+func (d *ocpGatewayDeployer) deploy(ctx context.Context, input api.GatewayDeployInput, reporter api.Reporter) error {
+	families, err := k8s.IPFamiliesOrDetected(ctx, d.K8sClient, input.IPFamilies)
+	if err != nil {
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	input.IPFamilies = families
+
+	computeClient, err := openstack.NewComputeV2(d.withContext(ctx), gophercloud.EndpointOpts{Region: d.Region})
 	if err != nil {
 		return errors.Wrap(err, "error creating the compute client")
 	}
 
-	networkClient, err := openstack.NewNetworkV2(d.Client, gophercloud.EndpointOpts{Region: d.Region})
+	networkClient, err := openstack.NewNetworkV2(d.withContext(ctx), gophercloud.EndpointOpts{Region: d.Region})
 	if err != nil {
 		return errors.Wrap(err, "error creating the network client")
 	}
 
+	err = api.ValidatePrerequisites(reporter, func() error {
+		err := validatePolicies(d.listSecurityGroupsCheck(networkClient), d.listServersCheck(computeClient),
+			networkQuotaCheck(networkClient, d.projectID), computeQuotaCheck(computeClient, d.projectID))
+		if err != nil {
+			return err
+		}
+
+		return d.validateQuotas(ctx, &input, computeClient, networkClient)
+	}, uncheckedChangesWarning(reporter, prepareChanges))
+	if err != nil {
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	reporter.Started("Configuring the required firewall rules for inter-cluster traffic")
+
 	groupName := d.InfraID + gwSecurityGroupSuffix
-	if err := d.createGWSecurityGroup(input.PublicPorts, groupName, computeClient, networkClient); err != nil {
+	publicPorts := input.PublicPortSpecs()
+
+	if err := d.createGWSecurityGroup(ctx, publicPorts, input.PublicIPFamilies(), groupName, computeClient, networkClient); err != nil {
 		return errors.Wrap(err, "creating gateway security group failed")
 	}
 
 	reporter.Succeeded("Opened External ports %q in security group %q on RHOS",
-		formatPorts(input.PublicPorts), groupName)
+		formatPorts(publicPorts), groupName)
 
 	reporter.Started("Configuring the required number of Submariner gateway pods")
 
-	gwNodes, err := d.K8sClient.ListGatewayNodes()
+	gwNodes, err := d.K8sClient.ListGatewayNodes(ctx)
 	if err != nil {
 		return errors.Wrap(err, "listing the existing gatway nodes failed")
 	}
 
-	return d.deployGWNode(gwNodes, input.Gateways, groupName, computeClient, reporter)
+	if gatewaysToRemove := input.GatewaysToRemove(len(gwNodes.Items)); gatewaysToRemove > 0 {
+		return d.removeGateways(ctx, &input, gwNodes, gatewaysToRemove, groupName, computeClient, reporter)
+	}
+
+	return d.deployGWNode(ctx, gwNodes, input.Gateways, groupName, computeClient, reporter)
 }
 
-func (d *ocpGatewayDeployer) deployGWNode(gwNodes *v1.NodeList, gatewayCount int, groupName string,
+func (d *ocpGatewayDeployer) deployGWNode(ctx context.Context, gwNodes *v1.NodeList, gatewayCount int, groupName string,
 	computeClient *gophercloud.ServiceClient, reporter api.Reporter) error {
 	numGatewayNodes := len(gwNodes.Items)
 
-	if numGatewayNodes == gatewayCount {
+	if numGatewayNodes >= gatewayCount {
 		reporter.Succeeded("Current Submariner gateways match the required number of Submariner gateways")
 		return nil
 	}
 
-	// Currently, we only support increasing the number of Gateway nodes which could be a valid use-case
-	// to convert a non-HA deployment to an HA deployment. We are not supporting decreasing the Gateway
-	// nodes (for now) as it might impact the datapath if we accidentally delete the active GW node.
+	// Surplus gateway nodes have already been removed by removeGateways, so only increases are handled here.
 	var err error
 
 	if numGatewayNodes < gatewayCount {
 		gatewayNodesToDeploy := gatewayCount - numGatewayNodes
 
 		if d.dedicatedGWNode {
-			err = d.deployDedicatedGWNode(gatewayNodesToDeploy, reporter)
+			err = d.deployDedicatedGWNode(ctx, gatewayNodesToDeploy, reporter)
 		} else {
-			err = d.tagExistingNode(groupName, computeClient, gatewayNodesToDeploy, reporter)
+			err = d.tagExistingNode(ctx, groupName, computeClient, gatewayNodesToDeploy, reporter)
 		}
 	}
 
 	return err
 }
 
-func (d *ocpGatewayDeployer) deployDedicatedGWNode(gatewayNodesToDeploy int, reporter api.Reporter) error {
+func (d *ocpGatewayDeployer) deployDedicatedGWNode(ctx context.Context, gatewayNodesToDeploy int, reporter api.Reporter) error {
 	for i := 0; i < gatewayNodesToDeploy; i++ {
 		gwNodeName := d.InfraID + "-submariner-gw" + strconv.Itoa(i)
-		reporter.Started(fmt.Sprintf("Deploying dedicated gateway node %s",
-			gwNodeName))
+		api.ForResource(reporter, api.MachineSetResource, d.InfraID+"-submariner-gw-"+strconv.Itoa(i)).
+			Started(fmt.Sprintf("Deploying dedicated gateway node %s", gwNodeName))
 
-		err := d.deployGateway(strconv.Itoa(i))
+		err := d.deployGateway(ctx, strconv.Itoa(i))
 		if err != nil {
 			reporter.Failed(err)
 			return err
 		}
 
+		metrics.GatewaysDeployed(ctx, "rhos", 1)
+
 		reporter.Succeeded("Successfully deployed Submariner gateway node")
 	}
 
 	return nil
 }
 
-func (d *ocpGatewayDeployer) tagExistingNode(groupName string, computeClient *gophercloud.ServiceClient,
+func (d *ocpGatewayDeployer) tagExistingNode(ctx context.Context, groupName string, computeClient *gophercloud.ServiceClient,
 	gatewayNodesToDeploy int, reporter api.Reporter) error {
-	workerNodes, err := d.K8sClient.ListNodesWithLabel("node-role.kubernetes.io/worker")
+	workerNodes, err := d.K8sClient.ListNodesWithLabel(ctx, "node-role.kubernetes.io/worker")
 	if err != nil {
 		return errors.Wrapf(err, "failed to list k8s nodes in project %q", d.projectID)
 	}
@@ -226,17 +296,29 @@ func (d *ocpGatewayDeployer) tagExistingNode(groupName string, computeClient *go
 			continue
 		}
 
-		reporter.Started(fmt.Sprintf("Configuring worker node %q as Submariner gateway node", nodes[i].Name))
+		api.ForResource(reporter, api.NodeLabelResource, nodes[i].Name).
+			Started(fmt.Sprintf("Configuring worker node %q as Submariner gateway node", nodes[i].Name))
 
-		err := d.K8sClient.AddGWLabelOnNode(nodes[i].Name)
+		err := d.K8sClient.AddGWLabelOnNode(ctx, nodes[i].Name)
 		if err != nil {
 			return errors.Wrapf(err, "failed to label the node %q as Submariner gateway node", nodes[i].Name)
 		}
 
-		if err = d.openGatewayPort(groupName, nodes[i].Name, computeClient); err != nil {
+		node := &nodes[i]
+		api.OnRollback(ctx, "remove the gateway label from node "+node.Name, func(ctx context.Context) error {
+			return d.removeGatewayLabel(ctx, node)
+		})
+
+		if err := api.RecordResource(ctx, d.Inventory, nodeLabelResource(node.Name)); err != nil {
+			return err // nolint:wrapcheck // No need to wrap here
+		}
+
+		if err = d.openGatewayPort(ctx, groupName, nodes[i].Name, computeClient); err != nil {
 			return errors.Wrap(err, "failed to open the Submariner gateway port")
 		}
 
+		metrics.GatewaysDeployed(ctx, "rhos", 1)
+
 		gatewayNodesToDeploy--
 		if gatewayNodesToDeploy <= 0 {
 			reporter.Succeeded("Successfully deployed Submariner gateway node")
@@ -252,15 +334,100 @@ func (d *ocpGatewayDeployer) tagExistingNode(groupName string, computeClient *go
 	return nil
 }
 
+func (d *ocpGatewayDeployer) removeGateways(ctx context.Context, input *api.GatewayDeployInput, gwNodes *v1.NodeList, count int,
+	groupName string, computeClient *gophercloud.ServiceClient, reporter api.Reporter) error {
+	activeNode, err := input.FindActiveGatewayNode(ctx, d.K8sClient.GetActiveGatewayNode)
+	if err != nil {
+		reporter.Failed(err)
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	for _, node := range d.surplusGatewayNodes(gwNodes, count, activeNode) {
+		api.ForResource(reporter, api.NodeLabelResource, node.Name).
+			Started(fmt.Sprintf("Removing the surplus Submariner gateway node %q", node.Name))
+
+		err = d.removeFirewallRulesFromGW(ctx, groupName, node.Name, computeClient)
+		if err != nil {
+			reporter.Failed(err)
+			return errors.Wrapf(err, "error deleting the Submariner gateway security group rules from node: %q", node.Name)
+		}
+
+		if index, dedicated := d.dedicatedGatewayIndex(node.Name); dedicated {
+			err = d.deleteGateway(ctx, index)
+		} else {
+			err = d.removeGatewayLabel(ctx, node)
+		}
+
+		if err != nil {
+			reporter.Failed(err)
+			return errors.Wrapf(err, "failed to remove the Submariner gateway node %q", node.Name)
+		}
+
+		reporter.Succeeded("Successfully removed the Submariner gateway node")
+	}
+
+	return nil
+}
+
+// surplusGatewayNodes returns the given number of the cluster's gateway nodes to remove, skipping the node hosting
+// the active gateway.
+func (d *ocpGatewayDeployer) surplusGatewayNodes(gwNodes *v1.NodeList, count int, activeNode string) []*v1.Node {
+	surplus := []*v1.Node{}
+
+	for i := range gwNodes.Items {
+		if len(surplus) == count {
+			break
+		}
+
+		node := &gwNodes.Items[i]
+		if strings.HasPrefix(node.Name, d.InfraID) && node.Name != activeNode {
+			surplus = append(surplus, node)
+		}
+	}
+
+	return surplus
+}
+
+// dedicatedGatewayIndex returns the index of the machine set which deployed the named node, and whether the node
+// is a dedicated gateway node at all. Machine set node names are the machine set name followed by a random suffix.
+func (d *ocpGatewayDeployer) dedicatedGatewayIndex(nodeName string) (string, bool) {
+	prefix := d.InfraID + "-submariner-gw-"
+	if !strings.HasPrefix(nodeName, prefix) {
+		return "", false
+	}
+
+	return strings.SplitN(strings.TrimPrefix(nodeName, prefix), "-", 2)[0], true
+}
+
 func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
-	reporter.Started("Removing the Submariner gateway configuration from nodes ")
+	return d.CleanupWithContext(context.TODO(), reporter)
+}
 
-	computeClient, err := openstack.NewComputeV2(d.Client, gophercloud.EndpointOpts{Region: d.Region})
+func (d *ocpGatewayDeployer) CleanupWithContext(ctx context.Context, reporter api.Reporter) error {
+	computeClient, err := openstack.NewComputeV2(d.withContext(ctx), gophercloud.EndpointOpts{Region: d.Region})
 	if err != nil {
 		return errors.Wrapf(err, "error creating the compute client for the region: %q", d.Region)
 	}
 
-	gwNodesList, err := d.K8sClient.ListGatewayNodes()
+	networkClient, err := openstack.NewNetworkV2(d.withContext(ctx), gophercloud.EndpointOpts{Region: d.Region})
+	if err != nil {
+		return errors.Wrapf(err, "error creating the network client for the region: %q", d.Region)
+	}
+
+	err = api.ValidatePrerequisites(reporter, func() error {
+		return validatePolicies(d.listSecurityGroupsCheck(networkClient), d.listServersCheck(computeClient))
+	}, uncheckedChangesWarning(reporter, cleanupChanges))
+	if err != nil {
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	reporter.Started("Removing the Submariner gateway configuration from nodes ")
+
+	if d.Inventory != nil {
+		return d.cleanupInventory(ctx, computeClient, networkClient, reporter)
+	}
+
+	gwNodesList, err := d.K8sClient.ListGatewayNodes(ctx)
 	if err != nil {
 		return errors.Wrap(err, "error listing the Submariner gateway nodes")
 	}
@@ -271,6 +438,7 @@ func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
 	for i := range gwNodes {
 		// Check if the instance belongs to the cluster (identified via infraID) we are operating on.
 		if !strings.HasPrefix(gwNodes[i].Name, d.InfraID) {
+			api.Warn(reporter, "Skipping gateway node %q which doesn't belong to cluster %q", gwNodes[i].Name, d.InfraID)
 			continue
 		}
 
@@ -278,16 +446,17 @@ func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
 		// the gateway node was deployed using the OCPMachineSet API otherwise it's an existing worker node.
 		prefix := d.InfraID + "-submariner-gw-"
 
-		err = d.removeFirewallRulesFromGW(groupName, gwNodes[i].Name, computeClient)
+		err = d.removeFirewallRulesFromGW(ctx, groupName, gwNodes[i].Name, computeClient)
 		if err != nil {
 			return errors.Wrapf(err, "error deleting the Submariner gateway security group rules from node: %q",
 				gwNodes[i].Name)
 		}
 
 		if strings.HasPrefix(gwNodes[i].Name, prefix) {
-			reporter.Started(fmt.Sprintf("Deleting the gateway instance %q", gwNodes[i].Name))
+			api.ForResource(reporter, api.MachineSetResource, d.InfraID+"-submariner-gw-"+strconv.Itoa(i)).
+				Started(fmt.Sprintf("Deleting the gateway instance %q", gwNodes[i].Name))
 
-			err = d.deleteGateway(strconv.Itoa(i))
+			err = d.deleteGateway(ctx, strconv.Itoa(i))
 			if err != nil {
 				return errors.Wrapf(err, "error deleting the Submariner gateway security group rules from node: %q",
 					gwNodes[i].Name)
@@ -295,8 +464,9 @@ func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
 
 			reporter.Succeeded("Successfully deleted the instance")
 		} else {
-			reporter.Started(fmt.Sprintf("Removing the gateway configuration from instance %q", gwNodes[i].Name))
-			err = d.K8sClient.RemoveGWLabelFromWorkerNode(&gwNodes[i])
+			api.ForResource(reporter, api.NodeLabelResource, gwNodes[i].Name).
+				Started(fmt.Sprintf("Removing the gateway configuration from instance %q", gwNodes[i].Name))
+			err = d.K8sClient.RemoveGWLabelFromWorkerNode(ctx, &gwNodes[i])
 			if err != nil {
 				return errors.Wrap(err, "failed to remove labels from worker node")
 			}
@@ -307,7 +477,7 @@ func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
 
 	reporter.Succeeded("Successfully removed the Submariner gateway configuration from the nodes")
 
-	reporter.Started("Deleting the Submariner gateway security group")
+	api.ForResource(reporter, api.SecurityGroupResource, groupName).Started("Deleting the Submariner gateway security group")
 
 	err = d.deleteSG(groupName, computeClient)
 	if err != nil {
@@ -319,20 +489,55 @@ func (d *ocpGatewayDeployer) Cleanup(reporter api.Reporter) error {
 	return nil
 }
 
+// cleanupInventory removes the gateway resources recorded in the inventory, most recently created first.
+func (d *ocpGatewayDeployer) cleanupInventory(ctx context.Context, computeClient, networkClient *gophercloud.ServiceClient,
+	reporter api.Reporter) error {
+	err := api.CleanupInventory(ctx, d.Inventory, api.GatewayScope, func(resource *api.InventoryResource) error {
+		switch resource.Kind { // nolint:exhaustive // The other kinds are security group resources
+		case api.MachineSetResource:
+			return d.deleteGateway(ctx, resource.Parent)
+		case api.NodeLabelResource:
+			return d.K8sClient.RemoveGWLabelFromWorkerNode(ctx, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: resource.ID}})
+		default:
+			return d.removeInventoried(resource, computeClient, networkClient)
+		}
+	})
+	if err != nil {
+		reporter.Failed(err)
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	reporter.Succeeded("Successfully removed the Submariner gateway configuration")
+
+	return nil
+}
+
+func (d *ocpGatewayDeployer) removeGatewayLabel(ctx context.Context, node *v1.Node) error {
+	if err := d.K8sClient.RemoveGWLabelFromWorkerNode(ctx, node); err != nil {
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	return api.ForgetResource(ctx, d.Inventory, nodeLabelResource(node.Name))
+}
+
 func formatPorts(ports []api.PortSpec) string {
 	portStrs := []string{}
 	for _, port := range ports {
-		portStrs = append(portStrs, fmt.Sprintf("%d/%s", port.Port, port.Protocol))
+		portStrs = append(portStrs, port.String())
 	}
 
 	return strings.Join(portStrs, ", ")
 }
 
-func (d *ocpGatewayDeployer) deleteGateway(index string) error {
+func (d *ocpGatewayDeployer) deleteGateway(ctx context.Context, index string) error {
 	machineSet, err := d.initMachineSet(index)
 	if err != nil {
 		return err
 	}
 
-	return errors.Wrap(d.msDeployer.Delete(machineSet), "error deleting the submariner gateway node")
+	if err := d.msDeployer.Delete(ctx, machineSet); err != nil {
+		return errors.Wrap(err, "error deleting the submariner gateway node")
+	}
+
+	return api.ForgetResource(ctx, d.Inventory, machineSetResource(machineSet.GetName(), index))
 }
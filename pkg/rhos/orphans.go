@@ -0,0 +1,96 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"context"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/pagination"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+// ScanOrphans searches for the Submariner gateway and internal security groups of the cluster, and the servers they're
+// still attached to.
+func (rc *rhosCloud) ScanOrphans(ctx context.Context) ([]api.InventoryResource, error) {
+	computeClient, err := openstack.NewComputeV2(rc.withContext(ctx), gophercloud.EndpointOpts{Region: rc.Region})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "creating compute client failed for region %q", rc.Region)
+	}
+
+	orphans := []api.InventoryResource{}
+
+	for _, group := range []struct {
+		scope api.InventoryScope
+		name  string
+	}{
+		{api.GatewayScope, rc.InfraID + gwSecurityGroupSuffix},
+		{api.CloudScope, rc.InfraID + internalSecurityGroupSuffix},
+	} {
+		securityGroup, found, err := getSecurityGroup(group.name, computeClient)
+		if err != nil {
+			return nil, err
+		}
+
+		if !found {
+			continue
+		}
+
+		err = servers.List(computeClient, servers.ListOpts{Name: rc.InfraID}).EachPage(func(page pagination.Page) (bool, error) {
+			serverList, err := servers.ExtractServers(page)
+			if err != nil {
+				return false, errors.WithMessage(err, "getting the server List failed")
+			}
+
+			for i := range serverList {
+				if hasSecurityGroup(&serverList[i], group.name) {
+					orphans = append(orphans, attachmentResource(group.scope, group.name, &serverList[i]))
+				}
+			}
+
+			return true, nil
+		})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to list the servers matching %q", rc.InfraID)
+		}
+
+		orphans = append(orphans, securityGroupResource(group.scope, &securityGroup, false))
+	}
+
+	return orphans, nil
+}
+
+func (rc *rhosCloud) DeleteOrphans(ctx context.Context, orphans []api.InventoryResource, reporter api.Reporter) error {
+	computeClient, err := openstack.NewComputeV2(rc.withContext(ctx), gophercloud.EndpointOpts{Region: rc.Region})
+	if err != nil {
+		return errors.WithMessagef(err, "creating compute client failed for region %q", rc.Region)
+	}
+
+	networkClient, err := openstack.NewNetworkV2(rc.withContext(ctx), gophercloud.EndpointOpts{Region: rc.Region})
+	if err != nil {
+		return errors.WithMessagef(err, "creating network client failed for region %q", rc.Region)
+	}
+
+	return api.DeleteOrphans(orphans, reporter, func(resource *api.InventoryResource) error {
+		return rc.removeInventoried(resource, computeClient, networkClient)
+	})
+}
@@ -0,0 +1,329 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/secgroups"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
+	"github.com/gophercloud/gophercloud/pagination"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+)
+
+func (rc *rhosCloud) PlanPrepareForSubmariner(ctx context.Context, input api.PrepareForSubmarinerInput) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	families, err := k8s.IPFamiliesOrDetected(ctx, rc.K8sClient, input.IPFamilies)
+	if err != nil {
+		return nil, err // nolint:wrapcheck // No need to wrap here
+	}
+
+	input.IPFamilies = families
+
+	computeClient, err := openstack.NewComputeV2(rc.withContext(ctx), gophercloud.EndpointOpts{Region: rc.Region})
+	if err != nil {
+		return nil, errors.WithMessage(err, "Error creating the compute client")
+	}
+
+	groupName := rc.InfraID + internalSecurityGroupSuffix
+
+	group, isFound, err := getSecurityGroup(groupName, computeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isFound {
+		plan.Add(api.ChangeCreate, api.SecurityGroupResource, groupName, "")
+	}
+
+	var existingRules []rules.SecGroupRule
+
+	if isFound {
+		networkClient, err := openstack.NewNetworkV2(rc.withContext(ctx), gophercloud.EndpointOpts{Region: rc.Region})
+		if err != nil {
+			return nil, errors.WithMessage(err, "Error creating the network client")
+		}
+
+		existingRules, err = listIngressRules(group.ID, networkClient)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, family := range input.InternalIPFamilies() {
+		for _, port := range input.InternalPorts {
+			if !containsGroupRule(existingRules, group.ID, family, port) {
+				plan.Add(api.ChangeCreate, api.SecurityGroupRuleResource, groupName, "allow %s %s from %s", family, port, groupName)
+			}
+		}
+	}
+
+	serverNames, err := serversWithoutGroup(rc.InfraID, groupName, computeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range serverNames {
+		plan.Add(api.ChangeCreate, api.SecurityGroupAttachmentResource, groupName, "attach to server %s", name)
+	}
+
+	return plan, nil
+}
+
+func (rc *rhosCloud) PlanCleanupAfterSubmariner(ctx context.Context) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	if rc.Inventory != nil {
+		if err := api.PlanInventoryCleanup(ctx, rc.Inventory, api.CloudScope, plan); err != nil {
+			return nil, err // nolint:wrapcheck // No need to wrap here
+		}
+
+		return plan, nil
+	}
+
+	computeClient, err := openstack.NewComputeV2(rc.withContext(ctx), gophercloud.EndpointOpts{Region: rc.Region})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "creating compute client failed for region %q", rc.Region)
+	}
+
+	groupName := rc.InfraID + internalSecurityGroupSuffix
+
+	isFound, err := checkIfSecurityGroupPresent(groupName, computeClient)
+	if err != nil || !isFound {
+		return plan, err
+	}
+
+	serverNames, err := listServerNames(rc.InfraID, computeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range serverNames {
+		plan.Add(api.ChangeDelete, api.SecurityGroupAttachmentResource, groupName, "detach from server %s", name)
+	}
+
+	plan.Add(api.ChangeDelete, api.SecurityGroupResource, groupName, "")
+
+	return plan, nil
+}
+
+func (d *ocpGatewayDeployer) PlanDeploy(ctx context.Context, input api.GatewayDeployInput) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	families, err := k8s.IPFamiliesOrDetected(ctx, d.K8sClient, input.IPFamilies)
+	if err != nil {
+		return nil, err // nolint:wrapcheck // No need to wrap here
+	}
+
+	input.IPFamilies = families
+
+	computeClient, err := openstack.NewComputeV2(d.withContext(ctx), gophercloud.EndpointOpts{Region: d.Region})
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating the compute client")
+	}
+
+	groupName := d.InfraID + gwSecurityGroupSuffix
+
+	group, isFound, err := getSecurityGroup(groupName, computeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isFound {
+		plan.Add(api.ChangeCreate, api.SecurityGroupResource, groupName, "")
+	}
+
+	existingPorts := rulePorts(group.Rules, func(rule *secgroups.Rule) bool {
+		return rule.IPRange.CIDR != ""
+	})
+
+	for _, family := range input.PublicIPFamilies() {
+		for _, port := range input.PublicPortSpecs() {
+			for _, cidr := range port.SourcesFor(family) {
+				if !containsSourcePort(existingPorts, port, cidr) {
+					plan.Add(api.ChangeCreate, api.SecurityGroupRuleResource, groupName, "allow %s from %s", port, cidr)
+				}
+			}
+		}
+	}
+
+	gwNodes, err := d.K8sClient.ListGatewayNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing the existing gatway nodes failed")
+	}
+
+	if gatewaysToRemove := input.GatewaysToRemove(len(gwNodes.Items)); gatewaysToRemove > 0 {
+		activeNode, err := input.FindActiveGatewayNode(ctx, d.K8sClient.GetActiveGatewayNode)
+		if err != nil {
+			return nil, err // nolint:wrapcheck // No need to wrap here
+		}
+
+		for _, node := range d.surplusGatewayNodes(gwNodes, gatewaysToRemove, activeNode) {
+			if err := d.planRemoveGatewayNode(plan, node.Name, groupName); err != nil {
+				return nil, err
+			}
+		}
+
+		return plan, nil
+	}
+
+	gatewayNodesToDeploy := input.Gateways - len(gwNodes.Items)
+	if gatewayNodesToDeploy <= 0 {
+		return plan, nil
+	}
+
+	if d.dedicatedGWNode {
+		for i := 0; i < gatewayNodesToDeploy; i++ {
+			machineSet, err := d.initMachineSet(strconv.Itoa(i))
+			if err != nil {
+				return nil, err
+			}
+
+			plan.Add(api.ChangeApply, api.MachineSetResource, machineSet.GetName(), "dedicated gateway node of type %s",
+				d.instanceType)
+		}
+
+		return plan, nil
+	}
+
+	workerNodes, err := d.K8sClient.ListNodesWithLabel(ctx, "node-role.kubernetes.io/worker")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list k8s nodes in project %q", d.projectID)
+	}
+
+	nodes := workerNodes.Items
+	for i := range nodes {
+		if nodes[i].GetLabels()[submarinerGatewayNodeTag] == "true" {
+			continue
+		}
+
+		plan.Add(api.ChangeCreate, api.NodeLabelResource, nodes[i].Name, "gateway label")
+		plan.Add(api.ChangeCreate, api.SecurityGroupAttachmentResource, groupName, "attach to server %s", nodes[i].Name)
+
+		gatewayNodesToDeploy--
+		if gatewayNodesToDeploy <= 0 {
+			return plan, nil
+		}
+	}
+
+	return nil, fmt.Errorf("there are insufficient nodes to deploy the required number of gateways (%d)", input.Gateways)
+}
+
+func (d *ocpGatewayDeployer) PlanCleanup(ctx context.Context) (*api.Plan, error) {
+	plan := &api.Plan{}
+
+	if d.Inventory != nil {
+		if err := api.PlanInventoryCleanup(ctx, d.Inventory, api.GatewayScope, plan); err != nil {
+			return nil, err // nolint:wrapcheck // No need to wrap here
+		}
+
+		return plan, nil
+	}
+
+	computeClient, err := openstack.NewComputeV2(d.withContext(ctx), gophercloud.EndpointOpts{Region: d.Region})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating the compute client for the region: %q", d.Region)
+	}
+
+	gwNodesList, err := d.K8sClient.ListGatewayNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the Submariner gateway nodes")
+	}
+
+	groupName := d.InfraID + gwSecurityGroupSuffix
+	gwNodes := gwNodesList.Items
+
+	for i := range gwNodes {
+		if !strings.HasPrefix(gwNodes[i].Name, d.InfraID) {
+			continue
+		}
+
+		plan.Add(api.ChangeDelete, api.SecurityGroupAttachmentResource, groupName, "detach from server %s", gwNodes[i].Name)
+
+		if strings.HasPrefix(gwNodes[i].Name, d.InfraID+"-submariner-gw-") {
+			machineSet, err := d.initMachineSet(strconv.Itoa(i))
+			if err != nil {
+				return nil, err
+			}
+
+			plan.Add(api.ChangeDelete, api.MachineSetResource, machineSet.GetName(), "dedicated gateway node")
+		} else {
+			plan.Add(api.ChangeDelete, api.NodeLabelResource, gwNodes[i].Name, "gateway label")
+		}
+	}
+
+	isFound, err := checkIfSecurityGroupPresent(groupName, computeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if isFound {
+		plan.Add(api.ChangeDelete, api.SecurityGroupResource, groupName, "")
+	}
+
+	return plan, nil
+}
+
+func (d *ocpGatewayDeployer) planRemoveGatewayNode(plan *api.Plan, nodeName, groupName string) error {
+	plan.Add(api.ChangeDelete, api.SecurityGroupAttachmentResource, groupName, "detach from server %s", nodeName)
+
+	index, dedicated := d.dedicatedGatewayIndex(nodeName)
+	if !dedicated {
+		plan.Add(api.ChangeDelete, api.NodeLabelResource, nodeName, "gateway label")
+		return nil
+	}
+
+	machineSet, err := d.initMachineSet(index)
+	if err != nil {
+		return err
+	}
+
+	plan.Add(api.ChangeDelete, api.MachineSetResource, machineSet.GetName(), "dedicated gateway node")
+
+	return nil
+}
+
+func listServerNames(name string, computeClient *gophercloud.ServiceClient) ([]string, error) {
+	names := []string{}
+
+	pager := servers.List(computeClient, servers.ListOpts{Name: name})
+	err := pager.EachPage(func(page pagination.Page) (bool, error) {
+		serverList, err := servers.ExtractServers(page)
+		if err != nil {
+			return false, errors.WithMessage(err, "getting the server List failed")
+		}
+
+		for i := range serverList {
+			names = append(names, serverList[i].Name)
+		}
+
+		return true, nil
+	})
+
+	return names, errors.WithMessagef(err, "failed to list the servers matching %q", name)
+}
@@ -0,0 +1,158 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/quotasets"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/secgroups"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/quotas"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+// validateQuotas checks that the Nova and Neutron quotas of the project allow for the gateway security group and its
+// rules, along with the dedicated gateway nodes which still need to be deployed.
+func (d *ocpGatewayDeployer) validateQuotas(ctx context.Context, input *api.GatewayDeployInput,
+	computeClient, networkClient *gophercloud.ServiceClient) error {
+	networkQuotas, err := quotas.GetDetail(networkClient, d.projectID).Extract()
+	if err != nil {
+		return errors.Wrapf(err, "error retrieving the Neutron quotas of project %q", d.projectID)
+	}
+
+	groupName := d.InfraID + gwSecurityGroupSuffix
+
+	group, isFound, err := getSecurityGroup(groupName, computeClient)
+	if err != nil {
+		return err
+	}
+
+	newGroups := 0
+	if !isFound {
+		newGroups = 1
+	}
+
+	quotaList := []api.Quota{
+		neutronQuota("security groups", &networkQuotas.SecurityGroup, newGroups),
+		neutronQuota("security group rules", &networkQuotas.SecurityGroupRule, missingRules(input, &group)),
+	}
+
+	if d.dedicatedGWNode {
+		computeQuotas, err := d.computeQuotas(ctx, input, computeClient)
+		if err != nil {
+			return err
+		}
+
+		quotaList = append(quotaList, computeQuotas...)
+	}
+
+	return api.ValidateQuotas(quotaList...)
+}
+
+func (d *ocpGatewayDeployer) computeQuotas(ctx context.Context, input *api.GatewayDeployInput,
+	computeClient *gophercloud.ServiceClient) ([]api.Quota, error) {
+	gwNodes, err := d.K8sClient.ListGatewayNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing the existing gateway nodes failed")
+	}
+
+	newGateways := input.Gateways - len(gwNodes.Items)
+	if newGateways <= 0 {
+		return nil, nil
+	}
+
+	flavor, err := findFlavor(d.instanceType, computeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	quotaSet, err := quotasets.GetDetail(computeClient, d.projectID).Extract()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error retrieving the Nova quotas of project %q", d.projectID)
+	}
+
+	return []api.Quota{
+		novaQuota("instances", &quotaSet.Instances, newGateways),
+		novaQuota("cores", &quotaSet.Cores, flavor.VCPUs*newGateways),
+		novaQuota("RAM (MiB)", &quotaSet.RAM, flavor.RAM*newGateways),
+	}, nil
+}
+
+func findFlavor(name string, computeClient *gophercloud.ServiceClient) (*flavors.Flavor, error) {
+	pages, err := flavors.ListDetail(computeClient, flavors.ListOpts{}).AllPages()
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the Nova flavors")
+	}
+
+	flavorList, err := flavors.ExtractFlavors(pages)
+	if err != nil {
+		return nil, errors.Wrap(err, "error extracting the Nova flavors")
+	}
+
+	for i := range flavorList {
+		if flavorList[i].Name == name {
+			return &flavorList[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("the Nova flavor %q doesn't exist", name)
+}
+
+// missingRules returns the number of rules which need to be added to the gateway security group.
+func missingRules(input *api.GatewayDeployInput, group *secgroups.SecurityGroup) int {
+	existingPorts := rulePorts(group.Rules, func(rule *secgroups.Rule) bool {
+		return rule.IPRange.CIDR != ""
+	})
+
+	missing := 0
+
+	for _, family := range input.PublicIPFamilies() {
+		for _, port := range input.PublicPortSpecs() {
+			for _, cidr := range port.SourcesFor(family) {
+				if !containsSourcePort(existingPorts, port, cidr) {
+					missing++
+				}
+			}
+		}
+	}
+
+	return missing
+}
+
+func neutronQuota(name string, detail *quotas.QuotaDetail, required int) api.Quota {
+	return api.Quota{
+		Name:     "Neutron " + name,
+		Limit:    int64(detail.Limit),
+		Used:     int64(detail.Used + detail.Reserved),
+		Required: int64(required),
+	}
+}
+
+func novaQuota(name string, detail *quotasets.QuotaDetail, required int) api.Quota {
+	return api.Quota{
+		Name:     "Nova " + name,
+		Limit:    int64(detail.Limit),
+		Used:     int64(detail.InUse + detail.Reserved),
+		Required: int64(required),
+	}
+}
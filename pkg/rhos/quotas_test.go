@@ -0,0 +1,176 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	ocpFake "github.com/submariner-io/cloud-prepare/pkg/ocp/fake"
+	"github.com/submariner-io/cloud-prepare/pkg/rhos"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeFake "k8s.io/client-go/kubernetes/fake"
+)
+
+const gatewayGroupName = infraID + "-submariner-gw-sg"
+
+var _ = Describe("Quotas", func() {
+	var (
+		t                   *fakeOpenStack
+		mockCtrl            *gomock.Controller
+		msDeployer          *ocpFake.MockMachineSetDeployer
+		gatewayNodes        []runtime.Object
+		dedicatedGWNode     bool
+		input               api.GatewayDeployInput
+		deployedMachineSets []string
+	)
+
+	BeforeEach(func() {
+		t = &fakeOpenStack{}
+		t.beforeEach()
+
+		// The gateway security group already allows the public port, so deploying makes no OpenStack changes.
+		t.securityGroups = []map[string]interface{}{securityGroup(gatewayGroupName, 4500)}
+
+		mockCtrl = gomock.NewController(GinkgoT())
+		msDeployer = ocpFake.NewMockMachineSetDeployer(mockCtrl)
+		deployedMachineSets = nil
+
+		msDeployer.EXPECT().Deploy(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, machineSet *unstructured.Unstructured) error {
+				deployedMachineSets = append(deployedMachineSets, machineSet.GetName())
+				return nil
+			}).AnyTimes()
+
+		gatewayNodes = nil
+		dedicatedGWNode = true
+
+		input = api.GatewayDeployInput{
+			PublicPorts: []api.PortSpec{{Port: 4500, Protocol: "udp"}},
+			Gateways:    1,
+		}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+		t.afterEach()
+	})
+
+	deploy := func() error {
+		gwDeployer := rhos.NewOcpGatewayDeployer(rhos.CloudInfo{
+			Client:    t.providerClient(),
+			InfraID:   infraID,
+			Region:    region,
+			K8sClient: k8s.NewInterface(kubeFake.NewSimpleClientset(gatewayNodes...)),
+		}, msDeployer, projectID, flavor, "rhcos", "openstack", dedicatedGWNode)
+
+		return gwDeployer.Deploy(input, api.NewLoggingReporter())
+	}
+
+	When("the quotas allow for the gateways", func() {
+		It("should deploy them", func() {
+			Expect(deploy()).To(Succeed())
+			Expect(deployedMachineSets).To(HaveLen(1))
+			Expect(t.recordedWrites()).To(BeEmpty())
+		})
+	})
+
+	When("the gateway security group doesn't exist and the Neutron security groups quota is exhausted", func() {
+		BeforeEach(func() {
+			t.securityGroups = nil
+			t.networkQuotas["security_group"] = newQuotaDetail(10, 10)
+		})
+
+		It("should fail without making any change", func() {
+			err := deploy()
+			Expect(errors.Is(err, api.ErrQuotaExceeded)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("Neutron security groups")))
+			Expect(t.recordedWrites()).To(BeEmpty())
+			Expect(deployedMachineSets).To(BeEmpty())
+		})
+	})
+
+	When("the gateway security group misses rules and the Neutron security group rules quota is exhausted", func() {
+		BeforeEach(func() {
+			t.securityGroups = []map[string]interface{}{securityGroup(gatewayGroupName)}
+			t.networkQuotas["security_group_rule"] = newQuotaDetail(100, 100)
+		})
+
+		It("should fail without making any change", func() {
+			err := deploy()
+			Expect(errors.Is(err, api.ErrQuotaExceeded)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("Neutron security group rules")))
+			Expect(t.recordedWrites()).To(BeEmpty())
+		})
+	})
+
+	When("the Nova cores quota doesn't allow for the dedicated gateway nodes", func() {
+		BeforeEach(func() {
+			t.computeQuotas["cores"] = newQuotaDetail(38, 40)
+		})
+
+		It("should fail without deploying any gateway", func() {
+			err := deploy()
+			Expect(errors.Is(err, api.ErrQuotaExceeded)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("Nova cores")))
+			Expect(deployedMachineSets).To(BeEmpty())
+		})
+	})
+
+	When("the dedicated gateway nodes are already deployed", func() {
+		BeforeEach(func() {
+			t.computeQuotas["cores"] = newQuotaDetail(40, 40)
+			gatewayNodes = []runtime.Object{gatewayNode("gateway-0")}
+		})
+
+		It("should not check the Nova quotas", func() {
+			Expect(deploy()).To(Succeed())
+			Expect(deployedMachineSets).To(BeEmpty())
+		})
+	})
+
+	When("the gateways aren't dedicated nodes", func() {
+		BeforeEach(func() {
+			t.computeQuotas["cores"] = newQuotaDetail(40, 40)
+			dedicatedGWNode = false
+			gatewayNodes = []runtime.Object{gatewayNode("worker-0")}
+		})
+
+		It("should not check the Nova quotas", func() {
+			Expect(deploy()).To(Succeed())
+		})
+	})
+})
+
+func gatewayNode(name string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{k8s.SubmarinerGatewayLabel: "true"},
+		},
+	}
+}
@@ -0,0 +1,78 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/retry"
+)
+
+// retryFunc returns a gophercloud retry function which puts the errors returned by the OpenStack APIs in the api error
+// categories, and retries the requests according to the given policy. The given retry function, if any, is called
+// first and may still retry the request itself.
+func retryFunc(policy *retry.Policy, next gophercloud.RetryFunc) gophercloud.RetryFunc {
+	return func(ctx context.Context, method, url string, options *gophercloud.RequestOpts, err error, failCount uint) error {
+		if next != nil {
+			err = next(ctx, method, url, options, err, failCount)
+			if err == nil {
+				return nil
+			}
+		}
+
+		err = categorizeError(err)
+
+		if policy.ShouldRetry(ctx, isRetriable, err, int(failCount)) {
+			return nil
+		}
+
+		return err
+	}
+}
+
+// isRetriable returns true for the transient errors, and for the conflicts caused by concurrent updates of the same
+// resource, which Neutron and Nova report like the conflicts with existing resources.
+func isRetriable(err error) bool {
+	return errors.Is(err, api.ErrTransient) ||
+		(errors.Is(err, api.ErrConflict) && !bytes.Contains(responseBody(err), []byte("Exists")))
+}
+
+// rateLimitedTransport waits for the rate limiter of the retry policy before sending each request.
+type rateLimitedTransport struct {
+	policy    *retry.Policy
+	transport http.RoundTripper
+}
+
+func (t *rateLimitedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if err := t.policy.Wait(request.Context()); err != nil {
+		return nil, err // nolint:wrapcheck // No need to wrap here
+	}
+
+	transport := t.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return transport.RoundTrip(request) // nolint:wrapcheck // No need to wrap here
+}
@@ -0,0 +1,99 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos_test
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/retry"
+	"github.com/submariner-io/cloud-prepare/pkg/rhos"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeFake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Retries", func() {
+	const createGroup = "POST /os-security-groups"
+
+	var t *fakeOpenStack
+
+	BeforeEach(func() {
+		t = &fakeOpenStack{}
+		t.beforeEach()
+	})
+
+	AfterEach(func() {
+		t.afterEach()
+	})
+
+	// createGatewayGroup deploys the gateways, which creates the missing gateway security group, with a policy
+	// allowing two retries. Nova always responds to the request with the given status and body.
+	createGatewayGroup := func(status int, body string) error {
+		t.failures[createGroup] = errorResponse{status: status, body: body}
+
+		gwDeployer := rhos.NewOcpGatewayDeployer(rhos.CloudInfo{
+			Client:    t.providerClient(),
+			InfraID:   infraID,
+			Region:    region,
+			K8sClient: k8s.NewInterface(kubeFake.NewSimpleClientset()),
+			RetryPolicy: &retry.Policy{
+				Backoff: wait.Backoff{Steps: 2, Duration: time.Millisecond, Factor: 1},
+			},
+		}, nil, projectID, flavor, "rhcos", "openstack", false)
+
+		return gwDeployer.Deploy(api.GatewayDeployInput{
+			PublicPorts: []api.PortSpec{{Port: 4500, Protocol: "udp"}},
+			Gateways:    1,
+		}, api.NewLoggingReporter())
+	}
+
+	sentRequests := func() int {
+		count := 0
+
+		for _, write := range t.recordedWrites() {
+			if write == createGroup {
+				count++
+			}
+		}
+
+		return count
+	}
+
+	table.DescribeTable("should retry the requests as many times as the policy allows, only if they may then succeed",
+		func(status int, body string, category error, requests int) {
+			err := createGatewayGroup(status, body)
+
+			Expect(errors.Is(err, category)).To(BeTrue())
+			Expect(sentRequests()).To(Equal(requests))
+		},
+		table.Entry("service unavailable", http.StatusServiceUnavailable, "", api.ErrTransient, 3),
+		table.Entry("concurrent update", http.StatusConflict, `{"conflictingRequest": {"message": "In use"}}`,
+			api.ErrConflict, 3),
+		table.Entry("existing resource", http.StatusConflict, `{"NeutronError": {"type": "SecurityGroupRuleExists"}}`,
+			api.ErrConflict, 1),
+		table.Entry("not found", http.StatusNotFound, "", api.ErrNotFound, 1),
+		table.Entry("quota exceeded", http.StatusConflict, `{"NeutronError": {"type": "OverQuota"}}`, api.ErrQuotaExceeded, 1),
+	)
+})
@@ -19,17 +19,20 @@ limitations under the License.
 package rhos
 
 import (
+	"context"
+
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
 	"github.com/pkg/errors"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/metrics"
 )
 
 const (
 	gwSecurityGroupSuffix       = "-submariner-gw-sg"
 	internalSecurityGroupSuffix = "-submariner-internal-sg"
 	submarinerGatewayNodeTag    = "submariner-io-gateway-node"
-	allNetworkCIDR              = "0.0.0.0/0"
 )
 
 type rhosCloud struct {
@@ -44,19 +47,47 @@ func NewCloud(info CloudInfo) api.Cloud {
 }
 
 func (rc *rhosCloud) PrepareForSubmariner(input api.PrepareForSubmarinerInput, reporter api.Reporter) error {
-	reporter.Started("Opening internal ports for intra-cluster communications on RHOS")
+	return rc.PrepareForSubmarinerWithContext(context.TODO(), input, reporter)
+}
+
+func (rc *rhosCloud) PrepareForSubmarinerWithContext(ctx context.Context, input api.PrepareForSubmarinerInput,
+	reporter api.Reporter) error {
+	return metrics.ObserveOperation(ctx, "rhos", metrics.PrepareForSubmariner, func() error {
+		return api.RunWithRollback(ctx, input.Rollback, reporter, func(ctx context.Context) error {
+			return rc.prepareForSubmariner(ctx, input, reporter)
+		})
+	})
+}
 
-	computeClient, err := openstack.NewComputeV2(rc.Client, gophercloud.EndpointOpts{Region: rc.Region})
+func (rc *rhosCloud) prepareForSubmariner(ctx context.Context, input api.PrepareForSubmarinerInput, reporter api.Reporter) error {
+	families, err := k8s.IPFamiliesOrDetected(ctx, rc.K8sClient, input.IPFamilies)
+	if err != nil {
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	input.IPFamilies = families
+
+	computeClient, err := openstack.NewComputeV2(rc.withContext(ctx), gophercloud.EndpointOpts{Region: rc.Region})
 	if err != nil {
 		return errors.WithMessage(err, "Error creating the compute client")
 	}
 
-	networkClient, err := openstack.NewNetworkV2(rc.Client, gophercloud.EndpointOpts{Region: rc.Region})
+	networkClient, err := openstack.NewNetworkV2(rc.withContext(ctx), gophercloud.EndpointOpts{Region: rc.Region})
 	if err != nil {
 		return errors.WithMessage(err, "Error creating the network client")
 	}
 
-	if err := rc.openInternalPorts(rc.InfraID, input.InternalPorts, computeClient, networkClient); err != nil {
+	err = api.ValidatePrerequisites(reporter, func() error {
+		return validatePolicies(rc.listSecurityGroupsCheck(networkClient), rc.listServersCheck(computeClient))
+	}, uncheckedChangesWarning(reporter, prepareChanges))
+	if err != nil {
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	reporter.Started("Opening internal ports for intra-cluster communications on RHOS")
+
+	err = rc.openInternalPorts(ctx, rc.InfraID, input.InternalPorts, input.InternalIPFamilies(), computeClient, networkClient)
+	if err != nil {
 		reporter.Failed(err)
 		return err
 	}
@@ -68,13 +99,43 @@ func (rc *rhosCloud) PrepareForSubmariner(input api.PrepareForSubmarinerInput, r
 }
 
 func (rc *rhosCloud) CleanupAfterSubmariner(reporter api.Reporter) error {
-	reporter.Started("Revoking intra-cluster communication permissions")
+	return rc.CleanupAfterSubmarinerWithContext(context.TODO(), reporter)
+}
 
-	computeClient, err := openstack.NewComputeV2(rc.Client, gophercloud.EndpointOpts{Region: rc.Region})
+func (rc *rhosCloud) CleanupAfterSubmarinerWithContext(ctx context.Context, reporter api.Reporter) error {
+	computeClient, err := openstack.NewComputeV2(rc.withContext(ctx), gophercloud.EndpointOpts{Region: rc.Region})
 	if err != nil {
 		return errors.WithMessagef(err, "creating compute client failed for region %q", rc.Region)
 	}
 
+	networkClient, err := openstack.NewNetworkV2(rc.withContext(ctx), gophercloud.EndpointOpts{Region: rc.Region})
+	if err != nil {
+		return errors.WithMessagef(err, "creating network client failed for region %q", rc.Region)
+	}
+
+	err = api.ValidatePrerequisites(reporter, func() error {
+		return validatePolicies(rc.listSecurityGroupsCheck(networkClient), rc.listServersCheck(computeClient))
+	}, uncheckedChangesWarning(reporter, cleanupChanges))
+	if err != nil {
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	reporter.Started("Revoking intra-cluster communication permissions")
+
+	if rc.Inventory != nil {
+		err = api.CleanupInventory(ctx, rc.Inventory, api.CloudScope, func(resource *api.InventoryResource) error {
+			return rc.removeInventoried(resource, computeClient, networkClient)
+		})
+		if err != nil {
+			reporter.Failed(err)
+			return err // nolint:wrapcheck // No need to wrap here
+		}
+
+		reporter.Succeeded("Revoked intra-cluster communication permissions")
+
+		return nil
+	}
+
 	if err := rc.removeInternalFirewallRules(rc.InfraID, computeClient); err != nil {
 		reporter.Failed(err)
 		return err
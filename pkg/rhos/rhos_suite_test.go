@@ -0,0 +1,179 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gophercloud/gophercloud"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	infraID   = "test-infra"
+	region    = "test-region"
+	projectID = "test-project"
+	flavor    = "m1.large"
+)
+
+func TestRHOS(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RHOS Suite")
+}
+
+// quotaDetail is the usage and limit of a Nova or Neutron quota, serialized for either service.
+type quotaDetail struct {
+	Used     int `json:"used"`
+	InUse    int `json:"in_use"`
+	Reserved int `json:"reserved"`
+	Limit    int `json:"limit"`
+}
+
+type errorResponse struct {
+	status int
+	body   string
+}
+
+func newQuotaDetail(used, limit int) quotaDetail {
+	return quotaDetail{Used: used, InUse: used, Limit: limit}
+}
+
+// fakeOpenStack is a minimal Nova and Neutron endpoint serving the project's quotas, flavors and security groups. It
+// only serves read-only requests: the others fail, with the response in failures if any, and are recorded in writes.
+type fakeOpenStack struct {
+	server *httptest.Server
+
+	// failures maps the requests, e.g. "POST /os-security-groups", to their error responses.
+	failures map[string]errorResponse
+
+	networkQuotas map[string]quotaDetail
+	computeQuotas map[string]quotaDetail
+
+	// securityGroups are the Nova security groups, each a map of their fields.
+	securityGroups []map[string]interface{}
+
+	mutex  sync.Mutex
+	writes []string
+}
+
+func (f *fakeOpenStack) beforeEach() {
+	f.networkQuotas = map[string]quotaDetail{
+		"security_group":      newQuotaDetail(1, 10),
+		"security_group_rule": newQuotaDetail(10, 100),
+	}
+	f.computeQuotas = map[string]quotaDetail{
+		"instances": newQuotaDetail(6, 10),
+		"cores":     newQuotaDetail(24, 40),
+		"ram":       newQuotaDetail(49152, 81920),
+	}
+	f.securityGroups = nil
+	f.failures = map[string]errorResponse{}
+	f.writes = nil
+
+	f.server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+}
+
+func (f *fakeOpenStack) afterEach() {
+	f.server.Close()
+}
+
+// providerClient returns a provider client using the fake endpoint for all the services.
+func (f *fakeOpenStack) providerClient() *gophercloud.ProviderClient {
+	return &gophercloud.ProviderClient{
+		TokenID: "test-token",
+		EndpointLocator: func(_ gophercloud.EndpointOpts) (string, error) {
+			return f.server.URL + "/", nil
+		},
+	}
+}
+
+func (f *fakeOpenStack) recordedWrites() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.writes
+}
+
+func (f *fakeOpenStack) serveHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		f.mutex.Lock()
+		f.writes = append(f.writes, request.Method+" "+request.URL.Path)
+		f.mutex.Unlock()
+
+		failure, found := f.failures[request.Method+" "+request.URL.Path]
+		if !found {
+			failure = errorResponse{status: http.StatusInternalServerError}
+		}
+
+		writer.WriteHeader(failure.status)
+		_, err := writer.Write([]byte(failure.body))
+		Expect(err).To(Succeed())
+
+		return
+	}
+
+	var body interface{}
+
+	switch path := request.URL.Path; {
+	case path == "/v2.0/security-groups":
+		body = map[string]interface{}{"security_groups": []interface{}{}}
+	case path == "/v2.0/quotas/"+projectID+"/details.json":
+		body = map[string]interface{}{"quota": f.networkQuotas}
+	case path == "/servers/detail":
+		body = map[string]interface{}{"servers": []interface{}{}}
+	case path == "/os-quota-sets/"+projectID+"/detail":
+		body = map[string]interface{}{"quota_set": f.computeQuotas}
+	case path == "/os-security-groups":
+		body = map[string]interface{}{"security_groups": f.securityGroups}
+	case strings.HasPrefix(path, "/flavors/detail"):
+		body = map[string]interface{}{"flavors": []interface{}{
+			map[string]interface{}{"id": "1", "name": flavor, "vcpus": 4, "ram": 8192, "disk": 40},
+		}}
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	Expect(json.NewEncoder(writer).Encode(body)).To(Succeed())
+}
+
+// securityGroup returns a Nova security group with the given name, allowing traffic from anywhere to the given UDP
+// ports.
+func securityGroup(name string, ports ...int) map[string]interface{} {
+	rules := []interface{}{}
+
+	for _, port := range ports {
+		rules = append(rules, map[string]interface{}{
+			"id":          name + "-rule",
+			"from_port":   port,
+			"to_port":     port,
+			"ip_protocol": "udp",
+			"ip_range":    map[string]interface{}{"cidr": "0.0.0.0/0"},
+		})
+	}
+
+	return map[string]interface{}{"id": name, "name": name, "rules": rules}
+}
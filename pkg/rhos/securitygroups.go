@@ -19,7 +19,12 @@ limitations under the License.
 package rhos
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
 	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/secgroups"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
@@ -27,6 +32,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/submariner-io/cloud-prepare/pkg/api"
 	"github.com/submariner-io/cloud-prepare/pkg/k8s"
+	"github.com/submariner-io/cloud-prepare/pkg/retry"
 )
 
 type CloudInfo struct {
@@ -34,9 +40,46 @@ type CloudInfo struct {
 	InfraID   string
 	Region    string
 	K8sClient k8s.Interface
+
+	// Inventory, if set, records the resources which are created, and drives their cleanup.
+	Inventory api.Inventory
+
+	// RetryPolicy, if set, retries the requests which fail with transient errors, and limits their rate.
+	RetryPolicy *retry.Policy
+}
+
+// withContext returns a copy of the provider client which issues all its requests using the given context, tracing
+// each of them. The copy has its own token: when it expires, the provider client is re-authenticated, unless another
+// copy already did, and the copy picks up the new token.
+func (c *CloudInfo) withContext(ctx context.Context) *gophercloud.ProviderClient {
+	parent := c.Client
+	client := *parent
+	client.UseTokenLock()
+	client.CopyTokenFrom(parent)
+	client.Context = ctx
+	client.RetryFunc = retryFunc(c.RetryPolicy, parent.RetryFunc)
+	client.HTTPClient.Transport = &tracingTransport{transport: client.HTTPClient.Transport}
+
+	if parent.ReauthFunc != nil {
+		client.ReauthFunc = func() error {
+			if err := parent.Reauthenticate(client.Token()); err != nil {
+				return err // nolint:wrapcheck // No need to wrap here
+			}
+
+			client.CopyTokenFrom(parent)
+
+			return nil
+		}
+	}
+
+	if c.RetryPolicy != nil {
+		client.HTTPClient.Transport = &rateLimitedTransport{policy: c.RetryPolicy, transport: client.HTTPClient.Transport}
+	}
+
+	return &client
 }
 
-func (c *CloudInfo) openInternalPorts(infraID string, ports []api.PortSpec,
+func (c *CloudInfo) openInternalPorts(ctx context.Context, infraID string, ports []api.PortSpec, families []api.IPFamily,
 	computeClient, networkClient *gophercloud.ServiceClient) error {
 	groupName := infraID + internalSecurityGroupSuffix
 	opts := secgroups.CreateOpts{
@@ -44,24 +87,38 @@ func (c *CloudInfo) openInternalPorts(infraID string, ports []api.PortSpec,
 		Description: "Submariner Internal",
 	}
 
-	isFound, err := checkIfSecurityGroupPresent(groupName, computeClient)
+	group, isFound, err := getSecurityGroup(groupName, computeClient)
+	if err != nil {
+		return err
+	}
+
 	if isFound {
-		return nil
+		err = api.RecordResource(ctx, c.Inventory, securityGroupResource(api.CloudScope, &group, true))
+	} else {
+		group, err = c.createSecurityGroup(ctx, api.CloudScope, &opts, computeClient, networkClient)
 	}
 
 	if err != nil {
 		return err
 	}
 
-	group, err := secgroups.Create(computeClient, opts).Extract()
+	// Only the missing rules are created, so that rules removed from an existing group are restored.
+	existingRules, err := listIngressRules(group.ID, networkClient)
 	if err != nil {
-		return errors.WithMessagef(err, "creating security group failed")
+		return err
 	}
 
-	for _, port := range ports {
-		err = c.createSGRule(group.ID, group.ID, "", port.Port, port.Protocol, networkClient)
-		if err != nil {
-			return errors.WithMessage(err, "creating security group rule failed")
+	// Rules referencing a remote group only apply to a single ether type, so one is needed per IP family.
+	for _, family := range families {
+		for _, port := range ports {
+			if containsGroupRule(existingRules, group.ID, family, port) {
+				continue
+			}
+
+			err = c.createSGRule(ctx, api.CloudScope, group.ID, group.ID, "", family, port, networkClient)
+			if err != nil {
+				return errors.WithMessage(err, "creating security group rule failed")
+			}
 		}
 	}
 
@@ -72,10 +129,18 @@ func (c *CloudInfo) openInternalPorts(infraID string, ports []api.PortSpec,
 			return false, errors.WithMessage(err, "getting the server List failed")
 		}
 		for i := range serverList {
-			err := secgroups.AddServer(computeClient, serverList[i].ID, groupName).ExtractErr()
+			if hasSecurityGroup(&serverList[i], groupName) {
+				continue
+			}
+
+			err := addServer(computeClient, serverList[i].ID, groupName)
 			if err != nil {
 				return false, errors.WithMessage(err, "failed to add the security group to the server")
 			}
+
+			if err := c.recordCreated(ctx, attachmentResource(api.CloudScope, groupName, &serverList[i])); err != nil {
+				return false, err
+			}
 		}
 
 		return true, nil
@@ -96,7 +161,7 @@ func (c *CloudInfo) removeInternalFirewallRules(infraID string,
 			return false, errors.WithMessage(err, "getting the server List failed")
 		}
 		for i := range serverList {
-			err = secgroups.RemoveServer(computeClient, serverList[i].ID, groupName).ExtractErr()
+			err = removeServer(computeClient, serverList[i].ID, groupName)
 			if err != nil {
 				notFoundError := &gophercloud.ErrDefault404{}
 				if errors.As(err, notFoundError) {
@@ -114,37 +179,63 @@ func (c *CloudInfo) removeInternalFirewallRules(infraID string,
 	return errors.WithMessage(err, "failed to remove security group from servers")
 }
 
-func (c *CloudInfo) createGWSecurityGroup(ports []api.PortSpec, groupName string, computeClient *gophercloud.ServiceClient,
-	networkClient *gophercloud.ServiceClient) error {
-	isFound, err := checkIfSecurityGroupPresent(groupName, computeClient)
+func (c *CloudInfo) createGWSecurityGroup(ctx context.Context, ports []api.PortSpec, families []api.IPFamily, groupName string,
+	computeClient *gophercloud.ServiceClient, networkClient *gophercloud.ServiceClient) error {
+	group, isFound, err := getSecurityGroup(groupName, computeClient)
 	if err != nil {
 		return err
 	}
 
 	if isFound {
-		return nil
+		err = api.RecordResource(ctx, c.Inventory, securityGroupResource(api.GatewayScope, &group, true))
+	} else {
+		group, err = c.createSecurityGroup(ctx, api.GatewayScope, &secgroups.CreateOpts{
+			Name:        groupName,
+			Description: "Submariner Gateway",
+		}, computeClient, networkClient)
 	}
 
-	opts := secgroups.CreateOpts{
-		Name:        groupName,
-		Description: "Submariner Gateway",
-	}
-
-	group, err := secgroups.Create(computeClient, opts).Extract()
 	if err != nil {
-		return errors.WithMessage(err, "failed to create g/w security group")
+		return err
 	}
 
-	for _, port := range ports {
-		err = c.createSGRule(group.ID, "", allNetworkCIDR, port.Port, port.Protocol, networkClient)
-		if err != nil {
-			return errors.WithMessagef(err, "creating security group rule failed")
+	existingPorts := rulePorts(group.Rules, func(rule *secgroups.Rule) bool {
+		return rule.IPRange.CIDR != ""
+	})
+
+	for _, family := range families {
+		for _, port := range ports {
+			for _, cidr := range port.SourcesFor(family) {
+				if containsSourcePort(existingPorts, port, cidr) {
+					continue
+				}
+
+				err = c.createSGRule(ctx, api.GatewayScope, group.ID, "", cidr, family, port, networkClient)
+				if err != nil {
+					return errors.WithMessagef(err, "creating security group rule failed")
+				}
+			}
 		}
 	}
 
 	return nil
 }
 
+func (c *CloudInfo) createSecurityGroup(ctx context.Context, scope api.InventoryScope, opts *secgroups.CreateOpts,
+	computeClient, networkClient *gophercloud.ServiceClient) (secgroups.SecurityGroup, error) {
+	var group *secgroups.SecurityGroup
+
+	err := audited(computeClient, "secgroups.Create", opts.Name, opts, func() (err error) {
+		group, err = secgroups.Create(computeClient, opts).Extract()
+		return err
+	})
+	if err != nil {
+		return secgroups.SecurityGroup{}, errors.WithMessagef(err, "creating security group %q failed", opts.Name)
+	}
+
+	return *group, c.recordCreated(ctx, securityGroupResource(scope, group, false))
+}
+
 func checkIfSecurityGroupPresent(groupName string, computeClient *gophercloud.ServiceClient) (bool, error) {
 	pager := secgroups.List(computeClient)
 	var isFound bool
@@ -163,7 +254,7 @@ func checkIfSecurityGroupPresent(groupName string, computeClient *gophercloud.Se
 	return isFound, errors.WithMessagef(err, "error getting the security group : %q", groupName)
 }
 
-func (c *CloudInfo) openGatewayPort(groupName, nodeName string, computeClient *gophercloud.ServiceClient) error {
+func (c *CloudInfo) openGatewayPort(ctx context.Context, groupName, nodeName string, computeClient *gophercloud.ServiceClient) error {
 	opts := servers.ListOpts{Name: nodeName}
 	pager := servers.List(computeClient, opts)
 
@@ -173,11 +264,15 @@ func (c *CloudInfo) openGatewayPort(groupName, nodeName string, computeClient *g
 			return false, errors.WithMessagef(err, "getting the server List failed for node %q", nodeName)
 		}
 		for i := range serverList {
-			err = secgroups.AddServer(computeClient, serverList[i].ID, groupName).ExtractErr()
+			err = addServer(computeClient, serverList[i].ID, groupName)
 			if err != nil {
 				return false, errors.WithMessagef(err, "adding security group %q to the server %q failed",
 					groupName, serverList[i].Name)
 			}
+
+			if err := c.recordCreated(ctx, attachmentResource(api.GatewayScope, groupName, &serverList[i])); err != nil {
+				return false, err
+			}
 		}
 
 		return true, nil
@@ -186,7 +281,8 @@ func (c *CloudInfo) openGatewayPort(groupName, nodeName string, computeClient *g
 	return errors.WithMessagef(err, "open gateway ports failed")
 }
 
-func (c *CloudInfo) removeFirewallRulesFromGW(groupName, nodeName string, computeClient *gophercloud.ServiceClient) error {
+func (c *CloudInfo) removeFirewallRulesFromGW(ctx context.Context, groupName, nodeName string,
+	computeClient *gophercloud.ServiceClient) error {
 	opts := servers.ListOpts{Name: nodeName}
 	pager := servers.List(computeClient, opts)
 
@@ -196,7 +292,7 @@ func (c *CloudInfo) removeFirewallRulesFromGW(groupName, nodeName string, comput
 			return false, errors.WithMessagef(err, "getting the server list failed")
 		}
 		for i := range serverList {
-			err = secgroups.RemoveServer(computeClient, serverList[i].ID, groupName).ExtractErr()
+			err = removeServer(computeClient, serverList[i].ID, groupName)
 			if err != nil {
 				notFoundError := &gophercloud.ErrDefault404{}
 				if errors.As(err, notFoundError) {
@@ -206,6 +302,10 @@ func (c *CloudInfo) removeFirewallRulesFromGW(groupName, nodeName string, comput
 				return false, errors.WithMessagef(err, "failed to remove the firewall for"+
 					" the server: %q", serverList[i].Name)
 			}
+
+			if err := api.ForgetResource(ctx, c.Inventory, attachmentResource(api.GatewayScope, groupName, &serverList[i])); err != nil {
+				return false, err // nolint:wrapcheck // No need to wrap here
+			}
 		}
 
 		return true, nil
@@ -235,27 +335,169 @@ func (c *CloudInfo) deleteSG(groupName string, computeClient *gophercloud.Servic
 	})
 
 	if err == nil && isFound {
-		err = secgroups.Delete(computeClient, securityGroupID).ExtractErr()
+		err = deleteSecurityGroup(computeClient, securityGroupID)
 	}
 
 	return errors.WithMessagef(err, "error deleting the security group %q", groupName)
 }
 
-func (c *CloudInfo) createSGRule(group, remoteGroupID, remoteIPPrefix string, port uint16,
-	protocol string, networkClient *gophercloud.ServiceClient) error {
+func (c *CloudInfo) createSGRule(ctx context.Context, scope api.InventoryScope, group, remoteGroupID, remoteIPPrefix string,
+	family api.IPFamily, port api.PortSpec, networkClient *gophercloud.ServiceClient) error {
 	opts := rules.CreateOpts{
 		Direction:      "ingress",
-		EtherType:      rules.EtherType4,
+		EtherType:      etherType(family),
 		SecGroupID:     group,
-		PortRangeMax:   int(port),
-		PortRangeMin:   int(port),
-		Protocol:       rules.RuleProtocol(protocol),
+		PortRangeMax:   int(port.LastPort()),
+		PortRangeMin:   int(port.Port),
+		Protocol:       rules.RuleProtocol(port.Protocol),
 		RemoteGroupID:  remoteGroupID,
 		RemoteIPPrefix: remoteIPPrefix,
 	}
 
-	_, err := rules.Create(networkClient, opts).Extract()
+	var rule *rules.SecGroupRule
+
+	err := audited(networkClient, "rules.Create", group, opts, func() (err error) {
+		rule, err = rules.Create(networkClient, opts).Extract()
+		return err
+	})
+	if err != nil {
+		return errors.WithMessagef(err, "failed creating security group rule with port %s , protocol %q,"+
+			"remotegroupID %q, remoteIPprefix %q , in security group %q", port.PortString(), port.Protocol, remoteGroupID, remoteIPPrefix, group)
+	}
+
+	source := remoteIPPrefix
+	if source == "" {
+		source = remoteGroupID
+	}
+
+	return c.recordCreated(ctx, api.InventoryResource{
+		Scope:       scope,
+		Kind:        api.SecurityGroupRuleResource,
+		ID:          rule.ID,
+		Parent:      group,
+		Description: fmt.Sprintf("allow %s %s from %s", family, port, source),
+	})
+}
+
+func etherType(family api.IPFamily) rules.RuleEtherType {
+	if family == api.IPv6Family {
+		return rules.EtherType6
+	}
+
+	return rules.EtherType4
+}
+
+// listIngressRules returns the ingress rules of the security group. They are listed through Neutron since, unlike
+// Nova's, its rules include their ether type.
+func listIngressRules(groupID string, networkClient *gophercloud.ServiceClient) ([]rules.SecGroupRule, error) {
+	var groupRules []rules.SecGroupRule
+
+	err := rules.List(networkClient, rules.ListOpts{SecGroupID: groupID, Direction: "ingress"}).EachPage(
+		func(page pagination.Page) (bool, error) {
+			pageRules, err := rules.ExtractRules(page)
+			if err != nil {
+				return false, errors.WithMessage(err, "failed to extract the security group rules from results")
+			}
+
+			groupRules = append(groupRules, pageRules...)
+
+			return true, nil
+		})
+
+	return groupRules, errors.WithMessagef(err, "error listing the rules of the security group %q", groupID)
+}
+
+// containsGroupRule returns true if the rules allow the port from the remote group for the given IP family.
+func containsGroupRule(groupRules []rules.SecGroupRule, remoteGroupID string, family api.IPFamily, port api.PortSpec) bool {
+	for i := range groupRules {
+		rule := &groupRules[i]
+
+		if rule.RemoteGroupID == remoteGroupID && rule.EtherType == string(etherType(family)) &&
+			strings.EqualFold(rule.Protocol, port.Protocol) && rule.PortRangeMin == int(port.Port) &&
+			rule.PortRangeMax == int(port.LastPort()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// recordCreated records the resource created by the operation in the inventory, and registers its removal if the
+// operation is rolled back. The removal uses clients bound to the rollback's context, since the operation's own
+// clients are bound to its context, which may be done by then.
+func (c *CloudInfo) recordCreated(ctx context.Context, resource api.InventoryResource) error {
+	api.OnRollback(ctx, fmt.Sprintf("remove %s %s", resource.Kind, resource.Description), func(ctx context.Context) error {
+		computeClient, networkClient, err := c.serviceClients(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := c.removeInventoried(&resource, computeClient, networkClient); err != nil {
+			return err
+		}
+
+		return api.ForgetResource(ctx, c.Inventory, resource)
+	})
+
+	return api.RecordResource(ctx, c.Inventory, resource)
+}
+
+// serviceClients returns the compute and network clients issuing their requests using the given context.
+func (c *CloudInfo) serviceClients(ctx context.Context) (*gophercloud.ServiceClient, *gophercloud.ServiceClient, error) {
+	computeClient, err := openstack.NewComputeV2(c.withContext(ctx), gophercloud.EndpointOpts{Region: c.Region})
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "error creating the compute client")
+	}
+
+	networkClient, err := openstack.NewNetworkV2(c.withContext(ctx), gophercloud.EndpointOpts{Region: c.Region})
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "error creating the network client")
+	}
+
+	return computeClient, networkClient, nil
+}
+
+func securityGroupResource(scope api.InventoryScope, group *secgroups.SecurityGroup, preExisting bool) api.InventoryResource {
+	return api.InventoryResource{
+		Scope:       scope,
+		Kind:        api.SecurityGroupResource,
+		ID:          group.ID,
+		Description: group.Name,
+		PreExisting: preExisting,
+	}
+}
+
+// attachmentResource returns the inventory resource for the attachment of the named security group to the server.
+// Attachments are identified by server, within the security group's name, which is what is needed to detach them.
+func attachmentResource(scope api.InventoryScope, groupName string, server *servers.Server) api.InventoryResource {
+	return api.InventoryResource{
+		Scope:       scope,
+		Kind:        api.SecurityGroupAttachmentResource,
+		ID:          server.ID,
+		Parent:      groupName,
+		Description: "attached to server " + server.Name,
+	}
+}
+
+// removeInventoried removes the security group, rule or attachment recorded in the inventory.
+func (c *CloudInfo) removeInventoried(resource *api.InventoryResource, computeClient, networkClient *gophercloud.ServiceClient) error {
+	var err error
+
+	switch resource.Kind { // nolint:exhaustive // Only the security group resources are handled here
+	case api.SecurityGroupAttachmentResource:
+		err = removeServer(computeClient, resource.ID, resource.Parent)
+	case api.SecurityGroupRuleResource:
+		err = deleteRule(networkClient, resource.ID)
+	case api.SecurityGroupResource:
+		err = deleteSecurityGroup(computeClient, resource.ID)
+	default:
+		return fmt.Errorf("unexpected %s resource in the RHOS inventory", resource.Kind)
+	}
+
+	notFoundError := &gophercloud.ErrDefault404{}
+	if errors.As(err, notFoundError) {
+		return nil
+	}
 
-	return errors.WithMessagef(err, "failed creating security group rule with port %d , protocol %q,"+
-		"remotegroupID %q, remoteIPprefix %q , in security group %q", port, protocol, remoteGroupID, remoteIPPrefix, group)
+	return errors.WithMessagef(err, "error removing %s %q", resource.Kind, resource.ID)
 }
@@ -0,0 +1,242 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"context"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/secgroups"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/pagination"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+func (rc *rhosCloud) Status(ctx context.Context) (*api.CloudStatus, error) {
+	status := &api.CloudStatus{InternalPorts: []api.PortSpec{}}
+
+	computeClient, err := openstack.NewComputeV2(rc.withContext(ctx), gophercloud.EndpointOpts{Region: rc.Region})
+	if err != nil {
+		return nil, errors.WithMessage(err, "Error creating the compute client")
+	}
+
+	groupName := rc.InfraID + internalSecurityGroupSuffix
+
+	group, found, err := getSecurityGroup(groupName, computeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return status, nil
+	}
+
+	status.InternalPorts = rulePorts(group.Rules, func(rule *secgroups.Rule) bool {
+		return rule.Group.Name == groupName
+	})
+
+	unattached, err := serversWithoutGroup(rc.InfraID, groupName, computeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range unattached {
+		status.AddIssue("server %q doesn't have the internal security group %q", name, groupName)
+	}
+
+	return status, nil
+}
+
+func (d *ocpGatewayDeployer) Status(ctx context.Context) (*api.GatewayStatus, error) {
+	status := &api.GatewayStatus{}
+
+	computeClient, err := openstack.NewComputeV2(d.withContext(ctx), gophercloud.EndpointOpts{Region: d.Region})
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating the compute client")
+	}
+
+	groupName := d.InfraID + gwSecurityGroupSuffix
+
+	group, found, err := getSecurityGroup(groupName, computeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if found {
+		status.Firewall = &api.FirewallStatus{
+			Kind: api.SecurityGroupResource,
+			Name: groupName,
+			Ports: rulePorts(group.Rules, func(rule *secgroups.Rule) bool {
+				return rule.IPRange.CIDR != ""
+			}),
+		}
+	}
+
+	template, err := d.initMachineSet("")
+	if err != nil {
+		return nil, err
+	}
+
+	machineSets, err := d.msDeployer.List(ctx, template, d.InfraID+"-submariner-gw-")
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the gateway machine sets")
+	}
+
+	for i := range machineSets {
+		status.MachineSets = append(status.MachineSets, machineSets[i].GetName())
+	}
+
+	gwNodes, err := d.K8sClient.ListGatewayNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the Submariner gateway nodes")
+	}
+
+	for i := range gwNodes.Items {
+		status.GatewayNodes = append(status.GatewayNodes, gwNodes.Items[i].Name)
+
+		if !found {
+			continue
+		}
+
+		unattached, err := serversWithoutGroup(gwNodes.Items[i].Name, groupName, computeClient)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range unattached {
+			status.AddIssue("gateway server %q doesn't have the gateway security group %q", name, groupName)
+		}
+	}
+
+	if !found && len(gwNodes.Items) > 0 {
+		status.AddIssue("gateways are deployed but the gateway security group %q doesn't exist", groupName)
+	} else if found && len(gwNodes.Items) == 0 && len(machineSets) == 0 {
+		status.AddIssue("the gateway security group %q exists but no gateways are deployed", groupName)
+	}
+
+	return status, nil
+}
+
+// getSecurityGroup returns the named security group, and whether it was found.
+func getSecurityGroup(groupName string, computeClient *gophercloud.ServiceClient) (secgroups.SecurityGroup, bool, error) {
+	var group secgroups.SecurityGroup
+
+	found := false
+
+	err := secgroups.List(computeClient).EachPage(func(page pagination.Page) (bool, error) {
+		groups, err := secgroups.ExtractSecurityGroups(page)
+		if err != nil {
+			return false, errors.WithMessagef(err, "failed to extract the security group %q from results", groupName)
+		}
+
+		for i := range groups {
+			if groups[i].Name == groupName {
+				group = groups[i]
+				found = true
+
+				return false, nil
+			}
+		}
+
+		return true, nil
+	})
+
+	return group, found, errors.WithMessagef(err, "error getting the security group : %q", groupName)
+}
+
+// serversWithoutGroup returns the names of the servers matching the given name which don't have the security group.
+func serversWithoutGroup(name, groupName string, computeClient *gophercloud.ServiceClient) ([]string, error) {
+	names := []string{}
+
+	err := servers.List(computeClient, servers.ListOpts{Name: name}).EachPage(func(page pagination.Page) (bool, error) {
+		serverList, err := servers.ExtractServers(page)
+		if err != nil {
+			return false, errors.WithMessage(err, "getting the server List failed")
+		}
+
+		for i := range serverList {
+			if !hasSecurityGroup(&serverList[i], groupName) {
+				names = append(names, serverList[i].Name)
+			}
+		}
+
+		return true, nil
+	})
+
+	return names, errors.WithMessagef(err, "failed to list the servers matching %q", name)
+}
+
+func hasSecurityGroup(server *servers.Server, groupName string) bool {
+	for _, group := range server.SecurityGroups {
+		if name, ok := group["name"].(string); ok && name == groupName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func rulePorts(rules []secgroups.Rule, sourceMatches func(*secgroups.Rule) bool) []api.PortSpec {
+	ports := []api.PortSpec{}
+
+	for i := range rules {
+		if !sourceMatches(&rules[i]) {
+			continue
+		}
+
+		port := api.PortSpec{Port: uint16(rules[i].FromPort), Protocol: rules[i].IPProtocol}
+		if rules[i].ToPort != rules[i].FromPort {
+			port.EndPort = uint16(rules[i].ToPort)
+		}
+
+		if rules[i].IPRange.CIDR != "" {
+			port.SourceCIDRs = []string{rules[i].IPRange.CIDR}
+		} else if containsPort(ports, port) {
+			// Rules referencing a group are duplicated for each IP family.
+			continue
+		}
+
+		ports = append(ports, port)
+	}
+
+	return ports
+}
+
+// containsSourcePort returns true if the ports include the given port, allowed from the given CIDR.
+func containsSourcePort(ports []api.PortSpec, port api.PortSpec, cidr string) bool {
+	for i := range ports {
+		if ports[i].Matches(port) && len(ports[i].SourceCIDRs) == 1 && ports[i].SourceCIDRs[0] == cidr {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsPort(ports []api.PortSpec, port api.PortSpec) bool {
+	for i := range ports {
+		if ports[i].Matches(port) && len(ports[i].SourceCIDRs) == 0 {
+			return true
+		}
+	}
+
+	return false
+}
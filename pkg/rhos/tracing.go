@@ -0,0 +1,54 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"net/http"
+
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+// tracingTransport traces each request sent to the OpenStack APIs in a span named after its method and path, e.g.
+// "GET /v2.0/security-groups", see api.StartCall.
+type tracingTransport struct {
+	transport http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx, span := api.StartCall(request.Context(), request.Method+" "+request.URL.Path,
+		append(semconv.HTTPClientAttributesFromHTTPRequest(request), semconv.CloudProviderKey.String("openstack"))...)
+
+	transport := t.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	response, err := transport.RoundTrip(request.WithContext(ctx))
+	if err != nil {
+		api.EndCall(span, err)
+		return nil, err // nolint:wrapcheck // No need to wrap here
+	}
+
+	span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(response.StatusCode)...)
+	span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(response.StatusCode))
+	api.EndCall(span, nil)
+
+	return response, nil
+}
@@ -0,0 +1,123 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rhos
+
+import (
+	"fmt"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/quotasets"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/quotas"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+	"github.com/gophercloud/gophercloud/pagination"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// policyCheck is a read-only request authorized by the Neutron or Nova policy, since OpenStack has no dry-run mode
+// to check the requests which make changes.
+type policyCheck struct {
+	operation string
+	request   func() error
+}
+
+// validatePolicies runs all the given checks, returning an error for each operation which isn't allowed.
+func validatePolicies(checks ...policyCheck) error {
+	errs := []error{}
+
+	for _, check := range checks {
+		err := check.request()
+		if err == nil {
+			continue
+		}
+
+		forbiddenError := &gophercloud.ErrDefault403{}
+		if errors.As(err, forbiddenError) {
+			errs = append(errs, api.Errorf(api.ErrPermissionDenied, "no permission to %s, it is forbidden by the policy of the project",
+				check.operation))
+		} else {
+			errs = append(errs, errors.WithMessagef(err, "error while checking permissions to %s", check.operation))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// The changes whose permissions can't be checked by the policy checks.
+const (
+	prepareChanges = "create security groups and their rules, and add them to servers"
+	cleanupChanges = "delete security groups, and remove them from servers"
+)
+
+// uncheckedChangesWarning returns a prerequisite check which warns that the permissions to make the given changes
+// aren't checked beforehand, since the policy checks only exercise read-only requests. Missing permissions are only
+// detected when the changes are made, and may leave a partial preparation unless rollback is requested.
+func uncheckedChangesWarning(reporter api.Reporter, changes string) func() error {
+	return func() error {
+		api.Warn(reporter, "OpenStack has no dry-run mode, so the permissions to %s will only be checked when making the changes",
+			changes)
+
+		return nil
+	}
+}
+
+func (c *CloudInfo) listSecurityGroupsCheck(networkClient *gophercloud.ServiceClient) policyCheck {
+	return policyCheck{
+		operation: "list the Neutron security groups",
+		request: func() error {
+			_, err := groups.List(networkClient, groups.ListOpts{Name: c.InfraID + internalSecurityGroupSuffix}).AllPages()
+			return err // nolint:wrapcheck // No need to wrap here
+		},
+	}
+}
+
+func (c *CloudInfo) listServersCheck(computeClient *gophercloud.ServiceClient) policyCheck {
+	return policyCheck{
+		operation: "list the Nova servers",
+		request: func() error {
+			// The first page is enough to check the policy.
+			return servers.List(computeClient, servers.ListOpts{Name: c.InfraID}).EachPage( // nolint:wrapcheck // No need to wrap
+				func(_ pagination.Page) (bool, error) {
+					return false, nil
+				})
+		},
+	}
+}
+
+func networkQuotaCheck(networkClient *gophercloud.ServiceClient, projectID string) policyCheck {
+	return policyCheck{
+		operation: fmt.Sprintf("read the Neutron quotas of project %q", projectID),
+		request: func() error {
+			_, err := quotas.GetDetail(networkClient, projectID).Extract()
+			return err // nolint:wrapcheck // No need to wrap here
+		},
+	}
+}
+
+func computeQuotaCheck(computeClient *gophercloud.ServiceClient, projectID string) policyCheck {
+	return policyCheck{
+		operation: fmt.Sprintf("read the Nova quotas of project %q", projectID),
+		request: func() error {
+			_, err := quotasets.GetDetail(computeClient, projectID).Extract()
+			return err // nolint:wrapcheck // No need to wrap here
+		},
+	}
+}
@@ -0,0 +1,229 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+const (
+	awsSecurityGroup     = "aws_security_group"
+	awsSecurityGroupRule = "aws_security_group_rule"
+	awsGatewayGroupLabel = "submariner_gw"
+)
+
+// AWSFacts are the facts discovered about an AWS cluster which the configuration refers to.
+type AWSFacts struct {
+	InfraID string
+	VpcID   string
+
+	// WorkerSecurityGroupID and MasterSecurityGroupID are the IDs of the "{infraID}-worker-sg" and
+	// "{infraID}-master-sg" security groups.
+	WorkerSecurityGroupID string
+	MasterSecurityGroupID string
+
+	// PublicSubnets are the public subnets of the VPC which can host gateways.
+	PublicSubnets []AWSSubnet
+
+	Gateway Gateway
+}
+
+// AWSSubnet is a public subnet of the cluster's VPC.
+type AWSSubnet struct {
+	ID               string
+	Name             string
+	AvailabilityZone string
+
+	// Tagged is true if the subnet is already tagged for Submariner gateways; such subnets are used first.
+	Tagged bool
+}
+
+// RenderAWS renders the security group rules opening the internal ports, if prepare is set, and the gateway security
+// group, the subnet tags and the gateway instances, if deploy is set. As on AWS, a gateway is deployed in every public
+// subnet unless a number of gateways is requested.
+func RenderAWS(prepare *api.PrepareForSubmarinerInput, deploy *api.GatewayDeployInput, facts *AWSFacts) ([]byte, error) {
+	if facts.InfraID == "" || facts.WorkerSecurityGroupID == "" {
+		return nil, errors.New("the infrastructure ID and the worker security group ID are required")
+	}
+
+	f := newFile()
+	f.requireProvider("aws", "hashicorp/aws")
+
+	if prepare != nil {
+		if facts.MasterSecurityGroupID == "" {
+			return nil, errors.New("the master security group ID is required to open the internal ports")
+		}
+
+		renderAWSInternalRules(f, prepare, facts)
+	}
+
+	if deploy != nil {
+		if err := renderAWSGateways(f, deploy, facts); err != nil {
+			return nil, err
+		}
+	}
+
+	return f.bytes(), nil
+}
+
+// renderAWSInternalRules allows the internal ports between the workers, and between the workers and the masters, with
+// the same rules for all IP families as the provider.
+func renderAWSInternalRules(f *file, input *api.PrepareForSubmarinerInput, facts *AWSFacts) {
+	groups := []struct {
+		source, destination, sourceID, destinationID, description string
+	}{
+		{"worker", "worker", facts.WorkerSecurityGroupID, facts.WorkerSecurityGroupID, "between the workers"},
+		{"worker", "master", facts.WorkerSecurityGroupID, facts.MasterSecurityGroupID, "from worker to master nodes"},
+		{"master", "worker", facts.MasterSecurityGroupID, facts.WorkerSecurityGroupID, "from master to worker nodes"},
+	}
+
+	for _, port := range input.InternalPorts {
+		for _, group := range groups {
+			rule, _ := f.resource(awsSecurityGroupRule, "internal", portLabel(port), group.source, "to", group.destination)
+			rule.set("type", str("ingress")).
+				set("security_group_id", str(group.destinationID)).
+				set("source_security_group_id", str(group.sourceID))
+			setAWSPorts(rule, port)
+			rule.set("description", str("Internal Submariner traffic "+group.description))
+		}
+	}
+}
+
+func renderAWSGateways(f *file, input *api.GatewayDeployInput, facts *AWSFacts) error {
+	if facts.VpcID == "" {
+		return errors.New("the VPC ID is required to deploy the gateways")
+	}
+
+	if err := facts.Gateway.validate(); err != nil {
+		return err
+	}
+
+	subnets := sortedSubnets(facts.PublicSubnets)
+
+	count, err := gatewayCount(input, len(subnets), len(subnets), "public subnets")
+	if err != nil {
+		return err
+	}
+
+	groupName := facts.InfraID + "-submariner-gw-sg"
+	group, _ := f.resource(awsSecurityGroup, awsGatewayGroupLabel)
+	group.set("name", str(groupName)).
+		set("description", str(gatewaySecurityGroupDescription)).
+		set("vpc_id", str(facts.VpcID)).
+		set("tags", stringMap(map[string]string{
+			"Name":                                   groupName,
+			"kubernetes.io/cluster/" + facts.InfraID: "owned",
+		}))
+
+	families := input.PublicIPFamilies()
+
+	for _, port := range input.PublicPortSpecs() {
+		for _, family := range families {
+			for _, cidr := range port.SourcesFor(family) {
+				cidrs := "cidr_blocks"
+				if family == api.IPv6Family {
+					cidrs = "ipv6_cidr_blocks"
+				}
+
+				rule, _ := f.resource(awsSecurityGroupRule, "public", portLabel(port), familyLabel(family))
+				rule.set("type", str("ingress")).
+					set("security_group_id", reference(awsSecurityGroup, awsGatewayGroupLabel, "id")).
+					set(cidrs, strs(cidr))
+				setAWSPorts(rule, port)
+				rule.set("description", str("Public Submariner traffic"))
+			}
+		}
+	}
+
+	for i := range subnets[:count] {
+		renderAWSGateway(f, &subnets[i], facts)
+	}
+
+	return nil
+}
+
+// renderAWSGateway tags the public subnet for Submariner gateways, and deploys a gateway instance in it.
+func renderAWSGateway(f *file, subnet *AWSSubnet, facts *AWSFacts) {
+	subnetName := subnet.Name
+	if subnetName == "" {
+		subnetName = subnet.ID
+	}
+
+	for _, tag := range []string{"kubernetes.io/role/internal-elb", "submariner.io/gateway"} {
+		subnetTag, _ := f.resource("aws_ec2_tag", subnetName, tag)
+		subnetTag.set("resource_id", str(subnet.ID)).
+			set("key", str(tag)).
+			set("value", str(""))
+	}
+
+	instance, _ := f.resource("aws_instance", "gateway", subnet.AvailabilityZone)
+	instance.set("ami", str(facts.Gateway.Image)).
+		set("instance_type", str(facts.Gateway.InstanceType)).
+		set("subnet_id", str(subnet.ID)).
+		set("vpc_security_group_ids", list{str(facts.WorkerSecurityGroupID), reference(awsSecurityGroup, awsGatewayGroupLabel, "id")}).
+		set("iam_instance_profile", str(facts.InfraID+"-worker-profile")).
+		set("associate_public_ip_address", boolean(true))
+
+	if facts.Gateway.UserData != "" {
+		instance.set("user_data", str(facts.Gateway.UserData))
+	}
+
+	instance.set("tags", stringMap(map[string]string{
+		"Name":                                   facts.InfraID + "-submariner-gw-" + subnet.AvailabilityZone,
+		"kubernetes.io/cluster/" + facts.InfraID: "owned",
+		"submariner.io":                          "gateway",
+	}))
+}
+
+// setAWSPorts sets the ports of the rule as the provider sets those of its permissions.
+func setAWSPorts(rule *block, port api.PortSpec) {
+	from, to := int(port.Port), int(port.LastPort())
+
+	if port.Port == 0 && port.EndPort == 0 && (strings.EqualFold(port.Protocol, "tcp") || strings.EqualFold(port.Protocol, "udp")) {
+		to = 65535
+	}
+
+	rule.set("protocol", str(port.Protocol)).
+		set("from_port", number(from)).
+		set("to_port", number(to))
+}
+
+// sortedSubnets returns the subnets already tagged for gateways first, each sorted by name and ID so that the subnets
+// which are used don't depend on the order they were discovered in.
+func sortedSubnets(subnets []AWSSubnet) []AWSSubnet {
+	sorted := append([]AWSSubnet{}, subnets...)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Tagged != sorted[j].Tagged {
+			return sorted[i].Tagged
+		}
+
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	return sorted
+}
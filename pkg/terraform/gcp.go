@@ -0,0 +1,186 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/gcp"
+)
+
+const googleFirewall = "google_compute_firewall"
+
+// GCPFacts are the facts discovered about a GCP cluster which the configuration refers to.
+type GCPFacts struct {
+	InfraID   string
+	ProjectID string
+
+	// Network is the cluster's network, "projects/{projectID}/global/networks/{infraID}-network" by default.
+	Network string
+
+	// Subnetwork is the subnetwork the gateways are attached to, "{infraID}-worker-subnet" by default.
+	Subnetwork string
+
+	// Zones are the zones of the region which can host gateways, one per zone.
+	Zones []string
+
+	Gateway Gateway
+}
+
+// RenderGCP renders the firewall rule opening the internal ports, if prepare is set, and the firewall rules opening
+// the public ports to the gateways and the gateway instances, if deploy is set. As on GCP, gateways are only deployed
+// if a number of gateways is requested.
+func RenderGCP(prepare *api.PrepareForSubmarinerInput, deploy *api.GatewayDeployInput, facts *GCPFacts) ([]byte, error) {
+	if facts.InfraID == "" || facts.ProjectID == "" {
+		return nil, errors.New("the infrastructure ID and the project ID are required")
+	}
+
+	network := facts.Network
+	if network == "" {
+		network = fmt.Sprintf("projects/%s/global/networks/%s-network", facts.ProjectID, facts.InfraID)
+	}
+
+	f := newFile()
+	f.requireProvider("google", "hashicorp/google")
+
+	if prepare != nil {
+		rule := newGCPFirewall(f, "submariner_internal", facts, network, gcp.InternalRuleName(facts.InfraID))
+		rule.set("source_tags", strs(facts.InfraID+"-worker", facts.InfraID+"-master")).
+			set("target_tags", strs(facts.InfraID+"-worker", facts.InfraID+"-master"))
+		allowPorts(rule, prepare.InternalPorts)
+	}
+
+	if deploy != nil {
+		if err := renderGCPGateways(f, deploy, facts, network); err != nil {
+			return nil, err
+		}
+	}
+
+	return f.bytes(), nil
+}
+
+func renderGCPGateways(f *file, input *api.GatewayDeployInput, facts *GCPFacts, network string) error {
+	ports := input.PublicPortSpecs()
+
+	if err := gcp.ValidatePublicPorts(facts.InfraID, ports); err != nil {
+		return err // nolint:wrapcheck // No need to wrap here
+	}
+
+	var sourcePort api.PortSpec
+	if len(ports) > 0 {
+		sourcePort = ports[0]
+	}
+
+	for _, family := range input.PublicIPFamilies() {
+		sources := sourcePort.SourcesFor(family)
+		if len(sources) == 0 {
+			continue
+		}
+
+		label := "submariner_public"
+		if family == api.IPv6Family {
+			label = "submariner_public_v6"
+		}
+
+		rule := newGCPFirewall(f, label, facts, network, gcp.PublicRuleName(facts.InfraID, family))
+		rule.set("source_ranges", strs(sources...)).
+			set("target_tags", strs(gcp.GatewayNodeTag))
+		allowPorts(rule, ports)
+	}
+
+	zones := append([]string{}, facts.Zones...)
+	sort.Strings(zones)
+
+	count, err := gatewayCount(input, 0, len(zones), "zones")
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		if err := facts.Gateway.validate(); err != nil {
+			return err
+		}
+	}
+
+	subnetwork := facts.Subnetwork
+	if subnetwork == "" {
+		subnetwork = facts.InfraID + "-worker-subnet"
+	}
+
+	for _, zone := range zones[:count] {
+		renderGCPGateway(f, zone, facts, network, subnetwork)
+	}
+
+	return nil
+}
+
+func renderGCPGateway(f *file, zone string, facts *GCPFacts, network, subnetwork string) {
+	instance, _ := f.resource("google_compute_instance", "gateway", zone)
+	instance.set("name", str(facts.InfraID+"-submariner-gw-"+zone)).
+		set("project", str(facts.ProjectID)).
+		set("zone", str(zone)).
+		set("machine_type", str(facts.Gateway.InstanceType)).
+		set("can_ip_forward", boolean(true)).
+		set("tags", strs(facts.InfraID+"-worker", gcp.GatewayNodeTag))
+
+	if facts.Gateway.UserData != "" {
+		instance.set("metadata", stringMap(map[string]string{"user-data": facts.Gateway.UserData}))
+	}
+
+	disk := instance.add(newBlock("boot_disk"))
+	disk.set("auto_delete", boolean(true))
+	disk.add(newBlock("initialize_params")).
+		set("image", str(facts.Gateway.Image)).
+		set("size", number(128)).
+		set("type", str("pd-ssd"))
+
+	networkInterface := instance.add(newBlock("network_interface"))
+	networkInterface.set("network", str(network)).
+		set("subnetwork", str(subnetwork))
+	networkInterface.add(newBlock("access_config"))
+
+	instance.add(newBlock("service_account")).
+		set("email", str(fmt.Sprintf("%s-w@%s.iam.gserviceaccount.com", facts.InfraID, facts.ProjectID))).
+		set("scopes", strs("https://www.googleapis.com/auth/cloud-platform"))
+}
+
+// newGCPFirewall adds an ingress firewall rule with the given name.
+func newGCPFirewall(f *file, label string, facts *GCPFacts, network, name string) *block {
+	rule, _ := f.resource(googleFirewall, label)
+	rule.set("name", str(name)).
+		set("project", str(facts.ProjectID)).
+		set("network", str(network)).
+		set("direction", str("INGRESS"))
+
+	return rule
+}
+
+func allowPorts(rule *block, ports []api.PortSpec) {
+	for _, port := range ports {
+		allow := rule.add(newBlock("allow"))
+		allow.set("protocol", str(port.Protocol))
+
+		if portRange := port.PortString(); portRange != "" {
+			allow.set("ports", strs(portRange))
+		}
+	}
+}
@@ -0,0 +1,270 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const indentation = "  "
+
+// value is an HCL expression, written at the given indentation if it spans several lines.
+type value interface {
+	write(out *strings.Builder, indent string)
+}
+
+// expression is an expression written as is, e.g. a number, a boolean or a reference to another resource.
+type expression string
+
+func (e expression) write(out *strings.Builder, _ string) {
+	out.WriteString(string(e))
+}
+
+func reference(resourceType, label, attribute string) expression {
+	return expression(resourceType + "." + label + "." + attribute)
+}
+
+func number(n int) expression {
+	return expression(fmt.Sprintf("%d", n))
+}
+
+func boolean(b bool) expression {
+	return expression(fmt.Sprintf("%t", b))
+}
+
+// str is a quoted string, with its template sequences escaped so that it is used literally.
+type str string
+
+var stringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "${", "$${", "%{", "%%{")
+
+func (s str) write(out *strings.Builder, _ string) {
+	out.WriteString(`"` + stringEscaper.Replace(string(s)) + `"`)
+}
+
+// list is a tuple written on a single line.
+type list []value
+
+func strs(values ...string) list {
+	l := make(list, len(values))
+	for i := range values {
+		l[i] = str(values[i])
+	}
+
+	return l
+}
+
+func (l list) write(out *strings.Builder, indent string) {
+	out.WriteString("[")
+
+	for i, v := range l {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+
+		v.write(out, indent)
+	}
+
+	out.WriteString("]")
+}
+
+// object is an object written with a field per line, in order.
+type object []field
+
+type field struct {
+	key   string
+	value value
+}
+
+// stringMap returns an object with the quoted keys of the map, sorted, and their string values.
+func stringMap(m map[string]string) object {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	o := make(object, len(keys))
+	for i, key := range keys {
+		var quoted strings.Builder
+
+		str(key).write(&quoted, "")
+		o[i] = field{key: quoted.String(), value: str(m[key])}
+	}
+
+	return o
+}
+
+func (o object) write(out *strings.Builder, indent string) {
+	out.WriteString("{\n")
+	writeFields(out, indent+indentation, o)
+	out.WriteString(indent + "}")
+}
+
+// writeFields writes the fields with their equal signs aligned, as terraform fmt does.
+func writeFields(out *strings.Builder, indent string, fields []field) {
+	width := 0
+
+	for i := range fields {
+		if len(fields[i].key) > width {
+			width = len(fields[i].key)
+		}
+	}
+
+	for i := range fields {
+		out.WriteString(indent + fields[i].key + strings.Repeat(" ", width-len(fields[i].key)) + " = ")
+		fields[i].value.write(out, indent)
+		out.WriteString("\n")
+	}
+}
+
+// block is an HCL block, e.g. a resource, whose attributes and nested blocks are written in the order they're set.
+type block struct {
+	blockType string
+	labels    []string
+	items     []item
+}
+
+// item is either an attribute or a nested block.
+type item struct {
+	attribute field
+	block     *block
+}
+
+func newBlock(blockType string, labels ...string) *block {
+	return &block{blockType: blockType, labels: labels}
+}
+
+func (b *block) set(name string, v value) *block {
+	b.items = append(b.items, item{attribute: field{key: name, value: v}})
+	return b
+}
+
+// add adds the nested block and returns it.
+func (b *block) add(nested *block) *block {
+	b.items = append(b.items, item{block: nested})
+	return nested
+}
+
+func (b *block) write(out *strings.Builder, indent string) {
+	out.WriteString(indent + b.blockType)
+
+	for _, label := range b.labels {
+		out.WriteString(" ")
+		str(label).write(out, indent)
+	}
+
+	if len(b.items) == 0 {
+		out.WriteString(" {}\n")
+		return
+	}
+
+	out.WriteString(" {\n")
+
+	// Nested blocks are separated from each other and from the attributes by blank lines.
+	for i := 0; i < len(b.items); {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+
+		if b.items[i].block != nil {
+			b.items[i].block.write(out, indent+indentation)
+			i++
+
+			continue
+		}
+
+		var attributes []field
+
+		for ; i < len(b.items) && b.items[i].block == nil; i++ {
+			attributes = append(attributes, b.items[i].attribute)
+		}
+
+		writeFields(out, indent+indentation, attributes)
+	}
+
+	out.WriteString(indent + "}\n")
+}
+
+// file is a Terraform configuration made of top-level blocks.
+type file struct {
+	blocks []*block
+	labels map[string]int
+}
+
+func newFile() *file {
+	return &file{labels: map[string]int{}}
+}
+
+// resource adds a resource with a label derived from the given parts, which is made unique among the resources of the
+// same type by a numbered suffix. The label is returned, to refer to the resource.
+func (f *file) resource(resourceType string, labelParts ...string) (*block, string) {
+	label := identifier(labelParts...)
+
+	f.labels[resourceType+"."+label]++
+	if count := f.labels[resourceType+"."+label]; count > 1 {
+		label = fmt.Sprintf("%s_%d", label, count)
+	}
+
+	resource := newBlock("resource", resourceType, label)
+	f.blocks = append(f.blocks, resource)
+
+	return resource, label
+}
+
+// requireProvider adds the terraform block requiring the provider with the given name and source.
+func (f *file) requireProvider(name, source string) {
+	terraform := newBlock("terraform")
+	terraform.add(newBlock("required_providers")).set(name, object{{key: "source", value: str(source)}})
+	f.blocks = append(f.blocks, terraform)
+}
+
+func (f *file) bytes() []byte {
+	var out strings.Builder
+
+	for i, b := range f.blocks {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+
+		b.write(&out, "")
+	}
+
+	return []byte(out.String())
+}
+
+// identifier joins the parts into a valid Terraform identifier, replacing any other character with an underscore.
+func identifier(parts ...string) string {
+	id := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_') {
+			return unicode.ToLower(r)
+		}
+
+		return '_'
+	}, strings.Join(parts, "_"))
+
+	if id == "" || unicode.IsDigit(rune(id[0])) || id[0] == '-' {
+		id = "_" + id
+	}
+
+	return id
+}
@@ -0,0 +1,177 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+const (
+	openStackSecurityGroup     = "openstack_networking_secgroup_v2"
+	openStackSecurityGroupRule = "openstack_networking_secgroup_rule_v2"
+	openStackInternalLabel     = "submariner_internal"
+	openStackGatewayLabel      = "submariner_gw"
+)
+
+// OpenStackFacts are the facts discovered about an OpenStack cluster which the configuration refers to.
+type OpenStackFacts struct {
+	InfraID string
+
+	// WorkerSecurityGroup is the name of the security group of the workers, "{infraID}-worker" by default.
+	WorkerSecurityGroup string
+
+	// Network is the name of the network the gateways are attached to, "{infraID}-openshift" by default.
+	Network string
+
+	// Ports are the network ports of the cluster's servers, which the internal security group is added to.
+	Ports []OpenStackPort
+
+	Gateway Gateway
+}
+
+// OpenStackPort is the network port of a server of the cluster.
+type OpenStackPort struct {
+	ID     string
+	Server string
+}
+
+// RenderOpenStack renders the internal security group, its rules and its attachment to the cluster's servers, if
+// prepare is set, and the gateway security group, its rules and the gateway instances, if deploy is set. As on
+// OpenStack, gateways are only deployed if a number of gateways is requested.
+func RenderOpenStack(prepare *api.PrepareForSubmarinerInput, deploy *api.GatewayDeployInput, facts *OpenStackFacts) ([]byte, error) {
+	if facts.InfraID == "" {
+		return nil, errors.New("the infrastructure ID is required")
+	}
+
+	f := newFile()
+	f.requireProvider("openstack", "terraform-provider-openstack/openstack")
+
+	if prepare != nil {
+		renderOpenStackInternalGroup(f, prepare, facts)
+	}
+
+	if deploy != nil {
+		if err := renderOpenStackGateways(f, deploy, facts, prepare != nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return f.bytes(), nil
+}
+
+func renderOpenStackInternalGroup(f *file, input *api.PrepareForSubmarinerInput, facts *OpenStackFacts) {
+	group, _ := f.resource(openStackSecurityGroup, openStackInternalLabel)
+	group.set("name", str(facts.InfraID+"-submariner-internal-sg")).
+		set("description", str(internalSecurityGroupDescription))
+
+	groupID := reference(openStackSecurityGroup, openStackInternalLabel, "id")
+
+	// As with the provider, a rule is needed per IP family.
+	for _, family := range input.InternalIPFamilies() {
+		for _, port := range input.InternalPorts {
+			rule, _ := f.resource(openStackSecurityGroupRule, "internal", familyLabel(family), portLabel(port))
+			setOpenStackRule(rule, groupID, family, port)
+			rule.set("remote_group_id", groupID)
+		}
+	}
+
+	for _, port := range facts.Ports {
+		association, _ := f.resource("openstack_networking_port_secgroup_associate_v2", "internal", port.Server)
+		association.set("port_id", str(port.ID)).
+			set("enforce", boolean(false)).
+			set("security_group_ids", list{groupID})
+	}
+}
+
+func renderOpenStackGateways(f *file, input *api.GatewayDeployInput, facts *OpenStackFacts, withInternalGroup bool) error {
+	count := input.Gateways
+
+	if count > 0 {
+		if err := facts.Gateway.validate(); err != nil {
+			return err
+		}
+	}
+
+	group, _ := f.resource(openStackSecurityGroup, openStackGatewayLabel)
+	group.set("name", str(facts.InfraID+"-submariner-gw-sg")).
+		set("description", str(gatewaySecurityGroupDescription))
+
+	groupID := reference(openStackSecurityGroup, openStackGatewayLabel, "id")
+
+	for _, family := range input.PublicIPFamilies() {
+		for _, port := range input.PublicPortSpecs() {
+			for _, cidr := range port.SourcesFor(family) {
+				rule, _ := f.resource(openStackSecurityGroupRule, "public", familyLabel(family), portLabel(port))
+				setOpenStackRule(rule, groupID, family, port)
+				rule.set("remote_ip_prefix", str(cidr))
+			}
+		}
+	}
+
+	var internalGroup value = str(facts.InfraID + "-submariner-internal-sg")
+	if withInternalGroup {
+		internalGroup = reference(openStackSecurityGroup, openStackInternalLabel, "name")
+	}
+
+	workerGroup := facts.WorkerSecurityGroup
+	if workerGroup == "" {
+		workerGroup = facts.InfraID + "-worker"
+	}
+
+	network := facts.Network
+	if network == "" {
+		network = facts.InfraID + "-openshift"
+	}
+
+	for i := 0; i < count; i++ {
+		instance, _ := f.resource("openstack_compute_instance_v2", "gateway", fmt.Sprint(i))
+		instance.set("name", str(fmt.Sprintf("%s-submariner-gw-%d", facts.InfraID, i))).
+			set("image_name", str(facts.Gateway.Image)).
+			set("flavor_name", str(facts.Gateway.InstanceType)).
+			set("security_groups", list{str(workerGroup), reference(openStackSecurityGroup, openStackGatewayLabel, "name"), internalGroup})
+
+		if facts.Gateway.UserData != "" {
+			instance.set("user_data", str(facts.Gateway.UserData))
+		}
+
+		instance.set("metadata", stringMap(map[string]string{
+			"Name":               facts.InfraID + "-worker",
+			"openshiftClusterID": facts.InfraID,
+		})).set("tags", strs("openshiftClusterID="+facts.InfraID, submarinerGatewayNodeTag))
+
+		instance.add(newBlock("network")).set("name", str(network))
+	}
+
+	return nil
+}
+
+func setOpenStackRule(rule *block, groupID value, family api.IPFamily, port api.PortSpec) {
+	rule.set("security_group_id", groupID).
+		set("direction", str("ingress")).
+		set("ethertype", str(string(family))).
+		set("protocol", str(port.Protocol))
+
+	if port.Port != 0 || port.EndPort != 0 {
+		rule.set("port_range_min", number(int(port.Port))).
+			set("port_range_max", number(int(port.LastPort())))
+	}
+}
@@ -0,0 +1,84 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package terraform renders the changes made by the providers to prepare a cloud for Submariner and deploy its gateways
+// as Terraform configurations, so that they can be applied by Terraform pipelines rather than by the providers. The
+// configurations only depend on the inputs and on the facts discovered about the cluster, so rendering them again
+// gives the same output.
+package terraform
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+)
+
+const (
+	gatewaySecurityGroupDescription  = "Submariner Gateway"
+	internalSecurityGroupDescription = "Submariner Internal"
+	submarinerGatewayNodeTag         = "submariner-io-gateway-node"
+)
+
+// Gateway describes the dedicated gateway instances to render.
+type Gateway struct {
+	// Image is the AMI ID on AWS, or the name of the image on GCP and OpenStack.
+	Image string
+
+	// InstanceType is the instance type on AWS, the machine type on GCP, or the flavor on OpenStack.
+	InstanceType string
+
+	// UserData, if set, is passed to the instances, typically the Ignition configuration making them join the cluster.
+	UserData string
+}
+
+func (g *Gateway) validate() error {
+	if g.Image == "" || g.InstanceType == "" {
+		return errors.New("the image and the instance type of the gateways are required")
+	}
+
+	return nil
+}
+
+// portLabel returns the part of the resource labels identifying the port, e.g. "udp_4500" or "tcp_4490-4500".
+func portLabel(port api.PortSpec) string {
+	if ports := port.PortString(); ports != "" {
+		return port.Protocol + "_" + ports
+	}
+
+	return port.Protocol
+}
+
+func familyLabel(family api.IPFamily) string {
+	return strings.ToLower(string(family))
+}
+
+// gatewayCount returns the number of gateways requested, or defaultCount if the deployer's default policy is requested,
+// and fails if there are fewer places than requested to deploy them.
+func gatewayCount(input *api.GatewayDeployInput, defaultCount, available int, places string) (int, error) {
+	count := input.Gateways
+	if count <= 0 {
+		count = defaultCount
+	}
+
+	if count > available {
+		return 0, errors.Errorf("%d gateways were requested but there are only %d %s to deploy them", count, available, places)
+	}
+
+	return count, nil
+}
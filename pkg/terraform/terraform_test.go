@@ -0,0 +1,205 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/cloud-prepare/pkg/api"
+	"github.com/submariner-io/cloud-prepare/pkg/terraform"
+)
+
+const infraID = "test-infra"
+
+var _ = Describe("Terraform", func() {
+	var (
+		prepare *api.PrepareForSubmarinerInput
+		deploy  *api.GatewayDeployInput
+		gateway terraform.Gateway
+	)
+
+	BeforeEach(func() {
+		prepare = &api.PrepareForSubmarinerInput{
+			InternalPorts: []api.PortSpec{{Port: 4800, Protocol: "udp"}, {Protocol: "50"}},
+			IPFamilies:    []api.IPFamily{api.IPv4Family, api.IPv6Family},
+		}
+
+		deploy = &api.GatewayDeployInput{
+			PublicPorts:       []api.PortSpec{{Port: 4500, Protocol: "udp"}, {Port: 4490, EndPort: 4495, Protocol: "udp"}},
+			PublicSourceCIDRs: []string{"198.51.100.0/24", "2001:db8::/32"},
+			Gateways:          2,
+		}
+
+		gateway = terraform.Gateway{
+			Image:        "test-image",
+			InstanceType: "test-type",
+			UserData:     `{"ignition":{"config":{"merge":[{"source":"${ignition_url}"}]}}}`,
+		}
+	})
+
+	Context("on AWS", func() {
+		var facts *terraform.AWSFacts
+
+		BeforeEach(func() {
+			facts = &terraform.AWSFacts{
+				InfraID:               infraID,
+				VpcID:                 "vpc-1234",
+				WorkerSecurityGroupID: "sg-worker",
+				MasterSecurityGroupID: "sg-master",
+				PublicSubnets: []terraform.AWSSubnet{
+					{ID: "subnet-b", Name: infraID + "-public-us-east-1b", AvailabilityZone: "us-east-1b"},
+					{ID: "subnet-a", Name: infraID + "-public-us-east-1a", AvailabilityZone: "us-east-1a"},
+					{ID: "subnet-c", Name: infraID + "-public-us-east-1c", AvailabilityZone: "us-east-1c", Tagged: true},
+				},
+				Gateway: gateway,
+			}
+		})
+
+		It("should render the preparation and the gateways", func() {
+			output, err := terraform.RenderAWS(prepare, deploy, facts)
+			Expect(err).To(Succeed())
+			Expect(string(output)).To(Equal(golden("aws.tf", output)))
+		})
+
+		When("the number of gateways isn't requested", func() {
+			It("should deploy a gateway in every public subnet", func() {
+				deploy.Gateways = 0
+
+				Expect(terraform.RenderAWS(nil, deploy, facts)).To(And(
+					ContainSubstring(`resource "aws_instance" "gateway_us-east-1a"`),
+					ContainSubstring(`resource "aws_instance" "gateway_us-east-1b"`),
+					ContainSubstring(`resource "aws_instance" "gateway_us-east-1c"`)))
+			})
+		})
+
+		When("more gateways are requested than there are public subnets", func() {
+			It("should return an error", func() {
+				deploy.Gateways = 4
+
+				_, err := terraform.RenderAWS(nil, deploy, facts)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		When("only the preparation is rendered", func() {
+			It("should not require the gateway facts", func() {
+				facts.VpcID = ""
+				facts.Gateway = terraform.Gateway{}
+
+				output, err := terraform.RenderAWS(prepare, nil, facts)
+				Expect(err).To(Succeed())
+				Expect(string(output)).ToNot(ContainSubstring("aws_instance"))
+			})
+		})
+	})
+
+	Context("on GCP", func() {
+		var facts *terraform.GCPFacts
+
+		BeforeEach(func() {
+			facts = &terraform.GCPFacts{
+				InfraID:   infraID,
+				ProjectID: "test-project",
+				Zones:     []string{"us-east1-c", "us-east1-b", "us-east1-d"},
+				Gateway:   gateway,
+			}
+		})
+
+		It("should render the preparation and the gateways", func() {
+			output, err := terraform.RenderGCP(prepare, deploy, facts)
+			Expect(err).To(Succeed())
+			Expect(string(output)).To(Equal(golden("gcp.tf", output)))
+		})
+
+		When("the public ports have different source CIDRs", func() {
+			It("should return an error", func() {
+				deploy.PublicPorts[0].SourceCIDRs = []string{"203.0.113.0/24"}
+
+				_, err := terraform.RenderGCP(nil, deploy, facts)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		When("a public port repeats the source CIDRs of the others", func() {
+			It("should render them as the same sources", func() {
+				deploy.PublicPorts[0].SourceCIDRs = []string{"198.51.100.0/24", "2001:db8::/32", "198.51.100.0/24"}
+
+				_, err := terraform.RenderGCP(nil, deploy, facts)
+				Expect(err).To(Succeed())
+			})
+		})
+	})
+
+	Context("on OpenStack", func() {
+		var facts *terraform.OpenStackFacts
+
+		BeforeEach(func() {
+			facts = &terraform.OpenStackFacts{
+				InfraID: infraID,
+				Ports: []terraform.OpenStackPort{
+					{ID: "port-master-0", Server: infraID + "-master-0"},
+					{ID: "port-worker-0", Server: infraID + "-worker-0"},
+				},
+				Gateway: gateway,
+			}
+		})
+
+		It("should render the preparation and the gateways", func() {
+			output, err := terraform.RenderOpenStack(prepare, deploy, facts)
+			Expect(err).To(Succeed())
+			Expect(string(output)).To(Equal(golden("openstack.tf", output)))
+		})
+
+		When("only the gateways are rendered", func() {
+			It("should refer to the internal security group by name", func() {
+				Expect(terraform.RenderOpenStack(nil, deploy, facts)).To(ContainSubstring(
+					`security_groups = ["test-infra-worker", openstack_networking_secgroup_v2.submariner_gw.name, ` +
+						`"test-infra-submariner-internal-sg"]`))
+			})
+		})
+
+		When("the gateway image isn't set", func() {
+			It("should return an error", func() {
+				facts.Gateway.Image = ""
+
+				_, err := terraform.RenderOpenStack(nil, deploy, facts)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
+
+// golden returns the content of the golden file in testdata, after rewriting it with the actual output if UPDATE_GOLDEN
+// is set.
+func golden(name string, actual []byte) string {
+	path := filepath.Join("testdata", name)
+
+	if os.Getenv("UPDATE_GOLDEN") != "" {
+		Expect(ioutil.WriteFile(path, actual, 0o600)).To(Succeed())
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	Expect(err).To(Succeed())
+
+	return string(expected)
+}
@@ -0,0 +1,171 @@
+terraform {
+  required_providers {
+    aws = {
+      source = "hashicorp/aws"
+    }
+  }
+}
+
+resource "aws_security_group_rule" "internal_udp_4800_worker_to_worker" {
+  type                     = "ingress"
+  security_group_id        = "sg-worker"
+  source_security_group_id = "sg-worker"
+  protocol                 = "udp"
+  from_port                = 4800
+  to_port                  = 4800
+  description              = "Internal Submariner traffic between the workers"
+}
+
+resource "aws_security_group_rule" "internal_udp_4800_worker_to_master" {
+  type                     = "ingress"
+  security_group_id        = "sg-master"
+  source_security_group_id = "sg-worker"
+  protocol                 = "udp"
+  from_port                = 4800
+  to_port                  = 4800
+  description              = "Internal Submariner traffic from worker to master nodes"
+}
+
+resource "aws_security_group_rule" "internal_udp_4800_master_to_worker" {
+  type                     = "ingress"
+  security_group_id        = "sg-worker"
+  source_security_group_id = "sg-master"
+  protocol                 = "udp"
+  from_port                = 4800
+  to_port                  = 4800
+  description              = "Internal Submariner traffic from master to worker nodes"
+}
+
+resource "aws_security_group_rule" "internal_50_worker_to_worker" {
+  type                     = "ingress"
+  security_group_id        = "sg-worker"
+  source_security_group_id = "sg-worker"
+  protocol                 = "50"
+  from_port                = 0
+  to_port                  = 0
+  description              = "Internal Submariner traffic between the workers"
+}
+
+resource "aws_security_group_rule" "internal_50_worker_to_master" {
+  type                     = "ingress"
+  security_group_id        = "sg-master"
+  source_security_group_id = "sg-worker"
+  protocol                 = "50"
+  from_port                = 0
+  to_port                  = 0
+  description              = "Internal Submariner traffic from worker to master nodes"
+}
+
+resource "aws_security_group_rule" "internal_50_master_to_worker" {
+  type                     = "ingress"
+  security_group_id        = "sg-worker"
+  source_security_group_id = "sg-master"
+  protocol                 = "50"
+  from_port                = 0
+  to_port                  = 0
+  description              = "Internal Submariner traffic from master to worker nodes"
+}
+
+resource "aws_security_group" "submariner_gw" {
+  name        = "test-infra-submariner-gw-sg"
+  description = "Submariner Gateway"
+  vpc_id      = "vpc-1234"
+  tags        = {
+    "Name"                             = "test-infra-submariner-gw-sg"
+    "kubernetes.io/cluster/test-infra" = "owned"
+  }
+}
+
+resource "aws_security_group_rule" "public_udp_4500_ipv4" {
+  type              = "ingress"
+  security_group_id = aws_security_group.submariner_gw.id
+  cidr_blocks       = ["198.51.100.0/24"]
+  protocol          = "udp"
+  from_port         = 4500
+  to_port           = 4500
+  description       = "Public Submariner traffic"
+}
+
+resource "aws_security_group_rule" "public_udp_4500_ipv6" {
+  type              = "ingress"
+  security_group_id = aws_security_group.submariner_gw.id
+  ipv6_cidr_blocks  = ["2001:db8::/32"]
+  protocol          = "udp"
+  from_port         = 4500
+  to_port           = 4500
+  description       = "Public Submariner traffic"
+}
+
+resource "aws_security_group_rule" "public_udp_4490-4495_ipv4" {
+  type              = "ingress"
+  security_group_id = aws_security_group.submariner_gw.id
+  cidr_blocks       = ["198.51.100.0/24"]
+  protocol          = "udp"
+  from_port         = 4490
+  to_port           = 4495
+  description       = "Public Submariner traffic"
+}
+
+resource "aws_security_group_rule" "public_udp_4490-4495_ipv6" {
+  type              = "ingress"
+  security_group_id = aws_security_group.submariner_gw.id
+  ipv6_cidr_blocks  = ["2001:db8::/32"]
+  protocol          = "udp"
+  from_port         = 4490
+  to_port           = 4495
+  description       = "Public Submariner traffic"
+}
+
+resource "aws_ec2_tag" "test-infra-public-us-east-1c_kubernetes_io_role_internal-elb" {
+  resource_id = "subnet-c"
+  key         = "kubernetes.io/role/internal-elb"
+  value       = ""
+}
+
+resource "aws_ec2_tag" "test-infra-public-us-east-1c_submariner_io_gateway" {
+  resource_id = "subnet-c"
+  key         = "submariner.io/gateway"
+  value       = ""
+}
+
+resource "aws_instance" "gateway_us-east-1c" {
+  ami                         = "test-image"
+  instance_type               = "test-type"
+  subnet_id                   = "subnet-c"
+  vpc_security_group_ids      = ["sg-worker", aws_security_group.submariner_gw.id]
+  iam_instance_profile        = "test-infra-worker-profile"
+  associate_public_ip_address = true
+  user_data                   = "{\"ignition\":{\"config\":{\"merge\":[{\"source\":\"$${ignition_url}\"}]}}}"
+  tags                        = {
+    "Name"                             = "test-infra-submariner-gw-us-east-1c"
+    "kubernetes.io/cluster/test-infra" = "owned"
+    "submariner.io"                    = "gateway"
+  }
+}
+
+resource "aws_ec2_tag" "test-infra-public-us-east-1a_kubernetes_io_role_internal-elb" {
+  resource_id = "subnet-a"
+  key         = "kubernetes.io/role/internal-elb"
+  value       = ""
+}
+
+resource "aws_ec2_tag" "test-infra-public-us-east-1a_submariner_io_gateway" {
+  resource_id = "subnet-a"
+  key         = "submariner.io/gateway"
+  value       = ""
+}
+
+resource "aws_instance" "gateway_us-east-1a" {
+  ami                         = "test-image"
+  instance_type               = "test-type"
+  subnet_id                   = "subnet-a"
+  vpc_security_group_ids      = ["sg-worker", aws_security_group.submariner_gw.id]
+  iam_instance_profile        = "test-infra-worker-profile"
+  associate_public_ip_address = true
+  user_data                   = "{\"ignition\":{\"config\":{\"merge\":[{\"source\":\"$${ignition_url}\"}]}}}"
+  tags                        = {
+    "Name"                             = "test-infra-submariner-gw-us-east-1a"
+    "kubernetes.io/cluster/test-infra" = "owned"
+    "submariner.io"                    = "gateway"
+  }
+}
@@ -0,0 +1,131 @@
+terraform {
+  required_providers {
+    google = {
+      source = "hashicorp/google"
+    }
+  }
+}
+
+resource "google_compute_firewall" "submariner_internal" {
+  name        = "test-infra-submariner-internal-ports-ingress"
+  project     = "test-project"
+  network     = "projects/test-project/global/networks/test-infra-network"
+  direction   = "INGRESS"
+  source_tags = ["test-infra-worker", "test-infra-master"]
+  target_tags = ["test-infra-worker", "test-infra-master"]
+
+  allow {
+    protocol = "udp"
+    ports    = ["4800"]
+  }
+
+  allow {
+    protocol = "50"
+  }
+}
+
+resource "google_compute_firewall" "submariner_public" {
+  name          = "test-infra-submariner-public-ports-ingress"
+  project       = "test-project"
+  network       = "projects/test-project/global/networks/test-infra-network"
+  direction     = "INGRESS"
+  source_ranges = ["198.51.100.0/24"]
+  target_tags   = ["submariner-io-gateway-node"]
+
+  allow {
+    protocol = "udp"
+    ports    = ["4500"]
+  }
+
+  allow {
+    protocol = "udp"
+    ports    = ["4490-4495"]
+  }
+}
+
+resource "google_compute_firewall" "submariner_public_v6" {
+  name          = "test-infra-submariner-public-ports-v6-ingress"
+  project       = "test-project"
+  network       = "projects/test-project/global/networks/test-infra-network"
+  direction     = "INGRESS"
+  source_ranges = ["2001:db8::/32"]
+  target_tags   = ["submariner-io-gateway-node"]
+
+  allow {
+    protocol = "udp"
+    ports    = ["4500"]
+  }
+
+  allow {
+    protocol = "udp"
+    ports    = ["4490-4495"]
+  }
+}
+
+resource "google_compute_instance" "gateway_us-east1-b" {
+  name           = "test-infra-submariner-gw-us-east1-b"
+  project        = "test-project"
+  zone           = "us-east1-b"
+  machine_type   = "test-type"
+  can_ip_forward = true
+  tags           = ["test-infra-worker", "submariner-io-gateway-node"]
+  metadata       = {
+    "user-data" = "{\"ignition\":{\"config\":{\"merge\":[{\"source\":\"$${ignition_url}\"}]}}}"
+  }
+
+  boot_disk {
+    auto_delete = true
+
+    initialize_params {
+      image = "test-image"
+      size  = 128
+      type  = "pd-ssd"
+    }
+  }
+
+  network_interface {
+    network    = "projects/test-project/global/networks/test-infra-network"
+    subnetwork = "test-infra-worker-subnet"
+
+    access_config {}
+  }
+
+  service_account {
+    email  = "test-infra-w@test-project.iam.gserviceaccount.com"
+    scopes = ["https://www.googleapis.com/auth/cloud-platform"]
+  }
+}
+
+resource "google_compute_instance" "gateway_us-east1-c" {
+  name           = "test-infra-submariner-gw-us-east1-c"
+  project        = "test-project"
+  zone           = "us-east1-c"
+  machine_type   = "test-type"
+  can_ip_forward = true
+  tags           = ["test-infra-worker", "submariner-io-gateway-node"]
+  metadata       = {
+    "user-data" = "{\"ignition\":{\"config\":{\"merge\":[{\"source\":\"$${ignition_url}\"}]}}}"
+  }
+
+  boot_disk {
+    auto_delete = true
+
+    initialize_params {
+      image = "test-image"
+      size  = 128
+      type  = "pd-ssd"
+    }
+  }
+
+  network_interface {
+    network    = "projects/test-project/global/networks/test-infra-network"
+    subnetwork = "test-infra-worker-subnet"
+
+    access_config {}
+  }
+
+  service_account {
+    email  = "test-infra-w@test-project.iam.gserviceaccount.com"
+    scopes = ["https://www.googleapis.com/auth/cloud-platform"]
+  }
+}
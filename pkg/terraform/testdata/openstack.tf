@@ -0,0 +1,139 @@
+terraform {
+  required_providers {
+    openstack = {
+      source = "terraform-provider-openstack/openstack"
+    }
+  }
+}
+
+resource "openstack_networking_secgroup_v2" "submariner_internal" {
+  name        = "test-infra-submariner-internal-sg"
+  description = "Submariner Internal"
+}
+
+resource "openstack_networking_secgroup_rule_v2" "internal_ipv4_udp_4800" {
+  security_group_id = openstack_networking_secgroup_v2.submariner_internal.id
+  direction         = "ingress"
+  ethertype         = "IPv4"
+  protocol          = "udp"
+  port_range_min    = 4800
+  port_range_max    = 4800
+  remote_group_id   = openstack_networking_secgroup_v2.submariner_internal.id
+}
+
+resource "openstack_networking_secgroup_rule_v2" "internal_ipv4_50" {
+  security_group_id = openstack_networking_secgroup_v2.submariner_internal.id
+  direction         = "ingress"
+  ethertype         = "IPv4"
+  protocol          = "50"
+  remote_group_id   = openstack_networking_secgroup_v2.submariner_internal.id
+}
+
+resource "openstack_networking_secgroup_rule_v2" "internal_ipv6_udp_4800" {
+  security_group_id = openstack_networking_secgroup_v2.submariner_internal.id
+  direction         = "ingress"
+  ethertype         = "IPv6"
+  protocol          = "udp"
+  port_range_min    = 4800
+  port_range_max    = 4800
+  remote_group_id   = openstack_networking_secgroup_v2.submariner_internal.id
+}
+
+resource "openstack_networking_secgroup_rule_v2" "internal_ipv6_50" {
+  security_group_id = openstack_networking_secgroup_v2.submariner_internal.id
+  direction         = "ingress"
+  ethertype         = "IPv6"
+  protocol          = "50"
+  remote_group_id   = openstack_networking_secgroup_v2.submariner_internal.id
+}
+
+resource "openstack_networking_port_secgroup_associate_v2" "internal_test-infra-master-0" {
+  port_id            = "port-master-0"
+  enforce            = false
+  security_group_ids = [openstack_networking_secgroup_v2.submariner_internal.id]
+}
+
+resource "openstack_networking_port_secgroup_associate_v2" "internal_test-infra-worker-0" {
+  port_id            = "port-worker-0"
+  enforce            = false
+  security_group_ids = [openstack_networking_secgroup_v2.submariner_internal.id]
+}
+
+resource "openstack_networking_secgroup_v2" "submariner_gw" {
+  name        = "test-infra-submariner-gw-sg"
+  description = "Submariner Gateway"
+}
+
+resource "openstack_networking_secgroup_rule_v2" "public_ipv4_udp_4500" {
+  security_group_id = openstack_networking_secgroup_v2.submariner_gw.id
+  direction         = "ingress"
+  ethertype         = "IPv4"
+  protocol          = "udp"
+  port_range_min    = 4500
+  port_range_max    = 4500
+  remote_ip_prefix  = "198.51.100.0/24"
+}
+
+resource "openstack_networking_secgroup_rule_v2" "public_ipv4_udp_4490-4495" {
+  security_group_id = openstack_networking_secgroup_v2.submariner_gw.id
+  direction         = "ingress"
+  ethertype         = "IPv4"
+  protocol          = "udp"
+  port_range_min    = 4490
+  port_range_max    = 4495
+  remote_ip_prefix  = "198.51.100.0/24"
+}
+
+resource "openstack_networking_secgroup_rule_v2" "public_ipv6_udp_4500" {
+  security_group_id = openstack_networking_secgroup_v2.submariner_gw.id
+  direction         = "ingress"
+  ethertype         = "IPv6"
+  protocol          = "udp"
+  port_range_min    = 4500
+  port_range_max    = 4500
+  remote_ip_prefix  = "2001:db8::/32"
+}
+
+resource "openstack_networking_secgroup_rule_v2" "public_ipv6_udp_4490-4495" {
+  security_group_id = openstack_networking_secgroup_v2.submariner_gw.id
+  direction         = "ingress"
+  ethertype         = "IPv6"
+  protocol          = "udp"
+  port_range_min    = 4490
+  port_range_max    = 4495
+  remote_ip_prefix  = "2001:db8::/32"
+}
+
+resource "openstack_compute_instance_v2" "gateway_0" {
+  name            = "test-infra-submariner-gw-0"
+  image_name      = "test-image"
+  flavor_name     = "test-type"
+  security_groups = ["test-infra-worker", openstack_networking_secgroup_v2.submariner_gw.name, openstack_networking_secgroup_v2.submariner_internal.name]
+  user_data       = "{\"ignition\":{\"config\":{\"merge\":[{\"source\":\"$${ignition_url}\"}]}}}"
+  metadata        = {
+    "Name"               = "test-infra-worker"
+    "openshiftClusterID" = "test-infra"
+  }
+  tags            = ["openshiftClusterID=test-infra", "submariner-io-gateway-node"]
+
+  network {
+    name = "test-infra-openshift"
+  }
+}
+
+resource "openstack_compute_instance_v2" "gateway_1" {
+  name            = "test-infra-submariner-gw-1"
+  image_name      = "test-image"
+  flavor_name     = "test-type"
+  security_groups = ["test-infra-worker", openstack_networking_secgroup_v2.submariner_gw.name, openstack_networking_secgroup_v2.submariner_internal.name]
+  user_data       = "{\"ignition\":{\"config\":{\"merge\":[{\"source\":\"$${ignition_url}\"}]}}}"
+  metadata        = {
+    "Name"               = "test-infra-worker"
+    "openshiftClusterID" = "test-infra"
+  }
+  tags            = ["openshiftClusterID=test-infra", "submariner-io-gateway-node"]
+
+  network {
+    name = "test-infra-openshift"
+  }
+}